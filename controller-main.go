@@ -127,6 +127,7 @@ func genAuthFirstTime() (*AuthConfig, *probe.Error) {
 		Name:            "admin",
 		AccessKeyID:     "admin",
 		SecretAccessKey: string(mustGenerateSecretAccessKey()),
+		Admin:           true,
 	}
 	config.Users["user"] = &AuthUser{
 		Name:            "user",
@@ -152,7 +153,11 @@ type accessKeys struct {
 }
 
 func (a accessKeys) String() string {
-	return colorizeMessage(fmt.Sprintf("Username: %s, AccessKey: %s, SecretKey: %s", a.Name, a.AccessKeyID, a.SecretAccessKey))
+	message := fmt.Sprintf("Username: %s, AccessKey: %s, SecretKey: %s", a.Name, a.AccessKeyID, a.SecretAccessKey)
+	if a.Secondary != nil {
+		message += fmt.Sprintf(", SecondaryAccessKey: %s, SecondarySecretKey: %s", a.Secondary.AccessKeyID, a.Secondary.SecretAccessKey)
+	}
+	return colorizeMessage(message)
 }
 
 // JSON - json formatted output
@@ -85,9 +85,6 @@ func stripRPCAccessKeyID(authHeaderValue string) (string, *probe.Error) {
 	if err != nil {
 		return "", err.Trace()
 	}
-	if credentialElements[0] != "admin" {
-		return "", probe.NewError(errAccessKeyIDInvalid)
-	}
 	return credentialElements[0], nil
 }
 
@@ -106,17 +103,16 @@ func initSignatureRPC(req *http.Request) (*rpcSignature, *probe.Error) {
 	authFields := strings.Split(strings.TrimSpace(authHeaderValue), ",")
 	signedHeaders := strings.Split(strings.Split(strings.TrimSpace(authFields[1]), "=")[1], ";")
 	signature := strings.Split(strings.TrimSpace(authFields[2]), "=")[1]
-	for _, user := range authConfig.Users {
-		if user.AccessKeyID == accessKeyID {
-			signature := &rpcSignature{
-				AccessKeyID:     user.AccessKeyID,
-				SecretAccessKey: user.SecretAccessKey,
-				Signature:       signature,
-				SignedHeaders:   signedHeaders,
-				Request:         req,
-			}
-			return signature, nil
-		}
-	}
-	return nil, probe.NewError(errAccessKeyIDInvalid)
+	// rpc is reserved to admin users, whatever their access key id
+	user, ok := findAuthUser(authConfig, accessKeyID)
+	if !ok || !user.Admin {
+		return nil, probe.NewError(errAccessKeyIDInvalid)
+	}
+	return &rpcSignature{
+		AccessKeyID:     user.AccessKeyID,
+		SecretAccessKey: user.SecretAccessKey,
+		Signature:       signature,
+		SignedHeaders:   signedHeaders,
+		Request:         req,
+	}, nil
 }
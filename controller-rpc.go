@@ -24,6 +24,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/gorilla/rpc/v2/json"
 	"github.com/minio/minio-xl/pkg/probe"
@@ -67,6 +68,7 @@ func generateAuth(args *AuthArgs, reply *AuthRep) *probe.Error {
 		AccessKeyID:     string(accessKeyID),
 		SecretAccessKey: string(secretAccessKey),
 	}
+	auditAuth(config, args.User, authActionGenerate, string(accessKeyID))
 	if err := SaveConfig(config); err != nil {
 		return err.Trace()
 	}
@@ -109,12 +111,182 @@ func resetAuth(args *AuthArgs, reply *AuthRep) *probe.Error {
 	reply.SecretAccessKey = string(secretAccessKey)
 	reply.Name = args.User
 
-	config.Users[args.User] = &AuthUser{
-		Name:            args.User,
+	// permissions of the user are kept as is, all previous keys are invalidated right away
+	config.Users[args.User].AccessKeyID = string(accessKeyID)
+	config.Users[args.User].SecretAccessKey = string(secretAccessKey)
+	config.Users[args.User].Secondary = nil
+	auditAuth(config, args.User, authActionReset, string(accessKeyID))
+	return SaveConfig(config).Trace()
+}
+
+// setRotateAuthReply fill reply with current keys of the user
+func setRotateAuthReply(user *AuthUser, reply *RotateAuthRep) {
+	reply.Name = user.Name
+	reply.AccessKeyID = user.AccessKeyID
+	reply.SecretAccessKey = user.SecretAccessKey
+	reply.Secondary = user.Secondary
+}
+
+// addAuth generate secondary auth keys for a user, accepted along with the primary keys
+func addAuth(args *RotateAuthArgs, reply *RotateAuthRep) *probe.Error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err.Trace()
+	}
+	user, ok := config.Users[args.User]
+	if !ok {
+		return probe.NewError(errors.New("User not found"))
+	}
+	if user.Secondary != nil && !user.Secondary.isExpired() {
+		return probe.NewError(errors.New("Secondary credentials already set, if you wish to change this invoke Revoke() method"))
+	}
+	accessKeyID, err := generateAccessKeyID()
+	if err != nil {
+		return err.Trace()
+	}
+	secretAccessKey, err := generateSecretAccessKey()
+	if err != nil {
+		return err.Trace()
+	}
+	user.Secondary = &AuthKey{
 		AccessKeyID:     string(accessKeyID),
 		SecretAccessKey: string(secretAccessKey),
 	}
-	return SaveConfig(config).Trace()
+	auditAuth(config, args.User, authActionAdd, string(accessKeyID))
+	if err := SaveConfig(config); err != nil {
+		return err.Trace()
+	}
+	setRotateAuthReply(user, reply)
+	return nil
+}
+
+// promoteAuth swap primary and secondary auth keys for a user, previous primary keys expire after grace
+func promoteAuth(args *RotateAuthArgs, reply *RotateAuthRep) *probe.Error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err.Trace()
+	}
+	user, ok := config.Users[args.User]
+	if !ok {
+		return probe.NewError(errors.New("User not found"))
+	}
+	if user.Secondary == nil || user.Secondary.isExpired() {
+		return probe.NewError(errors.New("Secondary credentials not found, if you wish to add them invoke Add() method"))
+	}
+	if args.Grace < 0 {
+		return probe.NewError(errors.New("Invalid grace period"))
+	}
+	previous := &AuthKey{
+		AccessKeyID:     user.AccessKeyID,
+		SecretAccessKey: user.SecretAccessKey,
+	}
+	if args.Grace > 0 {
+		previous.Expiry = time.Now().UTC().Add(time.Duration(args.Grace) * time.Second)
+	}
+	user.AccessKeyID = user.Secondary.AccessKeyID
+	user.SecretAccessKey = user.Secondary.SecretAccessKey
+	user.Secondary = previous
+	auditAuth(config, args.User, authActionPromote, user.AccessKeyID)
+	if err := SaveConfig(config); err != nil {
+		return err.Trace()
+	}
+	setRotateAuthReply(user, reply)
+	return nil
+}
+
+// revokeAuth remove secondary auth keys for a user
+func revokeAuth(args *RotateAuthArgs, reply *RotateAuthRep) *probe.Error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err.Trace()
+	}
+	user, ok := config.Users[args.User]
+	if !ok {
+		return probe.NewError(errors.New("User not found"))
+	}
+	if user.Secondary == nil {
+		return probe.NewError(errors.New("Secondary credentials not found"))
+	}
+	auditAuth(config, args.User, authActionRevoke, user.Secondary.AccessKeyID)
+	user.Secondary = nil
+	if err := SaveConfig(config); err != nil {
+		return err.Trace()
+	}
+	setRotateAuthReply(user, reply)
+	return nil
+}
+
+// fetchAuthAudit fetch changes to auth keys for a user, or for all users if none given
+func fetchAuthAudit(args *AuthArgs, reply *AuthAuditRep) *probe.Error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err.Trace()
+	}
+	reply.Entries = []AuthAuditEntry{}
+	for _, entry := range config.Audit {
+		if args.User == "" || entry.User == args.User {
+			reply.Entries = append(reply.Entries, entry)
+		}
+	}
+	return nil
+}
+
+// generateSessionAuth generate temporary auth keys acting for a user
+func generateSessionAuth(args *SessionAuthArgs, reply *SessionAuthRep) *probe.Error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err.Trace()
+	}
+	if _, ok := config.Users[args.User]; !ok {
+		return probe.NewError(errors.New("User not found"))
+	}
+	duration := defaultSessionDuration
+	if args.Duration != 0 {
+		duration = time.Duration(args.Duration) * time.Second
+	}
+	if duration < minSessionDuration || duration > maxSessionDuration {
+		return probe.NewError(errors.New("Invalid duration, must be between " + minSessionDuration.String() + " and " + maxSessionDuration.String()))
+	}
+	accessKeyID, err := generateAccessKeyID()
+	if err != nil {
+		return err.Trace()
+	}
+	secretAccessKey, err := generateSecretAccessKey()
+	if err != nil {
+		return err.Trace()
+	}
+	sessionToken, err := generateSessionToken()
+	if err != nil {
+		return err.Trace()
+	}
+	session := &AuthSession{
+		AccessKeyID:     string(accessKeyID),
+		SecretAccessKey: string(secretAccessKey),
+		SessionToken:    sessionToken,
+		User:            config.Users[args.User].Name,
+		Expiry:          time.Now().UTC().Add(duration),
+		ReadOnly:        args.ReadOnly,
+		Buckets:         args.Buckets,
+	}
+	if config.Sessions == nil {
+		config.Sessions = make(map[string]*AuthSession)
+	}
+	// expired sessions are of no use anymore, drop them while here
+	for key, s := range config.Sessions {
+		if s.isExpired() {
+			delete(config.Sessions, key)
+		}
+	}
+	config.Sessions[session.AccessKeyID] = session
+	if err := SaveConfig(config); err != nil {
+		return err.Trace()
+	}
+	reply.Name = args.User
+	reply.AccessKeyID = session.AccessKeyID
+	reply.SecretAccessKey = session.SecretAccessKey
+	reply.SessionToken = session.SessionToken
+	reply.Expiry = session.Expiry
+	return nil
 }
 
 // Generate auth keys
@@ -150,6 +322,58 @@ func (s *controllerRPCService) ResetAuth(r *http.Request, args *AuthArgs, reply
 	return nil
 }
 
+// Add auth keys, generates secondary auth keys to rotate in
+func (s *controllerRPCService) AddAuth(r *http.Request, args *RotateAuthArgs, reply *RotateAuthRep) error {
+	if strings.TrimSpace(args.User) == "" {
+		return errors.New("Invalid argument")
+	}
+	if err := addAuth(args, reply); err != nil {
+		return probe.WrapError(err)
+	}
+	return nil
+}
+
+// Promote auth keys, secondary auth keys become primary
+func (s *controllerRPCService) PromoteAuth(r *http.Request, args *RotateAuthArgs, reply *RotateAuthRep) error {
+	if strings.TrimSpace(args.User) == "" {
+		return errors.New("Invalid argument")
+	}
+	if err := promoteAuth(args, reply); err != nil {
+		return probe.WrapError(err)
+	}
+	return nil
+}
+
+// Revoke auth keys, secondary auth keys are no longer accepted
+func (s *controllerRPCService) RevokeAuth(r *http.Request, args *RotateAuthArgs, reply *RotateAuthRep) error {
+	if strings.TrimSpace(args.User) == "" {
+		return errors.New("Invalid argument")
+	}
+	if err := revokeAuth(args, reply); err != nil {
+		return probe.WrapError(err)
+	}
+	return nil
+}
+
+// Fetch auth audit, changes to auth keys
+func (s *controllerRPCService) FetchAuthAudit(r *http.Request, args *AuthArgs, reply *AuthAuditRep) error {
+	if err := fetchAuthAudit(args, reply); err != nil {
+		return probe.WrapError(err)
+	}
+	return nil
+}
+
+// Generate temporary auth keys, expire after requested duration
+func (s *controllerRPCService) GenerateSessionAuth(r *http.Request, args *SessionAuthArgs, reply *SessionAuthRep) error {
+	if strings.TrimSpace(args.User) == "" {
+		return errors.New("Invalid argument")
+	}
+	if err := generateSessionAuth(args, reply); err != nil {
+		return probe.WrapError(err)
+	}
+	return nil
+}
+
 func readAuthConfig() (*AuthConfig, *probe.Error) {
 	authConfig, err := LoadConfig()
 	if err != nil {
@@ -23,8 +23,11 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/gorilla/rpc/v2/json"
+	"github.com/minio/minio-xl/pkg/donut"
 	. "gopkg.in/check.v1"
 )
 
@@ -52,12 +55,22 @@ func (s *ControllerRPCSuite) SetUpSuite(c *C) {
 	secretAccessKey, perr := generateSecretAccessKey()
 	c.Assert(perr, IsNil)
 
+	// admin keys are not necessarily named 'admin', once rotated for instance
+	accessKeyID, perr := generateAccessKeyID()
+	c.Assert(perr, IsNil)
+
 	authConf := &AuthConfig{}
 	authConf.Users = make(map[string]*AuthUser)
 	authConf.Users["admin"] = &AuthUser{
 		Name:            "admin",
-		AccessKeyID:     "admin",
+		AccessKeyID:     string(accessKeyID),
 		SecretAccessKey: string(secretAccessKey),
+		Admin:           true,
+	}
+	authConf.Users["user"] = &AuthUser{
+		Name:            "user",
+		AccessKeyID:     string(mustGenerateAccessKeyID()),
+		SecretAccessKey: string(mustGenerateSecretAccessKey()),
 	}
 	s.config = authConf
 
@@ -66,7 +79,7 @@ func (s *ControllerRPCSuite) SetUpSuite(c *C) {
 	c.Assert(perr, IsNil)
 
 	testControllerRPC = httptest.NewServer(getControllerRPCHandler(false))
-	testServerRPC = httptest.NewUnstartedServer(getServerRPCHandler(false))
+	testServerRPC = httptest.NewUnstartedServer(getServerRPCHandler(false, nil))
 	testServerRPC.Config.Addr = ":9002"
 	testServerRPC.Start()
 
@@ -99,6 +112,35 @@ func (s *ControllerRPCSuite) TestMemStats(c *C) {
 	c.Assert(reply, Not(DeepEquals), MemStatsRep{})
 }
 
+func (s *ControllerRPCSuite) TestServerCacheStats(c *C) {
+	conf := &donut.Config{}
+	conf.Version = "0.0.1"
+	conf.MaxSize = 100000
+	donut.SetDonutConfigPath(filepath.Join(s.root, "donut.json"))
+	c.Assert(donut.SaveConfig(conf), IsNil)
+	d, perr := donut.New()
+	c.Assert(perr, IsNil)
+	server := httptest.NewServer(getServerRPCHandler(false, d))
+	defer server.Close()
+
+	op := rpcOperation{
+		Method:  "Server.MemStats",
+		Request: ServerArg{},
+	}
+	req, err := newRPCRequest(s.config, server.URL+"/rpc", op, http.DefaultTransport)
+	c.Assert(err, IsNil)
+	resp, err := req.Do()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	var reply MemStatsRep
+	c.Assert(json.DecodeClientResponse(resp.Body, &reply), IsNil)
+	resp.Body.Close()
+	c.Assert(reply.Total > 0, Equals, true)
+	c.Assert(reply.Cache.MaxSize, Equals, uint64(100000))
+	c.Assert(reply.Cache.Items, Equals, 0)
+}
+
 func (s *ControllerRPCSuite) TestDiskStats(c *C) {
 	op := rpcOperation{
 		Method:  "Controller.GetServerDiskStats",
@@ -255,3 +297,193 @@ func (s *ControllerRPCSuite) TestAuth(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
 }
+
+func (s *ControllerRPCSuite) TestSessionAuth(c *C) {
+	op := rpcOperation{
+		Method:  "Controller.GenerateSessionAuth",
+		Request: SessionAuthArgs{User: "admin", Duration: 900, ReadOnly: true, Buckets: []string{"bucket"}},
+	}
+	req, err := newRPCRequest(s.config, testControllerRPC.URL+"/rpc", op, http.DefaultTransport)
+	c.Assert(err, IsNil)
+	c.Assert(req.Get("Content-Type"), Equals, "application/json")
+	resp, err := req.Do()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	var reply SessionAuthRep
+	c.Assert(json.DecodeClientResponse(resp.Body, &reply), IsNil)
+	resp.Body.Close()
+	c.Assert(len(reply.AccessKeyID), Equals, 20)
+	c.Assert(len(reply.SecretAccessKey), Equals, 40)
+	c.Assert(len(reply.SessionToken), Not(Equals), 0)
+	c.Assert(reply.Name, Equals, "admin")
+	c.Assert(reply.Expiry.After(time.Now().UTC()), Equals, true)
+
+	user, perr := getAuthUser(reply.AccessKeyID)
+	c.Assert(perr, IsNil)
+	c.Assert(user.Name, Equals, "admin")
+	c.Assert(user.SecretAccessKey, Equals, reply.SecretAccessKey)
+	c.Assert(verifySession(user, reply.SessionToken), IsNil)
+	c.Assert(verifySession(user, "").ToGoError(), Equals, errInvalidSessionToken)
+	c.Assert(user.Session.isAllowed("bucket", "object", readPermission), Equals, true)
+	c.Assert(user.Session.isAllowed("bucket", "object", writePermission), Equals, false)
+	c.Assert(user.Session.isAllowed("other", "object", readPermission), Equals, false)
+	// policy of the session applies even though it acts for an admin
+	c.Assert(user.Admin, Equals, false)
+	c.Assert(API{}.isUserAllowed(user, "bucket", "object", writePermission), Equals, false)
+	c.Assert(API{}.isUserAllowed(user, "other", "object", readPermission), Equals, false)
+
+	user.Session.Expiry = time.Now().UTC().Add(-time.Minute)
+	c.Assert(verifySession(user, reply.SessionToken).ToGoError(), Equals, errSessionExpired)
+
+	// these operations should fail
+
+	/// duration out of limits
+	op = rpcOperation{
+		Method:  "Controller.GenerateSessionAuth",
+		Request: SessionAuthArgs{User: "admin", Duration: 60},
+	}
+	req, err = newRPCRequest(s.config, testControllerRPC.URL+"/rpc", op, http.DefaultTransport)
+	c.Assert(err, IsNil)
+	resp, err = req.Do()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+
+	/// unknown user
+	op = rpcOperation{
+		Method:  "Controller.GenerateSessionAuth",
+		Request: SessionAuthArgs{User: "nouser"},
+	}
+	req, err = newRPCRequest(s.config, testControllerRPC.URL+"/rpc", op, http.DefaultTransport)
+	c.Assert(err, IsNil)
+	resp, err = req.Do()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+func (s *ControllerRPCSuite) TestAdminOnly(c *C) {
+	// requests signed with keys of a user who is not an admin are refused
+	user := *s.config.Users["user"]
+	user.Admin = true
+	config := &AuthConfig{Users: map[string]*AuthUser{"user": &user}}
+	op := rpcOperation{
+		Method:  "Controller.FetchAuth",
+		Request: AuthArgs{User: "admin"},
+	}
+	req, err := newRPCRequest(config, testControllerRPC.URL+"/rpc", op, http.DefaultTransport)
+	c.Assert(err, IsNil)
+	resp, err := req.Do()
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusForbidden)
+
+	// requests are not signed without keys of an admin
+	_, err = newRPCRequest(&AuthConfig{Users: map[string]*AuthUser{"user": s.config.Users["user"]}}, testControllerRPC.URL+"/rpc", op, http.DefaultTransport)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *ControllerRPCSuite) TestOldConfig(c *C) {
+	defer func() { c.Assert(SaveConfig(s.config), IsNil) }()
+
+	// users.json as written before users had permissions, keys of 'admin' keep working
+	admin := s.config.Users["admin"]
+	oldConfig := `{
+	"Version": "0.0.1",
+	"Users": {
+		"admin": {"name": "admin", "accessKeyId": "` + admin.AccessKeyID + `", "secretAccessKey": "` + admin.SecretAccessKey + `"}
+	}
+}`
+	c.Assert(ioutil.WriteFile(filepath.Join(s.root, "users.json"), []byte(oldConfig), 0600), IsNil)
+
+	config, perr := LoadConfig()
+	c.Assert(perr, IsNil)
+	op := rpcOperation{
+		Method:  "Controller.GetServerMemStats",
+		Request: ControllerArgs{Host: s.url.Host},
+	}
+	req, err := newRPCRequest(config, testControllerRPC.URL+"/rpc", op, http.DefaultTransport)
+	c.Assert(err, IsNil)
+	resp, err := req.Do()
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+}
+
+func (s *ControllerRPCSuite) TestAuthRotation(c *C) {
+	rotate := func(method string, args interface{}, reply interface{}) int {
+		op := rpcOperation{
+			Method:  method,
+			Request: args,
+		}
+		req, err := newRPCRequest(s.config, testControllerRPC.URL+"/rpc", op, http.DefaultTransport)
+		c.Assert(err, IsNil)
+		c.Assert(req.Get("Content-Type"), Equals, "application/json")
+		resp, err := req.Do()
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			c.Assert(json.DecodeClientResponse(resp.Body, reply), IsNil)
+		}
+		return resp.StatusCode
+	}
+	var authReply AuthRep
+	c.Assert(rotate("Controller.GenerateAuth", AuthArgs{User: "rotateuser"}, &authReply), Equals, http.StatusOK)
+
+	var addReply RotateAuthRep
+	c.Assert(rotate("Controller.AddAuth", RotateAuthArgs{User: "rotateuser"}, &addReply), Equals, http.StatusOK)
+	c.Assert(addReply.AccessKeyID, Equals, authReply.AccessKeyID)
+	c.Assert(addReply.Secondary, NotNil)
+	c.Assert(len(addReply.Secondary.AccessKeyID), Equals, 20)
+	c.Assert(addReply.Secondary.Expiry.IsZero(), Equals, true)
+
+	/// adding while secondary keys are valid fails
+	c.Assert(rotate("Controller.AddAuth", RotateAuthArgs{User: "rotateuser"}, &RotateAuthRep{}), Equals, http.StatusBadRequest)
+
+	// both key pairs are accepted
+	user, perr := getAuthUser(authReply.AccessKeyID)
+	c.Assert(perr, IsNil)
+	c.Assert(user.SecretAccessKey, Equals, authReply.SecretAccessKey)
+	user, perr = getAuthUser(addReply.Secondary.AccessKeyID)
+	c.Assert(perr, IsNil)
+	c.Assert(user.Name, Equals, "rotateuser")
+	c.Assert(user.SecretAccessKey, Equals, addReply.Secondary.SecretAccessKey)
+
+	var promoteReply RotateAuthRep
+	c.Assert(rotate("Controller.PromoteAuth", RotateAuthArgs{User: "rotateuser", Grace: 3600}, &promoteReply), Equals, http.StatusOK)
+	c.Assert(promoteReply.AccessKeyID, Equals, addReply.Secondary.AccessKeyID)
+	c.Assert(promoteReply.Secondary.AccessKeyID, Equals, authReply.AccessKeyID)
+	c.Assert(promoteReply.Secondary.Expiry.After(time.Now().UTC()), Equals, true)
+	_, perr = getAuthUser(authReply.AccessKeyID)
+	c.Assert(perr, IsNil)
+
+	var revokeReply RotateAuthRep
+	c.Assert(rotate("Controller.RevokeAuth", RotateAuthArgs{User: "rotateuser"}, &revokeReply), Equals, http.StatusOK)
+	c.Assert(revokeReply.AccessKeyID, Equals, promoteReply.AccessKeyID)
+	c.Assert(revokeReply.Secondary, IsNil)
+	_, perr = getAuthUser(authReply.AccessKeyID)
+	c.Assert(perr.ToGoError(), Equals, errAccessKeyIDInvalid)
+
+	/// promoting without secondary keys fails
+	c.Assert(rotate("Controller.PromoteAuth", RotateAuthArgs{User: "rotateuser"}, &RotateAuthRep{}), Equals, http.StatusBadRequest)
+
+	// expired secondary keys are not accepted
+	config, perr := LoadConfig()
+	c.Assert(perr, IsNil)
+	config.Users["rotateuser"].Secondary = &AuthKey{
+		AccessKeyID:     authReply.AccessKeyID,
+		SecretAccessKey: authReply.SecretAccessKey,
+		Expiry:          time.Now().UTC().Add(-time.Minute),
+	}
+	c.Assert(SaveConfig(config), IsNil)
+	_, perr = getAuthUser(authReply.AccessKeyID)
+	c.Assert(perr.ToGoError(), Equals, errAccessKeyIDInvalid)
+
+	var auditReply AuthAuditRep
+	c.Assert(rotate("Controller.FetchAuthAudit", AuthArgs{User: "rotateuser"}, &auditReply), Equals, http.StatusOK)
+	c.Assert(len(auditReply.Entries), Equals, 4)
+	actions := []string{authActionGenerate, authActionAdd, authActionPromote, authActionRevoke}
+	for i, entry := range auditReply.Entries {
+		c.Assert(entry.User, Equals, "rotateuser")
+		c.Assert(entry.Action, Equals, actions[i])
+	}
+}
@@ -45,6 +45,25 @@ EXAMPLES:
       $ minio donut {{.Name}} operational-data /mnt/export1 /mnt/export2 /mnt/export3 /mnt/export4 /mnt/export5 \
        /mnt/export6 /mnt/export7 /mnt/export8 /mnt/export9 /mnt/export10 /mnt/export11 \
        /mnt/export12 /mnt/export13 /mnt/export14 /mnt/export15 /mnt/export16
+`,
+		},
+		{
+			Name:        "key",
+			Description: "add a new master key for server side encryption",
+			Action:      keyDonutMain,
+			CustomHelpTemplate: `NAME:
+  minio donut {{.Name}} - {{.Description}}
+
+USAGE:
+  minio donut {{.Name}}
+
+  New data keys are sealed with the new master key, previous master keys are kept to read
+  existing objects. Data keys of existing objects are resealed on next server start, after
+  which previous master keys may be removed from the key store.
+
+EXAMPLES:
+  1. Add a master key, or rotate master keys if there are any
+      $ minio donut {{.Name}}
 `,
 		},
 	}
@@ -78,6 +97,9 @@ func makeDonutMain(c *cli.Context) {
 			Fatalln(probe.NewError(err))
 		}
 	}
+	if err := donut.FormatDisks(donutName, disks); err != nil {
+		Fatalln(err.Trace())
+	}
 
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -98,3 +120,21 @@ func makeDonutMain(c *cli.Context) {
 
 	Infoln("Success!")
 }
+
+func keyDonutMain(c *cli.Context) {
+	if c.Args().Present() {
+		cli.ShowCommandHelpAndExit(c, "key", 1)
+	}
+	keys, err := donut.LoadKeyStore()
+	if err != nil {
+		Fatalln(err.Trace())
+	}
+	keyID, err := keys.AddKey()
+	if err != nil {
+		Fatalln(err.Trace())
+	}
+	if err := donut.SaveKeyStore(keys); err != nil {
+		Fatalln(err.Trace())
+	}
+	Infoln("Master key " + keyID + " added.")
+}
@@ -42,6 +42,11 @@ var (
 		Usage: "ADDRESS:PORT for management console access.",
 	}
 
+	addressWebsiteFlag = cli.StringFlag{
+		Name:  "address-website",
+		Usage: "ADDRESS:PORT for static website access, disabled if empty.",
+	}
+
 	ratelimitFlag = cli.IntFlag{
 		Name:  "ratelimit",
 		Hide:  true,
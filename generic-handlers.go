@@ -23,7 +23,7 @@ import (
 	"time"
 
 	router "github.com/gorilla/mux"
-	"github.com/rs/cors"
+	"github.com/minio/minio-xl/pkg/donut"
 )
 
 // MiddlewareHandler - useful to chain different middleware http.Handler
@@ -46,6 +46,11 @@ type resourceHandler struct {
 	handler http.Handler
 }
 
+type corsHandler struct {
+	handler http.Handler
+	donut   donut.Interface
+}
+
 func parseDate(req *http.Request) (time.Time, error) {
 	amzDate := req.Header.Get(http.CanonicalHeaderKey("x-amz-date"))
 	switch {
@@ -118,9 +123,62 @@ func (h timeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.handler.ServeHTTP(w, r)
 }
 
-// CorsHandler handler for CORS (Cross Origin Resource Sharing)
-func CorsHandler(h http.Handler) http.Handler {
-	return cors.Default().Handler(h)
+// CorsHandler handler for CORS (Cross Origin Resource Sharing), cross origin
+// requests are validated against the CORS configuration of the requested bucket
+func CorsHandler(api API) MiddlewareHandler {
+	return func(h http.Handler) http.Handler {
+		return corsHandler{handler: h, donut: api.Donut}
+	}
+}
+
+func (h corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+	if bucket == "" {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	// responses vary with the requesting origin
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	requestMethod := r.Header.Get("Access-Control-Request-Method")
+	isPreflight := r.Method == "OPTIONS" && origin != "" && requestMethod != ""
+	if origin == "" {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	var config CORSConfiguration
+	var found bool
+	bucketMetadata, err := h.donut.GetBucketMetadata(bucket)
+	if err == nil {
+		if data, ok := bucketMetadata.Metadata[corsMetadataKey]; ok {
+			config, found = parseCORSConfiguration([]byte(data))
+		}
+	}
+	if isPreflight {
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		headers := getCORSRequestHeaders(r)
+		if !found {
+			writeErrorResponse(w, r, CORSForbidden, r.URL.Path)
+			return
+		}
+		rule, ok := config.findRule(origin, requestMethod, headers)
+		if !ok {
+			writeErrorResponse(w, r, CORSForbidden, r.URL.Path)
+			return
+		}
+		setCORSPreflightHeaders(w, rule, origin, headers)
+		writeSuccessResponse(w)
+		return
+	}
+	if found {
+		if rule, ok := config.findRule(origin, r.Method, nil); ok {
+			setCORSHeaders(w, rule, origin)
+		}
+	}
+	h.handler.ServeHTTP(w, r)
 }
 
 // IgnoreResourcesHandler -
@@ -32,6 +32,7 @@ type minioConfig struct {
 	Address           string
 	ControllerAddress string
 	RPCAddress        string
+	WebsiteAddress    string
 	Anonymous         bool
 	TLS               bool
 	CertFile          string
@@ -99,6 +100,7 @@ func registerApp() *cli.App {
 	registerFlag(addressFlag)
 	registerFlag(addressControllerFlag)
 	registerFlag(addressServerRPCFlag)
+	registerFlag(addressWebsiteFlag)
 	registerFlag(ratelimitFlag)
 	registerFlag(anonymousFlag)
 	registerFlag(certFlag)
@@ -33,6 +33,11 @@ type File struct {
 
 // Close the file replacing, returns an error if any
 func (f *File) Close() error {
+	// contents are on stable storage before they are made visible
+	if err := f.File.Sync(); err != nil {
+		f.File.Close()
+		return err
+	}
 	// close the embedded fd
 	if err := f.File.Close(); err != nil {
 		return err
@@ -41,7 +46,8 @@ func (f *File) Close() error {
 	if err := os.Rename(f.Name(), f.file); err != nil {
 		return err
 	}
-	return nil
+	// rename itself is durable only once the directory is synced
+	return syncDir(filepath.Dir(f.file))
 }
 
 // CloseAndPurge removes the temp file, closes the transaction and returns an error if any
@@ -75,3 +81,31 @@ func FileCreate(filePath string) (*File, error) {
 	}
 	return &File{File: f, file: filePath}, nil
 }
+
+// Rename renames oldPath to newPath replacing it if it exists, parent directories of newPath are created
+// if they don't exist, returns once the rename is on stable storage
+func Rename(oldPath, newPath string) error {
+	if err := os.MkdirAll(filepath.Dir(newPath), 0700); err != nil {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(newPath)); err != nil {
+		return err
+	}
+	if filepath.Dir(oldPath) == filepath.Dir(newPath) {
+		return nil
+	}
+	return syncDir(filepath.Dir(oldPath))
+}
+
+// syncDir commits directory entries of a directory to stable storage
+func syncDir(dirPath string) error {
+	d, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -64,3 +64,15 @@ func (s *MySuite) TestAtomicPurge(c *C) {
 	err = f.Close()
 	c.Assert(err, Not(IsNil))
 }
+
+func (s *MySuite) TestRename(c *C) {
+	f, err := FileCreate(filepath.Join(s.root, "stagedfile"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	err = Rename(filepath.Join(s.root, "stagedfile"), filepath.Join(s.root, "committed", "file"))
+	c.Assert(err, IsNil)
+	_, err = os.Stat(filepath.Join(s.root, "stagedfile"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(filepath.Join(s.root, "committed", "file"))
+	c.Assert(err, IsNil)
+}
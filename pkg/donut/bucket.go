@@ -18,22 +18,22 @@ package donut
 
 import (
 	"bytes"
-	"fmt"
 	"hash"
 	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"crypto/cipher"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
 
 	"github.com/minio/minio-xl/pkg/crypto/sha256"
 	"github.com/minio/minio-xl/pkg/crypto/sha512"
-	"github.com/minio/minio-xl/pkg/donut/disk"
 	"github.com/minio/minio-xl/pkg/probe"
 	signv4 "github.com/minio/minio-xl/pkg/signature"
 )
@@ -50,6 +50,7 @@ type bucket struct {
 	donutName string
 	nodes     map[string]node
 	lock      *sync.Mutex
+	index     *bucketIndex
 }
 
 // newBucket - instantiate a new bucket
@@ -66,6 +67,7 @@ func newBucket(bucketName, aclType, donutName string, nodes map[string]node) (bu
 	b.donutName = donutName
 	b.nodes = nodes
 	b.lock = new(sync.Mutex)
+	b.index = new(bucketIndex)
 
 	metadata := BucketMetadata{}
 	metadata.Version = bucketMetadataVersion
@@ -73,7 +75,6 @@ func newBucket(bucketName, aclType, donutName string, nodes map[string]node) (bu
 	metadata.ACL = BucketACL(aclType)
 	metadata.Created = t
 	metadata.Metadata = make(map[string]string)
-	metadata.BucketObjects = make(map[string]struct{})
 
 	return b, metadata, nil
 }
@@ -83,53 +84,27 @@ func (b bucket) getBucketName() string {
 	return b.name
 }
 
-// getBucketMetadataReaders -
-func (b bucket) getBucketMetadataReaders() (map[int]io.ReadCloser, *probe.Error) {
-	readers := make(map[int]io.ReadCloser)
-	var disks map[int]disk.Disk
-	var err *probe.Error
-	for _, node := range b.nodes {
-		disks, err = node.ListDisks()
-		if err != nil {
-			return nil, err.Trace()
-		}
-	}
-	var bucketMetaDataReader io.ReadCloser
-	for order, disk := range disks {
-		bucketMetaDataReader, err = disk.Open(filepath.Join(b.donutName, bucketMetadataConfig))
-		if err != nil {
-			continue
-		}
-		readers[order] = bucketMetaDataReader
-	}
-	if err != nil {
-		return nil, err.Trace()
-	}
-	return readers, nil
+// bucketMetadataPath - path of the bucket metadata on a disk
+func (b bucket) bucketMetadataPath(disk nodeDisk) string {
+	return filepath.Join(b.donutName, disk.bucketSlice(b.name), bucketMetadataConfig)
 }
 
-// getBucketMetadata -
-func (b bucket) getBucketMetadata() (*AllBuckets, *probe.Error) {
-	metadata := new(AllBuckets)
-	var readers map[int]io.ReadCloser
-	{
-		var err *probe.Error
-		readers, err = b.getBucketMetadataReaders()
-		if err != nil {
-			return nil, err.Trace()
-		}
-	}
-	for _, reader := range readers {
-		defer reader.Close()
+// getBucketMetadata - read bucket metadata from the first disk it can be read from
+func (b bucket) getBucketMetadata() (BucketMetadata, *probe.Error) {
+	var metadata BucketMetadata
+	if err := b.decodeFile(b.bucketMetadataPath, &metadata); err != nil {
+		return BucketMetadata{}, err.Trace()
 	}
-	var err error
-	for _, reader := range readers {
-		jenc := json.NewDecoder(reader)
-		if err = jenc.Decode(metadata); err == nil {
-			return metadata, nil
-		}
+	return metadata, nil
+}
+
+// setBucketMetadata - write bucket metadata to all disks
+func (b bucket) setBucketMetadata(metadata BucketMetadata) *probe.Error {
+	writers, err := b.createWriters(b.bucketMetadataPath)
+	if err != nil {
+		return err.Trace()
 	}
-	return nil, probe.NewError(err)
+	return encodeWriters(writers, &metadata)
 }
 
 // GetObjectMetadata - get metadata for an object
@@ -139,70 +114,28 @@ func (b bucket) GetObjectMetadata(objectName string) (ObjectMetadata, *probe.Err
 	return b.readObjectMetadata(normalizeObjectName(objectName))
 }
 
-// ListObjects - list all objects
+// SetObjectMetadata - replace metadata for an object
+func (b bucket) SetObjectMetadata(objectName string, objMetadata ObjectMetadata) *probe.Error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.writeObjectMetadata(normalizeObjectName(objectName), objMetadata)
+}
+
+// ListObjects - list a page of objects, seeking through the bucket index
 func (b bucket) ListObjects(prefix, marker, delimiter string, maxkeys int) (ListObjectsResults, *probe.Error) {
 	b.lock.Lock()
 	defer b.lock.Unlock()
-	if maxkeys <= 0 {
-		maxkeys = 1000
-	}
-	var isTruncated bool
-	var objects []string
-	bucketMetadata, err := b.getBucketMetadata()
+	objectNames, commonPrefixes, nextMarker, isTruncated, err := listNames(b.indexNames, prefix, marker, delimiter, maxkeys)
 	if err != nil {
 		return ListObjectsResults{}, err.Trace()
 	}
-	for objectName := range bucketMetadata.Buckets[b.getBucketName()].Multiparts {
-		if strings.HasPrefix(objectName, strings.TrimSpace(prefix)) {
-			if objectName > marker {
-				objects = append(objects, objectName)
-			}
-		}
-	}
-	for objectName := range bucketMetadata.Buckets[b.getBucketName()].BucketObjects {
-		if strings.HasPrefix(objectName, strings.TrimSpace(prefix)) {
-			if objectName > marker {
-				objects = append(objects, objectName)
-			}
-		}
-	}
-	if strings.TrimSpace(prefix) != "" {
-		objects = TrimPrefix(objects, prefix)
-	}
-	var prefixes []string
-	var filteredObjects []string
-	filteredObjects = objects
-	if strings.TrimSpace(delimiter) != "" {
-		filteredObjects = HasNoDelimiter(objects, delimiter)
-		prefixes = HasDelimiter(objects, delimiter)
-		prefixes = SplitDelimiter(prefixes, delimiter)
-		prefixes = SortUnique(prefixes)
-	}
-	var results []string
-	var commonPrefixes []string
-
-	for _, commonPrefix := range prefixes {
-		commonPrefixes = append(commonPrefixes, prefix+commonPrefix)
-	}
-	filteredObjects = RemoveDuplicates(filteredObjects)
-	sort.Strings(filteredObjects)
-	for _, objectName := range filteredObjects {
-		if len(results) >= maxkeys {
-			isTruncated = true
-			break
-		}
-		results = append(results, prefix+objectName)
-	}
-	results = RemoveDuplicates(results)
-	commonPrefixes = RemoveDuplicates(commonPrefixes)
-	sort.Strings(commonPrefixes)
-
 	listObjects := ListObjectsResults{}
 	listObjects.Objects = make(map[string]ObjectMetadata)
 	listObjects.CommonPrefixes = commonPrefixes
+	listObjects.NextMarker = nextMarker
 	listObjects.IsTruncated = isTruncated
 
-	for _, objectName := range results {
+	for _, objectName := range objectNames {
 		objMetadata, err := b.readObjectMetadata(normalizeObjectName(objectName))
 		if err != nil {
 			return ListObjectsResults{}, err.Trace()
@@ -212,40 +145,68 @@ func (b bucket) ListObjects(prefix, marker, delimiter string, maxkeys int) (List
 	return listObjects, nil
 }
 
-// ReadObject - open an object to read
-func (b bucket) ReadObject(objectName string) (reader io.ReadCloser, size int64, err *probe.Error) {
+// ReadObject - open an object to read, encrypted objects are read only with the key they were encrypted with
+func (b bucket) ReadObject(objectName string, encryption *Encryption) (reader io.ReadCloser, size int64, err *probe.Error) {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 	reader, writer := io.Pipe()
-	// get list of objects
-	bucketMetadata, err := b.getBucketMetadata()
+	// check if object exists
+	exists, err := b.hasObject(objectName)
 	if err != nil {
 		return nil, 0, err.Trace()
 	}
-	// check if object exists
-	if _, ok := bucketMetadata.Buckets[b.getBucketName()].BucketObjects[objectName]; !ok {
+	if !exists {
 		return nil, 0, probe.NewError(ObjectNotFound{Object: objectName})
 	}
 	objMetadata, err := b.readObjectMetadata(normalizeObjectName(objectName))
 	if err != nil {
 		return nil, 0, err.Trace()
 	}
+	if err := verifyEncryption(objMetadata, encryption); err != nil {
+		return nil, 0, err.Trace()
+	}
 	// read and reply back to GetObject() request in a go-routine
-	go b.readObjectData(normalizeObjectName(objectName), writer, objMetadata)
+	go b.readObjectData(normalizeObjectName(objectName), writer, objMetadata, encryption)
 	return reader, objMetadata.Size, nil
 }
 
-// WriteObject - write a new object into bucket
-func (b bucket) WriteObject(objectName string, objectData io.Reader, size int64, expectedMD5Sum string, metadata map[string]string, signature *signv4.Signature) (ObjectMetadata, *probe.Error) {
+// WriteObject - write a new object into bucket, object is encrypted before erasure encoding if requested
+func (b bucket) WriteObject(objectName string, objectData io.Reader, size int64, expectedMD5Sum string, metadata map[string]string, signature *signv4.Signature, encryption *Encryption) (ObjectMetadata, *probe.Error) {
+	return b.writeObject(objectName, false, objectData, size, expectedMD5Sum, metadata, signature, encryption)
+}
+
+// WriteObjectPart - write a part of a multipart upload into bucket, parts are kept out of the object index
+func (b bucket) WriteObjectPart(objectName string, objectData io.Reader, size int64, expectedMD5Sum string, metadata map[string]string, signature *signv4.Signature) (ObjectMetadata, *probe.Error) {
+	return b.writeObject(objectName, true, objectData, size, expectedMD5Sum, metadata, signature, nil)
+}
+
+// writeObject - stage an object and commit it, objects are added to the index as part of the commit
+func (b bucket) writeObject(objectName string, part bool, objectData io.Reader, size int64, expectedMD5Sum string, metadata map[string]string, signature *signv4.Signature, encryption *Encryption) (ObjectMetadata, *probe.Error) {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 	if objectName == "" || objectData == nil {
 		return ObjectMetadata{}, probe.NewError(InvalidArgument{})
 	}
-	writers, err := b.getObjectWriters(normalizeObjectName(objectName), "data")
+	txID, err := newUUID()
 	if err != nil {
 		return ObjectMetadata{}, err.Trace()
 	}
+	objMetadata, staged, err := b.stageObject(txID, objectName, objectData, size, expectedMD5Sum, metadata, signature, encryption)
+	if err != nil {
+		return ObjectMetadata{}, err.Trace()
+	}
+	if err := b.commitObject(txID, objectName, part, staged); err != nil {
+		return ObjectMetadata{}, err.Trace()
+	}
+	return objMetadata, nil
+}
+
+// stageObject - write object data and metadata to the staging area of all disks, replies which disks have them staged
+func (b bucket) stageObject(txID, objectName string, objectData io.Reader, size int64, expectedMD5Sum string, metadata map[string]string, signature *signv4.Signature, encryption *Encryption) (ObjectMetadata, []bool, *probe.Error) {
+	writers, err := b.getStagingWriters(txID, "data")
+	if err != nil {
+		return ObjectMetadata{}, nil, err.Trace()
+	}
 	sumMD5 := md5.New()
 	sum512 := sha512.New()
 	var sum256 hash.Hash
@@ -260,28 +221,45 @@ func (b bucket) WriteObject(objectName string, objectData io.Reader, size int64,
 	objMetadata := ObjectMetadata{}
 	objMetadata.Version = objectMetadataVersion
 	objMetadata.Created = time.Now().UTC()
+	// checksums saved with the object, requests are verified with checksums of plain data regardless
+	dataMD5, data512 := sumMD5, sum512
+	if encryption != nil {
+		stream, err := encryption.encrypt(&objMetadata)
+		if err != nil {
+			b.abortStaging(txID, writers)
+			return ObjectMetadata{}, nil, err.Trace()
+		}
+		// only encrypted data is written to disks
+		objectData = cipher.StreamReader{S: stream, R: io.TeeReader(objectData, mwriter)}
+		mwriter = ioutil.Discard
+		// checksums of objects encrypted with customer keys are of encrypted data
+		if !encryption.isServerManaged() {
+			dataMD5, data512 = md5.New(), sha512.New()
+			mwriter = io.MultiWriter(dataMD5, data512)
+		}
+	}
 	// if total writers are only '1' do not compute erasure
 	switch len(writers) == 1 {
 	case true:
 		mw := io.MultiWriter(writers[0], mwriter)
 		totalLength, err := io.Copy(mw, objectData)
 		if err != nil {
-			CleanupWritersOnError(writers)
-			return ObjectMetadata{}, probe.NewError(err)
+			b.abortStaging(txID, writers)
+			return ObjectMetadata{}, nil, probe.NewError(err)
 		}
 		objMetadata.Size = totalLength
 	case false:
 		// calculate data and parity dictated by total number of writers
 		k, m, err := b.getDataAndParity(len(writers))
 		if err != nil {
-			CleanupWritersOnError(writers)
-			return ObjectMetadata{}, err.Trace()
+			b.abortStaging(txID, writers)
+			return ObjectMetadata{}, nil, err.Trace()
 		}
 		// write encoded data with k, m and writers
 		chunkCount, totalLength, err := b.writeObjectData(k, m, writers, objectData, size, mwriter)
 		if err != nil {
-			CleanupWritersOnError(writers)
-			return ObjectMetadata{}, err.Trace()
+			b.abortStaging(txID, writers)
+			return ObjectMetadata{}, nil, err.Trace()
 		}
 		/// donutMetadata section
 		objMetadata.BlockSize = blockSize
@@ -292,21 +270,21 @@ func (b bucket) WriteObject(objectName string, objectData io.Reader, size int64,
 	}
 	objMetadata.Bucket = b.getBucketName()
 	objMetadata.Object = objectName
-	dataMD5sum := sumMD5.Sum(nil)
-	dataSHA512sum := sum512.Sum(nil)
+	dataMD5sum := dataMD5.Sum(nil)
+	dataSHA512sum := data512.Sum(nil)
 	if signature != nil {
 		ok, err := signature.DoesSignatureMatch(hex.EncodeToString(sum256.Sum(nil)))
 		if err != nil {
 			// error occurred while doing signature calculation, we return and also cleanup any temporary writers.
-			CleanupWritersOnError(writers)
-			return ObjectMetadata{}, err.Trace()
+			b.abortStaging(txID, writers)
+			return ObjectMetadata{}, nil, err.Trace()
 		}
 		if !ok {
 			// purge all writers, when control flow reaches here
 			//
 			// Signature mismatch occurred all temp files to be removed and all data purged.
-			CleanupWritersOnError(writers)
-			return ObjectMetadata{}, probe.NewError(signv4.DoesNotMatch{})
+			b.abortStaging(txID, writers)
+			return ObjectMetadata{}, nil, probe.NewError(signv4.DoesNotMatch{})
 		}
 	}
 	objMetadata.MD5Sum = hex.EncodeToString(dataMD5sum)
@@ -314,22 +292,28 @@ func (b bucket) WriteObject(objectName string, objectData io.Reader, size int64,
 
 	// Verify if the written object is equal to what is expected, only if it is requested as such
 	if strings.TrimSpace(expectedMD5Sum) != "" {
-		if err := b.isMD5SumEqual(strings.TrimSpace(expectedMD5Sum), objMetadata.MD5Sum); err != nil {
-			return ObjectMetadata{}, err.Trace()
+		if err := b.isMD5SumEqual(strings.TrimSpace(expectedMD5Sum), hex.EncodeToString(sumMD5.Sum(nil))); err != nil {
+			b.abortStaging(txID, writers)
+			return ObjectMetadata{}, nil, err.Trace()
 		}
 	}
+	// object data is on stable storage before its metadata is staged
+	closeWriters(writers)
 	objMetadata.Metadata = metadata
-	// write object specific metadata
-	if err := b.writeObjectMetadata(normalizeObjectName(objectName), objMetadata); err != nil {
-		// purge all writers, when control flow reaches here
-		CleanupWritersOnError(writers)
-		return ObjectMetadata{}, err.Trace()
+	objMetadataWriters, err := b.getStagingWriters(txID, objectMetadataConfig)
+	if err != nil {
+		b.abortStaging(txID, nil)
+		return ObjectMetadata{}, nil, err.Trace()
 	}
-	// close all writers, when control flow reaches here
-	for _, writer := range writers {
-		writer.Close()
+	if err := encodeWriters(objMetadataWriters, &objMetadata); err != nil {
+		b.abortStaging(txID, nil)
+		return ObjectMetadata{}, nil, err.Trace()
 	}
-	return objMetadata, nil
+	staged := make([]bool, len(writers))
+	for i := range writers {
+		staged[i] = writers[i] != nil && objMetadataWriters[i] != nil
+	}
+	return objMetadata, staged, nil
 }
 
 // isMD5SumEqual - returns error if md5sum mismatches, other its `nil`
@@ -360,18 +344,7 @@ func (b bucket) writeObjectMetadata(objectName string, objMetadata ObjectMetadat
 	if err != nil {
 		return err.Trace()
 	}
-	for _, objMetadataWriter := range objMetadataWriters {
-		jenc := json.NewEncoder(objMetadataWriter)
-		if err := jenc.Encode(&objMetadata); err != nil {
-			// Close writers and purge all temporary entries
-			CleanupWritersOnError(objMetadataWriters)
-			return probe.NewError(err)
-		}
-	}
-	for _, objMetadataWriter := range objMetadataWriters {
-		objMetadataWriter.Close()
-	}
-	return nil
+	return encodeWriters(objMetadataWriters, &objMetadata)
 }
 
 // readObjectMetadata - read object metadata
@@ -452,6 +425,9 @@ func (b bucket) writeObjectData(k, m uint8, writers []io.WriteCloser, objectData
 				return 0, 0, probe.NewError(err)
 			}
 			for blockIndex, block := range encodedBlocks {
+				if writers[blockIndex] == nil {
+					continue
+				}
 				errCh := make(chan error, 1)
 				go func(writer io.Writer, reader io.Reader, errCh chan<- error) {
 					defer close(errCh)
@@ -459,6 +435,10 @@ func (b bucket) writeObjectData(k, m uint8, writers []io.WriteCloser, objectData
 					errCh <- err
 				}(writers[blockIndex], bytes.NewReader(block), errCh)
 				if err := <-errCh; err != nil {
+					// blocks lost with a failing disk are recovered from parity later
+					if dropWriter(writers, blockIndex) {
+						continue
+					}
 					// Returning error is fine here CleanupErrors() would cleanup writers
 					return 0, 0, probe.NewError(err)
 				}
@@ -474,7 +454,7 @@ func (b bucket) writeObjectData(k, m uint8, writers []io.WriteCloser, objectData
 }
 
 // readObjectData -
-func (b bucket) readObjectData(objectName string, writer *io.PipeWriter, objMetadata ObjectMetadata) {
+func (b bucket) readObjectData(objectName string, writer *io.PipeWriter, objMetadata ObjectMetadata, encryption *Encryption) {
 	readers, err := b.getObjectReaders(objectName, "data")
 	if err != nil {
 		writer.CloseWithError(probe.WrapError(err))
@@ -498,8 +478,21 @@ func (b bucket) readObjectData(objectName string, writer *io.PipeWriter, objMeta
 		}
 	}
 	hasher := md5.New()
-	sum512hasher := sha256.New()
+	sum512hasher := sha512.New()
 	mwriter := io.MultiWriter(writer, hasher, sum512hasher)
+	if objMetadata.IsEncrypted() {
+		stream, err := encryption.decrypt(objMetadata, 0)
+		if err != nil {
+			writer.CloseWithError(probe.WrapError(err))
+			return
+		}
+		// checksums of objects encrypted with customer keys are of encrypted data
+		if objMetadata.IsCustomerEncrypted() {
+			mwriter = io.MultiWriter(cipher.StreamWriter{S: stream, W: writer}, hasher, sum512hasher)
+		} else {
+			mwriter = cipher.StreamWriter{S: stream, W: mwriter}
+		}
+	}
 	switch len(readers) > 1 {
 	case true:
 		encoder, err := newEncoder(objMetadata.DataDisks, objMetadata.ParityDisks)
@@ -521,7 +514,7 @@ func (b bucket) readObjectData(objectName string, writer *io.PipeWriter, objMeta
 			totalLeft = totalLeft - int64(objMetadata.BlockSize)
 		}
 	case false:
-		_, err := io.Copy(writer, readers[0])
+		_, err := io.Copy(mwriter, readers[0])
 		if err != nil {
 			writer.CloseWithError(probe.WrapError(probe.NewError(err)))
 			return
@@ -588,52 +581,92 @@ func (b bucket) decodeEncodedData(totalLeft, blockSize int64, readers map[int]io
 
 // getObjectReaders -
 func (b bucket) getObjectReaders(objectName, objectMeta string) (map[int]io.ReadCloser, *probe.Error) {
-	readers := make(map[int]io.ReadCloser)
-	var disks map[int]disk.Disk
-	var err *probe.Error
-	nodeSlice := 0
-	for _, node := range b.nodes {
-		disks, err = node.ListDisks()
+	return b.openReaders(func(disk nodeDisk) string {
+		return filepath.Join(b.donutName, disk.bucketSlice(b.name), objectName, objectMeta)
+	})
+}
+
+// decodeFile - decode a file from the first disk it can be read from, disks are read one after another
+func (b bucket) decodeFile(diskPath func(disk nodeDisk) string, v interface{}) *probe.Error {
+	disks, err := listNodeDisks(b.nodes)
+	if err != nil {
+		return err.Trace()
+	}
+	var openErr, decodeErr *probe.Error
+	for _, disk := range disks {
+		reader, err := disk.Open(diskPath(disk))
 		if err != nil {
-			return nil, err.Trace()
-		}
-		for order, disk := range disks {
-			var objectSlice io.ReadCloser
-			bucketSlice := fmt.Sprintf("%s$%d$%d", b.name, nodeSlice, order)
-			objectPath := filepath.Join(b.donutName, bucketSlice, objectName, objectMeta)
-			objectSlice, err = disk.Open(objectPath)
-			if err == nil {
-				readers[order] = objectSlice
+			// a missing file is reported over a failing disk
+			if openErr == nil || os.IsNotExist(err.ToGoError()) {
+				openErr = err
 			}
+			continue
 		}
-		nodeSlice = nodeSlice + 1
+		jerr := json.NewDecoder(reader).Decode(v)
+		reader.Close()
+		if jerr == nil {
+			return nil
+		}
+		decodeErr = probe.NewError(jerr)
+	}
+	// a file which cannot be decoded is reported over a missing one
+	if decodeErr != nil {
+		return decodeErr.Trace()
 	}
+	return openErr.Trace()
+}
+
+// openReaders - readers for a file on all disks, at the path given by each disk
+func (b bucket) openReaders(diskPath func(disk nodeDisk) string) (map[int]io.ReadCloser, *probe.Error) {
+	readers := make(map[int]io.ReadCloser)
+	disks, err := listNodeDisks(b.nodes)
 	if err != nil {
 		return nil, err.Trace()
 	}
+	var openErr *probe.Error
+	for i, disk := range disks {
+		objectSlice, err := disk.Open(diskPath(disk))
+		if err != nil {
+			// a missing file is reported over a failing disk
+			if openErr == nil || os.IsNotExist(err.ToGoError()) {
+				openErr = err
+			}
+			continue
+		}
+		readers[i] = objectSlice
+	}
+	// failing disks are fine as long as one of them could be read
+	if len(readers) == 0 && openErr != nil {
+		return nil, openErr.Trace()
+	}
 	return readers, nil
 }
 
 // getObjectWriters -
 func (b bucket) getObjectWriters(objectName, objectMeta string) ([]io.WriteCloser, *probe.Error) {
-	var writers []io.WriteCloser
-	nodeSlice := 0
-	for _, node := range b.nodes {
-		disks, err := node.ListDisks()
+	return b.createWriters(func(disk nodeDisk) string {
+		return filepath.Join(b.donutName, disk.bucketSlice(b.name), objectName, objectMeta)
+	})
+}
+
+// createWriters - writers for a file on all disks, at the path given by each disk
+func (b bucket) createWriters(diskPath func(disk nodeDisk) string) ([]io.WriteCloser, *probe.Error) {
+	disks, err := listNodeDisks(b.nodes)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	writers := make([]io.WriteCloser, len(disks))
+	for i, disk := range disks {
+		objectSlice, err := disk.CreateFile(diskPath(disk))
 		if err != nil {
-			return nil, err.Trace()
-		}
-		writers = make([]io.WriteCloser, len(disks))
-		for order, disk := range disks {
-			bucketSlice := fmt.Sprintf("%s$%d$%d", b.name, nodeSlice, order)
-			objectPath := filepath.Join(b.donutName, bucketSlice, objectName, objectMeta)
-			objectSlice, err := disk.CreateFile(objectPath)
-			if err != nil {
-				return nil, err.Trace()
-			}
-			writers[order] = objectSlice
+			// failing disks are left out, their slices are recovered from parity
+			continue
 		}
-		nodeSlice = nodeSlice + 1
+		writers[i] = objectSlice
+	}
+	if !hasWriteQuorum(writers) {
+		CleanupWritersOnError(writers)
+		return nil, probe.NewError(InsufficientWriteQuorum{})
 	}
 	return writers, nil
 }
@@ -0,0 +1,193 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/minio/minio-xl/pkg/donut/disk"
+	. "gopkg.in/check.v1"
+)
+
+type MyBucketsSuite struct {
+	root string
+}
+
+var _ = Suite(&MyBucketsSuite{})
+
+func (s *MyBucketsSuite) SetUpSuite(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-buckets-")
+	c.Assert(err, IsNil)
+	s.root = root
+}
+
+func (s *MyBucketsSuite) TearDownSuite(c *C) {
+	os.RemoveAll(s.root)
+}
+
+// newCacheDonut - new donut without disks, with given bucket limit
+func newCacheDonut(configDir string, maxBuckets int) (Interface, error) {
+	conf := new(Config)
+	conf.Version = "0.0.1"
+	conf.MaxSize = 100000000
+	conf.MaxBuckets = maxBuckets
+	SetDonutConfigPath(filepath.Join(configDir, "donut.json"))
+	if err := SaveConfig(conf); err != nil {
+		return nil, err.ToGoError()
+	}
+	d, err := New()
+	if err != nil {
+		return nil, err.ToGoError()
+	}
+	return d, nil
+}
+
+func (s *MyBucketsSuite) TestManyBuckets(c *C) {
+	root, err := ioutil.TempDir(s.root, "donut-")
+	c.Assert(err, IsNil)
+	d, err := newCacheDonut(root, 0)
+	c.Assert(err, IsNil)
+	for i := 0; i < 1000; i++ {
+		c.Assert(d.MakeBucket(fmt.Sprintf("bucket-%04d", 999-i), "private", "", nil, nil), IsNil)
+	}
+	buckets, perr := d.ListBuckets()
+	c.Assert(perr, IsNil)
+	c.Assert(len(buckets), Equals, 1000)
+	for i, bucket := range buckets {
+		c.Assert(bucket.Name, Equals, fmt.Sprintf("bucket-%04d", i))
+	}
+}
+
+func (s *MyBucketsSuite) TestMaxBuckets(c *C) {
+	root, err := ioutil.TempDir(s.root, "donut-")
+	c.Assert(err, IsNil)
+	d, err := newCacheDonut(root, 2)
+	c.Assert(err, IsNil)
+	c.Assert(d.MakeBucket("bucket-1", "private", "", nil, nil), IsNil)
+	c.Assert(d.MakeBucket("bucket-2", "private", "", nil, nil), IsNil)
+	perr := d.MakeBucket("bucket-3", "private", "", nil, nil)
+	c.Assert(perr, Not(IsNil))
+	c.Assert(perr.ToGoError(), FitsTypeOf, TooManyBuckets{})
+}
+
+// benchBuckets - number of buckets benchmarks are run with
+const benchBuckets = 100000
+
+// newBenchDonut - donut over 4 memory disks, with given number of buckets made on them
+func newBenchDonut(b *testing.B, totalBuckets int) API {
+	n, err := newNode("localhost")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		dsk := disk.NewMemory("disk" + strconv.Itoa(i))
+		if err := dsk.MakeDir("test"); err != nil {
+			b.Fatal(err)
+		}
+		if err := n.AttachDisk(dsk, i); err != nil {
+			b.Fatal(err)
+		}
+	}
+	d := API{
+		config:        &Config{DonutName: "test"},
+		lock:          new(sync.Mutex),
+		nodes:         map[string]node{"localhost": n},
+		buckets:       make(map[string]bucket),
+		bucketsListed: new(bool),
+	}
+	for i := 0; i < totalBuckets; i++ {
+		if err := d.makeDonutBucket(fmt.Sprintf("bucket-%06d", i), "private", ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return d
+}
+
+func BenchmarkMakeBucket(b *testing.B) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-bench-")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	d, err := newCacheDonut(root, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := d.MakeBucket(fmt.Sprintf("bucket-%09d", i), "private", "", nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListBuckets(b *testing.B) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-bench-")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	d, err := newCacheDonut(root, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < benchBuckets; i++ {
+		if err := d.MakeBucket(fmt.Sprintf("bucket-%06d", i), "private", "", nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := d.ListBuckets(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLoadBuckets - buckets read from disks by New()
+func BenchmarkLoadBuckets(b *testing.B) {
+	d := newBenchDonut(b, benchBuckets)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.buckets = make(map[string]bucket)
+		*d.bucketsListed = false
+		buckets, err := d.listBuckets()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(buckets) != benchBuckets {
+			b.Fatalf("expected %d buckets, got %d", benchBuckets, len(buckets))
+		}
+	}
+}
+
+// BenchmarkMakeDonutBucket - bucket made on disks, with benchBuckets buckets already there
+func BenchmarkMakeDonutBucket(b *testing.B) {
+	d := newBenchDonut(b, benchBuckets)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := d.makeDonutBucket(fmt.Sprintf("new-bucket-%09d", i), "private", ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -25,6 +25,9 @@ import (
 
 var noExpiration = time.Duration(0)
 
+// maxMissedKeys - keys cache misses are counted for, counts start over once there are more
+const maxMissedKeys = 100000
+
 // Cache holds the required variables to compose an in memory cache system
 // which also provides expiring key mechanism and also maxSize
 type Cache struct {
@@ -44,23 +47,53 @@ type Cache struct {
 	// currentSize is a current size in memory
 	currentSize uint64
 
+	// expiration is how long new keys are kept for
+	expiration time.Duration
+
+	// nextExpire is when keys past their expiration are swept next, as the cache is used
+	nextExpire time.Time
+
+	// admitSize and admitMisses make up the admission policy for new keys
+	admitSize   uint64
+	admitMisses int
+
+	// missedKeys counts cache misses of keys which are not admitted yet
+	missedKeys map[interface{}]int
+
 	// OnEvicted - callback function for eviction
 	OnEvicted func(a ...interface{})
 
 	// totalEvicted counter to keep track of total expirations
 	totalEvicted int
+
+	// counters of expired keys, keys refused by the admission policy, cache hits and cache misses
+	totalExpired  int
+	totalRejected int
+	totalHits     uint64
+	totalMisses   uint64
 }
 
 // Stats current cache statistics
 type Stats struct {
-	Bytes   uint64
-	Items   int
-	Evicted int
+	MaxSize  uint64
+	Bytes    uint64
+	Items    int
+	Evicted  int
+	Expired  int
+	Rejected int
+	Hits     uint64
+	Misses   uint64
 }
 
 type element struct {
-	key   interface{}
-	value []byte
+	key     interface{}
+	value   []byte
+	expires time.Time
+}
+
+// expired - is element past its expiration
+func (e *element) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
 }
 
 // NewCache creates an inmemory cache
@@ -71,7 +104,9 @@ func NewCache(maxSize uint64) *Cache {
 	return &Cache{
 		items:        list.New(),
 		reverseItems: make(map[interface{}]*list.Element),
+		missedKeys:   make(map[interface{}]int),
 		maxSize:      maxSize,
+		expiration:   noExpiration,
 	}
 }
 
@@ -83,12 +118,66 @@ func (r *Cache) SetMaxSize(maxSize uint64) {
 	return
 }
 
-// Stats get current cache statistics
+// SetExpiration set how long keys set from now on are kept for, zero keeps them until evicted
+func (r *Cache) SetExpiration(expiration time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.expiration = expiration
+}
+
+// SetAdmission set the admission policy for new keys
+//
+// values no bigger than admitSize are always admitted, bigger ones only once their key
+// missed the cache admitMisses times, every value is admitted if admitMisses is zero
+func (r *Cache) SetAdmission(admitSize uint64, admitMisses int) {
+	r.Lock()
+	defer r.Unlock()
+	r.admitSize = admitSize
+	r.admitMisses = admitMisses
+}
+
+// Expire delete all keys past their expiration
+//
+// Get and Set delete them as well, at most once in the time keys are kept for, Append does not
+// to never expire a key while it is appended to
+func (r *Cache) Expire() {
+	r.Lock()
+	defer r.Unlock()
+	r.doExpireAll(time.Now())
+}
+
+// expireDue - delete all keys past their expiration if they were not deleted for as long as keys are kept for
+func (r *Cache) expireDue(now time.Time) {
+	if r.expiration == noExpiration || now.Before(r.nextExpire) {
+		return
+	}
+	r.nextExpire = now.Add(r.expiration)
+	r.doExpireAll(now)
+}
+
+func (r *Cache) doExpireAll(now time.Time) {
+	for ele := r.items.Back(); ele != nil; {
+		prev := ele.Prev()
+		if ele.Value.(*element).expired(now) {
+			r.doExpire(ele)
+		}
+		ele = prev
+	}
+}
+
+// Stats get current cache statistics, not to be called from OnEvicted
 func (r *Cache) Stats() Stats {
+	r.Lock()
+	defer r.Unlock()
 	return Stats{
-		Bytes:   r.currentSize,
-		Items:   r.items.Len(),
-		Evicted: r.totalEvicted,
+		MaxSize:  r.maxSize,
+		Bytes:    r.currentSize,
+		Items:    r.items.Len(),
+		Evicted:  r.totalEvicted,
+		Expired:  r.totalExpired,
+		Rejected: r.totalRejected,
+		Hits:     r.totalHits,
+		Misses:   r.totalMisses,
 	}
 }
 
@@ -96,10 +185,24 @@ func (r *Cache) Stats() Stats {
 func (r *Cache) Get(key interface{}) ([]byte, bool) {
 	r.Lock()
 	defer r.Unlock()
+	now := time.Now()
+	r.expireDue(now)
 	ele, hit := r.reverseItems[key]
+	if hit && ele.Value.(*element).expired(now) {
+		r.doExpire(ele)
+		hit = false
+	}
 	if !hit {
+		r.totalMisses++
+		if r.admitMisses > 0 {
+			if len(r.missedKeys) >= maxMissedKeys {
+				r.missedKeys = make(map[interface{}]int)
+			}
+			r.missedKeys[key]++
+		}
 		return nil, false
 	}
+	r.totalHits++
 	r.items.MoveToFront(ele)
 	return ele.Value.(*element).value, true
 }
@@ -121,23 +224,29 @@ func (r *Cache) Append(key interface{}, value []byte) bool {
 	r.Lock()
 	defer r.Unlock()
 	valueLen := uint64(len(value))
+	ele, hit := r.reverseItems[key]
 	if r.maxSize > 0 {
 		// check if the size of the object is not bigger than the
 		// capacity of the cache
 		if valueLen > r.maxSize {
 			return false
 		}
-		// remove random key if only we reach the maxSize threshold
-		for (r.currentSize + valueLen) > r.maxSize {
+	}
+	if !hit && !r.admit(key, valueLen) {
+		return false
+	}
+	if r.maxSize > 0 {
+		if hit {
+			r.items.MoveToFront(ele)
+		}
+		// remove oldest keys until the value fits, never the key appended to
+		for (r.currentSize+valueLen) > r.maxSize && r.items.Back() != ele {
 			r.doDeleteOldest()
-			break
 		}
 	}
-	ele, hit := r.reverseItems[key]
 	if !hit {
-		ele := r.items.PushFront(&element{key, value})
+		r.reverseItems[key] = r.items.PushFront(r.newElement(key, value))
 		r.currentSize += valueLen
-		r.reverseItems[key] = ele
 		return true
 	}
 	r.items.MoveToFront(ele)
@@ -150,6 +259,7 @@ func (r *Cache) Append(key interface{}, value []byte) bool {
 func (r *Cache) Set(key interface{}, value []byte) bool {
 	r.Lock()
 	defer r.Unlock()
+	r.expireDue(time.Now())
 	valueLen := uint64(len(value))
 	if r.maxSize > 0 {
 		// check if the size of the object is not bigger than the
@@ -157,15 +267,20 @@ func (r *Cache) Set(key interface{}, value []byte) bool {
 		if valueLen > r.maxSize {
 			return false
 		}
+	}
+	if _, hit := r.reverseItems[key]; hit {
+		return false
+	}
+	if !r.admit(key, valueLen) {
+		return false
+	}
+	if r.maxSize > 0 {
 		// remove random key if only we reach the maxSize threshold
 		for (r.currentSize + valueLen) > r.maxSize {
 			r.doDeleteOldest()
 		}
 	}
-	if _, hit := r.reverseItems[key]; hit {
-		return false
-	}
-	ele := r.items.PushFront(&element{key, value})
+	ele := r.items.PushFront(r.newElement(key, value))
 	r.currentSize += valueLen
 	r.reverseItems[key] = ele
 	return true
@@ -180,25 +295,51 @@ func (r *Cache) Delete(key interface{}) {
 		return
 	}
 	if ele != nil {
-		r.currentSize -= uint64(len(r.reverseItems[key].Value.(*element).value))
-		r.items.Remove(ele)
-		delete(r.reverseItems, key)
-		r.totalEvicted++
-		if r.OnEvicted != nil {
-			r.OnEvicted(key)
-		}
+		r.doDelete(ele, &r.totalEvicted)
+	}
+}
+
+// newElement - element holding a new key, expiring as set by SetExpiration
+func (r *Cache) newElement(key interface{}, value []byte) *element {
+	ele := &element{key: key, value: value}
+	if r.expiration != noExpiration {
+		ele.expires = time.Now().Add(r.expiration)
 	}
+	return ele
+}
+
+// admit - is a new key admitted by the admission policy, counts the keys refused
+func (r *Cache) admit(key interface{}, valueLen uint64) bool {
+	if r.admitMisses <= 0 || valueLen <= r.admitSize {
+		return true
+	}
+	if r.missedKeys[key] >= r.admitMisses {
+		delete(r.missedKeys, key)
+		return true
+	}
+	r.totalRejected++
+	return false
 }
 
 func (r *Cache) doDeleteOldest() {
 	ele := r.items.Back()
 	if ele != nil {
-		r.currentSize -= uint64(len(r.reverseItems[ele.Value.(*element).key].Value.(*element).value))
-		delete(r.reverseItems, ele.Value.(*element).key)
-		r.items.Remove(ele)
-		r.totalEvicted++
-		if r.OnEvicted != nil {
-			r.OnEvicted(ele.Value.(*element).key)
-		}
+		r.doDelete(ele, &r.totalEvicted)
+	}
+}
+
+func (r *Cache) doExpire(ele *list.Element) {
+	r.doDelete(ele, &r.totalExpired)
+}
+
+// doDelete - delete an element, counted by given counter before OnEvicted is called
+func (r *Cache) doDelete(ele *list.Element, counter *int) {
+	key := ele.Value.(*element).key
+	r.currentSize -= uint64(len(ele.Value.(*element).value))
+	delete(r.reverseItems, key)
+	r.items.Remove(ele)
+	*counter++
+	if r.OnEvicted != nil {
+		r.OnEvicted(key)
 	}
 }
@@ -18,6 +18,7 @@ package data
 
 import (
 	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -43,3 +44,97 @@ func (s *MySuite) TestCache(c *C) {
 	_, ok = cache.Get("filename")
 	c.Assert(ok, Equals, false)
 }
+
+func (s *MySuite) TestExpiration(c *C) {
+	cache := NewCache(1000)
+	var expired []interface{}
+	cache.OnEvicted = func(a ...interface{}) {
+		expired = append(expired, a[0])
+	}
+	cache.SetExpiration(50 * time.Millisecond)
+	c.Assert(cache.Set("expiring", []byte("Hello, world!")), Equals, true)
+	cache.SetExpiration(noExpiration)
+	c.Assert(cache.Set("lasting", []byte("Hello, world!")), Equals, true)
+
+	_, ok := cache.Get("expiring")
+	c.Assert(ok, Equals, true)
+	time.Sleep(100 * time.Millisecond)
+	_, ok = cache.Get("expiring")
+	c.Assert(ok, Equals, false)
+	_, ok = cache.Get("lasting")
+	c.Assert(ok, Equals, true)
+	c.Assert(expired, DeepEquals, []interface{}{"expiring"})
+
+	// expired keys are removed without being asked for
+	cache.SetExpiration(50 * time.Millisecond)
+	c.Assert(cache.Set("expiring", []byte("Hello, world!")), Equals, true)
+	time.Sleep(100 * time.Millisecond)
+	cache.Expire()
+	stats := cache.Stats()
+	c.Assert(stats.Items, Equals, 1)
+	c.Assert(stats.Expired, Equals, 2)
+	c.Assert(stats.Bytes, Equals, uint64(len("Hello, world!")))
+
+	// or once due, as other keys are asked for
+	c.Assert(cache.Set("expiring", []byte("Hello, world!")), Equals, true)
+	time.Sleep(100 * time.Millisecond)
+	_, ok = cache.Get("lasting")
+	c.Assert(ok, Equals, true)
+	stats = cache.Stats()
+	c.Assert(stats.Items, Equals, 1)
+	c.Assert(stats.Expired, Equals, 3)
+}
+
+func (s *MySuite) TestAdmission(c *C) {
+	cache := NewCache(1000)
+	cache.SetAdmission(5, 2)
+	big := []byte("Hello, world!")
+
+	// small values are admitted right away
+	_, ok := cache.Get("small")
+	c.Assert(ok, Equals, false)
+	c.Assert(cache.Append("small", []byte("Hello")), Equals, true)
+
+	// big values are admitted once asked for twice
+	_, ok = cache.Get("big")
+	c.Assert(ok, Equals, false)
+	c.Assert(cache.Append("big", big), Equals, false)
+	_, ok = cache.Get("big")
+	c.Assert(ok, Equals, false)
+	c.Assert(cache.Append("big", big), Equals, true)
+	data, ok := cache.Get("big")
+	c.Assert(ok, Equals, true)
+	c.Assert(data, DeepEquals, big)
+
+	// a one-off read of a big value does not flush the cache
+	_, ok = cache.Get("one-off")
+	c.Assert(ok, Equals, false)
+	c.Assert(cache.Set("one-off", make([]byte, 1000)), Equals, false)
+	_, ok = cache.Get("small")
+	c.Assert(ok, Equals, true)
+
+	stats := cache.Stats()
+	c.Assert(stats.Hits, Equals, uint64(2))
+	c.Assert(stats.Misses, Equals, uint64(4))
+	c.Assert(stats.Rejected, Equals, 2)
+	c.Assert(stats.Evicted, Equals, 0)
+}
+
+func (s *MySuite) TestEviction(c *C) {
+	cache := NewCache(10)
+	c.Assert(cache.Set("first", []byte("12345")), Equals, true)
+	c.Assert(cache.Set("second", []byte("12345")), Equals, true)
+
+	// appending evicts the oldest keys until it fits, never the key appended to
+	c.Assert(cache.Append("first", []byte("123")), Equals, true)
+	_, ok := cache.Get("second")
+	c.Assert(ok, Equals, false)
+	data, ok := cache.Get("first")
+	c.Assert(ok, Equals, true)
+	c.Assert(data, DeepEquals, []byte("12345123"))
+
+	stats := cache.Stats()
+	c.Assert(stats.MaxSize, Equals, uint64(10))
+	c.Assert(stats.Bytes, Equals, uint64(8))
+	c.Assert(stats.Evicted, Equals, 1)
+}
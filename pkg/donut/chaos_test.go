@@ -0,0 +1,182 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or impliedc.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/donut/disk"
+	"github.com/minio/minio-xl/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+type MyChaosSuite struct {
+	root string
+}
+
+var _ = Suite(&MyChaosSuite{})
+
+func (s *MyChaosSuite) SetUpSuite(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-chaos-")
+	c.Assert(err, IsNil)
+	s.root = root
+}
+
+func (s *MyChaosSuite) TearDownSuite(c *C) {
+	os.RemoveAll(s.root)
+}
+
+// chaosFaults - faults injected into the first disk, which holds the first data block of every chunk
+//
+// slices carry no checksums of their own, bit flips are detected by the object checksum but not corrected
+var chaosFaults = []struct {
+	name    string
+	faults  disk.Faults
+	corrupt bool
+}{
+	{name: "fail-open", faults: disk.Faults{FailOpen: true}},
+	{name: "short-read", faults: disk.Faults{ShortRead: true}},
+	{name: "bit-flip", faults: disk.Faults{BitFlip: true}, corrupt: true},
+	{name: "latency", faults: disk.Faults{Latency: time.Millisecond}},
+	{name: "offline", faults: disk.Faults{OfflineAfter: 4096}},
+}
+
+// newChaosDonut - new donut over given number of disks, faults are configured by disk order
+func (s *MyChaosSuite) newChaosDonut(c *C, totalDisks int, faults map[int]disk.Faults) (API, []string) {
+	root, err := ioutil.TempDir(s.root, "donut-")
+	c.Assert(err, IsNil)
+	diskPaths := make([]string, totalDisks)
+	for i := range diskPaths {
+		diskPaths[i] = filepath.Join(root, strconv.Itoa(i))
+		c.Assert(os.MkdirAll(diskPaths[i], 0700), IsNil)
+	}
+	conf := new(Config)
+	conf.Version = "0.0.1"
+	conf.DonutName = "test"
+	conf.NodeDiskMap = map[string][]string{"localhost": diskPaths}
+	conf.MaxSize = 100000000
+	conf.Faults = make(map[string]disk.Faults)
+	for order, f := range faults {
+		conf.Faults[diskPaths[order]] = f
+	}
+	SetDonutConfigPath(filepath.Join(root, "donut.json"))
+	c.Assert(SaveConfig(conf), IsNil)
+	d, perr := New()
+	c.Assert(perr, IsNil)
+	return d.(API), diskPaths
+}
+
+// injectFault - replace a disk of a running donut with a faulty one
+func injectFault(c *C, d API, order int, faults disk.Faults) {
+	n := d.nodes["localhost"]
+	disks, err := n.ListDisks()
+	c.Assert(err, IsNil)
+	c.Assert(n.AttachDisk(disk.NewFaulty(disks[order], faults), order), IsNil)
+}
+
+// assertChaosObject - object reads back intact, or fails with a checksum mismatch if corrupted
+func assertChaosObject(c *C, d API, object string, data []byte, corrupt bool, comment CommentInterface) {
+	var buffer bytes.Buffer
+	_, err := d.GetObject(&buffer, "bucket", object, 0, 0, nil)
+	if corrupt {
+		c.Assert(err, Not(IsNil), comment)
+		werr, ok := probe.UnwrapError(err.ToGoError())
+		c.Assert(ok, Equals, true, comment)
+		c.Assert(werr.ToGoError(), FitsTypeOf, ChecksumMismatch{}, comment)
+		return
+	}
+	c.Assert(err, IsNil, comment)
+	c.Assert(bytes.Equal(buffer.Bytes(), data), Equals, true, comment)
+}
+
+func (s *MyChaosSuite) TestChaos(c *C) {
+	data := make([]byte, 1024*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	for _, totalDisks := range []int{4, 8, 16} {
+		for _, fault := range chaosFaults {
+			comment := Commentf("%d disks, %s", totalDisks, fault.name)
+			d, _ := s.newChaosDonut(c, totalDisks, nil)
+			c.Assert(d.MakeBucket("bucket", "private", "", nil, nil), IsNil, comment)
+			_, err := d.CreateObject("bucket", "before", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+			c.Assert(err, IsNil, comment)
+
+			injectFault(c, d, 0, fault.faults)
+
+			_, err = d.CreateObject("bucket", "after", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+			c.Assert(err, IsNil, comment)
+			assertChaosObject(c, d, "before", data, fault.corrupt, comment)
+			c.Assert(d.Heal(), IsNil, comment)
+			assertChaosObject(c, d, "after", data, fault.corrupt, comment)
+		}
+	}
+}
+
+func (s *MyChaosSuite) TestHealDroppedSlices(c *C) {
+	d, diskPaths := s.newChaosDonut(c, 4, nil)
+	c.Assert(d.MakeBucket("bucket", "private", "", nil, nil), IsNil)
+	disks, err := d.nodes["localhost"].ListDisks()
+	c.Assert(err, IsNil)
+	firstDisk := disks[0]
+
+	// first disk goes offline while the object is written, its writer is dropped
+	injectFault(c, d, 0, disk.Faults{OfflineAfter: 4096})
+	data := make([]byte, 1024*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	_, perr := d.CreateObject("bucket", "object", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+	c.Assert(perr, IsNil)
+	slicePath := filepath.Join(diskPaths[0], "test", "bucket$0$0", "object")
+	_, e := os.Stat(filepath.Join(slicePath, "data"))
+	c.Assert(os.IsNotExist(e), Equals, true)
+
+	// disk is back, heal rebuilds its slice
+	c.Assert(d.nodes["localhost"].AttachDisk(firstDisk, 0), IsNil)
+	c.Assert(d.Heal(), IsNil)
+	_, e = os.Stat(filepath.Join(slicePath, "data"))
+	c.Assert(e, IsNil)
+	_, e = os.Stat(filepath.Join(slicePath, objectMetadataConfig))
+	c.Assert(e, IsNil)
+
+	// rebuilt slice is needed to read the object with as many disks failing as there are parity disks
+	injectFault(c, d, 1, disk.Faults{FailOpen: true})
+	injectFault(c, d, 2, disk.Faults{FailOpen: true})
+	d.objects.Delete("bucket/object")
+	assertChaosObject(c, d, "object", data, false, Commentf("healed"))
+}
+
+func (s *MyChaosSuite) TestFaultsFromConfig(c *C) {
+	d, diskPaths := s.newChaosDonut(c, 4, map[int]disk.Faults{0: {FailOpen: true}})
+	c.Assert(d.MakeBucket("bucket", "private", "", nil, nil), IsNil)
+	data := []byte("Hello World")
+	_, err := d.CreateObject("bucket", "object", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+	c.Assert(err, IsNil)
+	assertChaosObject(c, d, "object", data, false, Commentf("fail-open from config"))
+
+	// no slice reaches the faulty disk
+	_, e := os.Stat(filepath.Join(diskPaths[0], "test", "bucket$0$0", "object", "data"))
+	c.Assert(os.IsNotExist(e), Equals, true)
+	_, e = os.Stat(filepath.Join(diskPaths[1], "test", "bucket$0$1", "object", "data"))
+	c.Assert(e, IsNil)
+}
@@ -0,0 +1,240 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// commitMarker marks an object staged on a disk as committed, written once the object is staged on all disks
+type commitMarker struct {
+	Version string `json:"version"`
+	Bucket  string `json:"bucket"`
+	Object  string `json:"object"`
+	Part    bool   `json:"part,omitempty"` // parts of multipart uploads are kept out of the object index
+}
+
+// stagedFiles - files of a staged object, in the order they are moved into place
+var stagedFiles = []string{"data", objectMetadataConfig}
+
+// stagingPath - path of a file staged by a transaction
+func stagingPath(txID, name string) string {
+	return filepath.Join(stagingDir, txID, name)
+}
+
+// getStagingWriters - writers for a file staged by a transaction on all disks
+func (b bucket) getStagingWriters(txID, objectMeta string) ([]io.WriteCloser, *probe.Error) {
+	return b.createWriters(func(disk nodeDisk) string {
+		return stagingPath(txID, objectMeta)
+	})
+}
+
+// abortStaging - purge writers and discard everything staged by a transaction
+func (b bucket) abortStaging(txID string, writers []io.WriteCloser) {
+	CleanupWritersOnError(writers)
+	disks, err := listNodeDisks(b.nodes)
+	if err != nil {
+		return
+	}
+	// commit markers go first, a partially discarded transaction is never rolled forward
+	for _, disk := range disks {
+		disk.Delete(stagingPath(txID, commitConfig))
+	}
+	for _, disk := range disks {
+		disk.Delete(filepath.Join(stagingDir, txID))
+	}
+}
+
+// commitObject - move an object staged by a transaction into place on all disks it is staged on
+//
+// Once a commit marker is written to any of the disks the transaction is committed, if interrupted it
+// is rolled forward by recoverCommits. Object data is moved into place on all disks before any object
+// metadata, object metadata only ever refers to object data which is already in place. The object is
+// added to the index last, the transaction stays staged until it is.
+func (b bucket) commitObject(txID, objectName string, part bool, staged []bool) *probe.Error {
+	disks, err := listNodeDisks(b.nodes)
+	if err != nil {
+		b.abortStaging(txID, nil)
+		return err.Trace()
+	}
+	marker := commitMarker{
+		Version: commitVersion,
+		Bucket:  b.name,
+		Object:  objectName,
+		Part:    part,
+	}
+	var committed int
+	for i, disk := range disks {
+		if !staged[i] {
+			continue
+		}
+		if err := writeCommitMarker(disk, txID, marker); err != nil {
+			staged[i] = false
+			continue
+		}
+		committed++
+	}
+	if committed == 0 || committed < writeQuorum(len(disks)) {
+		b.abortStaging(txID, nil)
+		return probe.NewError(InsufficientWriteQuorum{})
+	}
+	for _, name := range stagedFiles {
+		for i, disk := range disks {
+			if !staged[i] {
+				continue
+			}
+			objectPath := filepath.Join(b.donutName, disk.bucketSlice(b.name), normalizeObjectName(objectName), name)
+			if err := disk.Rename(stagingPath(txID, name), objectPath); err != nil {
+				// left staged, rolled forward on next start
+				staged[i] = false
+			}
+		}
+	}
+	if !part {
+		if err := b.indexObject(objectName); err != nil {
+			// left staged, indexed when rolled forward on next start
+			return err.Trace(objectName)
+		}
+	}
+	for i, disk := range disks {
+		if staged[i] {
+			disk.Delete(filepath.Join(stagingDir, txID))
+		}
+	}
+	return nil
+}
+
+// writeCommitMarker - write commit marker of a transaction to a disk
+func writeCommitMarker(disk nodeDisk, txID string, marker commitMarker) *probe.Error {
+	writer, err := disk.CreateFile(stagingPath(txID, commitConfig))
+	if err != nil {
+		return err.Trace()
+	}
+	if err := json.NewEncoder(writer).Encode(&marker); err != nil {
+		writer.CloseAndPurge()
+		return probe.NewError(err)
+	}
+	if err := writer.Close(); err != nil {
+		return probe.NewError(err)
+	}
+	return nil
+}
+
+// readCommitMarker - read commit marker of a transaction from a disk
+func readCommitMarker(disk nodeDisk, txID string) (commitMarker, *probe.Error) {
+	reader, err := disk.Open(stagingPath(txID, commitConfig))
+	if err != nil {
+		return commitMarker{}, err.Trace()
+	}
+	defer reader.Close()
+	var marker commitMarker
+	if err := json.NewDecoder(reader).Decode(&marker); err != nil {
+		return commitMarker{}, probe.NewError(err)
+	}
+	return marker, nil
+}
+
+// recoverCommits - roll forward committed transactions interrupted by a crash, roll back all others
+func (donut API) recoverCommits() *probe.Error {
+	disks, err := listNodeDisks(donut.nodes)
+	if err != nil {
+		return err.Trace()
+	}
+	markers := make(map[string]commitMarker)
+	staged := make(map[string][]bool)
+	for i, disk := range disks {
+		dirs, err := disk.ListDir(stagingDir)
+		if err != nil {
+			continue
+		}
+		for _, dir := range dirs {
+			txID := dir.Name()
+			if staged[txID] == nil {
+				staged[txID] = make([]bool, len(disks))
+			}
+			staged[txID][i] = true
+			if marker, err := readCommitMarker(disk, txID); err == nil {
+				markers[txID] = marker
+			}
+		}
+	}
+	for txID, onDisks := range staged {
+		marker, committed := markers[txID]
+		rolledForward := make([]bool, len(disks))
+		for i, disk := range disks {
+			if !onDisks[i] {
+				continue
+			}
+			if committed {
+				if err := donut.rollForward(disk, txID, marker); err != nil {
+					// left staged, retried on next start
+					continue
+				}
+			}
+			rolledForward[i] = true
+		}
+		if committed && !marker.Part {
+			if err := donut.indexCommitted(marker); err != nil {
+				// left staged, indexing is retried on next start
+				continue
+			}
+		}
+		for i, disk := range disks {
+			if rolledForward[i] {
+				disk.Delete(filepath.Join(stagingDir, txID))
+			}
+		}
+	}
+	return nil
+}
+
+// indexCommitted - add an object of a committed transaction to the index, objects already indexed are left as is
+func (donut API) indexCommitted(marker commitMarker) *probe.Error {
+	if err := donut.listDonutBuckets(); err != nil {
+		return err.Trace()
+	}
+	bkt, ok := donut.buckets[marker.Bucket]
+	if !ok {
+		// none of the bucket slices are left
+		return nil
+	}
+	return bkt.indexObject(marker.Object).Trace(marker.Bucket, marker.Object)
+}
+
+// rollForward - move whatever is left staged by a committed transaction on a disk into place
+func (donut API) rollForward(disk nodeDisk, txID string, marker commitMarker) *probe.Error {
+	// without a commit marker or staged metadata, object data was not completely staged on this disk
+	if _, err := disk.Stat(stagingPath(txID, commitConfig)); err != nil {
+		if _, err := disk.Stat(stagingPath(txID, objectMetadataConfig)); err != nil {
+			return nil
+		}
+	}
+	for _, name := range stagedFiles {
+		if _, err := disk.Stat(stagingPath(txID, name)); err != nil {
+			continue
+		}
+		objectPath := filepath.Join(donut.config.DonutName, disk.bucketSlice(marker.Bucket), normalizeObjectName(marker.Object), name)
+		if err := disk.Rename(stagingPath(txID, name), objectPath); err != nil {
+			return err.Trace()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+type MyCommitSuite struct {
+	root string
+}
+
+var _ = Suite(&MyCommitSuite{})
+
+func (s *MyCommitSuite) SetUpSuite(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-commit-")
+	c.Assert(err, IsNil)
+	s.root = root
+}
+
+func (s *MyCommitSuite) TearDownSuite(c *C) {
+	os.RemoveAll(s.root)
+}
+
+// newCommitDonut - new donut over 4 disks with an object, replies the disks
+func (s *MyCommitSuite) newCommitDonut(c *C, data []byte) []string {
+	root, err := ioutil.TempDir(s.root, "donut-")
+	c.Assert(err, IsNil)
+	diskPaths := make([]string, 4)
+	for i := range diskPaths {
+		diskPaths[i] = filepath.Join(root, strconv.Itoa(i))
+		c.Assert(os.MkdirAll(diskPaths[i], 0700), IsNil)
+	}
+	putFormatObject(c, diskPaths, data)
+	return diskPaths
+}
+
+// stageCommitObject - stage a new version of the object without committing it, as if interrupted by a crash
+func stageCommitObject(c *C, diskPaths []string, data []byte) (bucket, string) {
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	b := d.(API).buckets["bucket"]
+	txID, err := newUUID()
+	c.Assert(err, IsNil)
+	_, staged, err := b.stageObject(txID, "object", bytes.NewReader(data), int64(len(data)), "", nil, nil, nil)
+	c.Assert(err, IsNil)
+	for _, ok := range staged {
+		c.Assert(ok, Equals, true)
+	}
+	return b, txID
+}
+
+// assertNothingStaged - staging area is empty on all disks
+func assertNothingStaged(c *C, diskPaths []string) {
+	for _, diskPath := range diskPaths {
+		entries, err := ioutil.ReadDir(filepath.Join(diskPath, stagingDir))
+		c.Assert(err, IsNil)
+		c.Assert(len(entries), Equals, 0)
+	}
+}
+
+func (s *MyCommitSuite) TestCommit(c *C) {
+	data := []byte("hello commit")
+	diskPaths := s.newCommitDonut(c, data)
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	assertFormatObject(c, d, data)
+	assertNothingStaged(c, diskPaths)
+}
+
+func (s *MyCommitSuite) TestRollBack(c *C) {
+	data := []byte("hello commit")
+	diskPaths := s.newCommitDonut(c, data)
+	stageCommitObject(c, diskPaths, []byte("staged, never committed"))
+
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	assertFormatObject(c, d, data)
+	assertNothingStaged(c, diskPaths)
+}
+
+func (s *MyCommitSuite) TestRollForward(c *C) {
+	data := []byte("hello commit")
+	diskPaths := s.newCommitDonut(c, data)
+	newData := []byte("staged and committed on one disk")
+	b, txID := stageCommitObject(c, diskPaths, newData)
+	disks, err := listNodeDisks(b.nodes)
+	c.Assert(err, IsNil)
+	marker := commitMarker{Version: commitVersion, Bucket: "bucket", Object: "object"}
+	c.Assert(writeCommitMarker(disks[0], txID, marker), IsNil)
+
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	assertFormatObject(c, d, newData)
+	assertNothingStaged(c, diskPaths)
+}
+
+func (s *MyCommitSuite) TestRollForwardIndexed(c *C) {
+	diskPaths := s.newCommitDonut(c, []byte("hello commit"))
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	b := d.(API).buckets["bucket"]
+	newData := []byte("new object staged and committed, crashed before it was indexed")
+	txID, err := newUUID()
+	c.Assert(err, IsNil)
+	_, _, err = b.stageObject(txID, "new/object", bytes.NewReader(newData), int64(len(newData)), "", nil, nil, nil)
+	c.Assert(err, IsNil)
+	disks, err := listNodeDisks(b.nodes)
+	c.Assert(err, IsNil)
+	marker := commitMarker{Version: commitVersion, Bucket: "bucket", Object: "new/object"}
+	c.Assert(writeCommitMarker(disks[0], txID, marker), IsNil)
+
+	d, err = openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	assertNothingStaged(c, diskPaths)
+	objects, _, err := d.ListObjects("bucket", BucketResourcesMetadata{Maxkeys: 1000})
+	c.Assert(err, IsNil)
+	c.Assert(len(objects), Equals, 2)
+	c.Assert(objects[0].Object, Equals, "new/object")
+	var buffer bytes.Buffer
+	_, err = d.GetObject(&buffer, "bucket", "new/object", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.Bytes(), DeepEquals, newData)
+}
+
+func (s *MyCommitSuite) TestRollForwardPartiallyMoved(c *C) {
+	data := []byte("hello commit")
+	diskPaths := s.newCommitDonut(c, data)
+	newData := []byte("staged, committed and partially moved into place")
+	b, txID := stageCommitObject(c, diskPaths, newData)
+	disks, err := listNodeDisks(b.nodes)
+	c.Assert(err, IsNil)
+	marker := commitMarker{Version: commitVersion, Bucket: "bucket", Object: "object"}
+	for _, disk := range disks {
+		c.Assert(writeCommitMarker(disk, txID, marker), IsNil)
+	}
+	// crash after object data of two disks was moved into place
+	for _, disk := range disks[:2] {
+		objectPath := filepath.Join("test", disk.bucketSlice("bucket"), "object", "data")
+		c.Assert(disk.Rename(stagingPath(txID, "data"), objectPath), IsNil)
+	}
+
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	assertFormatObject(c, d, newData)
+	assertNothingStaged(c, diskPaths)
+}
@@ -19,13 +19,15 @@ package donut
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"io"
 	"regexp"
 	"sort"
 	"strings"
 	"unicode/utf8"
 
-	"github.com/minio/minio-xl/pkg/atomic"
+	"github.com/minio/minio-xl/pkg/donut/disk"
+	"github.com/minio/minio-xl/pkg/probe"
 )
 
 // IsValidDonut - verify donut name is correct
@@ -185,6 +187,66 @@ func SortUnique(objects []string) []string {
 // CleanupWritersOnError purge writers on error
 func CleanupWritersOnError(writers []io.WriteCloser) {
 	for _, writer := range writers {
-		writer.(*atomic.File).CloseAndPurge()
+		if writer != nil {
+			writer.(disk.File).CloseAndPurge()
+		}
 	}
 }
+
+// writeQuorum - minimum number of disks a write has to reach, same as the number of data disks
+func writeQuorum(totalDisks int) int {
+	return totalDisks/2 + totalDisks%2
+}
+
+// hasWriteQuorum - are enough writers left, failed writers are nil
+func hasWriteQuorum(writers []io.WriteCloser) bool {
+	var live int
+	for _, writer := range writers {
+		if writer != nil {
+			live++
+		}
+	}
+	return live > 0 && live >= writeQuorum(len(writers))
+}
+
+// closeWriters - close writers making their contents visible, writers failing to close are dropped
+func closeWriters(writers []io.WriteCloser) {
+	for i, writer := range writers {
+		if writer == nil {
+			continue
+		}
+		if err := writer.Close(); err != nil {
+			writers[i] = nil
+		}
+	}
+}
+
+// encodeWriters - encode v to all writers and close them, failed writers are dropped
+func encodeWriters(writers []io.WriteCloser, v interface{}) *probe.Error {
+	for i, writer := range writers {
+		if writer == nil {
+			continue
+		}
+		jenc := json.NewEncoder(writer)
+		if err := jenc.Encode(v); err != nil {
+			if dropWriter(writers, i) {
+				continue
+			}
+			// Close writers and purge all temporary entries
+			CleanupWritersOnError(writers)
+			return probe.NewError(err)
+		}
+	}
+	closeWriters(writers)
+	if !hasWriteQuorum(writers) {
+		return probe.NewError(InsufficientWriteQuorum{})
+	}
+	return nil
+}
+
+// dropWriter - purge a failed writer, leaving the rest to carry on if write quorum is still met
+func dropWriter(writers []io.WriteCloser, i int) bool {
+	writers[i].(disk.File).CloseAndPurge()
+	writers[i] = nil
+	return hasWriteQuorum(writers)
+}
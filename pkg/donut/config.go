@@ -78,3 +78,11 @@ func LoadConfig() (*Config, *probe.Error) {
 	}
 	return qc.Data().(*Config), nil
 }
+
+// GetRegion - configured region, DefaultRegion if none
+func (a *Config) GetRegion() string {
+	if a.Region == "" {
+		return DefaultRegion
+	}
+	return a.Region
+}
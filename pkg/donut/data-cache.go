@@ -0,0 +1,85 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"time"
+
+	"github.com/minio/minio-xl/pkg/donut/cache/data"
+	"github.com/minio/minio-xl/pkg/probe"
+	"github.com/minio/minio-xl/pkg/sys"
+)
+
+// CacheConfig object data cache settings
+type CacheConfig struct {
+	// percent of system memory the cache is sized to, in place of max-size
+	MemoryPercent int `json:"memory-percent,omitempty"`
+
+	// how long objects read from disks stay cached, as in "1h30m", until evicted if not set
+	Expiration string `json:"expiration,omitempty"`
+
+	// objects read from disks are cached once read admit-reads times, objects no bigger than
+	// admit-size right away, all objects are cached right away if not set
+	AdmitReads int    `json:"admit-reads,omitempty"`
+	AdmitSize  uint64 `json:"admit-size,omitempty"`
+}
+
+// configureCache - size the object data cache, set its expiration and admission policy
+func (donut API) configureCache() *probe.Error {
+	conf := donut.config.Cache
+	if conf == nil {
+		return nil
+	}
+	if conf.MemoryPercent != 0 {
+		if conf.MemoryPercent < 0 || conf.MemoryPercent > 100 {
+			return probe.NewError(InvalidCacheConfig{Reason: "memory-percent must be between 1 and 100"})
+		}
+		memStats, err := sys.GetMemStats()
+		if err != nil {
+			return probe.NewError(err)
+		}
+		donut.config.MaxSize = memStats.Total / 100 * uint64(conf.MemoryPercent)
+		donut.objects.SetMaxSize(donut.config.MaxSize)
+	}
+	// without disks objects are kept nowhere else, none of them can expire or be refused
+	if len(donut.config.NodeDiskMap) == 0 {
+		if conf.Expiration != "" {
+			return probe.NewError(InvalidCacheConfig{Reason: "expiration needs disks, cached objects are kept nowhere else"})
+		}
+		if conf.AdmitReads > 1 {
+			return probe.NewError(InvalidCacheConfig{Reason: "admit-reads needs disks, cached objects are kept nowhere else"})
+		}
+	}
+	if conf.Expiration != "" {
+		expiration, err := time.ParseDuration(conf.Expiration)
+		if err != nil || expiration <= 0 {
+			return probe.NewError(InvalidCacheConfig{Reason: "expiration must be a positive duration"})
+		}
+		// expired objects are swept as objects are read, always under the donut lock, evicting
+		// them updates cached bucket metadata
+		donut.objects.SetExpiration(expiration)
+	}
+	if conf.AdmitReads > 1 {
+		donut.objects.SetAdmission(conf.AdmitSize, conf.AdmitReads)
+	}
+	return nil
+}
+
+// CacheStats - statistics of the object data cache
+func (donut API) CacheStats() data.Stats {
+	return donut.objects.Stats()
+}
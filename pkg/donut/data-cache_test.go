@@ -0,0 +1,149 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+type MyDataCacheSuite struct {
+	root string
+}
+
+var _ = Suite(&MyDataCacheSuite{})
+
+func (s *MyDataCacheSuite) SetUpSuite(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-cache-")
+	c.Assert(err, IsNil)
+	s.root = root
+}
+
+func (s *MyDataCacheSuite) TearDownSuite(c *C) {
+	os.RemoveAll(s.root)
+}
+
+// openCacheDonut - new donut with given cache settings, over 4 disks unless cache only
+func (s *MyDataCacheSuite) openCacheDonut(c *C, cacheOnly bool, cacheConfig *CacheConfig) (Interface, *probe.Error) {
+	root, err := ioutil.TempDir(s.root, "donut-")
+	c.Assert(err, IsNil)
+	conf := new(Config)
+	conf.Version = "0.0.1"
+	conf.MaxSize = 100000000
+	conf.Cache = cacheConfig
+	if !cacheOnly {
+		var diskPaths []string
+		for i := 0; i < 4; i++ {
+			diskPath := filepath.Join(root, "disk"+strconv.Itoa(i))
+			c.Assert(os.MkdirAll(diskPath, 0700), IsNil)
+			diskPaths = append(diskPaths, diskPath)
+		}
+		conf.DonutName = "test"
+		conf.NodeDiskMap = map[string][]string{"localhost": diskPaths}
+	}
+	SetDonutConfigPath(filepath.Join(root, "donut.json"))
+	c.Assert(SaveConfig(conf), IsNil)
+	return New()
+}
+
+func (s *MyDataCacheSuite) TestCacheConfig(c *C) {
+	_, err := s.openCacheDonut(c, true, &CacheConfig{MemoryPercent: 101})
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, InvalidCacheConfig{})
+
+	_, err = s.openCacheDonut(c, false, &CacheConfig{Expiration: "soon"})
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, InvalidCacheConfig{})
+
+	// without disks objects cannot expire or be refused, they are kept nowhere else
+	_, err = s.openCacheDonut(c, true, &CacheConfig{Expiration: "1h"})
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, InvalidCacheConfig{})
+	_, err = s.openCacheDonut(c, true, &CacheConfig{AdmitReads: 2})
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, InvalidCacheConfig{})
+
+	// cache is sized from system memory
+	d, err := s.openCacheDonut(c, true, &CacheConfig{MemoryPercent: 1})
+	c.Assert(err, IsNil)
+	c.Assert(d.CacheStats().MaxSize > 0, Equals, true)
+	c.Assert(d.CacheStats().MaxSize, Not(Equals), uint64(100000000))
+}
+
+func (s *MyDataCacheSuite) TestCacheExpiration(c *C) {
+	d, err := s.openCacheDonut(c, false, &CacheConfig{Expiration: "50ms"})
+	c.Assert(err, IsNil)
+	c.Assert(d.MakeBucket("bucket", "private", "", nil, nil), IsNil)
+	data := []byte("Hello, world!")
+	_, err = d.CreateObject("bucket", "object", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	// object is cached when read from disks, and read from disks again once it expires
+	for i := 0; i < 2; i++ {
+		var buffer bytes.Buffer
+		_, err = d.GetObject(&buffer, "bucket", "object", 0, 0, nil)
+		c.Assert(err, IsNil)
+		c.Assert(buffer.Bytes(), DeepEquals, data)
+	}
+	c.Assert(d.CacheStats().Hits, Equals, uint64(1))
+
+	// expired objects are swept as other objects are read
+	_, err = d.CreateObject("bucket", "other", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+	c.Assert(err, IsNil)
+	time.Sleep(200 * time.Millisecond)
+	_, err = d.GetObject(ioutil.Discard, "bucket", "other", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(d.CacheStats().Items, Equals, 1)
+	c.Assert(d.CacheStats().Expired, Equals, 1)
+
+	var buffer bytes.Buffer
+	_, err = d.GetObject(&buffer, "bucket", "object", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.Bytes(), DeepEquals, data)
+	objects, _, err := d.ListObjects("bucket", BucketResourcesMetadata{Maxkeys: 1000})
+	c.Assert(err, IsNil)
+	c.Assert(len(objects), Equals, 2)
+}
+
+func (s *MyDataCacheSuite) TestCacheAdmission(c *C) {
+	d, err := s.openCacheDonut(c, false, &CacheConfig{AdmitReads: 2, AdmitSize: 5})
+	c.Assert(err, IsNil)
+	c.Assert(d.MakeBucket("bucket", "private", "", nil, nil), IsNil)
+	data := []byte("Hello, world!")
+	_, err = d.CreateObject("bucket", "object", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	// object is read from disks twice before it is cached
+	for i := 0; i < 3; i++ {
+		var buffer bytes.Buffer
+		_, err = d.GetObject(&buffer, "bucket", "object", 0, 0, nil)
+		c.Assert(err, IsNil)
+		c.Assert(buffer.Bytes(), DeepEquals, data)
+	}
+	stats := d.CacheStats()
+	c.Assert(stats.Misses, Equals, uint64(2))
+	c.Assert(stats.Rejected, Equals, 1)
+	c.Assert(stats.Hits, Equals, uint64(1))
+	c.Assert(stats.Items, Equals, 1)
+}
@@ -39,6 +39,15 @@ type ObjectMetadata struct {
 	MD5Sum    string `json:"sys.md5sum"`
 	SHA512Sum string `json:"sys.sha512sum"`
 
+	// server side encryption, only a MAC of the key is stored
+	EncryptionAlgorithm string `json:"sys.encryptionAlgorithm,omitempty"`
+	EncryptionIV        string `json:"sys.encryptionIV,omitempty"`
+	EncryptionKeyMAC    string `json:"sys.encryptionKeyMAC,omitempty"`
+
+	// server managed encryption, data key sealed with the master key of id
+	EncryptionMasterKeyID string `json:"sys.encryptionMasterKeyID,omitempty"`
+	EncryptionSealedKey   string `json:"sys.encryptionSealedKey,omitempty"`
+
 	// metadata
 	Metadata map[string]string `json:"metadata"`
 }
@@ -48,27 +57,22 @@ type Metadata struct {
 	Version string `json:"version"`
 }
 
-// AllBuckets container for all buckets
-type AllBuckets struct {
-	Version string                    `json:"version"`
-	Buckets map[string]BucketMetadata `json:"buckets"`
-}
-
 // BucketMetadata container for bucket level metadata
 type BucketMetadata struct {
-	Version       string                      `json:"version"`
-	Name          string                      `json:"name"`
-	ACL           BucketACL                   `json:"acl"`
-	Created       time.Time                   `json:"created"`
-	Multiparts    map[string]MultiPartSession `json:"multiparts"`
-	Metadata      map[string]string           `json:"metadata"`
-	BucketObjects map[string]struct{}         `json:"objects"`
+	Version    string                      `json:"version"`
+	Name       string                      `json:"name"`
+	ACL        BucketACL                   `json:"acl"`
+	Owner      string                      `json:"owner,omitempty"`
+	Created    time.Time                   `json:"created"`
+	Multiparts map[string]MultiPartSession `json:"multiparts"`
+	Metadata   map[string]string           `json:"metadata"`
 }
 
 // ListObjectsResults container for list objects response
 type ListObjectsResults struct {
 	Objects        map[string]ObjectMetadata `json:"objects"`
 	CommonPrefixes []string                  `json:"commonPrefixes"`
+	NextMarker     string                    `json:"nextMarker"`
 	IsTruncated    bool                      `json:"isTruncated"`
 }
 
@@ -78,6 +82,10 @@ type MultiPartSession struct {
 	Initiated  time.Time               `json:"initiated"`
 	Parts      map[string]PartMetadata `json:"parts"`
 	TotalParts int                     `json:"total-parts"`
+	Metadata   map[string]string       `json:"metadata"`
+
+	// encryption of the object, kept in memory only for the duration of the session
+	encryption *Encryption
 }
 
 // PartMetadata - various types of individual part resources
@@ -106,6 +114,11 @@ type CompleteMultipartUpload struct {
 	Part []CompletePart
 }
 
+// CreateBucketConfiguration container for location constraint of a new bucket
+type CreateBucketConfiguration struct {
+	LocationConstraint string
+}
+
 // ObjectResourcesMetadata - various types of object resources
 type ObjectResourcesMetadata struct {
 	Bucket               string
@@ -154,4 +167,10 @@ type BucketResourcesMetadata struct {
 	Delimiter      string
 	IsTruncated    bool
 	CommonPrefixes []string
+
+	// list objects version 2, listing resumes after the marker a continuation token stands for
+	StartAfter            string
+	ContinuationToken     string
+	NextContinuationToken string
+	FetchOwner            bool
 }
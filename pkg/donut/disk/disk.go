@@ -18,6 +18,7 @@ package disk
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -29,32 +30,64 @@ import (
 	"github.com/minio/minio-xl/pkg/probe"
 )
 
-// Disk container for disk parameters
-type Disk struct {
+// Disk storage interface of a donut disk, all paths are relative to the root of the disk
+type Disk interface {
+	// MakeDir - make a directory along with its parents
+	MakeDir(dirname string) *probe.Error
+	// ListDir - list a directory, get only directories
+	ListDir(dirname string) ([]os.FileInfo, *probe.Error)
+	// CreateFile - create a file along with its parent directories, file is visible only once closed
+	CreateFile(filename string) (File, *probe.Error)
+	// Open - read a file
+	Open(filename string) (io.ReadCloser, *probe.Error)
+	// Stat - file or directory information
+	Stat(filename string) (os.FileInfo, *probe.Error)
+	// Rename - rename a file replacing the destination, parent directories of the destination are created
+	Rename(oldname, newname string) *probe.Error
+	// Delete - delete a file, or a directory with all of its contents
+	Delete(filename string) *probe.Error
+	// FSInfo - get disk filesystem and its usage information
+	FSInfo() (map[string]string, *probe.Error)
+
+	// GetPath - get root disk path
+	GetPath() string
+	// IsUsable - is disk usable, alive
+	IsUsable() bool
+}
+
+// File file created on a disk, written contents are visible only once closed
+type File interface {
+	io.WriteCloser
+	// CloseAndPurge - close the file discarding all of its contents
+	CloseAndPurge() error
+}
+
+// fsDisk disk on a local filesystem
+type fsDisk struct {
 	lock   *sync.Mutex
 	path   string
 	fsInfo map[string]string
 }
 
-// New - instantiate new disk
+// New - instantiate new disk on a local filesystem
 func New(diskPath string) (Disk, *probe.Error) {
 	if diskPath == "" {
-		return Disk{}, probe.NewError(InvalidArgument{})
+		return nil, probe.NewError(InvalidArgument{})
 	}
 	st, err := os.Stat(diskPath)
 	if err != nil {
-		return Disk{}, probe.NewError(err)
+		return nil, probe.NewError(err)
 	}
 
 	if !st.IsDir() {
-		return Disk{}, probe.NewError(syscall.ENOTDIR)
+		return nil, probe.NewError(syscall.ENOTDIR)
 	}
 	s := syscall.Statfs_t{}
 	err = syscall.Statfs(diskPath, &s)
 	if err != nil {
-		return Disk{}, probe.NewError(err)
+		return nil, probe.NewError(err)
 	}
-	disk := Disk{
+	disk := fsDisk{
 		lock:   &sync.Mutex{},
 		path:   diskPath,
 		fsInfo: make(map[string]string),
@@ -64,11 +97,11 @@ func New(diskPath string) (Disk, *probe.Error) {
 		disk.fsInfo["MountPoint"] = disk.path
 		return disk, nil
 	}
-	return Disk{}, probe.NewError(UnsupportedFilesystem{Type: strconv.FormatInt(int64(s.Type), 10)})
+	return nil, probe.NewError(UnsupportedFilesystem{Type: strconv.FormatInt(int64(s.Type), 10)})
 }
 
 // IsUsable - is disk usable, alive
-func (disk Disk) IsUsable() bool {
+func (disk fsDisk) IsUsable() bool {
 	_, err := os.Stat(disk.path)
 	if err != nil {
 		return false
@@ -77,29 +110,29 @@ func (disk Disk) IsUsable() bool {
 }
 
 // GetPath - get root disk path
-func (disk Disk) GetPath() string {
+func (disk fsDisk) GetPath() string {
 	return disk.path
 }
 
-// GetFSInfo - get disk filesystem and its usage information
-func (disk Disk) GetFSInfo() map[string]string {
+// FSInfo - get disk filesystem and its usage information
+func (disk fsDisk) FSInfo() (map[string]string, *probe.Error) {
 	disk.lock.Lock()
 	defer disk.lock.Unlock()
 
 	s := syscall.Statfs_t{}
 	err := syscall.Statfs(disk.path, &s)
 	if err != nil {
-		return nil
+		return nil, probe.NewError(err)
 	}
 	disk.fsInfo["Total"] = formatBytes(int64(s.Bsize) * int64(s.Blocks))
 	disk.fsInfo["Free"] = formatBytes(int64(s.Bsize) * int64(s.Bfree))
 	disk.fsInfo["TotalB"] = strconv.FormatInt(int64(s.Bsize)*int64(s.Blocks), 10)
 	disk.fsInfo["FreeB"] = strconv.FormatInt(int64(s.Bsize)*int64(s.Bfree), 10)
-	return disk.fsInfo
+	return disk.fsInfo, nil
 }
 
 // MakeDir - make a directory inside disk root path
-func (disk Disk) MakeDir(dirname string) *probe.Error {
+func (disk fsDisk) MakeDir(dirname string) *probe.Error {
 	disk.lock.Lock()
 	defer disk.lock.Unlock()
 	if err := os.MkdirAll(filepath.Join(disk.path, dirname), 0700); err != nil {
@@ -109,7 +142,7 @@ func (disk Disk) MakeDir(dirname string) *probe.Error {
 }
 
 // ListDir - list a directory inside disk root path, get only directories
-func (disk Disk) ListDir(dirname string) ([]os.FileInfo, *probe.Error) {
+func (disk fsDisk) ListDir(dirname string) ([]os.FileInfo, *probe.Error) {
 	disk.lock.Lock()
 	defer disk.lock.Unlock()
 
@@ -132,32 +165,8 @@ func (disk Disk) ListDir(dirname string) ([]os.FileInfo, *probe.Error) {
 	return directories, nil
 }
 
-// ListFiles - list a directory inside disk root path, get only files
-func (disk Disk) ListFiles(dirname string) ([]os.FileInfo, *probe.Error) {
-	disk.lock.Lock()
-	defer disk.lock.Unlock()
-
-	dir, err := os.Open(filepath.Join(disk.path, dirname))
-	if err != nil {
-		return nil, probe.NewError(err)
-	}
-	defer dir.Close()
-	contents, err := dir.Readdir(-1)
-	if err != nil {
-		return nil, probe.NewError(err)
-	}
-	var files []os.FileInfo
-	for _, content := range contents {
-		// Include only regular files, ignore everything else
-		if content.Mode().IsRegular() {
-			files = append(files, content)
-		}
-	}
-	return files, nil
-}
-
 // CreateFile - create a file inside disk root path, replies with custome disk.File which provides atomic writes
-func (disk Disk) CreateFile(filename string) (*atomic.File, *probe.Error) {
+func (disk fsDisk) CreateFile(filename string) (File, *probe.Error) {
 	disk.lock.Lock()
 	defer disk.lock.Unlock()
 
@@ -174,7 +183,7 @@ func (disk Disk) CreateFile(filename string) (*atomic.File, *probe.Error) {
 }
 
 // Open - read a file inside disk root path
-func (disk Disk) Open(filename string) (*os.File, *probe.Error) {
+func (disk fsDisk) Open(filename string) (io.ReadCloser, *probe.Error) {
 	disk.lock.Lock()
 	defer disk.lock.Unlock()
 
@@ -188,19 +197,48 @@ func (disk Disk) Open(filename string) (*os.File, *probe.Error) {
 	return dataFile, nil
 }
 
-// OpenFile - Use with caution
-func (disk Disk) OpenFile(filename string, flags int, perm os.FileMode) (*os.File, *probe.Error) {
+// Stat - file or directory information inside disk root path
+func (disk fsDisk) Stat(filename string) (os.FileInfo, *probe.Error) {
 	disk.lock.Lock()
 	defer disk.lock.Unlock()
 
-	if filename == "" {
-		return nil, probe.NewError(InvalidArgument{})
-	}
-	dataFile, err := os.OpenFile(filepath.Join(disk.path, filename), flags, perm)
+	st, err := os.Stat(filepath.Join(disk.path, filename))
 	if err != nil {
 		return nil, probe.NewError(err)
 	}
-	return dataFile, nil
+	return st, nil
+}
+
+// Rename - rename a file inside disk root path, returns once the rename is on stable storage
+func (disk fsDisk) Rename(oldname, newname string) *probe.Error {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	if oldname == "" || newname == "" {
+		return probe.NewError(InvalidArgument{})
+	}
+	if err := atomic.Rename(filepath.Join(disk.path, oldname), filepath.Join(disk.path, newname)); err != nil {
+		return probe.NewError(err)
+	}
+	return nil
+}
+
+// Delete - delete a file or a directory tree inside disk root path
+func (disk fsDisk) Delete(filename string) *probe.Error {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	if filename == "" {
+		return probe.NewError(InvalidArgument{})
+	}
+	filePath := filepath.Join(disk.path, filename)
+	if _, err := os.Stat(filePath); err != nil {
+		return probe.NewError(err)
+	}
+	if err := os.RemoveAll(filePath); err != nil {
+		return probe.NewError(err)
+	}
+	return nil
 }
 
 // formatBytes - Convert bytes to human readable string. Like a 2 MB, 64.2 KB, 52 B
@@ -19,7 +19,7 @@ package disk
 import (
 	"io/ioutil"
 	"os"
-	"path/filepath"
+	"sort"
 	"testing"
 
 	. "gopkg.in/check.v1"
@@ -47,9 +47,23 @@ func (s *MyDiskSuite) TearDownSuite(c *C) {
 	os.RemoveAll(s.path)
 }
 
+type MyMemoryDiskSuite struct {
+	MyDiskSuite
+}
+
+var _ = Suite(&MyMemoryDiskSuite{})
+
+func (s *MyMemoryDiskSuite) SetUpSuite(c *C) {
+	s.path = "memory"
+	s.disk = NewMemory(s.path)
+}
+
+func (s *MyMemoryDiskSuite) TearDownSuite(c *C) {}
+
 func (s *MyDiskSuite) TestDiskInfo(c *C) {
 	c.Assert(s.path, Equals, s.disk.GetPath())
-	fsInfo := s.disk.GetFSInfo()
+	fsInfo, err := s.disk.FSInfo()
+	c.Assert(err, IsNil)
 	c.Assert(fsInfo["MountPoint"], Equals, s.disk.GetPath())
 	c.Assert(fsInfo["FSType"], Not(Equals), "UNKNOWN")
 }
@@ -61,8 +75,10 @@ func (s *MyDiskSuite) TestDiskCreateDir(c *C) {
 func (s *MyDiskSuite) TestDiskCreateFile(c *C) {
 	f, err := s.disk.CreateFile("hello1")
 	c.Assert(err, IsNil)
-	c.Assert(f.Name(), Not(Equals), filepath.Join(s.path, "hello1"))
-	// close renames the file
+	// file is not visible until closed
+	_, err = s.disk.Stat("hello1")
+	c.Assert(err, Not(IsNil))
+	c.Assert(os.IsNotExist(err.ToGoError()), Equals, true)
 	f.Close()
 
 	// Open should be a success
@@ -73,12 +89,78 @@ func (s *MyDiskSuite) TestDiskCreateFile(c *C) {
 func (s *MyDiskSuite) TestDiskOpen(c *C) {
 	f1, err := s.disk.CreateFile("hello2")
 	c.Assert(err, IsNil)
-	c.Assert(f1.Name(), Not(Equals), filepath.Join(s.path, "hello2"))
-	// close renames the file
+	f1.Write([]byte("hello"))
 	f1.Close()
 
+	st, err := s.disk.Stat("hello2")
+	c.Assert(err, IsNil)
+	c.Assert(st.Size(), Equals, int64(5))
+
 	f2, err := s.disk.Open("hello2")
 	c.Assert(err, IsNil)
-	c.Assert(f2.Name(), Equals, filepath.Join(s.path, "hello2"))
 	defer f2.Close()
+	data, e := ioutil.ReadAll(f2)
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, "hello")
+}
+
+func (s *MyDiskSuite) TestDiskPurge(c *C) {
+	f, err := s.disk.CreateFile("dir/hello3")
+	c.Assert(err, IsNil)
+	f.Write([]byte("hello"))
+	c.Assert(f.CloseAndPurge(), IsNil)
+
+	_, err = s.disk.Open("dir/hello3")
+	c.Assert(err, Not(IsNil))
+	c.Assert(os.IsNotExist(err.ToGoError()), Equals, true)
+}
+
+func (s *MyDiskSuite) TestDiskListAndDelete(c *C) {
+	c.Assert(s.disk.MakeDir("list/b"), IsNil)
+	c.Assert(s.disk.MakeDir("list/a"), IsNil)
+	f, err := s.disk.CreateFile("list/a/file")
+	c.Assert(err, IsNil)
+	f.Close()
+
+	dirs, err := s.disk.ListDir("list")
+	c.Assert(err, IsNil)
+	c.Assert(len(dirs), Equals, 2)
+	var names []string
+	for _, dir := range dirs {
+		names = append(names, dir.Name())
+	}
+	sort.Strings(names)
+	c.Assert(names, DeepEquals, []string{"a", "b"})
+
+	c.Assert(s.disk.Delete("list/a"), IsNil)
+	_, err = s.disk.Stat("list/a/file")
+	c.Assert(err, Not(IsNil))
+	dirs, err = s.disk.ListDir("list")
+	c.Assert(err, IsNil)
+	c.Assert(len(dirs), Equals, 1)
+
+	err = s.disk.Delete("list/a")
+	c.Assert(err, Not(IsNil))
+	c.Assert(os.IsNotExist(err.ToGoError()), Equals, true)
+}
+
+func (s *MyDiskSuite) TestDiskRename(c *C) {
+	f, err := s.disk.CreateFile("staging/file")
+	c.Assert(err, IsNil)
+	_, werr := f.Write([]byte("renamed"))
+	c.Assert(werr, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(s.disk.Rename("staging/file", "committed/dir/file"), IsNil)
+	_, err = s.disk.Stat("staging/file")
+	c.Assert(os.IsNotExist(err.ToGoError()), Equals, true)
+	reader, err := s.disk.Open("committed/dir/file")
+	c.Assert(err, IsNil)
+	data, rerr := ioutil.ReadAll(reader)
+	c.Assert(rerr, IsNil)
+	reader.Close()
+	c.Assert(string(data), Equals, "renamed")
+
+	err = s.disk.Rename("staging/file", "committed/dir/file")
+	c.Assert(os.IsNotExist(err.ToGoError()), Equals, true)
 }
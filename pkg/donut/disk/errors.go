@@ -31,3 +31,22 @@ type UnsupportedFilesystem struct {
 func (e UnsupportedFilesystem) Error() string {
 	return "Unsupported filesystem: " + e.Type
 }
+
+// FaultInjected operation failed on purpose by a faulty disk
+type FaultInjected struct {
+	Op   string
+	Path string
+}
+
+func (e FaultInjected) Error() string {
+	return "Injected fault: " + e.Op + " " + e.Path
+}
+
+// DiskOffline disk went offline
+type DiskOffline struct {
+	Path string
+}
+
+func (e DiskOffline) Error() string {
+	return "Disk offline: " + e.Path
+}
@@ -0,0 +1,258 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or impliedisk.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Faults failures injected into a disk, zero value injects none
+type Faults struct {
+	// Open and CreateFile fail
+	FailOpen bool `json:"fail-open,omitempty"`
+	// files are cut short to half their size when read
+	ShortRead bool `json:"short-read,omitempty"`
+	// lowest bit of the first byte of every read is flipped
+	BitFlip bool `json:"bit-flip,omitempty"`
+	// every operation, read and write is delayed by this long, in nanoseconds in config
+	Latency time.Duration `json:"latency,omitempty"`
+	// disk goes offline once this many bytes are read or written, zero stays online
+	OfflineAfter int64 `json:"offline-after,omitempty"`
+}
+
+// faultyDisk disk wrapper injecting faults, meant for chaos testing
+type faultyDisk struct {
+	disk   Disk
+	faults Faults
+	state  *faultState
+}
+
+// faultState bytes transferred through a faulty disk so far
+type faultState struct {
+	lock        *sync.Mutex
+	transferred int64
+	offline     bool
+}
+
+// NewFaulty - wrap a disk injecting given faults
+func NewFaulty(disk Disk, faults Faults) Disk {
+	return faultyDisk{
+		disk:   disk,
+		faults: faults,
+		state:  &faultState{lock: &sync.Mutex{}},
+	}
+}
+
+// delay - add configured latency
+func (disk faultyDisk) delay() {
+	if disk.faults.Latency > 0 {
+		time.Sleep(disk.faults.Latency)
+	}
+}
+
+// isOffline - has the disk gone offline
+func (disk faultyDisk) isOffline() bool {
+	disk.state.lock.Lock()
+	defer disk.state.lock.Unlock()
+	return disk.state.offline
+}
+
+// transfer - account n bytes read or written, returns bytes allowed before going offline
+func (disk faultyDisk) transfer(n int) (int, bool) {
+	disk.state.lock.Lock()
+	defer disk.state.lock.Unlock()
+	if disk.state.offline {
+		return 0, true
+	}
+	if disk.faults.OfflineAfter > 0 && disk.state.transferred+int64(n) >= disk.faults.OfflineAfter {
+		allowed := int(disk.faults.OfflineAfter - disk.state.transferred)
+		disk.state.transferred = disk.faults.OfflineAfter
+		disk.state.offline = true
+		return allowed, true
+	}
+	disk.state.transferred += int64(n)
+	return n, false
+}
+
+// check - common checks before every operation
+func (disk faultyDisk) check() *probe.Error {
+	disk.delay()
+	if disk.isOffline() {
+		return probe.NewError(DiskOffline{Path: disk.disk.GetPath()})
+	}
+	return nil
+}
+
+// IsUsable - is disk usable, alive
+func (disk faultyDisk) IsUsable() bool {
+	return !disk.isOffline() && disk.disk.IsUsable()
+}
+
+// GetPath - get root disk path
+func (disk faultyDisk) GetPath() string {
+	return disk.disk.GetPath()
+}
+
+// FSInfo - get disk filesystem and its usage information
+func (disk faultyDisk) FSInfo() (map[string]string, *probe.Error) {
+	if err := disk.check(); err != nil {
+		return nil, err.Trace()
+	}
+	return disk.disk.FSInfo()
+}
+
+// MakeDir - make a directory
+func (disk faultyDisk) MakeDir(dirname string) *probe.Error {
+	if err := disk.check(); err != nil {
+		return err.Trace(dirname)
+	}
+	return disk.disk.MakeDir(dirname)
+}
+
+// ListDir - list a directory, get only directories
+func (disk faultyDisk) ListDir(dirname string) ([]os.FileInfo, *probe.Error) {
+	if err := disk.check(); err != nil {
+		return nil, err.Trace(dirname)
+	}
+	return disk.disk.ListDir(dirname)
+}
+
+// CreateFile - create a file
+func (disk faultyDisk) CreateFile(filename string) (File, *probe.Error) {
+	if err := disk.check(); err != nil {
+		return nil, err.Trace(filename)
+	}
+	if disk.faults.FailOpen {
+		return nil, probe.NewError(FaultInjected{Op: "create", Path: filename})
+	}
+	f, err := disk.disk.CreateFile(filename)
+	if err != nil {
+		return nil, err.Trace(filename)
+	}
+	return &faultyFile{File: f, disk: disk}, nil
+}
+
+// Open - read a file
+func (disk faultyDisk) Open(filename string) (io.ReadCloser, *probe.Error) {
+	if err := disk.check(); err != nil {
+		return nil, err.Trace(filename)
+	}
+	if disk.faults.FailOpen {
+		return nil, probe.NewError(FaultInjected{Op: "open", Path: filename})
+	}
+	remaining := int64(-1)
+	if disk.faults.ShortRead {
+		st, err := disk.disk.Stat(filename)
+		if err != nil {
+			return nil, err.Trace(filename)
+		}
+		remaining = st.Size() / 2
+	}
+	r, err := disk.disk.Open(filename)
+	if err != nil {
+		return nil, err.Trace(filename)
+	}
+	return &faultyReader{ReadCloser: r, disk: disk, remaining: remaining}, nil
+}
+
+// Stat - file or directory information
+func (disk faultyDisk) Stat(filename string) (os.FileInfo, *probe.Error) {
+	if err := disk.check(); err != nil {
+		return nil, err.Trace(filename)
+	}
+	return disk.disk.Stat(filename)
+}
+
+// Rename - rename a file replacing the destination
+func (disk faultyDisk) Rename(oldname, newname string) *probe.Error {
+	if err := disk.check(); err != nil {
+		return err.Trace(oldname, newname)
+	}
+	if disk.faults.FailOpen {
+		return probe.NewError(FaultInjected{Op: "rename", Path: oldname})
+	}
+	return disk.disk.Rename(oldname, newname)
+}
+
+// Delete - delete a file or a directory tree
+func (disk faultyDisk) Delete(filename string) *probe.Error {
+	if err := disk.check(); err != nil {
+		return err.Trace(filename)
+	}
+	return disk.disk.Delete(filename)
+}
+
+// faultyFile file being written to a faulty disk
+type faultyFile struct {
+	File
+	disk faultyDisk
+}
+
+// Write - write contents until the disk goes offline
+func (f *faultyFile) Write(p []byte) (int, error) {
+	f.disk.delay()
+	allowed, offline := f.disk.transfer(len(p))
+	if offline {
+		n, _ := f.File.Write(p[:allowed])
+		return n, DiskOffline{Path: f.disk.GetPath()}
+	}
+	return f.File.Write(p)
+}
+
+// Close - commit written contents, unless the disk went offline meanwhile
+func (f *faultyFile) Close() error {
+	if f.disk.isOffline() {
+		f.File.CloseAndPurge()
+		return DiskOffline{Path: f.disk.GetPath()}
+	}
+	return f.File.Close()
+}
+
+// faultyReader file being read from a faulty disk
+type faultyReader struct {
+	io.ReadCloser
+	disk      faultyDisk
+	remaining int64
+}
+
+// Read - read contents applying configured faults
+func (r *faultyReader) Read(p []byte) (int, error) {
+	r.disk.delay()
+	if r.remaining == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if r.remaining > 0 && int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if r.remaining > 0 {
+		r.remaining -= int64(n)
+	}
+	if n > 0 && r.disk.faults.BitFlip {
+		p[0] ^= 1
+	}
+	allowed, offline := r.disk.transfer(n)
+	if offline {
+		return allowed, DiskOffline{Path: r.disk.GetPath()}
+	}
+	return n, err
+}
@@ -0,0 +1,90 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or impliedisk.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"io"
+	"io/ioutil"
+
+	. "gopkg.in/check.v1"
+)
+
+type MyFaultyDiskSuite struct{}
+
+var _ = Suite(&MyFaultyDiskSuite{})
+
+// newFaultyDisk - memory disk holding a file named 'hello', wrapped with given faults
+func newFaultyDisk(c *C, faults Faults) Disk {
+	d := NewMemory("faulty")
+	f, err := d.CreateFile("hello")
+	c.Assert(err, IsNil)
+	f.Write([]byte("hello"))
+	c.Assert(f.Close(), IsNil)
+	return NewFaulty(d, faults)
+}
+
+func (s *MyFaultyDiskSuite) TestNoFaults(c *C) {
+	d := newFaultyDisk(c, Faults{})
+	r, err := d.Open("hello")
+	c.Assert(err, IsNil)
+	data, e := ioutil.ReadAll(r)
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, "hello")
+}
+
+func (s *MyFaultyDiskSuite) TestFailOpen(c *C) {
+	d := newFaultyDisk(c, Faults{FailOpen: true})
+	_, err := d.Open("hello")
+	c.Assert(err, Not(IsNil))
+	_, err = d.CreateFile("world")
+	c.Assert(err, Not(IsNil))
+	c.Assert(d.MakeDir("dir"), IsNil)
+}
+
+func (s *MyFaultyDiskSuite) TestShortRead(c *C) {
+	d := newFaultyDisk(c, Faults{ShortRead: true})
+	r, err := d.Open("hello")
+	c.Assert(err, IsNil)
+	data, e := ioutil.ReadAll(r)
+	c.Assert(e, Equals, io.ErrUnexpectedEOF)
+	c.Assert(string(data), Equals, "he")
+}
+
+func (s *MyFaultyDiskSuite) TestBitFlip(c *C) {
+	d := newFaultyDisk(c, Faults{BitFlip: true})
+	r, err := d.Open("hello")
+	c.Assert(err, IsNil)
+	data, e := ioutil.ReadAll(r)
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, "iello")
+}
+
+func (s *MyFaultyDiskSuite) TestOfflineAfter(c *C) {
+	d := newFaultyDisk(c, Faults{OfflineAfter: 8})
+	f, err := d.CreateFile("world")
+	c.Assert(err, IsNil)
+	n, e := f.Write([]byte("hello world"))
+	c.Assert(e, FitsTypeOf, DiskOffline{})
+	c.Assert(n, Equals, 8)
+	c.Assert(f.Close(), Not(IsNil))
+
+	c.Assert(d.IsUsable(), Equals, false)
+	_, err = d.Open("hello")
+	c.Assert(err, Not(IsNil))
+	_, err = d.Stat("world")
+	c.Assert(err, Not(IsNil))
+}
@@ -0,0 +1,290 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or impliedisk.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// memoryDisk disk kept in memory, meant for tests
+type memoryDisk struct {
+	lock  *sync.Mutex
+	path  string
+	dirs  map[string]time.Time
+	files map[string]memoryFileInfo
+	data  map[string][]byte
+}
+
+// memoryFileInfo file or directory information of a memory disk
+type memoryFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (f memoryFileInfo) Name() string       { return f.name }
+func (f memoryFileInfo) Size() int64        { return f.size }
+func (f memoryFileInfo) ModTime() time.Time { return f.modTime }
+func (f memoryFileInfo) IsDir() bool        { return f.isDir }
+func (f memoryFileInfo) Sys() interface{}   { return nil }
+func (f memoryFileInfo) Mode() os.FileMode {
+	if f.isDir {
+		return os.ModeDir | 0700
+	}
+	return 0600
+}
+
+// memoryFile file being written to a memory disk
+type memoryFile struct {
+	bytes.Buffer
+	disk     memoryDisk
+	filename string
+	closed   bool
+}
+
+// Close - make written contents visible
+func (f *memoryFile) Close() error {
+	if f.closed {
+		return os.ErrInvalid
+	}
+	f.closed = true
+	f.disk.lock.Lock()
+	defer f.disk.lock.Unlock()
+	f.disk.makeDir(filepath.Dir(f.filename))
+	f.disk.files[f.filename] = memoryFileInfo{
+		name:    filepath.Base(f.filename),
+		size:    int64(f.Len()),
+		modTime: time.Now().UTC(),
+	}
+	f.disk.data[f.filename] = f.Bytes()
+	return nil
+}
+
+// CloseAndPurge - discard written contents
+func (f *memoryFile) CloseAndPurge() error {
+	if f.closed {
+		return os.ErrInvalid
+	}
+	f.closed = true
+	f.Reset()
+	return nil
+}
+
+// NewMemory - instantiate new empty disk kept in memory, path is only used to identify the disk
+func NewMemory(diskPath string) Disk {
+	disk := memoryDisk{
+		lock:  &sync.Mutex{},
+		path:  diskPath,
+		dirs:  make(map[string]time.Time),
+		files: make(map[string]memoryFileInfo),
+		data:  make(map[string][]byte),
+	}
+	disk.dirs["."] = time.Now().UTC()
+	return disk
+}
+
+// cleanPath - clean relative path, root of the disk is '.'
+func cleanPath(name string) string {
+	return strings.TrimPrefix(filepath.Clean("/"+name), "/")
+}
+
+// makeDir - make a directory along with its parents, lock is to be held by the caller
+func (disk memoryDisk) makeDir(dirname string) {
+	for dirname = cleanPath(dirname); dirname != ""; dirname = cleanPath(filepath.Dir(dirname)) {
+		if _, ok := disk.dirs[dirname]; ok {
+			return
+		}
+		disk.dirs[dirname] = time.Now().UTC()
+	}
+}
+
+// IsUsable - is disk usable, alive
+func (disk memoryDisk) IsUsable() bool {
+	return true
+}
+
+// GetPath - get root disk path
+func (disk memoryDisk) GetPath() string {
+	return disk.path
+}
+
+// FSInfo - get disk filesystem and its usage information
+func (disk memoryDisk) FSInfo() (map[string]string, *probe.Error) {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	var used int64
+	for _, data := range disk.data {
+		used += int64(len(data))
+	}
+	return map[string]string{
+		"FSType":     "MEMORY",
+		"MountPoint": disk.path,
+		"Used":       formatBytes(used),
+	}, nil
+}
+
+// MakeDir - make a directory
+func (disk memoryDisk) MakeDir(dirname string) *probe.Error {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	if _, ok := disk.files[cleanPath(dirname)]; ok {
+		return probe.NewError(&os.PathError{Op: "mkdir", Path: dirname, Err: os.ErrExist})
+	}
+	disk.makeDir(dirname)
+	return nil
+}
+
+// ListDir - list a directory, get only directories
+func (disk memoryDisk) ListDir(dirname string) ([]os.FileInfo, *probe.Error) {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	dirname = cleanPath(dirname)
+	if dirname == "" {
+		dirname = "."
+	}
+	if _, ok := disk.dirs[dirname]; !ok {
+		return nil, probe.NewError(&os.PathError{Op: "open", Path: dirname, Err: os.ErrNotExist})
+	}
+	var names []string
+	for name := range disk.dirs {
+		if name != "." && filepath.Dir(name) == dirname {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var directories []os.FileInfo
+	for _, name := range names {
+		directories = append(directories, memoryFileInfo{
+			name:    filepath.Base(name),
+			modTime: disk.dirs[name],
+			isDir:   true,
+		})
+	}
+	return directories, nil
+}
+
+// CreateFile - create a file, written contents are kept aside until closed
+func (disk memoryDisk) CreateFile(filename string) (File, *probe.Error) {
+	if filename == "" {
+		return nil, probe.NewError(InvalidArgument{})
+	}
+	return &memoryFile{disk: disk, filename: cleanPath(filename)}, nil
+}
+
+// Open - read a file
+func (disk memoryDisk) Open(filename string) (io.ReadCloser, *probe.Error) {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	if filename == "" {
+		return nil, probe.NewError(InvalidArgument{})
+	}
+	data, ok := disk.data[cleanPath(filename)]
+	if !ok {
+		return nil, probe.NewError(&os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist})
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Stat - file or directory information
+func (disk memoryDisk) Stat(filename string) (os.FileInfo, *probe.Error) {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	name := cleanPath(filename)
+	if name == "" {
+		name = "."
+	}
+	if st, ok := disk.files[name]; ok {
+		return st, nil
+	}
+	if modTime, ok := disk.dirs[name]; ok {
+		return memoryFileInfo{name: filepath.Base(name), modTime: modTime, isDir: true}, nil
+	}
+	return nil, probe.NewError(&os.PathError{Op: "stat", Path: filename, Err: os.ErrNotExist})
+}
+
+// Rename - rename a file replacing the destination
+func (disk memoryDisk) Rename(oldname, newname string) *probe.Error {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	oldname, newname = cleanPath(oldname), cleanPath(newname)
+	if oldname == "" || newname == "" {
+		return probe.NewError(InvalidArgument{})
+	}
+	st, ok := disk.files[oldname]
+	if !ok {
+		return probe.NewError(&os.PathError{Op: "rename", Path: oldname, Err: os.ErrNotExist})
+	}
+	if _, ok := disk.dirs[newname]; ok {
+		return probe.NewError(&os.PathError{Op: "rename", Path: newname, Err: os.ErrExist})
+	}
+	disk.makeDir(filepath.Dir(newname))
+	st.name = filepath.Base(newname)
+	disk.files[newname] = st
+	disk.data[newname] = disk.data[oldname]
+	delete(disk.files, oldname)
+	delete(disk.data, oldname)
+	return nil
+}
+
+// Delete - delete a file or a directory tree
+func (disk memoryDisk) Delete(filename string) *probe.Error {
+	disk.lock.Lock()
+	defer disk.lock.Unlock()
+
+	name := cleanPath(filename)
+	if name == "" {
+		return probe.NewError(InvalidArgument{})
+	}
+	if _, ok := disk.files[name]; ok {
+		delete(disk.files, name)
+		delete(disk.data, name)
+		return nil
+	}
+	if _, ok := disk.dirs[name]; !ok {
+		return probe.NewError(&os.PathError{Op: "remove", Path: filename, Err: os.ErrNotExist})
+	}
+	prefix := name + "/"
+	for dir := range disk.dirs {
+		if dir == name || strings.HasPrefix(dir, prefix) {
+			delete(disk.dirs, dir)
+		}
+	}
+	for file := range disk.files {
+		if strings.HasPrefix(file, prefix) {
+			delete(disk.files, file)
+			delete(disk.data, file)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or impliedisk.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"io"
+	"os"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// offlineDisk disk that could not be reached, every operation fails
+type offlineDisk struct {
+	path string
+}
+
+// NewOffline - stand in for a disk that could not be reached, keeping its place in the donut
+func NewOffline(diskPath string) Disk {
+	return offlineDisk{path: diskPath}
+}
+
+// IsUsable - is disk usable, alive
+func (disk offlineDisk) IsUsable() bool {
+	return false
+}
+
+// GetPath - get root disk path
+func (disk offlineDisk) GetPath() string {
+	return disk.path
+}
+
+// FSInfo - get disk filesystem and its usage information
+func (disk offlineDisk) FSInfo() (map[string]string, *probe.Error) {
+	return nil, probe.NewError(DiskOffline{Path: disk.path})
+}
+
+// MakeDir - make a directory
+func (disk offlineDisk) MakeDir(dirname string) *probe.Error {
+	return probe.NewError(DiskOffline{Path: disk.path})
+}
+
+// ListDir - list a directory
+func (disk offlineDisk) ListDir(dirname string) ([]os.FileInfo, *probe.Error) {
+	return nil, probe.NewError(DiskOffline{Path: disk.path})
+}
+
+// CreateFile - create a file
+func (disk offlineDisk) CreateFile(filename string) (File, *probe.Error) {
+	return nil, probe.NewError(DiskOffline{Path: disk.path})
+}
+
+// Open - read a file
+func (disk offlineDisk) Open(filename string) (io.ReadCloser, *probe.Error) {
+	return nil, probe.NewError(DiskOffline{Path: disk.path})
+}
+
+// Stat - file or directory information
+func (disk offlineDisk) Stat(filename string) (os.FileInfo, *probe.Error) {
+	return nil, probe.NewError(DiskOffline{Path: disk.path})
+}
+
+// Rename - rename a file replacing the destination
+func (disk offlineDisk) Rename(oldname, newname string) *probe.Error {
+	return probe.NewError(DiskOffline{Path: disk.path})
+}
+
+// Delete - delete a file or a directory tree
+func (disk offlineDisk) Delete(filename string) *probe.Error {
+	return probe.NewError(DiskOffline{Path: disk.path})
+}
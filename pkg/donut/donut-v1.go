@@ -19,9 +19,7 @@ package donut
 import (
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"encoding/xml"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
@@ -34,7 +32,6 @@ import (
 
 	"github.com/minio/minio-xl/pkg/crypto/sha256"
 	"github.com/minio/minio-xl/pkg/crypto/sha512"
-	"github.com/minio/minio-xl/pkg/donut/disk"
 	"github.com/minio/minio-xl/pkg/probe"
 	signv4 "github.com/minio/minio-xl/pkg/signature"
 )
@@ -45,19 +42,33 @@ const (
 	bucketMetadataConfig = "bucketMetadata.json"
 	objectMetadataConfig = "objectMetadata.json"
 
+	// object index of a bucket
+	indexDir            = "$index"
+	indexManifestConfig = "manifest.json"
+
+	// disk format
+	formatConfig = "format.json"
+
+	// staging area of uncommitted objects, at the root of every disk
+	stagingDir   = ".staging"
+	commitConfig = "commit.json"
+
 	// versions
 	objectMetadataVersion = "1.0.0"
 	bucketMetadataVersion = "1.0.0"
+	formatVersion         = "1.0.0"
+	commitVersion         = "1.0.0"
+	indexVersion          = "1.0.0"
 )
 
 /// v1 API functions
 
 // makeBucket - make a new bucket
-func (donut API) makeBucket(bucket string, acl BucketACL) *probe.Error {
+func (donut API) makeBucket(bucket string, acl BucketACL, owner string) *probe.Error {
 	if bucket == "" || strings.TrimSpace(bucket) == "" {
 		return probe.NewError(InvalidArgument{})
 	}
-	return donut.makeDonutBucket(bucket, acl.String())
+	return donut.makeDonutBucket(bucket, acl.String(), owner)
 }
 
 // getBucketMetadata - get bucket metadata
@@ -68,11 +79,7 @@ func (donut API) getBucketMetadata(bucketName string) (BucketMetadata, *probe.Er
 	if _, ok := donut.buckets[bucketName]; !ok {
 		return BucketMetadata{}, probe.NewError(BucketNotFound{Bucket: bucketName})
 	}
-	metadata, err := donut.getDonutBucketMetadata()
-	if err != nil {
-		return BucketMetadata{}, err.Trace()
-	}
-	return metadata.Buckets[bucketName], nil
+	return donut.buckets[bucketName].getBucketMetadata()
 }
 
 // setBucketMetadata - set bucket metadata
@@ -80,18 +87,17 @@ func (donut API) setBucketMetadata(bucketName string, bucketMetadata map[string]
 	if err := donut.listDonutBuckets(); err != nil {
 		return err.Trace()
 	}
-	metadata, err := donut.getDonutBucketMetadata()
-	if err != nil {
-		return err.Trace()
+	if _, ok := donut.buckets[bucketName]; !ok {
+		return probe.NewError(BucketNotFound{Bucket: bucketName})
 	}
-	oldBucketMetadata := metadata.Buckets[bucketName]
-	acl, ok := bucketMetadata["acl"]
-	if !ok {
+	if len(bucketMetadata) == 0 {
 		return probe.NewError(InvalidArgument{})
 	}
-	oldBucketMetadata.ACL = BucketACL(acl)
-	metadata.Buckets[bucketName] = oldBucketMetadata
-	return donut.setDonutBucketMetadata(metadata)
+	oldBucketMetadata, err := donut.buckets[bucketName].getBucketMetadata()
+	if err != nil {
+		return err.Trace()
+	}
+	return donut.buckets[bucketName].setBucketMetadata(mergeBucketMetadata(oldBucketMetadata, bucketMetadata))
 }
 
 // listBuckets - return list of buckets
@@ -99,17 +105,19 @@ func (donut API) listBuckets() (map[string]BucketMetadata, *probe.Error) {
 	if err := donut.listDonutBuckets(); err != nil {
 		return nil, err.Trace()
 	}
-	metadata, err := donut.getDonutBucketMetadata()
-	if err != nil {
-		// intentionally left out the error when Donut is empty
-		// but we need to revisit this area in future - since we need
-		// to figure out between acceptable and unacceptable errors
-		return make(map[string]BucketMetadata), nil
-	}
-	if metadata == nil {
-		return make(map[string]BucketMetadata), nil
+	buckets := make(map[string]BucketMetadata)
+	for bucketName, bucket := range donut.buckets {
+		metadata, err := bucket.getBucketMetadata()
+		if err != nil {
+			// bucket left half made, its metadata was never written
+			if os.IsNotExist(err.ToGoError()) {
+				continue
+			}
+			return nil, err.Trace()
+		}
+		buckets[bucketName] = metadata
 	}
-	return metadata.Buckets, nil
+	return buckets, nil
 }
 
 // listObjects - return list of objects
@@ -128,7 +136,7 @@ func (donut API) listObjects(bucket, prefix, marker, delimiter string, maxkeys i
 }
 
 // putObject - put object
-func (donut API) putObject(bucket, object, expectedMD5Sum string, reader io.Reader, size int64, metadata map[string]string, signature *signv4.Signature) (ObjectMetadata, *probe.Error) {
+func (donut API) putObject(bucket, object, expectedMD5Sum string, reader io.Reader, size int64, metadata map[string]string, signature *signv4.Signature, encryption *Encryption) (ObjectMetadata, *probe.Error) {
 	if bucket == "" || strings.TrimSpace(bucket) == "" {
 		return ObjectMetadata{}, probe.NewError(InvalidArgument{})
 	}
@@ -141,21 +149,17 @@ func (donut API) putObject(bucket, object, expectedMD5Sum string, reader io.Read
 	if _, ok := donut.buckets[bucket]; !ok {
 		return ObjectMetadata{}, probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	bucketMeta, err := donut.getDonutBucketMetadata()
+	exists, err := donut.buckets[bucket].hasObject(object)
 	if err != nil {
 		return ObjectMetadata{}, err.Trace()
 	}
-	if _, ok := bucketMeta.Buckets[bucket].BucketObjects[object]; ok {
+	if exists {
 		return ObjectMetadata{}, probe.NewError(ObjectExists{Object: object})
 	}
-	objMetadata, err := donut.buckets[bucket].WriteObject(object, reader, size, expectedMD5Sum, metadata, signature)
+	objMetadata, err := donut.buckets[bucket].WriteObject(object, reader, size, expectedMD5Sum, metadata, signature, encryption)
 	if err != nil {
 		return ObjectMetadata{}, err.Trace()
 	}
-	bucketMeta.Buckets[bucket].BucketObjects[object] = struct{}{}
-	if err := donut.setDonutBucketMetadata(bucketMeta); err != nil {
-		return ObjectMetadata{}, err.Trace()
-	}
 	return objMetadata, nil
 }
 
@@ -173,18 +177,22 @@ func (donut API) putObjectPart(bucket, object, expectedMD5Sum, uploadID string,
 	if _, ok := donut.buckets[bucket]; !ok {
 		return PartMetadata{}, probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	bucketMeta, err := donut.getDonutBucketMetadata()
+	bucketMeta, err := donut.buckets[bucket].getBucketMetadata()
 	if err != nil {
 		return PartMetadata{}, err.Trace()
 	}
-	if _, ok := bucketMeta.Buckets[bucket].Multiparts[object]; !ok {
+	if _, ok := bucketMeta.Multiparts[object]; !ok {
 		return PartMetadata{}, probe.NewError(InvalidUploadID{UploadID: uploadID})
 	}
-	if _, ok := bucketMeta.Buckets[bucket].BucketObjects[object]; ok {
+	exists, err := donut.buckets[bucket].hasObject(object)
+	if err != nil {
+		return PartMetadata{}, err.Trace()
+	}
+	if exists {
 		return PartMetadata{}, probe.NewError(ObjectExists{Object: object})
 	}
 	objectPart := object + "/" + "multipart" + "/" + strconv.Itoa(partID)
-	objmetadata, err := donut.buckets[bucket].WriteObject(objectPart, reader, size, expectedMD5Sum, metadata, signature)
+	objmetadata, err := donut.buckets[bucket].WriteObjectPart(objectPart, reader, size, expectedMD5Sum, metadata, signature)
 	if err != nil {
 		return PartMetadata{}, err.Trace()
 	}
@@ -194,17 +202,17 @@ func (donut API) putObjectPart(bucket, object, expectedMD5Sum, uploadID string,
 		ETag:         objmetadata.MD5Sum,
 		Size:         objmetadata.Size,
 	}
-	multipartSession := bucketMeta.Buckets[bucket].Multiparts[object]
+	multipartSession := bucketMeta.Multiparts[object]
 	multipartSession.Parts[strconv.Itoa(partID)] = partMetadata
-	bucketMeta.Buckets[bucket].Multiparts[object] = multipartSession
-	if err := donut.setDonutBucketMetadata(bucketMeta); err != nil {
+	bucketMeta.Multiparts[object] = multipartSession
+	if err := donut.buckets[bucket].setBucketMetadata(bucketMeta); err != nil {
 		return PartMetadata{}, err.Trace()
 	}
 	return partMetadata, nil
 }
 
 // getObject - get object
-func (donut API) getObject(bucket, object string) (reader io.ReadCloser, size int64, err *probe.Error) {
+func (donut API) getObject(bucket, object string, encryption *Encryption) (reader io.ReadCloser, size int64, err *probe.Error) {
 	if bucket == "" || strings.TrimSpace(bucket) == "" {
 		return nil, 0, probe.NewError(InvalidArgument{})
 	}
@@ -217,7 +225,7 @@ func (donut API) getObject(bucket, object string) (reader io.ReadCloser, size in
 	if _, ok := donut.buckets[bucket]; !ok {
 		return nil, 0, probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	return donut.buckets[bucket].ReadObject(object)
+	return donut.buckets[bucket].ReadObject(object, encryption)
 }
 
 // getObjectMetadata - get object metadata
@@ -228,11 +236,11 @@ func (donut API) getObjectMetadata(bucket, object string) (ObjectMetadata, *prob
 	if _, ok := donut.buckets[bucket]; !ok {
 		return ObjectMetadata{}, probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	bucketMeta, err := donut.getDonutBucketMetadata()
+	exists, err := donut.buckets[bucket].hasObject(object)
 	if err != nil {
 		return ObjectMetadata{}, err.Trace()
 	}
-	if _, ok := bucketMeta.Buckets[bucket].BucketObjects[object]; !ok {
+	if !exists {
 		return ObjectMetadata{}, probe.NewError(ObjectNotFound{Object: object})
 	}
 	objectMetadata, err := donut.buckets[bucket].GetObjectMetadata(object)
@@ -242,19 +250,36 @@ func (donut API) getObjectMetadata(bucket, object string) (ObjectMetadata, *prob
 	return objectMetadata, nil
 }
 
+// setObjectMetadata - set object metadata
+func (donut API) setObjectMetadata(bucket, object string, objMetadata ObjectMetadata) *probe.Error {
+	if err := donut.listDonutBuckets(); err != nil {
+		return err.Trace()
+	}
+	if _, ok := donut.buckets[bucket]; !ok {
+		return probe.NewError(BucketNotFound{Bucket: bucket})
+	}
+	exists, err := donut.buckets[bucket].hasObject(object)
+	if err != nil {
+		return err.Trace()
+	}
+	if !exists {
+		return probe.NewError(ObjectNotFound{Object: object})
+	}
+	return donut.buckets[bucket].SetObjectMetadata(object, objMetadata)
+}
+
 // newMultipartUpload - new multipart upload request
-func (donut API) newMultipartUpload(bucket, object, contentType string) (string, *probe.Error) {
+func (donut API) newMultipartUpload(bucket, object string, metadata map[string]string) (string, *probe.Error) {
 	if err := donut.listDonutBuckets(); err != nil {
 		return "", err.Trace()
 	}
 	if _, ok := donut.buckets[bucket]; !ok {
 		return "", probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	allbuckets, err := donut.getDonutBucketMetadata()
+	bucketMetadata, err := donut.buckets[bucket].getBucketMetadata()
 	if err != nil {
 		return "", err.Trace()
 	}
-	bucketMetadata := allbuckets.Buckets[bucket]
 	multiparts := make(map[string]MultiPartSession)
 	if len(bucketMetadata.Multiparts) > 0 {
 		multiparts = bucketMetadata.Multiparts
@@ -269,12 +294,12 @@ func (donut API) newMultipartUpload(bucket, object, contentType string) (string,
 		Initiated:  time.Now().UTC(),
 		Parts:      make(map[string]PartMetadata),
 		TotalParts: 0,
+		Metadata:   metadata,
 	}
 	multiparts[object] = multipartSession
 	bucketMetadata.Multiparts = multiparts
-	allbuckets.Buckets[bucket] = bucketMetadata
 
-	if err := donut.setDonutBucketMetadata(allbuckets); err != nil {
+	if err := donut.buckets[bucket].setBucketMetadata(bucketMetadata); err != nil {
 		return "", err.Trace()
 	}
 
@@ -295,11 +320,10 @@ func (donut API) listObjectParts(bucket, object string, resources ObjectResource
 	if _, ok := donut.buckets[bucket]; !ok {
 		return ObjectResourcesMetadata{}, probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	allBuckets, err := donut.getDonutBucketMetadata()
+	bucketMetadata, err := donut.buckets[bucket].getBucketMetadata()
 	if err != nil {
 		return ObjectResourcesMetadata{}, err.Trace()
 	}
-	bucketMetadata := allBuckets.Buckets[bucket]
 	if _, ok := bucketMetadata.Multiparts[object]; !ok {
 		return ObjectResourcesMetadata{}, probe.NewError(InvalidUploadID{UploadID: resources.UploadID})
 	}
@@ -350,11 +374,10 @@ func (donut API) completeMultipartUpload(bucket, object, uploadID string, data i
 	if _, ok := donut.buckets[bucket]; !ok {
 		return ObjectMetadata{}, probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	allBuckets, err := donut.getDonutBucketMetadata()
+	bucketMetadata, err := donut.buckets[bucket].getBucketMetadata()
 	if err != nil {
 		return ObjectMetadata{}, err.Trace()
 	}
-	bucketMetadata := allBuckets.Buckets[bucket]
 	if _, ok := bucketMetadata.Multiparts[object]; !ok {
 		return ObjectMetadata{}, probe.NewError(InvalidUploadID{UploadID: uploadID})
 	}
@@ -419,11 +442,10 @@ func (donut API) listMultipartUploads(bucket string, resources BucketMultipartRe
 	if _, ok := donut.buckets[bucket]; !ok {
 		return BucketMultipartResourcesMetadata{}, probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	allbuckets, err := donut.getDonutBucketMetadata()
+	bucketMetadata, err := donut.buckets[bucket].getBucketMetadata()
 	if err != nil {
 		return BucketMultipartResourcesMetadata{}, err.Trace()
 	}
-	bucketMetadata := allbuckets.Buckets[bucket]
 	var uploads []*UploadMetadata
 	for key, session := range bucketMetadata.Multiparts {
 		if strings.HasPrefix(key, resources.Prefix) {
@@ -477,11 +499,10 @@ func (donut API) abortMultipartUpload(bucket, object, uploadID string) *probe.Er
 	if _, ok := donut.buckets[bucket]; !ok {
 		return probe.NewError(BucketNotFound{Bucket: bucket})
 	}
-	allbuckets, err := donut.getDonutBucketMetadata()
+	bucketMetadata, err := donut.buckets[bucket].getBucketMetadata()
 	if err != nil {
 		return err.Trace()
 	}
-	bucketMetadata := allbuckets.Buckets[bucket]
 	if _, ok := bucketMetadata.Multiparts[object]; !ok {
 		return probe.NewError(InvalidUploadID{UploadID: uploadID})
 	}
@@ -490,8 +511,7 @@ func (donut API) abortMultipartUpload(bucket, object, uploadID string) *probe.Er
 	}
 	delete(bucketMetadata.Multiparts, object)
 
-	allbuckets.Buckets[bucket] = bucketMetadata
-	if err := donut.setDonutBucketMetadata(allbuckets); err != nil {
+	if err := donut.buckets[bucket].setBucketMetadata(bucketMetadata); err != nil {
 		return err.Trace()
 	}
 
@@ -500,98 +520,8 @@ func (donut API) abortMultipartUpload(bucket, object, uploadID string) *probe.Er
 
 //// internal functions
 
-// getBucketMetadataWriters -
-func (donut API) getBucketMetadataWriters() ([]io.WriteCloser, *probe.Error) {
-	var writers []io.WriteCloser
-	for _, node := range donut.nodes {
-		disks, err := node.ListDisks()
-		if err != nil {
-			return nil, err.Trace()
-		}
-		writers = make([]io.WriteCloser, len(disks))
-		for order, disk := range disks {
-			bucketMetaDataWriter, err := disk.CreateFile(filepath.Join(donut.config.DonutName, bucketMetadataConfig))
-			if err != nil {
-				return nil, err.Trace()
-			}
-			writers[order] = bucketMetaDataWriter
-		}
-	}
-	return writers, nil
-}
-
-// getBucketMetadataReaders - readers are returned in map rather than slice
-func (donut API) getBucketMetadataReaders() (map[int]io.ReadCloser, *probe.Error) {
-	readers := make(map[int]io.ReadCloser)
-	disks := make(map[int]disk.Disk)
-	var err *probe.Error
-	for _, node := range donut.nodes {
-		nDisks := make(map[int]disk.Disk)
-		nDisks, err = node.ListDisks()
-		if err != nil {
-			return nil, err.Trace()
-		}
-		for k, v := range nDisks {
-			disks[k] = v
-		}
-	}
-	var bucketMetaDataReader io.ReadCloser
-	for order, disk := range disks {
-		bucketMetaDataReader, err = disk.Open(filepath.Join(donut.config.DonutName, bucketMetadataConfig))
-		if err != nil {
-			continue
-		}
-		readers[order] = bucketMetaDataReader
-	}
-	if err != nil {
-		return nil, err.Trace()
-	}
-	return readers, nil
-}
-
-// setDonutBucketMetadata -
-func (donut API) setDonutBucketMetadata(metadata *AllBuckets) *probe.Error {
-	writers, err := donut.getBucketMetadataWriters()
-	if err != nil {
-		return err.Trace()
-	}
-	for _, writer := range writers {
-		jenc := json.NewEncoder(writer)
-		if err := jenc.Encode(metadata); err != nil {
-			CleanupWritersOnError(writers)
-			return probe.NewError(err)
-		}
-	}
-	for _, writer := range writers {
-		writer.Close()
-	}
-	return nil
-}
-
-// getDonutBucketMetadata -
-func (donut API) getDonutBucketMetadata() (*AllBuckets, *probe.Error) {
-	metadata := &AllBuckets{}
-	readers, err := donut.getBucketMetadataReaders()
-	if err != nil {
-		return nil, err.Trace()
-	}
-	for _, reader := range readers {
-		defer reader.Close()
-	}
-	{
-		var err error
-		for _, reader := range readers {
-			jenc := json.NewDecoder(reader)
-			if err = jenc.Decode(metadata); err == nil {
-				return metadata, nil
-			}
-		}
-		return nil, probe.NewError(err)
-	}
-}
-
 // makeDonutBucket -
-func (donut API) makeDonutBucket(bucketName, acl string) *probe.Error {
+func (donut API) makeDonutBucket(bucketName, acl, owner string) *probe.Error {
 	if err := donut.listDonutBuckets(); err != nil {
 		return err.Trace()
 	}
@@ -602,55 +532,29 @@ func (donut API) makeDonutBucket(bucketName, acl string) *probe.Error {
 	if err != nil {
 		return err.Trace()
 	}
-	nodeNumber := 0
+	bucketMetadata.Owner = owner
 	donut.buckets[bucketName] = bkt
-	for _, node := range donut.nodes {
-		disks := make(map[int]disk.Disk)
-		disks, err = node.ListDisks()
-		if err != nil {
-			return err.Trace()
-		}
-		for order, disk := range disks {
-			bucketSlice := fmt.Sprintf("%s$%d$%d", bucketName, nodeNumber, order)
-			err := disk.MakeDir(filepath.Join(donut.config.DonutName, bucketSlice))
-			if err != nil {
-				return err.Trace()
-			}
-		}
-		nodeNumber = nodeNumber + 1
-	}
-	var metadata *AllBuckets
-	metadata, err = donut.getDonutBucketMetadata()
+	disks, err := listNodeDisks(donut.nodes)
 	if err != nil {
-		if os.IsNotExist(err.ToGoError()) {
-			metadata = new(AllBuckets)
-			metadata.Buckets = make(map[string]BucketMetadata)
-			metadata.Buckets[bucketName] = bucketMetadata
-			err = donut.setDonutBucketMetadata(metadata)
-			if err != nil {
-				return err.Trace()
-			}
-			return nil
-		}
 		return err.Trace()
 	}
-	metadata.Buckets[bucketName] = bucketMetadata
-	err = donut.setDonutBucketMetadata(metadata)
-	if err != nil {
-		return err.Trace()
+	for _, disk := range disks {
+		err := disk.MakeDir(filepath.Join(donut.config.DonutName, disk.bucketSlice(bucketName)))
+		if err != nil {
+			return err.Trace()
+		}
 	}
-	return nil
+	return bkt.setBucketMetadata(bucketMetadata)
 }
 
-// listDonutBuckets -
+// listDonutBuckets - list buckets on disks once, buckets made later are added by makeDonutBucket
 func (donut API) listDonutBuckets() *probe.Error {
-	var disks map[int]disk.Disk
-	var err *probe.Error
-	for _, node := range donut.nodes {
-		disks, err = node.ListDisks()
-		if err != nil {
-			return err.Trace()
-		}
+	if *donut.bucketsListed {
+		return nil
+	}
+	disks, err := listNodeDisks(donut.nodes)
+	if err != nil {
+		return err.Trace()
 	}
 	var dirs []os.FileInfo
 	for _, disk := range disks {
@@ -669,12 +573,16 @@ func (donut API) listDonutBuckets() *probe.Error {
 			return probe.NewError(CorruptedBackend{Backend: dir.Name()})
 		}
 		bucketName := splitDir[0]
-		// we dont need this once we cache from makeDonutBucket()
+		// buckets are kept across calls, they hold the index manifest read so far
+		if _, ok := donut.buckets[bucketName]; ok {
+			continue
+		}
 		bkt, _, err := newBucket(bucketName, "private", donut.config.DonutName, donut.nodes)
 		if err != nil {
 			return err.Trace()
 		}
 		donut.buckets[bucketName] = bkt
 	}
+	*donut.bucketsListed = true
 	return nil
 }
@@ -19,6 +19,7 @@ package donut
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/sha512"
 	"encoding/base64"
 	"encoding/hex"
 	"io/ioutil"
@@ -86,16 +87,16 @@ func (s *MyDonutSuite) TearDownSuite(c *C) {
 // test make bucket without name
 func (s *MyDonutSuite) TestBucketWithoutNameFails(c *C) {
 	// fail to create new bucket without a name
-	err := dd.MakeBucket("", "private", nil, nil)
+	err := dd.MakeBucket("", "private", "", nil, nil)
 	c.Assert(err, Not(IsNil))
 
-	err = dd.MakeBucket(" ", "private", nil, nil)
+	err = dd.MakeBucket(" ", "private", "", nil, nil)
 	c.Assert(err, Not(IsNil))
 }
 
 // test empty bucket
 func (s *MyDonutSuite) TestEmptyBucket(c *C) {
-	c.Assert(dd.MakeBucket("foo1", "private", nil, nil), IsNil)
+	c.Assert(dd.MakeBucket("foo1", "private", "", nil, nil), IsNil)
 	// check if bucket is empty
 	var resources BucketResourcesMetadata
 	resources.Maxkeys = 1
@@ -109,7 +110,7 @@ func (s *MyDonutSuite) TestEmptyBucket(c *C) {
 // test bucket list
 func (s *MyDonutSuite) TestMakeBucketAndList(c *C) {
 	// create bucket
-	err := dd.MakeBucket("foo2", "private", nil, nil)
+	err := dd.MakeBucket("foo2", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
 	// check bucket exists
@@ -121,20 +122,20 @@ func (s *MyDonutSuite) TestMakeBucketAndList(c *C) {
 
 // test re-create bucket
 func (s *MyDonutSuite) TestMakeBucketWithSameNameFails(c *C) {
-	err := dd.MakeBucket("foo3", "private", nil, nil)
+	err := dd.MakeBucket("foo3", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
-	err = dd.MakeBucket("foo3", "private", nil, nil)
+	err = dd.MakeBucket("foo3", "private", "", nil, nil)
 	c.Assert(err, Not(IsNil))
 }
 
 // test make multiple buckets
 func (s *MyDonutSuite) TestCreateMultipleBucketsAndList(c *C) {
 	// add a second bucket
-	err := dd.MakeBucket("foo4", "private", nil, nil)
+	err := dd.MakeBucket("foo4", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
-	err = dd.MakeBucket("bar1", "private", nil, nil)
+	err = dd.MakeBucket("bar1", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
 	buckets, err := dd.ListBuckets()
@@ -144,7 +145,7 @@ func (s *MyDonutSuite) TestCreateMultipleBucketsAndList(c *C) {
 	c.Assert(buckets[0].Name, Equals, "bar1")
 	c.Assert(buckets[1].Name, Equals, "foo4")
 
-	err = dd.MakeBucket("foobar1", "private", nil, nil)
+	err = dd.MakeBucket("foobar1", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
 	buckets, err = dd.ListBuckets()
@@ -156,7 +157,7 @@ func (s *MyDonutSuite) TestCreateMultipleBucketsAndList(c *C) {
 
 // test object create without bucket
 func (s *MyDonutSuite) TestNewObjectFailsWithoutBucket(c *C) {
-	_, err := dd.CreateObject("unknown", "obj", "", 0, nil, nil, nil)
+	_, err := dd.CreateObject("unknown", "obj", "", 0, nil, nil, nil, nil)
 	c.Assert(err, Not(IsNil))
 }
 
@@ -168,10 +169,10 @@ func (s *MyDonutSuite) TestNewObjectMetadata(c *C) {
 	expectedMd5Sum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
 	reader := ioutil.NopCloser(bytes.NewReader([]byte(data)))
 
-	err := dd.MakeBucket("foo6", "private", nil, nil)
+	err := dd.MakeBucket("foo6", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
-	objectMetadata, err := dd.CreateObject("foo6", "obj", expectedMd5Sum, int64(len(data)), reader, map[string]string{"contentType": "application/json"}, nil)
+	objectMetadata, err := dd.CreateObject("foo6", "obj", expectedMd5Sum, int64(len(data)), reader, map[string]string{"contentType": "application/json"}, nil, nil)
 	c.Assert(err, IsNil)
 	c.Assert(objectMetadata.MD5Sum, Equals, hex.EncodeToString(hasher.Sum(nil)))
 	c.Assert(objectMetadata.Metadata["contentType"], Equals, "application/json")
@@ -179,13 +180,13 @@ func (s *MyDonutSuite) TestNewObjectMetadata(c *C) {
 
 // test create object fails without name
 func (s *MyDonutSuite) TestNewObjectFailsWithEmptyName(c *C) {
-	_, err := dd.CreateObject("foo", "", "", 0, nil, nil, nil)
+	_, err := dd.CreateObject("foo", "", "", 0, nil, nil, nil, nil)
 	c.Assert(err, Not(IsNil))
 }
 
 // test create object
 func (s *MyDonutSuite) TestNewObjectCanBeWritten(c *C) {
-	err := dd.MakeBucket("foo", "private", nil, nil)
+	err := dd.MakeBucket("foo", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
 	data := "Hello World"
@@ -195,12 +196,12 @@ func (s *MyDonutSuite) TestNewObjectCanBeWritten(c *C) {
 	expectedMd5Sum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
 	reader := ioutil.NopCloser(bytes.NewReader([]byte(data)))
 
-	actualMetadata, err := dd.CreateObject("foo", "obj", expectedMd5Sum, int64(len(data)), reader, map[string]string{"contentType": "application/octet-stream"}, nil)
+	actualMetadata, err := dd.CreateObject("foo", "obj", expectedMd5Sum, int64(len(data)), reader, map[string]string{"contentType": "application/octet-stream"}, nil, nil)
 	c.Assert(err, IsNil)
 	c.Assert(actualMetadata.MD5Sum, Equals, hex.EncodeToString(hasher.Sum(nil)))
 
 	var buffer bytes.Buffer
-	size, err := dd.GetObject(&buffer, "foo", "obj", 0, 0)
+	size, err := dd.GetObject(&buffer, "foo", "obj", 0, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(size, Equals, int64(len(data)))
 	c.Assert(buffer.Bytes(), DeepEquals, []byte(data))
@@ -213,25 +214,25 @@ func (s *MyDonutSuite) TestNewObjectCanBeWritten(c *C) {
 
 // test list objects
 func (s *MyDonutSuite) TestMultipleNewObjects(c *C) {
-	c.Assert(dd.MakeBucket("foo5", "private", nil, nil), IsNil)
+	c.Assert(dd.MakeBucket("foo5", "private", "", nil, nil), IsNil)
 
 	one := ioutil.NopCloser(bytes.NewReader([]byte("one")))
 
-	_, err := dd.CreateObject("foo5", "obj1", "", int64(len("one")), one, nil, nil)
+	_, err := dd.CreateObject("foo5", "obj1", "", int64(len("one")), one, nil, nil, nil)
 	c.Assert(err, IsNil)
 
 	two := ioutil.NopCloser(bytes.NewReader([]byte("two")))
-	_, err = dd.CreateObject("foo5", "obj2", "", int64(len("two")), two, nil, nil)
+	_, err = dd.CreateObject("foo5", "obj2", "", int64(len("two")), two, nil, nil, nil)
 	c.Assert(err, IsNil)
 
 	var buffer1 bytes.Buffer
-	size, err := dd.GetObject(&buffer1, "foo5", "obj1", 0, 0)
+	size, err := dd.GetObject(&buffer1, "foo5", "obj1", 0, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(size, Equals, int64(len([]byte("one"))))
 	c.Assert(buffer1.Bytes(), DeepEquals, []byte("one"))
 
 	var buffer2 bytes.Buffer
-	size, err = dd.GetObject(&buffer2, "foo5", "obj2", 0, 0)
+	size, err = dd.GetObject(&buffer2, "foo5", "obj2", 0, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(size, Equals, int64(len([]byte("two"))))
 
@@ -270,11 +271,11 @@ func (s *MyDonutSuite) TestMultipleNewObjects(c *C) {
 	c.Assert(objectsMetadata[1].Object, Equals, "obj2")
 
 	three := ioutil.NopCloser(bytes.NewReader([]byte("three")))
-	_, err = dd.CreateObject("foo5", "obj3", "", int64(len("three")), three, nil, nil)
+	_, err = dd.CreateObject("foo5", "obj3", "", int64(len("three")), three, nil, nil, nil)
 	c.Assert(err, IsNil)
 
 	var buffer bytes.Buffer
-	size, err = dd.GetObject(&buffer, "foo5", "obj3", 0, 0)
+	size, err = dd.GetObject(&buffer, "foo5", "obj3", 0, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(size, Equals, int64(len([]byte("three"))))
 	c.Assert(buffer.Bytes(), DeepEquals, []byte("three"))
@@ -288,3 +289,195 @@ func (s *MyDonutSuite) TestMultipleNewObjects(c *C) {
 	c.Assert(resources.IsTruncated, Equals, true)
 	c.Assert(len(objectsMetadata), Equals, 2)
 }
+
+// test object and bucket tags
+func (s *MyDonutSuite) TestTags(c *C) {
+	c.Assert(dd.MakeBucket("foo7", "private", "", nil, nil), IsNil)
+
+	tags, err := DecodeTags("project=minio&env=test")
+	c.Assert(err, IsNil)
+	c.Assert(IsValidObjectTags(tags), IsNil)
+	c.Assert(tags.Encode(), Equals, "env=test&project=minio")
+
+	one := ioutil.NopCloser(bytes.NewReader([]byte("one")))
+	_, err = dd.CreateObject("foo7", "obj1", "", int64(len("one")), one, map[string]string{TaggingMetadataKey: tags.Encode()}, nil, nil)
+	c.Assert(err, IsNil)
+
+	objectMetadata, err := dd.GetObjectMetadata("foo7", "obj1")
+	c.Assert(err, IsNil)
+	c.Assert(TagFilter{Prefix: "obj", Tags: Tags{"env": "test"}}.Match(objectMetadata), Equals, true)
+	c.Assert(objectMetadata.Metadata[TaggingMetadataKey], Equals, tags.Encode())
+	c.Assert(TagFilter{Prefix: "obj"}.Match(objectMetadata), Equals, true)
+	c.Assert(TagFilter{Prefix: "other", Tags: Tags{"env": "test"}}.Match(objectMetadata), Equals, false)
+	c.Assert(TagFilter{Tags: Tags{"env": "prod"}}.Match(objectMetadata), Equals, false)
+
+	err = dd.SetObjectMetadata("foo7", "obj1", map[string]string{TaggingMetadataKey: "env=prod"})
+	c.Assert(err, IsNil)
+	objectMetadata, err = dd.GetObjectMetadata("foo7", "obj1")
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.Metadata[TaggingMetadataKey], Equals, "env=prod")
+	c.Assert(objectMetadata.Metadata["contentType"], Equals, "application/octet-stream")
+
+	err = dd.SetObjectMetadata("foo7", "obj1", map[string]string{TaggingMetadataKey: ""})
+	c.Assert(err, IsNil)
+	objectMetadata, err = dd.GetObjectMetadata("foo7", "obj1")
+	c.Assert(err, IsNil)
+	_, ok := objectMetadata.Metadata[TaggingMetadataKey]
+	c.Assert(ok, Equals, false)
+
+	err = dd.SetObjectMetadata("foo7", "obj2", map[string]string{TaggingMetadataKey: "env=prod"})
+	c.Assert(err, Not(IsNil))
+
+	err = dd.SetBucketMetadata("foo7", map[string]string{TaggingMetadataKey: "team=storage"})
+	c.Assert(err, IsNil)
+	bucketMetadata, err := dd.GetBucketMetadata("foo7")
+	c.Assert(err, IsNil)
+	c.Assert(bucketMetadata.Metadata[TaggingMetadataKey], Equals, "team=storage")
+	c.Assert(bucketMetadata.ACL.IsPrivate(), Equals, true)
+
+	tags = make(Tags)
+	for i := 0; i < 11; i++ {
+		tags["key"+strconv.Itoa(i)] = "value"
+	}
+	c.Assert(IsValidObjectTags(tags), Not(IsNil))
+	c.Assert(IsValidBucketTags(tags), IsNil)
+	c.Assert(IsValidObjectTags(Tags{"aws:key": "value"}), Not(IsNil))
+}
+
+func (s *MyDonutSuite) TestObjectEncryption(c *C) {
+	c.Assert(dd.MakeBucket("encrypted", "private", "", nil, nil), IsNil)
+
+	key := bytes.Repeat([]byte("k"), EncryptionKeySize)
+	encryption := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: key}
+	data := "encrypted object data spanning more than a single block of the cipher"
+	hasher := md5.New()
+	hasher.Write([]byte(data))
+	objectMetadata, err := dd.CreateObject("encrypted", "obj", "", int64(len(data)), bytes.NewReader([]byte(data)), nil, nil, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.IsEncrypted(), Equals, true)
+	c.Assert(objectMetadata.IsCustomerEncrypted(), Equals, true)
+	c.Assert(objectMetadata.EncryptionKeyMAC, Not(Equals), "")
+
+	// checksums of plain data are never saved, requests are still verified with them
+	storedMetadata, err := dd.GetObjectMetadata("encrypted", "obj")
+	c.Assert(err, IsNil)
+	c.Assert(storedMetadata.MD5Sum, Not(Equals), hex.EncodeToString(hasher.Sum(nil)))
+	c.Assert(storedMetadata.MD5Sum, Equals, objectMetadata.MD5Sum)
+	plainSHA512Sum := sha512.Sum512([]byte(data))
+	c.Assert(storedMetadata.SHA512Sum, Not(Equals), hex.EncodeToString(plainSHA512Sum[:]))
+	expectedMd5Sum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+	_, err = dd.CreateObject("encrypted", "verified", expectedMd5Sum, int64(len(data)), bytes.NewReader([]byte(data)), nil, nil, encryption)
+	c.Assert(err, IsNil)
+	_, err = dd.CreateObject("encrypted", "verified", expectedMd5Sum, int64(len("other")), bytes.NewReader([]byte("other")), nil, nil, encryption)
+	c.Assert(err, Not(IsNil))
+
+	var buffer bytes.Buffer
+	size, err := dd.GetObject(&buffer, "encrypted", "obj", 0, 0, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(len(data)))
+	c.Assert(buffer.String(), Equals, data)
+
+	// ranges are decrypted from their offset
+	buffer.Reset()
+	size, err = dd.GetObject(&buffer, "encrypted", "obj", 21, 30, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(30))
+	c.Assert(buffer.String(), Equals, data[21:51])
+
+	_, err = dd.GetObject(ioutil.Discard, "encrypted", "obj", 0, 0, nil)
+	c.Assert(err.ToGoError(), DeepEquals, EncryptionKeyMissing{Object: "obj"})
+
+	wrongKey := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: bytes.Repeat([]byte("w"), EncryptionKeySize)}
+	_, err = dd.GetObject(ioutil.Discard, "encrypted", "obj", 0, 0, wrongKey)
+	c.Assert(err.ToGoError(), DeepEquals, EncryptionKeyMismatch{Object: "obj"})
+
+	metadata, err := dd.GetObjectMetadata("encrypted", "obj")
+	c.Assert(err, IsNil)
+	c.Assert(VerifyEncryption(metadata, encryption), IsNil)
+	c.Assert(VerifyEncryption(metadata, wrongKey), Not(IsNil))
+
+	// keys are verified for unencrypted objects as well
+	_, err = dd.CreateObject("encrypted", "plain", "", int64(len("plain")), bytes.NewReader([]byte("plain")), nil, nil, nil)
+	c.Assert(err, IsNil)
+	_, err = dd.GetObject(ioutil.Discard, "encrypted", "plain", 0, 0, encryption)
+	c.Assert(err.ToGoError(), DeepEquals, ObjectNotEncrypted{Object: "plain"})
+
+	invalidKey := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: []byte("short")}
+	_, err = dd.CreateObject("encrypted", "invalid", "", int64(len("plain")), bytes.NewReader([]byte("plain")), nil, nil, invalidKey)
+	c.Assert(err.ToGoError(), DeepEquals, InvalidEncryptionKey{})
+}
+
+func (s *MyDonutSuite) TestObjectServerEncryption(c *C) {
+	c.Assert(dd.MakeBucket("server-encrypted", "private", "", nil, nil), IsNil)
+
+	data := "server encrypted object data spanning more than a single block of the cipher"
+	encryption := &Encryption{ServerAlgorithm: EncryptionAlgorithm}
+	_, err := dd.CreateObject("server-encrypted", "obj", "", int64(len(data)), bytes.NewReader([]byte(data)), nil, nil, encryption)
+	c.Assert(err.ToGoError(), DeepEquals, KeyStoreNotConfigured{})
+
+	keys := &KeyStore{Version: "0.0.1"}
+	oldKeyID, err := keys.AddKey()
+	c.Assert(err, IsNil)
+	c.Assert(SaveKeyStore(keys), IsNil)
+	c.Assert(dd.RotateKeys(), IsNil)
+
+	objectMetadata, err := dd.CreateObject("server-encrypted", "obj", "", int64(len(data)), bytes.NewReader([]byte(data)), nil, nil, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.IsServerEncrypted(), Equals, true)
+	c.Assert(objectMetadata.EncryptionMasterKeyID, Equals, oldKeyID)
+
+	// server managed keys are never provided for reads
+	var buffer bytes.Buffer
+	size, err := dd.GetObject(&buffer, "server-encrypted", "obj", 21, 30, nil)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(30))
+	c.Assert(buffer.String(), Equals, data[21:51])
+
+	customerKey := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: bytes.Repeat([]byte("k"), EncryptionKeySize)}
+	_, err = dd.GetObject(ioutil.Discard, "server-encrypted", "obj", 0, 0, customerKey)
+	c.Assert(err.ToGoError(), DeepEquals, ServerEncryptedObject{Object: "obj"})
+
+	// new objects are encrypted by default once bucket default encryption is set
+	c.Assert(dd.SetBucketMetadata("server-encrypted", map[string]string{EncryptionMetadataKey: EncryptionAlgorithm}), IsNil)
+	objectMetadata, err = dd.CreateObject("server-encrypted", "default", "", int64(len("default")), bytes.NewReader([]byte("default")), nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.IsServerEncrypted(), Equals, true)
+
+	// rotation reseals data keys, object data is left as is
+	metadata, err := dd.GetObjectMetadata("server-encrypted", "obj")
+	c.Assert(err, IsNil)
+	newKeyID, err := keys.AddKey()
+	c.Assert(err, IsNil)
+	c.Assert(SaveKeyStore(keys), IsNil)
+	c.Assert(dd.RotateKeys(), IsNil)
+	resealed, err := dd.GetObjectMetadata("server-encrypted", "obj")
+	c.Assert(err, IsNil)
+	c.Assert(resealed.EncryptionMasterKeyID, Equals, newKeyID)
+	c.Assert(resealed.EncryptionSealedKey, Not(Equals), metadata.EncryptionSealedKey)
+	c.Assert(resealed.EncryptionIV, Equals, metadata.EncryptionIV)
+
+	// objects are not scanned again until the master key changes
+	loaded, err := LoadKeyStore()
+	c.Assert(err, IsNil)
+	c.Assert(loaded.Resealed, Equals, newKeyID)
+	loaded.Current = oldKeyID
+	loaded.Resealed = oldKeyID
+	c.Assert(SaveKeyStore(loaded), IsNil)
+	c.Assert(dd.RotateKeys(), IsNil)
+	unchanged, err := dd.GetObjectMetadata("server-encrypted", "obj")
+	c.Assert(err, IsNil)
+	c.Assert(unchanged.EncryptionMasterKeyID, Equals, newKeyID)
+
+	// old master key is no longer needed
+	delete(keys.Keys, oldKeyID)
+	c.Assert(SaveKeyStore(keys), IsNil)
+	c.Assert(dd.RotateKeys(), IsNil)
+	buffer.Reset()
+	_, err = dd.GetObject(&buffer, "server-encrypted", "obj", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.String(), Equals, data)
+	buffer.Reset()
+	_, err = dd.GetObject(&buffer, "server-encrypted", "default", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.String(), Equals, "default")
+}
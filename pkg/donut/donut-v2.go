@@ -18,12 +18,14 @@ package donut
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/xml"
 	"io"
 	"io/ioutil"
-	"log"
+	"path/filepath"
 	"runtime/debug"
 	"sort"
 	"strconv"
@@ -34,15 +36,14 @@ import (
 	"github.com/minio/minio-xl/pkg/crypto/sha256"
 	"github.com/minio/minio-xl/pkg/donut/cache/data"
 	"github.com/minio/minio-xl/pkg/donut/cache/metadata"
+	"github.com/minio/minio-xl/pkg/donut/disk"
 	"github.com/minio/minio-xl/pkg/probe"
 	"github.com/minio/minio-xl/pkg/quick"
 	signv4 "github.com/minio/minio-xl/pkg/signature"
 )
 
-// total Number of buckets allowed
-const (
-	totalBuckets = 100
-)
+// DefaultRegion region of the server unless configured otherwise
+const DefaultRegion = "milkyway"
 
 // Config donut config
 type Config struct {
@@ -50,6 +51,21 @@ type Config struct {
 	MaxSize     uint64              `json:"max-size"`
 	DonutName   string              `json:"donut-name"`
 	NodeDiskMap map[string][]string `json:"node-disk-map"`
+
+	// region of the server, buckets can be created only in this region
+	Region string `json:"region,omitempty"`
+
+	// maximum number of buckets, unlimited if not set
+	MaxBuckets int `json:"max-buckets,omitempty"`
+
+	// object data cache settings, on top of max-size
+	Cache *CacheConfig `json:"cache,omitempty"`
+
+	// bucket notification targets by id
+	NotificationTargets map[string]NotificationTarget `json:"notification-targets,omitempty"`
+
+	// faults injected into disks by disk path, only meant for chaos testing
+	Faults map[string]disk.Faults `json:"faults,omitempty"`
 }
 
 // API - local variables
@@ -61,12 +77,16 @@ type API struct {
 	storedBuckets    *metadata.Cache
 	nodes            map[string]node
 	buckets          map[string]bucket
+	bucketsListed    *bool
+	notifier         *notifier
+	keys             *KeyStore
 }
 
 // storedBucket saved bucket
 type storedBucket struct {
 	bucketMetadata   BucketMetadata
 	objectMetadata   map[string]ObjectMetadata
+	objectIndex      *memoryIndex
 	partMetadata     map[string]map[int]PartMetadata
 	multiPartSession map[string]MultiPartSession
 }
@@ -91,10 +111,29 @@ func New() (Interface, *probe.Error) {
 	a.storedBuckets = metadata.NewCache()
 	a.nodes = make(map[string]node)
 	a.buckets = make(map[string]bucket)
+	a.bucketsListed = new(bool)
 	a.objects = data.NewCache(a.config.MaxSize)
 	a.multiPartObjects = make(map[string]*data.Cache)
 	a.objects.OnEvicted = a.evictedObject
 	a.lock = new(sync.Mutex)
+	if err := a.configureCache(); err != nil {
+		return nil, err.Trace()
+	}
+	a.keys, err = LoadKeyStore()
+	if err != nil {
+		return nil, err.Trace()
+	}
+
+	if len(a.config.NotificationTargets) > 0 {
+		donutConfigPath, err := getDonutConfigPath()
+		if err != nil {
+			return nil, err.Trace()
+		}
+		a.notifier, err = newNotifier(a.config.NotificationTargets, filepath.Join(filepath.Dir(donutConfigPath), "notifications"))
+		if err != nil {
+			return nil, err.Trace()
+		}
+	}
 
 	if len(a.config.NodeDiskMap) > 0 {
 		for k, v := range a.config.NodeDiskMap {
@@ -106,6 +145,14 @@ func New() (Interface, *probe.Error) {
 				return nil, err.Trace()
 			}
 		}
+		if err := a.migrateBucketMetadata(); err != nil {
+			return nil, err.Trace()
+		}
+		// objects left staged by a crash are committed or discarded before anything is read, once
+		// migrated indexes are in place to add them to
+		if err := a.recoverCommits(); err != nil {
+			return nil, err.Trace()
+		}
 		/// Initialization, populate all buckets into memory
 		buckets, err := a.listBuckets()
 		if err != nil {
@@ -115,19 +162,24 @@ func New() (Interface, *probe.Error) {
 			var newBucket = storedBucket{}
 			newBucket.bucketMetadata = v
 			newBucket.objectMetadata = make(map[string]ObjectMetadata)
+			newBucket.objectIndex = newMemoryIndex()
 			newBucket.multiPartSession = make(map[string]MultiPartSession)
 			newBucket.partMetadata = make(map[string]map[int]PartMetadata)
 			a.storedBuckets.Set(k, newBucket)
 		}
 		a.Heal()
+		// reseal data keys if master key is rotated since last start
+		if err := a.resealKeys(); err != nil {
+			return nil, err.Trace()
+		}
 	}
 	return a, nil
 }
 
 /// V2 API functions
 
-// GetObject - GET object from cache buffer
-func (donut API) GetObject(w io.Writer, bucket string, object string, start, length int64) (int64, *probe.Error) {
+// GetObject - GET object from cache buffer, encrypted objects are read only with the key they were encrypted with
+func (donut API) GetObject(w io.Writer, bucket string, object string, start, length int64, encryption *Encryption) (int64, *probe.Error) {
 	donut.lock.Lock()
 	defer donut.lock.Unlock()
 
@@ -151,57 +203,86 @@ func (donut API) GetObject(w io.Writer, bucket string, object string, start, len
 	var written int64
 	if !ok {
 		if len(donut.config.NodeDiskMap) > 0 {
-			reader, size, err := donut.getObject(bucket, object)
+			objMetadata, err := donut.getObjectMetadata(bucket, object)
 			if err != nil {
 				return 0, err.Trace()
 			}
-			if start > 0 {
-				if _, err := io.CopyN(ioutil.Discard, reader, start); err != nil {
-					return 0, probe.NewError(err)
-				}
+			objEncryption, err := donut.getObjectEncryption(objMetadata, encryption)
+			if err != nil {
+				return 0, err.Trace()
+			}
+			reader, size, err := donut.getObject(bucket, object, objEncryption)
+			if err != nil {
+				return 0, err.Trace()
+			}
+			// closing stops decoding the rest of the object after a range is read
+			defer reader.Close()
+			// plain data of encrypted objects is never cached, nor are unverified ranges
+			if objMetadata.IsEncrypted() || start > 0 || (length > 0 && length < size) {
+				return donut.copyObject(w, reader, start, length, size)
 			}
 			// new proxy writer to capture data read from disk
 			pw := NewProxyWriter(w)
-			{
-				var err error
-				if length > 0 {
-					written, err = io.CopyN(pw, reader, length)
-					if err != nil {
-						return 0, probe.NewError(err)
-					}
-				} else {
-					written, err = io.CopyN(pw, reader, size)
-					if err != nil {
-						return 0, probe.NewError(err)
-					}
-				}
+			written, err = donut.copyObject(pw, reader, 0, 0, size)
+			if err != nil {
+				return 0, err.Trace()
 			}
-			/// cache object read from disk
-			ok := donut.objects.Append(objectKey, pw.writtenBytes)
+			/// cache object read from disk, unless the cache refuses it, it is read from disk again next time
+			donut.objects.Append(objectKey, pw.writtenBytes)
 			pw.writtenBytes = nil
 			go debug.FreeOSMemory()
-			if !ok {
-				return 0, probe.NewError(InternalError{})
-			}
 			return written, nil
 		}
 		return 0, probe.NewError(ObjectNotFound{Object: object})
 	}
+	objMetadata := donut.storedBuckets.Get(bucket).(storedBucket).objectMetadata[objectKey]
+	encryption, perr := donut.getObjectEncryption(objMetadata, encryption)
+	if perr != nil {
+		return 0, perr.Trace()
+	}
+	reader, perr := newDecryptReader(bytes.NewBuffer(data[start:]), objMetadata, encryption, start)
+	if perr != nil {
+		return 0, perr.Trace()
+	}
 	var err error
 	if start == 0 && length == 0 {
-		written, err = io.CopyN(w, bytes.NewBuffer(data), int64(donut.objects.Len(objectKey)))
+		written, err = io.CopyN(w, reader, int64(donut.objects.Len(objectKey)))
 		if err != nil {
 			return 0, probe.NewError(err)
 		}
 		return written, nil
 	}
-	written, err = io.CopyN(w, bytes.NewBuffer(data[start:]), length)
+	written, err = io.CopyN(w, reader, length)
 	if err != nil {
 		return 0, probe.NewError(err)
 	}
 	return written, nil
 }
 
+// copyObject - copy range of an object read from disk, length of zero copies upto the end
+func (donut API) copyObject(w io.Writer, reader io.Reader, start, length, size int64) (int64, *probe.Error) {
+	if start > 0 {
+		if _, err := io.CopyN(ioutil.Discard, reader, start); err != nil {
+			return 0, probe.NewError(err)
+		}
+	}
+	if length == 0 {
+		length = size - start
+	}
+	written, err := io.CopyN(w, reader, length)
+	if err != nil {
+		return 0, probe.NewError(err)
+	}
+	// checksums are verified once all data is read, only whole objects are drained to learn
+	// about a mismatch, ranges are not verified rather than decoding the entire object
+	if start == 0 && length == size {
+		if _, err := io.Copy(ioutil.Discard, reader); err != nil {
+			return 0, probe.NewError(err)
+		}
+	}
+	return written, nil
+}
+
 // GetBucketMetadata -
 func (donut API) GetBucketMetadata(bucket string) (BucketMetadata, *probe.Error) {
 	donut.lock.Lock()
@@ -225,6 +306,19 @@ func (donut API) GetBucketMetadata(bucket string) (BucketMetadata, *probe.Error)
 	return donut.storedBuckets.Get(bucket).(storedBucket).bucketMetadata, nil
 }
 
+// Region - region of the server, DefaultRegion unless configured otherwise
+func (donut API) Region() string {
+	return donut.config.GetRegion()
+}
+
+// GetBucketLocation - region of a bucket, all buckets are located in the server region
+func (donut API) GetBucketLocation(bucket string) (string, *probe.Error) {
+	if _, err := donut.GetBucketMetadata(bucket); err != nil {
+		return "", err.Trace()
+	}
+	return donut.config.GetRegion(), nil
+}
+
 // SetBucketMetadata -
 func (donut API) SetBucketMetadata(bucket string, metadata map[string]string) *probe.Error {
 	donut.lock.Lock()
@@ -242,11 +336,41 @@ func (donut API) SetBucketMetadata(bucket string, metadata map[string]string) *p
 		}
 	}
 	storedBucket := donut.storedBuckets.Get(bucket).(storedBucket)
-	storedBucket.bucketMetadata.ACL = BucketACL(metadata["acl"])
+	storedBucket.bucketMetadata = mergeBucketMetadata(storedBucket.bucketMetadata, metadata)
 	donut.storedBuckets.Set(bucket, storedBucket)
 	return nil
 }
 
+// mergeMetadata - merge metadata into existing metadata, keys with an empty value are removed
+func mergeMetadata(existing, metadata map[string]string) map[string]string {
+	newMetadata := make(map[string]string)
+	for k, v := range existing {
+		newMetadata[k] = v
+	}
+	for k, v := range metadata {
+		if v == "" {
+			delete(newMetadata, k)
+			continue
+		}
+		newMetadata[k] = v
+	}
+	return newMetadata
+}
+
+// mergeBucketMetadata - merge metadata into bucket metadata, "acl" sets the bucket ACL
+func mergeBucketMetadata(bucketMetadata BucketMetadata, metadata map[string]string) BucketMetadata {
+	m := make(map[string]string)
+	for k, v := range metadata {
+		if k == "acl" {
+			bucketMetadata.ACL = BucketACL(v)
+			continue
+		}
+		m[k] = v
+	}
+	bucketMetadata.Metadata = mergeMetadata(bucketMetadata.Metadata, m)
+	return bucketMetadata
+}
+
 // isMD5SumEqual - returns error if md5sum mismatches, success its `nil`
 func isMD5SumEqual(expectedMD5Sum, actualMD5Sum string) *probe.Error {
 	if strings.TrimSpace(expectedMD5Sum) != "" && strings.TrimSpace(actualMD5Sum) != "" {
@@ -266,21 +390,23 @@ func isMD5SumEqual(expectedMD5Sum, actualMD5Sum string) *probe.Error {
 	return probe.NewError(InvalidArgument{})
 }
 
-// CreateObject - create an object
-func (donut API) CreateObject(bucket, key, expectedMD5Sum string, size int64, data io.Reader, metadata map[string]string, signature *signv4.Signature) (ObjectMetadata, *probe.Error) {
+// CreateObject - create an object, encrypted with the key provided if any
+func (donut API) CreateObject(bucket, key, expectedMD5Sum string, size int64, data io.Reader, metadata map[string]string, signature *signv4.Signature, encryption *Encryption) (ObjectMetadata, *probe.Error) {
 	donut.lock.Lock()
 	defer donut.lock.Unlock()
 
-	contentType := metadata["contentType"]
-	objectMetadata, err := donut.createObject(bucket, key, contentType, expectedMD5Sum, size, data, signature)
+	objectMetadata, err := donut.createObject(bucket, key, expectedMD5Sum, size, data, metadata, signature, encryption)
 	// free
 	debug.FreeOSMemory()
+	if err == nil {
+		donut.notify(EventObjectCreatedPut, objectMetadata)
+	}
 
 	return objectMetadata, err.Trace()
 }
 
 // createObject - PUT object to cache buffer
-func (donut API) createObject(bucket, key, contentType, expectedMD5Sum string, size int64, data io.Reader, signature *signv4.Signature) (ObjectMetadata, *probe.Error) {
+func (donut API) createObject(bucket, key, expectedMD5Sum string, size int64, data io.Reader, metadata map[string]string, signature *signv4.Signature, encryption *Encryption) (ObjectMetadata, *probe.Error) {
 	if len(donut.config.NodeDiskMap) == 0 {
 		if size > int64(donut.config.MaxSize) {
 			generic := GenericObjectError{Bucket: bucket, Object: key}
@@ -306,11 +432,26 @@ func (donut API) createObject(bucket, key, contentType, expectedMD5Sum string, s
 	if _, ok := storedBucket.objectMetadata[objectKey]; ok == true {
 		return ObjectMetadata{}, probe.NewError(ObjectExists{Object: key})
 	}
+	{
+		var err *probe.Error
+		encryption, err = donut.newObjectEncryption(storedBucket.bucketMetadata, encryption)
+		if err != nil {
+			return ObjectMetadata{}, err.Trace()
+		}
+	}
 
+	// copy metadata, it is saved as is along with the object
+	m := make(map[string]string)
+	for k, v := range metadata {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	contentType := strings.TrimSpace(m["contentType"])
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
-	contentType = strings.TrimSpace(contentType)
+	m["contentType"] = contentType
 	if strings.TrimSpace(expectedMD5Sum) != "" {
 		expectedMD5SumBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(expectedMD5Sum))
 		if err != nil {
@@ -321,17 +462,16 @@ func (donut API) createObject(bucket, key, contentType, expectedMD5Sum string, s
 	}
 
 	if len(donut.config.NodeDiskMap) > 0 {
+		m["contentLength"] = strconv.FormatInt(size, 10)
 		objMetadata, err := donut.putObject(
 			bucket,
 			key,
 			expectedMD5Sum,
 			data,
 			size,
-			map[string]string{
-				"contentType":   contentType,
-				"contentLength": strconv.FormatInt(size, 10),
-			},
+			m,
 			signature,
+			encryption,
 		)
 		if err != nil {
 			return ObjectMetadata{}, err.Trace()
@@ -341,9 +481,23 @@ func (donut API) createObject(bucket, key, contentType, expectedMD5Sum string, s
 		return objMetadata, nil
 	}
 
+	newObject := ObjectMetadata{}
+	// objects are kept encrypted in memory as well
+	var stream cipher.Stream
+	if encryption != nil {
+		var err *probe.Error
+		stream, err = encryption.encrypt(&newObject)
+		if err != nil {
+			return ObjectMetadata{}, err.Trace()
+		}
+	}
+
 	// calculate md5
 	hash := md5.New()
 	sha256hash := sha256.New()
+	// checksums of objects encrypted with customer keys are of encrypted data
+	customerEncrypted := encryption != nil && !encryption.isServerManaged()
+	encryptedHash := md5.New()
 
 	var err error
 	var totalLength int64
@@ -354,6 +508,12 @@ func (donut API) createObject(bucket, key, contentType, expectedMD5Sum string, s
 		if length != 0 {
 			hash.Write(byteBuffer[0:length])
 			sha256hash.Write(byteBuffer[0:length])
+			if stream != nil {
+				stream.XORKeyStream(byteBuffer[0:length], byteBuffer[0:length])
+			}
+			if customerEncrypted {
+				encryptedHash.Write(byteBuffer[0:length])
+			}
 			ok := donut.objects.Append(objectKey, byteBuffer[0:length])
 			if !ok {
 				return ObjectMetadata{}, probe.NewError(InternalError{})
@@ -362,6 +522,11 @@ func (donut API) createObject(bucket, key, contentType, expectedMD5Sum string, s
 			go debug.FreeOSMemory()
 		}
 	}
+	if err != io.EOF {
+		// Delete perhaps the object is already saved, due to the nature of append()
+		donut.objects.Delete(objectKey)
+		return ObjectMetadata{}, probe.NewError(err)
+	}
 	if size != 0 {
 		if totalLength != size {
 			// Delete perhaps the object is already saved, due to the nature of append()
@@ -369,9 +534,6 @@ func (donut API) createObject(bucket, key, contentType, expectedMD5Sum string, s
 			return ObjectMetadata{}, probe.NewError(IncompleteBody{Bucket: bucket, Object: key})
 		}
 	}
-	if err != io.EOF {
-		return ObjectMetadata{}, probe.NewError(err)
-	}
 	md5SumBytes := hash.Sum(nil)
 	md5Sum := hex.EncodeToString(md5SumBytes)
 	// Verify if the written object is equal to what is expected, only if it is requested as such
@@ -396,32 +558,32 @@ func (donut API) createObject(bucket, key, contentType, expectedMD5Sum string, s
 		}
 	}
 
-	m := make(map[string]string)
-	m["contentType"] = contentType
-	newObject := ObjectMetadata{
-		Bucket: bucket,
-		Object: key,
-
-		Metadata: m,
-		Created:  time.Now().UTC(),
-		MD5Sum:   md5Sum,
-		Size:     int64(totalLength),
+	newObject.Bucket = bucket
+	newObject.Object = key
+	newObject.Metadata = m
+	newObject.Created = time.Now().UTC()
+	newObject.MD5Sum = md5Sum
+	if customerEncrypted {
+		newObject.MD5Sum = hex.EncodeToString(encryptedHash.Sum(nil))
 	}
+	newObject.Size = int64(totalLength)
 
 	storedBucket.objectMetadata[objectKey] = newObject
+	storedBucket.objectIndex.add(key)
 	donut.storedBuckets.Set(bucket, storedBucket)
 	return newObject, nil
 }
 
-// MakeBucket - create bucket in cache
-func (donut API) MakeBucket(bucketName, acl string, location io.Reader, signature *signv4.Signature) *probe.Error {
+// MakeBucket - create bucket in cache, owner is the name of the user creating the bucket
+func (donut API) MakeBucket(bucketName, acl, owner string, location io.Reader, signature *signv4.Signature) *probe.Error {
 	donut.lock.Lock()
 	defer donut.lock.Unlock()
 
-	// do not have to parse location constraint, using this just for signature verification
 	locationSum := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	var locationConstraintBytes []byte
 	if location != nil {
-		locationConstraintBytes, err := ioutil.ReadAll(location)
+		var err error
+		locationConstraintBytes, err = ioutil.ReadAll(location)
 		if err != nil {
 			return probe.NewError(InternalError{})
 		}
@@ -438,7 +600,18 @@ func (donut API) MakeBucket(bucketName, acl string, location io.Reader, signatur
 		}
 	}
 
-	if donut.storedBuckets.Stats().Items == totalBuckets {
+	// an empty location constraint stands for the server region
+	if len(bytes.TrimSpace(locationConstraintBytes)) > 0 {
+		createBucketConfig := CreateBucketConfiguration{}
+		if err := xml.Unmarshal(locationConstraintBytes, &createBucketConfig); err != nil {
+			return probe.NewError(MalformedXML{})
+		}
+		if createBucketConfig.LocationConstraint != "" && createBucketConfig.LocationConstraint != donut.config.GetRegion() {
+			return probe.NewError(InvalidLocationConstraint{Location: createBucketConfig.LocationConstraint})
+		}
+	}
+
+	if donut.config.MaxBuckets > 0 && donut.storedBuckets.Stats().Items >= donut.config.MaxBuckets {
 		return probe.NewError(TooManyBuckets{Bucket: bucketName})
 	}
 	if !IsValidBucket(bucketName) {
@@ -456,18 +629,20 @@ func (donut API) MakeBucket(bucketName, acl string, location io.Reader, signatur
 		acl = "private"
 	}
 	if len(donut.config.NodeDiskMap) > 0 {
-		if err := donut.makeBucket(bucketName, BucketACL(acl)); err != nil {
+		if err := donut.makeBucket(bucketName, BucketACL(acl), owner); err != nil {
 			return err.Trace()
 		}
 	}
 	var newBucket = storedBucket{}
 	newBucket.objectMetadata = make(map[string]ObjectMetadata)
+	newBucket.objectIndex = newMemoryIndex()
 	newBucket.multiPartSession = make(map[string]MultiPartSession)
 	newBucket.partMetadata = make(map[string]map[int]PartMetadata)
 	newBucket.bucketMetadata = BucketMetadata{}
 	newBucket.bucketMetadata.Name = bucketName
 	newBucket.bucketMetadata.Created = time.Now().UTC()
 	newBucket.bucketMetadata.ACL = BucketACL(acl)
+	newBucket.bucketMetadata.Owner = owner
 	donut.storedBuckets.Set(bucketName, newBucket)
 	return nil
 }
@@ -487,7 +662,6 @@ func (donut API) ListObjects(bucket string, resources BucketResourcesMetadata) (
 		return nil, BucketResourcesMetadata{IsTruncated: false}, probe.NewError(BucketNotFound{Bucket: bucket})
 	}
 	var results []ObjectMetadata
-	var keys []string
 	if len(donut.config.NodeDiskMap) > 0 {
 		listObjects, err := donut.listObjects(
 			bucket,
@@ -501,6 +675,7 @@ func (donut API) ListObjects(bucket string, resources BucketResourcesMetadata) (
 		}
 		resources.CommonPrefixes = listObjects.CommonPrefixes
 		resources.IsTruncated = listObjects.IsTruncated
+		var keys []string
 		for key := range listObjects.Objects {
 			keys = append(keys, key)
 		}
@@ -508,53 +683,30 @@ func (donut API) ListObjects(bucket string, resources BucketResourcesMetadata) (
 		for _, key := range keys {
 			results = append(results, listObjects.Objects[key])
 		}
-		if resources.IsTruncated && resources.Delimiter != "" {
-			resources.NextMarker = results[len(results)-1].Object
+		if resources.IsTruncated {
+			resources.NextMarker = listObjects.NextMarker
 		}
 		return results, resources, nil
 	}
 	storedBucket := donut.storedBuckets.Get(bucket).(storedBucket)
-	for key := range storedBucket.objectMetadata {
-		if strings.HasPrefix(key, bucket+"/") {
-			key = key[len(bucket)+1:]
-			if strings.HasPrefix(key, resources.Prefix) {
-				if key > resources.Marker {
-					keys = append(keys, key)
-				}
-			}
-		}
-	}
-	if strings.TrimSpace(resources.Prefix) != "" {
-		keys = TrimPrefix(keys, resources.Prefix)
-	}
-	var prefixes []string
-	var filteredKeys []string
-	filteredKeys = keys
-	if strings.TrimSpace(resources.Delimiter) != "" {
-		filteredKeys = HasNoDelimiter(keys, resources.Delimiter)
-		prefixes = HasDelimiter(keys, resources.Delimiter)
-		prefixes = SplitDelimiter(prefixes, resources.Delimiter)
-		prefixes = SortUnique(prefixes)
+	keys, commonPrefixes, nextMarker, isTruncated, err := listNames(
+		storedBucket.objectIndex.names,
+		resources.Prefix,
+		resources.Marker,
+		resources.Delimiter,
+		resources.Maxkeys,
+	)
+	if err != nil {
+		return nil, BucketResourcesMetadata{IsTruncated: false}, err.Trace()
 	}
-	for _, commonPrefix := range prefixes {
-		resources.CommonPrefixes = append(resources.CommonPrefixes, resources.Prefix+commonPrefix)
+	resources.CommonPrefixes = commonPrefixes
+	resources.IsTruncated = isTruncated
+	if resources.IsTruncated {
+		resources.NextMarker = nextMarker
 	}
-	filteredKeys = RemoveDuplicates(filteredKeys)
-	sort.Strings(filteredKeys)
-
-	for _, key := range filteredKeys {
-		if len(results) == resources.Maxkeys {
-			resources.IsTruncated = true
-			if resources.IsTruncated && resources.Delimiter != "" {
-				resources.NextMarker = results[len(results)-1].Object
-			}
-			return results, resources, nil
-		}
-		object := storedBucket.objectMetadata[bucket+"/"+resources.Prefix+key]
-		results = append(results, object)
+	for _, key := range keys {
+		results = append(results, storedBucket.objectMetadata[bucket+"/"+key])
 	}
-	resources.CommonPrefixes = RemoveDuplicates(resources.CommonPrefixes)
-	sort.Strings(resources.CommonPrefixes)
 	return results, resources, nil
 }
 
@@ -570,18 +722,8 @@ func (donut API) ListBuckets() ([]BucketMetadata, *probe.Error) {
 	donut.lock.Lock()
 	defer donut.lock.Unlock()
 
-	var results []BucketMetadata
-	if len(donut.config.NodeDiskMap) > 0 {
-		buckets, err := donut.listBuckets()
-		if err != nil {
-			return nil, err.Trace()
-		}
-		for _, bucketMetadata := range buckets {
-			results = append(results, bucketMetadata)
-		}
-		sort.Sort(byBucketName(results))
-		return results, nil
-	}
+	// buckets on disks are all cached by New(), and kept in sync by MakeBucket and SetBucketMetadata
+	results := make([]BucketMetadata, 0, donut.storedBuckets.Stats().Items)
 	for _, bucket := range donut.storedBuckets.GetAll() {
 		results = append(results, bucket.(storedBucket).bucketMetadata)
 	}
@@ -622,14 +764,53 @@ func (donut API) GetObjectMetadata(bucket, key string) (ObjectMetadata, *probe.E
 	return ObjectMetadata{}, probe.NewError(ObjectNotFound{Object: key})
 }
 
+// SetObjectMetadata - set object metadata, keys with an empty value are removed
+func (donut API) SetObjectMetadata(bucket, key string, metadata map[string]string) *probe.Error {
+	donut.lock.Lock()
+	defer donut.lock.Unlock()
+
+	if !IsValidBucket(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+	if !IsValidObjectName(key) {
+		return probe.NewError(ObjectNameInvalid{Object: key})
+	}
+	if !donut.storedBuckets.Exists(bucket) {
+		return probe.NewError(BucketNotFound{Bucket: bucket})
+	}
+	storedBucket := donut.storedBuckets.Get(bucket).(storedBucket)
+	objectKey := bucket + "/" + key
+	objMetadata, ok := storedBucket.objectMetadata[objectKey]
+	if !ok {
+		if len(donut.config.NodeDiskMap) == 0 {
+			return probe.NewError(ObjectNotFound{Object: key})
+		}
+		var err *probe.Error
+		objMetadata, err = donut.getObjectMetadata(bucket, key)
+		if err != nil {
+			return err.Trace()
+		}
+	}
+	objMetadata.Metadata = mergeMetadata(objMetadata.Metadata, metadata)
+	if len(donut.config.NodeDiskMap) > 0 {
+		if err := donut.setObjectMetadata(bucket, key, objMetadata); err != nil {
+			return err.Trace()
+		}
+	}
+	storedBucket.objectMetadata[objectKey] = objMetadata
+	donut.storedBuckets.Set(bucket, storedBucket)
+	return nil
+}
+
 // evictedObject callback function called when an item is evicted from memory
 func (donut API) evictedObject(a ...interface{}) {
-	cacheStats := donut.objects.Stats()
-	log.Printf("CurrentSize: %d, CurrentItems: %d, TotalEvicted: %d",
-		cacheStats.Bytes, cacheStats.Items, cacheStats.Evicted)
 	key := a[0].(string)
 	// loop through all buckets
 	for _, bucket := range donut.storedBuckets.GetAll() {
+		// without disks an evicted object is gone, it is not a delete though and no event is sent
+		if objectMetadata, ok := bucket.(storedBucket).objectMetadata[key]; ok && len(donut.config.NodeDiskMap) == 0 {
+			bucket.(storedBucket).objectIndex.remove(objectMetadata.Object)
+		}
 		delete(bucket.(storedBucket).objectMetadata, key)
 	}
 	debug.FreeOSMemory()
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	. "gopkg.in/check.v1"
@@ -61,16 +62,16 @@ func (s *MyCacheSuite) TearDownSuite(c *C) {
 // test make bucket without name
 func (s *MyCacheSuite) TestBucketWithoutNameFails(c *C) {
 	// fail to create new bucket without a name
-	err := dc.MakeBucket("", "private", nil, nil)
+	err := dc.MakeBucket("", "private", "", nil, nil)
 	c.Assert(err, Not(IsNil))
 
-	err = dc.MakeBucket(" ", "private", nil, nil)
+	err = dc.MakeBucket(" ", "private", "", nil, nil)
 	c.Assert(err, Not(IsNil))
 }
 
 // test empty bucket
 func (s *MyCacheSuite) TestEmptyBucket(c *C) {
-	c.Assert(dc.MakeBucket("foo1", "private", nil, nil), IsNil)
+	c.Assert(dc.MakeBucket("foo1", "private", "", nil, nil), IsNil)
 	// check if bucket is empty
 	var resources BucketResourcesMetadata
 	resources.Maxkeys = 1
@@ -84,7 +85,7 @@ func (s *MyCacheSuite) TestEmptyBucket(c *C) {
 // test bucket list
 func (s *MyCacheSuite) TestMakeBucketAndList(c *C) {
 	// create bucket
-	err := dc.MakeBucket("foo2", "private", nil, nil)
+	err := dc.MakeBucket("foo2", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
 	// check bucket exists
@@ -96,20 +97,20 @@ func (s *MyCacheSuite) TestMakeBucketAndList(c *C) {
 
 // test re-create bucket
 func (s *MyCacheSuite) TestMakeBucketWithSameNameFails(c *C) {
-	err := dc.MakeBucket("foo3", "private", nil, nil)
+	err := dc.MakeBucket("foo3", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
-	err = dc.MakeBucket("foo3", "private", nil, nil)
+	err = dc.MakeBucket("foo3", "private", "", nil, nil)
 	c.Assert(err, Not(IsNil))
 }
 
 // test make multiple buckets
 func (s *MyCacheSuite) TestCreateMultipleBucketsAndList(c *C) {
 	// add a second bucket
-	err := dc.MakeBucket("foo4", "private", nil, nil)
+	err := dc.MakeBucket("foo4", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
-	err = dc.MakeBucket("bar1", "private", nil, nil)
+	err = dc.MakeBucket("bar1", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
 	buckets, err := dc.ListBuckets()
@@ -119,7 +120,7 @@ func (s *MyCacheSuite) TestCreateMultipleBucketsAndList(c *C) {
 	c.Assert(buckets[0].Name, Equals, "bar1")
 	c.Assert(buckets[1].Name, Equals, "foo4")
 
-	err = dc.MakeBucket("foobar1", "private", nil, nil)
+	err = dc.MakeBucket("foobar1", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
 	buckets, err = dc.ListBuckets()
@@ -131,7 +132,7 @@ func (s *MyCacheSuite) TestCreateMultipleBucketsAndList(c *C) {
 
 // test object create without bucket
 func (s *MyCacheSuite) TestNewObjectFailsWithoutBucket(c *C) {
-	_, err := dc.CreateObject("unknown", "obj", "", 0, nil, nil, nil)
+	_, err := dc.CreateObject("unknown", "obj", "", 0, nil, nil, nil, nil)
 	c.Assert(err, Not(IsNil))
 }
 
@@ -143,10 +144,10 @@ func (s *MyCacheSuite) TestNewObjectMetadata(c *C) {
 	expectedMd5Sum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
 	reader := ioutil.NopCloser(bytes.NewReader([]byte(data)))
 
-	err := dc.MakeBucket("foo6", "private", nil, nil)
+	err := dc.MakeBucket("foo6", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
-	objectMetadata, err := dc.CreateObject("foo6", "obj", expectedMd5Sum, int64(len(data)), reader, map[string]string{"contentType": "application/json"}, nil)
+	objectMetadata, err := dc.CreateObject("foo6", "obj", expectedMd5Sum, int64(len(data)), reader, map[string]string{"contentType": "application/json"}, nil, nil)
 	c.Assert(err, IsNil)
 	c.Assert(objectMetadata.MD5Sum, Equals, hex.EncodeToString(hasher.Sum(nil)))
 	c.Assert(objectMetadata.Metadata["contentType"], Equals, "application/json")
@@ -154,13 +155,13 @@ func (s *MyCacheSuite) TestNewObjectMetadata(c *C) {
 
 // test create object fails without name
 func (s *MyCacheSuite) TestNewObjectFailsWithEmptyName(c *C) {
-	_, err := dc.CreateObject("foo", "", "", 0, nil, nil, nil)
+	_, err := dc.CreateObject("foo", "", "", 0, nil, nil, nil, nil)
 	c.Assert(err, Not(IsNil))
 }
 
 // test create object
 func (s *MyCacheSuite) TestNewObjectCanBeWritten(c *C) {
-	err := dc.MakeBucket("foo", "private", nil, nil)
+	err := dc.MakeBucket("foo", "private", "", nil, nil)
 	c.Assert(err, IsNil)
 
 	data := "Hello World"
@@ -170,12 +171,12 @@ func (s *MyCacheSuite) TestNewObjectCanBeWritten(c *C) {
 	expectedMd5Sum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
 	reader := ioutil.NopCloser(bytes.NewReader([]byte(data)))
 
-	actualMetadata, err := dc.CreateObject("foo", "obj", expectedMd5Sum, int64(len(data)), reader, map[string]string{"contentType": "application/octet-stream"}, nil)
+	actualMetadata, err := dc.CreateObject("foo", "obj", expectedMd5Sum, int64(len(data)), reader, map[string]string{"contentType": "application/octet-stream"}, nil, nil)
 	c.Assert(err, IsNil)
 	c.Assert(actualMetadata.MD5Sum, Equals, hex.EncodeToString(hasher.Sum(nil)))
 
 	var buffer bytes.Buffer
-	size, err := dc.GetObject(&buffer, "foo", "obj", 0, 0)
+	size, err := dc.GetObject(&buffer, "foo", "obj", 0, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(size, Equals, int64(len(data)))
 	c.Assert(buffer.Bytes(), DeepEquals, []byte(data))
@@ -188,25 +189,25 @@ func (s *MyCacheSuite) TestNewObjectCanBeWritten(c *C) {
 
 // test list objects
 func (s *MyCacheSuite) TestMultipleNewObjects(c *C) {
-	c.Assert(dc.MakeBucket("foo5", "private", nil, nil), IsNil)
+	c.Assert(dc.MakeBucket("foo5", "private", "", nil, nil), IsNil)
 
 	one := ioutil.NopCloser(bytes.NewReader([]byte("one")))
 
-	_, err := dc.CreateObject("foo5", "obj1", "", int64(len("one")), one, nil, nil)
+	_, err := dc.CreateObject("foo5", "obj1", "", int64(len("one")), one, nil, nil, nil)
 	c.Assert(err, IsNil)
 
 	two := ioutil.NopCloser(bytes.NewReader([]byte("two")))
-	_, err = dc.CreateObject("foo5", "obj2", "", int64(len("two")), two, nil, nil)
+	_, err = dc.CreateObject("foo5", "obj2", "", int64(len("two")), two, nil, nil, nil)
 	c.Assert(err, IsNil)
 
 	var buffer1 bytes.Buffer
-	size, err := dc.GetObject(&buffer1, "foo5", "obj1", 0, 0)
+	size, err := dc.GetObject(&buffer1, "foo5", "obj1", 0, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(size, Equals, int64(len([]byte("one"))))
 	c.Assert(buffer1.Bytes(), DeepEquals, []byte("one"))
 
 	var buffer2 bytes.Buffer
-	size, err = dc.GetObject(&buffer2, "foo5", "obj2", 0, 0)
+	size, err = dc.GetObject(&buffer2, "foo5", "obj2", 0, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(size, Equals, int64(len([]byte("two"))))
 
@@ -245,11 +246,11 @@ func (s *MyCacheSuite) TestMultipleNewObjects(c *C) {
 	c.Assert(objectsMetadata[1].Object, Equals, "obj2")
 
 	three := ioutil.NopCloser(bytes.NewReader([]byte("three")))
-	_, err = dc.CreateObject("foo5", "obj3", "", int64(len("three")), three, nil, nil)
+	_, err = dc.CreateObject("foo5", "obj3", "", int64(len("three")), three, nil, nil, nil)
 	c.Assert(err, IsNil)
 
 	var buffer bytes.Buffer
-	size, err = dc.GetObject(&buffer, "foo5", "obj3", 0, 0)
+	size, err = dc.GetObject(&buffer, "foo5", "obj3", 0, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(size, Equals, int64(len([]byte("three"))))
 	c.Assert(buffer.Bytes(), DeepEquals, []byte("three"))
@@ -263,3 +264,262 @@ func (s *MyCacheSuite) TestMultipleNewObjects(c *C) {
 	c.Assert(resources.IsTruncated, Equals, true)
 	c.Assert(len(objectsMetadata), Equals, 2)
 }
+
+// test object and bucket tags
+func (s *MyCacheSuite) TestTags(c *C) {
+	c.Assert(dc.MakeBucket("foo7", "private", "", nil, nil), IsNil)
+
+	tags, err := DecodeTags("project=minio&env=test")
+	c.Assert(err, IsNil)
+	c.Assert(IsValidObjectTags(tags), IsNil)
+	c.Assert(tags.Encode(), Equals, "env=test&project=minio")
+
+	one := ioutil.NopCloser(bytes.NewReader([]byte("one")))
+	_, err = dc.CreateObject("foo7", "obj1", "", int64(len("one")), one, map[string]string{TaggingMetadataKey: tags.Encode()}, nil, nil)
+	c.Assert(err, IsNil)
+
+	objectMetadata, err := dc.GetObjectMetadata("foo7", "obj1")
+	c.Assert(err, IsNil)
+	c.Assert(TagFilter{Prefix: "obj", Tags: Tags{"env": "test"}}.Match(objectMetadata), Equals, true)
+	c.Assert(objectMetadata.Metadata[TaggingMetadataKey], Equals, tags.Encode())
+	c.Assert(TagFilter{Prefix: "obj"}.Match(objectMetadata), Equals, true)
+	c.Assert(TagFilter{Prefix: "other", Tags: Tags{"env": "test"}}.Match(objectMetadata), Equals, false)
+	c.Assert(TagFilter{Tags: Tags{"env": "prod"}}.Match(objectMetadata), Equals, false)
+
+	err = dc.SetObjectMetadata("foo7", "obj1", map[string]string{TaggingMetadataKey: "env=prod"})
+	c.Assert(err, IsNil)
+	objectMetadata, err = dc.GetObjectMetadata("foo7", "obj1")
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.Metadata[TaggingMetadataKey], Equals, "env=prod")
+	c.Assert(objectMetadata.Metadata["contentType"], Equals, "application/octet-stream")
+
+	err = dc.SetObjectMetadata("foo7", "obj1", map[string]string{TaggingMetadataKey: ""})
+	c.Assert(err, IsNil)
+	objectMetadata, err = dc.GetObjectMetadata("foo7", "obj1")
+	c.Assert(err, IsNil)
+	_, ok := objectMetadata.Metadata[TaggingMetadataKey]
+	c.Assert(ok, Equals, false)
+
+	err = dc.SetObjectMetadata("foo7", "obj2", map[string]string{TaggingMetadataKey: "env=prod"})
+	c.Assert(err, Not(IsNil))
+
+	err = dc.SetBucketMetadata("foo7", map[string]string{TaggingMetadataKey: "team=storage"})
+	c.Assert(err, IsNil)
+	bucketMetadata, err := dc.GetBucketMetadata("foo7")
+	c.Assert(err, IsNil)
+	c.Assert(bucketMetadata.Metadata[TaggingMetadataKey], Equals, "team=storage")
+	c.Assert(bucketMetadata.ACL.IsPrivate(), Equals, true)
+
+	tags = make(Tags)
+	for i := 0; i < 11; i++ {
+		tags["key"+strconv.Itoa(i)] = "value"
+	}
+	c.Assert(IsValidObjectTags(tags), Not(IsNil))
+	c.Assert(IsValidBucketTags(tags), IsNil)
+	c.Assert(IsValidObjectTags(Tags{"aws:key": "value"}), Not(IsNil))
+}
+
+func (s *MyCacheSuite) TestRegionLocationConstraint(c *C) {
+	location := bytes.NewReader([]byte("<CreateBucketConfiguration><LocationConstraint>" + DefaultRegion + "</LocationConstraint></CreateBucketConfiguration>"))
+	err := dc.MakeBucket("foo8", "private", "", location, nil)
+	c.Assert(err, IsNil)
+
+	region, err := dc.GetBucketLocation("foo8")
+	c.Assert(err, IsNil)
+	c.Assert(region, Equals, DefaultRegion)
+
+	location = bytes.NewReader([]byte("<CreateBucketConfiguration><LocationConstraint>us-east-1</LocationConstraint></CreateBucketConfiguration>"))
+	err = dc.MakeBucket("foo9", "private", "", location, nil)
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), DeepEquals, InvalidLocationConstraint{Location: "us-east-1"})
+
+	err = dc.MakeBucket("foo9", "private", "", bytes.NewReader([]byte("<CreateBucketConfiguration>")), nil)
+	c.Assert(err, Not(IsNil))
+
+	_, err = dc.GetBucketLocation("foo9")
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *MyCacheSuite) TestObjectEncryption(c *C) {
+	c.Assert(dc.MakeBucket("encrypted", "private", "", nil, nil), IsNil)
+
+	key := bytes.Repeat([]byte("k"), EncryptionKeySize)
+	encryption := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: key}
+	data := "encrypted object data spanning more than a single block of the cipher"
+	hasher := md5.New()
+	hasher.Write([]byte(data))
+	objectMetadata, err := dc.CreateObject("encrypted", "obj", "", int64(len(data)), bytes.NewReader([]byte(data)), nil, nil, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.IsEncrypted(), Equals, true)
+	c.Assert(objectMetadata.IsCustomerEncrypted(), Equals, true)
+	c.Assert(objectMetadata.EncryptionKeyMAC, Not(Equals), "")
+
+	// checksums of plain data are never saved, requests are still verified with them
+	storedMetadata, err := dc.GetObjectMetadata("encrypted", "obj")
+	c.Assert(err, IsNil)
+	c.Assert(storedMetadata.MD5Sum, Not(Equals), hex.EncodeToString(hasher.Sum(nil)))
+	c.Assert(storedMetadata.MD5Sum, Equals, objectMetadata.MD5Sum)
+	expectedMd5Sum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+	_, err = dc.CreateObject("encrypted", "verified", expectedMd5Sum, int64(len(data)), bytes.NewReader([]byte(data)), nil, nil, encryption)
+	c.Assert(err, IsNil)
+	_, err = dc.CreateObject("encrypted", "verified", expectedMd5Sum, int64(len("other")), bytes.NewReader([]byte("other")), nil, nil, encryption)
+	c.Assert(err, Not(IsNil))
+
+	var buffer bytes.Buffer
+	size, err := dc.GetObject(&buffer, "encrypted", "obj", 0, 0, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(len(data)))
+	c.Assert(buffer.String(), Equals, data)
+
+	// ranges are decrypted from their offset
+	buffer.Reset()
+	size, err = dc.GetObject(&buffer, "encrypted", "obj", 21, 30, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(30))
+	c.Assert(buffer.String(), Equals, data[21:51])
+
+	_, err = dc.GetObject(ioutil.Discard, "encrypted", "obj", 0, 0, nil)
+	c.Assert(err.ToGoError(), DeepEquals, EncryptionKeyMissing{Object: "obj"})
+
+	wrongKey := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: bytes.Repeat([]byte("w"), EncryptionKeySize)}
+	_, err = dc.GetObject(ioutil.Discard, "encrypted", "obj", 0, 0, wrongKey)
+	c.Assert(err.ToGoError(), DeepEquals, EncryptionKeyMismatch{Object: "obj"})
+
+	metadata, err := dc.GetObjectMetadata("encrypted", "obj")
+	c.Assert(err, IsNil)
+	c.Assert(VerifyEncryption(metadata, encryption), IsNil)
+	c.Assert(VerifyEncryption(metadata, wrongKey), Not(IsNil))
+
+	// keys are verified for unencrypted objects as well
+	_, err = dc.CreateObject("encrypted", "plain", "", int64(len("plain")), bytes.NewReader([]byte("plain")), nil, nil, nil)
+	c.Assert(err, IsNil)
+	_, err = dc.GetObject(ioutil.Discard, "encrypted", "plain", 0, 0, encryption)
+	c.Assert(err.ToGoError(), DeepEquals, ObjectNotEncrypted{Object: "plain"})
+
+	invalidKey := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: []byte("short")}
+	_, err = dc.CreateObject("encrypted", "invalid", "", int64(len("plain")), bytes.NewReader([]byte("plain")), nil, nil, invalidKey)
+	c.Assert(err.ToGoError(), DeepEquals, InvalidEncryptionKey{})
+}
+
+func (s *MyCacheSuite) TestObjectEncryptionMultipart(c *C) {
+	c.Assert(dc.MakeBucket("encrypted-multipart", "private", "", nil, nil), IsNil)
+
+	encryption := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: bytes.Repeat([]byte("k"), EncryptionKeySize)}
+	uploadID, err := dc.NewMultipartUpload("encrypted-multipart", "obj", nil, encryption)
+	c.Assert(err, IsNil)
+
+	// parts are accepted only with the key of the session
+	_, err = dc.CreateObjectPart("encrypted-multipart", "obj", uploadID, 1, "", "", int64(len("one")), bytes.NewReader([]byte("one")), nil, nil)
+	c.Assert(err.ToGoError(), DeepEquals, EncryptionKeyMissing{Object: "obj"})
+	wrongKey := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: bytes.Repeat([]byte("w"), EncryptionKeySize)}
+	_, err = dc.CreateObjectPart("encrypted-multipart", "obj", uploadID, 1, "", "", int64(len("one")), bytes.NewReader([]byte("one")), nil, wrongKey)
+	c.Assert(err.ToGoError(), DeepEquals, EncryptionKeyMismatch{Object: "obj"})
+
+	etag1, err := dc.CreateObjectPart("encrypted-multipart", "obj", uploadID, 1, "", "", int64(len("one")), bytes.NewReader([]byte("one")), nil, encryption)
+	c.Assert(err, IsNil)
+	etag2, err := dc.CreateObjectPart("encrypted-multipart", "obj", uploadID, 2, "", "", int64(len("two")), bytes.NewReader([]byte("two")), nil, encryption)
+	c.Assert(err, IsNil)
+
+	complete := "<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>" + etag1 + "</ETag></Part>" +
+		"<Part><PartNumber>2</PartNumber><ETag>" + etag2 + "</ETag></Part></CompleteMultipartUpload>"
+	objectMetadata, err := dc.CompleteMultipartUpload("encrypted-multipart", "obj", uploadID, bytes.NewReader([]byte(complete)), nil)
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.IsEncrypted(), Equals, true)
+
+	var buffer bytes.Buffer
+	_, err = dc.GetObject(&buffer, "encrypted-multipart", "obj", 0, 0, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.String(), Equals, "onetwo")
+}
+
+func (s *MyCacheSuite) TestObjectServerEncryption(c *C) {
+	c.Assert(dc.MakeBucket("server-encrypted", "private", "", nil, nil), IsNil)
+
+	data := "server encrypted object data spanning more than a single block of the cipher"
+	encryption := &Encryption{ServerAlgorithm: EncryptionAlgorithm}
+	_, err := dc.CreateObject("server-encrypted", "obj", "", int64(len(data)), bytes.NewReader([]byte(data)), nil, nil, encryption)
+	c.Assert(err.ToGoError(), DeepEquals, KeyStoreNotConfigured{})
+
+	keys := &KeyStore{Version: "0.0.1"}
+	oldKeyID, err := keys.AddKey()
+	c.Assert(err, IsNil)
+	c.Assert(SaveKeyStore(keys), IsNil)
+	c.Assert(dc.RotateKeys(), IsNil)
+
+	objectMetadata, err := dc.CreateObject("server-encrypted", "obj", "", int64(len(data)), bytes.NewReader([]byte(data)), nil, nil, encryption)
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.IsServerEncrypted(), Equals, true)
+	c.Assert(objectMetadata.EncryptionMasterKeyID, Equals, oldKeyID)
+
+	// server managed keys are never provided for reads
+	var buffer bytes.Buffer
+	size, err := dc.GetObject(&buffer, "server-encrypted", "obj", 21, 30, nil)
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, int64(30))
+	c.Assert(buffer.String(), Equals, data[21:51])
+
+	customerKey := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: bytes.Repeat([]byte("k"), EncryptionKeySize)}
+	_, err = dc.GetObject(ioutil.Discard, "server-encrypted", "obj", 0, 0, customerKey)
+	c.Assert(err.ToGoError(), DeepEquals, ServerEncryptedObject{Object: "obj"})
+
+	// new objects are encrypted by default once bucket default encryption is set
+	c.Assert(dc.SetBucketMetadata("server-encrypted", map[string]string{EncryptionMetadataKey: EncryptionAlgorithm}), IsNil)
+	objectMetadata, err = dc.CreateObject("server-encrypted", "default", "", int64(len("default")), bytes.NewReader([]byte("default")), nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.IsServerEncrypted(), Equals, true)
+
+	// rotation reseals data keys, object data is left as is
+	metadata, err := dc.GetObjectMetadata("server-encrypted", "obj")
+	c.Assert(err, IsNil)
+	newKeyID, err := keys.AddKey()
+	c.Assert(err, IsNil)
+	c.Assert(SaveKeyStore(keys), IsNil)
+	c.Assert(dc.RotateKeys(), IsNil)
+	resealed, err := dc.GetObjectMetadata("server-encrypted", "obj")
+	c.Assert(err, IsNil)
+	c.Assert(resealed.EncryptionMasterKeyID, Equals, newKeyID)
+	c.Assert(resealed.EncryptionSealedKey, Not(Equals), metadata.EncryptionSealedKey)
+	c.Assert(resealed.EncryptionIV, Equals, metadata.EncryptionIV)
+
+	// old master key is no longer needed
+	delete(keys.Keys, oldKeyID)
+	c.Assert(SaveKeyStore(keys), IsNil)
+	c.Assert(dc.RotateKeys(), IsNil)
+	buffer.Reset()
+	_, err = dc.GetObject(&buffer, "server-encrypted", "obj", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.String(), Equals, data)
+	buffer.Reset()
+	_, err = dc.GetObject(&buffer, "server-encrypted", "default", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.String(), Equals, "default")
+}
+
+func (s *MyCacheSuite) TestObjectServerEncryptionMultipart(c *C) {
+	c.Assert(dc.MakeBucket("server-encrypted-multipart", "private", "", nil, nil), IsNil)
+	c.Assert(dc.SetBucketMetadata("server-encrypted-multipart", map[string]string{EncryptionMetadataKey: EncryptionAlgorithm}), IsNil)
+
+	uploadID, err := dc.NewMultipartUpload("server-encrypted-multipart", "obj", nil, nil)
+	c.Assert(err, IsNil)
+
+	// parts carry no encryption, data key of the session is used
+	customerKey := &Encryption{CustomerAlgorithm: EncryptionAlgorithm, CustomerKey: bytes.Repeat([]byte("k"), EncryptionKeySize)}
+	_, err = dc.CreateObjectPart("server-encrypted-multipart", "obj", uploadID, 1, "", "", int64(len("one")), bytes.NewReader([]byte("one")), nil, customerKey)
+	c.Assert(err.ToGoError(), DeepEquals, ServerEncryptedObject{Object: "obj"})
+
+	etag1, err := dc.CreateObjectPart("server-encrypted-multipart", "obj", uploadID, 1, "", "", int64(len("one")), bytes.NewReader([]byte("one")), nil, nil)
+	c.Assert(err, IsNil)
+	etag2, err := dc.CreateObjectPart("server-encrypted-multipart", "obj", uploadID, 2, "", "", int64(len("two")), bytes.NewReader([]byte("two")), nil, nil)
+	c.Assert(err, IsNil)
+
+	complete := "<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>" + etag1 + "</ETag></Part>" +
+		"<Part><PartNumber>2</PartNumber><ETag>" + etag2 + "</ETag></Part></CompleteMultipartUpload>"
+	objectMetadata, err := dc.CompleteMultipartUpload("server-encrypted-multipart", "obj", uploadID, bytes.NewReader([]byte(complete)), nil)
+	c.Assert(err, IsNil)
+	c.Assert(objectMetadata.IsServerEncrypted(), Equals, true)
+
+	var buffer bytes.Buffer
+	_, err = dc.GetObject(&buffer, "server-encrypted-multipart", "obj", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.String(), Equals, "onetwo")
+}
@@ -0,0 +1,274 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+
+	"github.com/minio/minio-xl/pkg/crypto/sha256"
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Objects are encrypted with AES-256 in counter mode, with a random IV per object. Counter mode keeps
+// the size of an object as is and allows to decrypt from any offset. Keys are never stored, only a MAC
+// of the key to verify keys provided for reads. Server managed keys are stored sealed, please read
+// keystore.go for more information.
+//
+// Checksums are computed on plain data, except for objects encrypted with customer provided keys.
+// Checksums of plain data would confirm guesses of it to anyone reading object metadata, checksums of
+// encrypted data are saved for these instead, their ETag is not the MD5 of their content.
+
+// EncryptionAlgorithm only supported algorithm for server side encryption
+const EncryptionAlgorithm = "AES256"
+
+// EncryptionKeySize size of encryption keys in bytes
+const EncryptionKeySize = 32
+
+// Encryption server side encryption parameters of a request
+type Encryption struct {
+	// customer provided key (SSE-C), used as is
+	CustomerAlgorithm string
+	CustomerKey       []byte
+
+	// server managed key (SSE-S3), a random data key per object
+	ServerAlgorithm string
+
+	// data key and its sealed form, set by donut for server managed keys
+	dataKey     []byte
+	masterKeyID string
+	sealedKey   string
+}
+
+// isServerManaged - returns true if key is managed by the server
+func (e Encryption) isServerManaged() bool {
+	return e.ServerAlgorithm != ""
+}
+
+// algorithm - encryption algorithm of the request
+func (e Encryption) algorithm() string {
+	if e.isServerManaged() {
+		return e.ServerAlgorithm
+	}
+	return e.CustomerAlgorithm
+}
+
+// key - key objects are encrypted with
+func (e Encryption) key() []byte {
+	if e.isServerManaged() {
+		return e.dataKey
+	}
+	return e.CustomerKey
+}
+
+// newEncryptionIV - random IV for a new object
+func newEncryptionIV() ([]byte, *probe.Error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, probe.NewError(err)
+	}
+	return iv, nil
+}
+
+// validate - verify encryption parameters of a request
+func (e Encryption) validate() *probe.Error {
+	if e.isServerManaged() {
+		if e.ServerAlgorithm != EncryptionAlgorithm || e.CustomerAlgorithm != "" {
+			return probe.NewError(InvalidEncryptionAlgorithm{Algorithm: e.ServerAlgorithm})
+		}
+		return nil
+	}
+	if e.CustomerAlgorithm != EncryptionAlgorithm {
+		return probe.NewError(InvalidEncryptionAlgorithm{Algorithm: e.CustomerAlgorithm})
+	}
+	if len(e.CustomerKey) != EncryptionKeySize {
+		return probe.NewError(InvalidEncryptionKey{})
+	}
+	return nil
+}
+
+// keyMAC - MAC of the key of an object, hex encoded
+func (e Encryption) keyMAC(iv []byte) string {
+	mac := hmac.New(sha256.New, e.key())
+	mac.Write(iv)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// stream - key stream of an object starting at offset, offset is consumed upto the block it is in
+func (e Encryption) stream(iv []byte, offset int64) (cipher.Stream, *probe.Error) {
+	block, err := aes.NewCipher(e.key())
+	if err != nil {
+		return nil, probe.NewError(err)
+	}
+	// counter is the IV incremented by number of blocks before offset
+	counter := make([]byte, aes.BlockSize)
+	copy(counter, iv)
+	carry := uint64(offset / aes.BlockSize)
+	for i := aes.BlockSize - 1; i >= 0 && carry > 0; i-- {
+		sum := uint64(counter[i]) + carry&0xff
+		counter[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+	stream := cipher.NewCTR(block, counter)
+	if skip := offset % aes.BlockSize; skip > 0 {
+		discard := make([]byte, skip)
+		stream.XORKeyStream(discard, discard)
+	}
+	return stream, nil
+}
+
+// encrypt - start encryption of a new object, sets encryption metadata of the object
+func (e Encryption) encrypt(objMetadata *ObjectMetadata) (cipher.Stream, *probe.Error) {
+	iv, err := newEncryptionIV()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	objMetadata.EncryptionAlgorithm = e.algorithm()
+	objMetadata.EncryptionIV = hex.EncodeToString(iv)
+	objMetadata.EncryptionKeyMAC = e.keyMAC(iv)
+	objMetadata.EncryptionMasterKeyID = e.masterKeyID
+	objMetadata.EncryptionSealedKey = e.sealedKey
+	return e.stream(iv, 0)
+}
+
+// decrypt - key stream of an encrypted object starting at offset
+func (e Encryption) decrypt(objMetadata ObjectMetadata, offset int64) (cipher.Stream, *probe.Error) {
+	iv, err := hex.DecodeString(objMetadata.EncryptionIV)
+	if err != nil {
+		return nil, probe.NewError(ObjectCorrupted{Object: objMetadata.Object})
+	}
+	return e.stream(iv, offset)
+}
+
+// newDecryptReader - reader of plain data of an encrypted object read from offset, reads as is if not encrypted
+func newDecryptReader(reader io.Reader, objMetadata ObjectMetadata, encryption *Encryption, offset int64) (io.Reader, *probe.Error) {
+	if !objMetadata.IsEncrypted() {
+		return reader, nil
+	}
+	stream, err := encryption.decrypt(objMetadata, offset)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	return cipher.StreamReader{S: stream, R: reader}, nil
+}
+
+// IsEncrypted - returns true if object is stored encrypted
+func (o ObjectMetadata) IsEncrypted() bool {
+	return o.EncryptionAlgorithm != ""
+}
+
+// IsServerEncrypted - returns true if object is stored encrypted with a server managed key
+func (o ObjectMetadata) IsServerEncrypted() bool {
+	return o.EncryptionSealedKey != ""
+}
+
+// IsCustomerEncrypted - returns true if object is stored encrypted with a customer provided key,
+// checksums of the object are of encrypted data
+func (o ObjectMetadata) IsCustomerEncrypted() bool {
+	return o.IsEncrypted() && !o.IsServerEncrypted()
+}
+
+// VerifyEncryption - verify encryption parameters of a request reading an object, an encrypted object
+// is readable only with the key it was encrypted with, server managed keys are never provided
+func VerifyEncryption(objMetadata ObjectMetadata, encryption *Encryption) *probe.Error {
+	if objMetadata.IsServerEncrypted() {
+		if encryption != nil {
+			return probe.NewError(ServerEncryptedObject{Object: objMetadata.Object})
+		}
+		return nil
+	}
+	return verifyEncryption(objMetadata, encryption)
+}
+
+// verifyEncryption - verify key of an encrypted object is the key it was encrypted with
+func verifyEncryption(objMetadata ObjectMetadata, encryption *Encryption) *probe.Error {
+	if !objMetadata.IsEncrypted() {
+		if encryption != nil {
+			return probe.NewError(ObjectNotEncrypted{Object: objMetadata.Object})
+		}
+		return nil
+	}
+	if encryption == nil {
+		return probe.NewError(EncryptionKeyMissing{Object: objMetadata.Object})
+	}
+	if err := encryption.validate(); err != nil {
+		return err.Trace()
+	}
+	iv, err := hex.DecodeString(objMetadata.EncryptionIV)
+	if err != nil {
+		return probe.NewError(ObjectCorrupted{Object: objMetadata.Object})
+	}
+	if !hmac.Equal([]byte(encryption.keyMAC(iv)), []byte(objMetadata.EncryptionKeyMAC)) {
+		return probe.NewError(EncryptionKeyMismatch{Object: objMetadata.Object})
+	}
+	return nil
+}
+
+// isSameEncryption - returns true if both requests use the same encryption parameters
+func isSameEncryption(a, b *Encryption) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.algorithm() == b.algorithm() && hmac.Equal(a.key(), b.key())
+}
+
+// newObjectEncryption - encryption of a new object, server managed encryption gets a new data key. Objects
+// in buckets with default encryption are encrypted even if the request asks for none
+func (donut API) newObjectEncryption(bucketMetadata BucketMetadata, encryption *Encryption) (*Encryption, *probe.Error) {
+	if encryption == nil {
+		algorithm, ok := bucketMetadata.Metadata[EncryptionMetadataKey]
+		if !ok || algorithm == "" {
+			return nil, nil
+		}
+		encryption = &Encryption{ServerAlgorithm: algorithm}
+	}
+	if err := encryption.validate(); err != nil {
+		return nil, err.Trace()
+	}
+	// data key is already set for parts of a multipart session
+	if !encryption.isServerManaged() || encryption.dataKey != nil {
+		return encryption, nil
+	}
+	return donut.keys.newDataKey(encryption.ServerAlgorithm)
+}
+
+// getObjectEncryption - verify encryption of a request reading an object, returns the encryption to decrypt
+// the object with. Data keys of server managed encryption are unsealed with the master key they are sealed with
+func (donut API) getObjectEncryption(objMetadata ObjectMetadata, encryption *Encryption) (*Encryption, *probe.Error) {
+	if err := VerifyEncryption(objMetadata, encryption); err != nil {
+		return nil, err.Trace()
+	}
+	if !objMetadata.IsServerEncrypted() {
+		return encryption, nil
+	}
+	dataKey, err := donut.keys.unsealKey(objMetadata.EncryptionMasterKeyID, objMetadata.EncryptionSealedKey)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	encryption = &Encryption{
+		ServerAlgorithm: objMetadata.EncryptionAlgorithm,
+		dataKey:         dataKey,
+	}
+	if err := verifyEncryption(objMetadata, encryption); err != nil {
+		return nil, err.Trace()
+	}
+	return encryption, nil
+}
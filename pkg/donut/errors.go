@@ -125,6 +125,13 @@ func (e ChecksumMismatch) Error() string {
 	return "Checksum mismatch"
 }
 
+// InsufficientWriteQuorum too few disks took a write
+type InsufficientWriteQuorum struct{}
+
+func (e InsufficientWriteQuorum) Error() string {
+	return "Insufficient write quorum"
+}
+
 // MissingPOSTPolicy missing post policy
 type MissingPOSTPolicy struct{}
 
@@ -247,7 +254,7 @@ func (e BucketNameInvalid) Error() string {
 
 // Return string an error formatted as the given text
 func (e TooManyBuckets) Error() string {
-	return "Bucket limit exceeded beyond 100, cannot create bucket: " + e.Bucket
+	return "Bucket limit exceeded, cannot create bucket: " + e.Bucket
 }
 
 // Return string an error formatted as the given text
@@ -331,3 +338,153 @@ type MalformedXML struct{}
 func (e MalformedXML) Error() string {
 	return "Malformed XML"
 }
+
+// InvalidTag invalid tag key, value or too many tags
+type InvalidTag struct {
+	Key string
+}
+
+func (e InvalidTag) Error() string {
+	if e.Key == "" {
+		return "Invalid tag set"
+	}
+	return "Invalid tag: " + e.Key
+}
+
+// InvalidEventName event not supported for notifications
+type InvalidEventName struct {
+	Name string
+}
+
+func (e InvalidEventName) Error() string {
+	return "Event not supported for notifications: " + e.Name
+}
+
+// InvalidTargetARN notification target does not exist or is not well-formed
+type InvalidTargetARN struct {
+	ARN string
+}
+
+func (e InvalidTargetARN) Error() string {
+	return "Invalid notification target ARN: " + e.ARN
+}
+
+// InvalidLocationConstraint location constraint does not match server region
+type InvalidLocationConstraint struct {
+	Location string
+}
+
+func (e InvalidLocationConstraint) Error() string {
+	return "Invalid location constraint: " + e.Location
+}
+
+// InvalidEncryptionAlgorithm encryption algorithm is not supported
+type InvalidEncryptionAlgorithm struct {
+	Algorithm string
+}
+
+func (e InvalidEncryptionAlgorithm) Error() string {
+	return "Invalid encryption algorithm: " + e.Algorithm
+}
+
+// InvalidEncryptionKey encryption key is not a valid key
+type InvalidEncryptionKey struct{}
+
+func (e InvalidEncryptionKey) Error() string {
+	return "Invalid encryption key"
+}
+
+// EncryptionKeyMissing object is encrypted, but no key is provided
+type EncryptionKeyMissing struct {
+	Object string
+}
+
+func (e EncryptionKeyMissing) Error() string {
+	return "Encryption key missing for encrypted object: " + e.Object
+}
+
+// EncryptionKeyMismatch key provided is not the key object is encrypted with
+type EncryptionKeyMismatch struct {
+	Object string
+}
+
+func (e EncryptionKeyMismatch) Error() string {
+	return "Encryption key does not match for object: " + e.Object
+}
+
+// ObjectNotEncrypted key provided for an object which is not encrypted
+type ObjectNotEncrypted struct {
+	Object string
+}
+
+func (e ObjectNotEncrypted) Error() string {
+	return "Object is not encrypted: " + e.Object
+}
+
+// ServerEncryptedObject key provided for an object encrypted with a server managed key
+type ServerEncryptedObject struct {
+	Object string
+}
+
+func (e ServerEncryptedObject) Error() string {
+	return "Object is encrypted with a server managed key: " + e.Object
+}
+
+// KeyStoreNotConfigured server managed encryption requested, but no master key is configured
+type KeyStoreNotConfigured struct{}
+
+func (e KeyStoreNotConfigured) Error() string {
+	return "No master key configured for server managed encryption"
+}
+
+// MasterKeyNotFound master key a data key is sealed with is not in the key store
+type MasterKeyNotFound struct {
+	KeyID string
+}
+
+func (e MasterKeyNotFound) Error() string {
+	return "Master key not found: " + e.KeyID
+}
+
+// DiskFormatted disk is already formatted
+type DiskFormatted struct {
+	Path string
+}
+
+func (e DiskFormatted) Error() string {
+	return "Disk is already formatted: " + e.Path
+}
+
+// ForeignDisk disk is formatted for another donut or node
+type ForeignDisk struct {
+	Path string
+}
+
+func (e ForeignDisk) Error() string {
+	return "Disk belongs to another donut or node: " + e.Path
+}
+
+// AmbiguousNode as many disks are formatted for one node as for another
+type AmbiguousNode struct{}
+
+func (e AmbiguousNode) Error() string {
+	return "Disks are evenly split between nodes, node cannot be identified"
+}
+
+// UnformattedDisk disk is not formatted and cannot take the place of a missing disk
+type UnformattedDisk struct {
+	Path string
+}
+
+func (e UnformattedDisk) Error() string {
+	return "Unformatted disk cannot be added to the donut: " + e.Path
+}
+
+// InvalidCacheConfig cache settings of the donut config are not valid
+type InvalidCacheConfig struct {
+	Reason string
+}
+
+func (e InvalidCacheConfig) Error() string {
+	return "Invalid cache config: " + e.Reason
+}
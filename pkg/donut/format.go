@@ -0,0 +1,235 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/minio/minio-xl/pkg/donut/disk"
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// diskFormat identity of a disk, written to the root of every disk
+type diskFormat struct {
+	Version string `json:"version"`
+	Donut   string `json:"donut"`
+	Node    string `json:"node"`
+	UUID    string `json:"uuid"`
+	Order   int    `json:"order"`
+}
+
+// newUUID - random version 4 uuid
+func newUUID() (string, *probe.Error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", probe.NewError(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// newDiskFormat - format of a disk at given order
+func newDiskFormat(donutName, nodeUUID string, order int) (*diskFormat, *probe.Error) {
+	diskUUID, err := newUUID()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	format := &diskFormat{
+		Version: formatVersion,
+		Donut:   donutName,
+		Node:    nodeUUID,
+		UUID:    diskUUID,
+		Order:   order,
+	}
+	return format, nil
+}
+
+// readDiskFormat - read format of a disk
+func readDiskFormat(d disk.Disk) (*diskFormat, *probe.Error) {
+	reader, err := d.Open(formatConfig)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	defer reader.Close()
+	format := &diskFormat{}
+	if err := json.NewDecoder(reader).Decode(format); err != nil {
+		return nil, probe.NewError(err)
+	}
+	return format, nil
+}
+
+// writeDiskFormat - write format of a disk
+func writeDiskFormat(d disk.Disk, format *diskFormat) *probe.Error {
+	writer, err := d.CreateFile(formatConfig)
+	if err != nil {
+		return err.Trace()
+	}
+	if err := json.NewEncoder(writer).Encode(format); err != nil {
+		writer.CloseAndPurge()
+		return probe.NewError(err)
+	}
+	if err := writer.Close(); err != nil {
+		return probe.NewError(err)
+	}
+	return nil
+}
+
+// FormatDisks - format disks of a new donut, a disk's order is its position in diskPaths
+func FormatDisks(donutName string, diskPaths []string) *probe.Error {
+	var disks []disk.Disk
+	for _, diskPath := range diskPaths {
+		d, err := disk.New(diskPath)
+		if err != nil {
+			return err.Trace(diskPath)
+		}
+		if _, err := d.Stat(formatConfig); err == nil {
+			return probe.NewError(DiskFormatted{Path: diskPath})
+		}
+		disks = append(disks, d)
+	}
+	nodeUUID, err := newUUID()
+	if err != nil {
+		return err.Trace()
+	}
+	for order, d := range disks {
+		format, err := newDiskFormat(donutName, nodeUUID, order)
+		if err != nil {
+			return err.Trace()
+		}
+		if err := writeDiskFormat(d, format); err != nil {
+			return err.Trace(d.GetPath())
+		}
+	}
+	return nil
+}
+
+// orderDisks - order disks of a node by their format, regardless of the order they are configured in
+//
+// A node with no formatted disks is formatted in configured order. The node is the one most disks are
+// formatted for, disks of another donut or node are refused, an empty unformatted disk replaces a
+// missing one, disks which cannot be identified are left out and their place is held by an offline disk.
+func (donut API) orderDisks(disks []disk.Disk) ([]disk.Disk, *probe.Error) {
+	donutName := donut.config.DonutName
+	formats := make([]*diskFormat, len(disks))
+	votes := make(map[string]int)
+	for i, d := range disks {
+		format, err := readDiskFormat(d)
+		if err != nil {
+			continue
+		}
+		if format.Donut != donutName {
+			return nil, probe.NewError(ForeignDisk{Path: d.GetPath()})
+		}
+		votes[format.Node]++
+		formats[i] = format
+	}
+	nodeUUID := ""
+	for node, count := range votes {
+		if nodeUUID == "" || count > votes[nodeUUID] {
+			nodeUUID = node
+		}
+	}
+	for node, count := range votes {
+		if node != nodeUUID && count == votes[nodeUUID] {
+			return nil, probe.NewError(AmbiguousNode{})
+		}
+	}
+	for i, format := range formats {
+		if format != nil && format.Node != nodeUUID {
+			return nil, probe.NewError(ForeignDisk{Path: disks[i].GetPath()})
+		}
+	}
+	if nodeUUID == "" {
+		// a new node, or one created before disks were formatted
+		return disks, donut.formatNode(disks)
+	}
+
+	ordered := make([]disk.Disk, len(disks))
+	for i, format := range formats {
+		if format == nil {
+			continue
+		}
+		if format.Order < 0 || format.Order >= len(ordered) || ordered[format.Order] != nil {
+			return nil, probe.NewError(ForeignDisk{Path: disks[i].GetPath()})
+		}
+		ordered[format.Order] = disks[i]
+	}
+	for i, format := range formats {
+		if format != nil {
+			continue
+		}
+		d := disks[i]
+		if _, err := d.Stat(formatConfig); err == nil || !os.IsNotExist(err.ToGoError()) {
+			// format is there but unreadable, or disk is unreachable
+			continue
+		}
+		if dirs, err := d.ListDir(donutName); err == nil && len(dirs) > 0 {
+			return nil, probe.NewError(UnformattedDisk{Path: d.GetPath()})
+		}
+		order := freeOrder(ordered, i)
+		if order < 0 {
+			return nil, probe.NewError(UnformattedDisk{Path: d.GetPath()})
+		}
+		format, err := newDiskFormat(donutName, nodeUUID, order)
+		if err != nil {
+			return nil, err.Trace()
+		}
+		if err := writeDiskFormat(d, format); err != nil {
+			continue
+		}
+		ordered[order] = d
+	}
+	for order, d := range ordered {
+		if d == nil {
+			ordered[order] = disk.NewOffline(disks[order].GetPath())
+		}
+	}
+	return ordered, nil
+}
+
+// formatNode - format disks of a node in given order, disks which cannot be written are left unformatted
+func (donut API) formatNode(disks []disk.Disk) *probe.Error {
+	nodeUUID, err := newUUID()
+	if err != nil {
+		return err.Trace()
+	}
+	for order, d := range disks {
+		format, err := newDiskFormat(donut.config.DonutName, nodeUUID, order)
+		if err != nil {
+			return err.Trace()
+		}
+		writeDiskFormat(d, format)
+	}
+	return nil
+}
+
+// freeOrder - first free order, preferring the given one
+func freeOrder(ordered []disk.Disk, preferred int) int {
+	if ordered[preferred] == nil {
+		return preferred
+	}
+	for order, d := range ordered {
+		if d == nil {
+			return order
+		}
+	}
+	return -1
+}
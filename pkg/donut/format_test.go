@@ -0,0 +1,191 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/minio/minio-xl/pkg/donut/disk"
+	"github.com/minio/minio-xl/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+type MyFormatSuite struct {
+	root string
+}
+
+var _ = Suite(&MyFormatSuite{})
+
+func (s *MyFormatSuite) SetUpSuite(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-format-")
+	c.Assert(err, IsNil)
+	s.root = root
+}
+
+func (s *MyFormatSuite) TearDownSuite(c *C) {
+	os.RemoveAll(s.root)
+}
+
+// newDiskPaths - empty disks for a new donut
+func (s *MyFormatSuite) newDiskPaths(c *C, totalDisks int) []string {
+	root, err := ioutil.TempDir(s.root, "donut-")
+	c.Assert(err, IsNil)
+	diskPaths := make([]string, totalDisks)
+	for i := range diskPaths {
+		diskPaths[i] = filepath.Join(root, strconv.Itoa(i))
+		c.Assert(os.MkdirAll(diskPaths[i], 0700), IsNil)
+	}
+	return diskPaths
+}
+
+// openDonut - open donut over disks, configured in given order
+func openDonut(c *C, donutName string, diskPaths []string) (Interface, *probe.Error) {
+	conf := new(Config)
+	conf.Version = "0.0.1"
+	conf.DonutName = donutName
+	conf.NodeDiskMap = map[string][]string{"localhost": diskPaths}
+	conf.MaxSize = 100000000
+	SetDonutConfigPath(filepath.Join(filepath.Dir(diskPaths[0]), "donut.json"))
+	c.Assert(SaveConfig(conf), IsNil)
+	return New()
+}
+
+// putFormatObject - new donut with an object
+func putFormatObject(c *C, diskPaths []string, data []byte) {
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	c.Assert(d.MakeBucket("bucket", "private", "", nil, nil), IsNil)
+	_, err = d.CreateObject("bucket", "object", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+	c.Assert(err, IsNil)
+}
+
+// assertFormatObject - object reads back intact
+func assertFormatObject(c *C, d Interface, data []byte) {
+	var buffer bytes.Buffer
+	_, err := d.GetObject(&buffer, "bucket", "object", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(buffer.Bytes(), DeepEquals, data)
+}
+
+func (s *MyFormatSuite) TestFormatDisks(c *C) {
+	diskPaths := s.newDiskPaths(c, 4)
+	c.Assert(FormatDisks("test", diskPaths), IsNil)
+	for order, diskPath := range diskPaths {
+		d, err := disk.New(diskPath)
+		c.Assert(err, IsNil)
+		format, err := readDiskFormat(d)
+		c.Assert(err, IsNil)
+		c.Assert(format.Donut, Equals, "test")
+		c.Assert(format.Order, Equals, order)
+		c.Assert(format.UUID, Not(Equals), "")
+	}
+
+	err := FormatDisks("test", diskPaths)
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, DiskFormatted{})
+}
+
+func (s *MyFormatSuite) TestReorderedDisks(c *C) {
+	data := []byte("hello format")
+	diskPaths := s.newDiskPaths(c, 4)
+	putFormatObject(c, diskPaths, data)
+
+	reordered := []string{diskPaths[3], diskPaths[1], diskPaths[0], diskPaths[2]}
+	d, err := openDonut(c, "test", reordered)
+	c.Assert(err, IsNil)
+	assertFormatObject(c, d, data)
+
+	disks, err := d.(API).nodes["localhost"].ListDisks()
+	c.Assert(err, IsNil)
+	for order, diskPath := range diskPaths {
+		c.Assert(disks[order].GetPath(), Equals, diskPath)
+	}
+}
+
+func (s *MyFormatSuite) TestForeignDisk(c *C) {
+	data := []byte("hello format")
+	diskPaths := s.newDiskPaths(c, 4)
+	putFormatObject(c, diskPaths, data)
+
+	foreignPaths := s.newDiskPaths(c, 4)
+	c.Assert(FormatDisks("other", foreignPaths), IsNil)
+	_, err := openDonut(c, "test", []string{diskPaths[0], diskPaths[1], foreignPaths[2], diskPaths[3]})
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, ForeignDisk{})
+
+	// same donut name, but another node
+	otherPaths := s.newDiskPaths(c, 4)
+	c.Assert(FormatDisks("test", otherPaths), IsNil)
+	_, err = openDonut(c, "test", []string{diskPaths[0], otherPaths[1], diskPaths[2], diskPaths[3]})
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, ForeignDisk{})
+
+	// node is the one most disks agree on, a stray first disk is the one refused
+	_, err = openDonut(c, "test", []string{otherPaths[0], diskPaths[1], diskPaths[2], diskPaths[3]})
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), DeepEquals, ForeignDisk{Path: otherPaths[0]})
+
+	// node cannot be told with disks evenly split
+	_, err = openDonut(c, "test", []string{diskPaths[0], diskPaths[1], otherPaths[2], otherPaths[3]})
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), DeepEquals, AmbiguousNode{})
+}
+
+func (s *MyFormatSuite) TestReplacedDisk(c *C) {
+	data := []byte("hello format")
+	diskPaths := s.newDiskPaths(c, 4)
+	putFormatObject(c, diskPaths, data)
+
+	c.Assert(os.RemoveAll(diskPaths[2]), IsNil)
+	c.Assert(os.MkdirAll(diskPaths[2], 0700), IsNil)
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	c.Assert(d.Heal(), IsNil)
+	assertFormatObject(c, d, data)
+
+	newDisk, err := disk.New(diskPaths[2])
+	c.Assert(err, IsNil)
+	format, err := readDiskFormat(newDisk)
+	c.Assert(err, IsNil)
+	c.Assert(format.Order, Equals, 2)
+}
+
+func (s *MyFormatSuite) TestUnformattedDiskWithData(c *C) {
+	data := []byte("hello format")
+	diskPaths := s.newDiskPaths(c, 4)
+	putFormatObject(c, diskPaths, data)
+
+	c.Assert(os.Remove(filepath.Join(diskPaths[1], formatConfig)), IsNil)
+	_, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, UnformattedDisk{})
+}
+
+func (s *MyFormatSuite) TestMissingDisk(c *C) {
+	data := []byte("hello format")
+	diskPaths := s.newDiskPaths(c, 4)
+	putFormatObject(c, diskPaths, data)
+
+	c.Assert(os.RemoveAll(diskPaths[0]), IsNil)
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	assertFormatObject(c, d, data)
+}
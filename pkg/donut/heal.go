@@ -17,11 +17,13 @@
 package donut
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 
-	"github.com/minio/minio-xl/pkg/donut/disk"
 	"github.com/minio/minio-xl/pkg/probe"
 )
 
@@ -30,40 +32,157 @@ func (donut API) healBuckets() *probe.Error {
 	if err := donut.listDonutBuckets(); err != nil {
 		return err.Trace()
 	}
-	bucketMetadata, err := donut.getDonutBucketMetadata()
+	disks, err := listNodeDisks(donut.nodes)
 	if err != nil {
 		return err.Trace()
 	}
-	disks := make(map[int]disk.Disk)
-	for _, node := range donut.nodes {
-		nDisks, err := node.ListDisks()
-		if err != nil {
+	for _, disk := range disks {
+		if disk.IsUsable() {
+			disk.MakeDir(donut.config.DonutName)
+			for bucket := range donut.buckets {
+				err := disk.MakeDir(filepath.Join(donut.config.DonutName, disk.bucketSlice(bucket)))
+				if err != nil {
+					// disk is failing, nothing to heal it with
+					break
+				}
+			}
+		}
+	}
+	for _, bucket := range donut.buckets {
+		if err := bucket.healMetadata(); err != nil {
 			return err.Trace()
 		}
-		for k, v := range nDisks {
-			disks[k] = v
+	}
+	return nil
+}
+
+// healMetadata - write bucket metadata and object index back to all disks
+func (b bucket) healMetadata() *probe.Error {
+	bucketMetadata, err := b.getBucketMetadata()
+	if err != nil {
+		// bucket left half made, nothing to heal it with
+		if os.IsNotExist(err.ToGoError()) {
+			return nil
 		}
+		return err.Trace()
 	}
-	for order, disk := range disks {
-		if disk.IsUsable() {
-			disk.MakeDir(donut.config.DonutName)
-			bucketMetadataWriter, err := disk.CreateFile(filepath.Join(donut.config.DonutName, bucketMetadataConfig))
-			if err != nil {
+	if err := b.setBucketMetadata(bucketMetadata); err != nil {
+		return err.Trace()
+	}
+	manifest, err := b.getIndexManifest()
+	if err != nil {
+		return err.Trace()
+	}
+	for i, shard := range manifest.Shards {
+		objects, err := b.readIndexShard(manifest, i)
+		if err != nil {
+			return err.Trace()
+		}
+		if err := b.writeIndexShard(shard.ID, objects); err != nil {
+			return err.Trace()
+		}
+		for _, object := range objects {
+			if err := b.healObjectData(object); err != nil {
 				return err.Trace()
 			}
-			defer bucketMetadataWriter.Close()
-			jenc := json.NewEncoder(bucketMetadataWriter)
-			if err := jenc.Encode(bucketMetadata); err != nil {
-				return probe.NewError(err)
+		}
+	}
+	return b.writeIndexFile(indexManifestConfig, manifest)
+}
+
+// healObjectData - rebuild slices of an object missing from usable disks, such as those of disks
+// dropped while the object was written, by decoding the slices left on the other disks
+func (b bucket) healObjectData(objectName string) *probe.Error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	objectName = normalizeObjectName(objectName)
+	disks, err := listNodeDisks(b.nodes)
+	if err != nil {
+		return err.Trace()
+	}
+	objMetadata, err := b.readObjectMetadata(objectName)
+	if err != nil {
+		return err.Trace()
+	}
+	// nothing to rebuild slices with without parity, or once disks are added
+	if len(disks) == 1 || int(objMetadata.DataDisks)+int(objMetadata.ParityDisks) != len(disks) {
+		return nil
+	}
+	readers, err := b.getObjectReaders(objectName, "data")
+	if err != nil {
+		return err.Trace()
+	}
+	for _, reader := range readers {
+		defer reader.Close()
+	}
+	writers := make([]io.WriteCloser, len(disks))
+	var missing int
+	for i, disk := range disks {
+		if _, ok := readers[i]; ok || !disk.IsUsable() {
+			continue
+		}
+		writer, err := disk.CreateFile(filepath.Join(b.donutName, disk.bucketSlice(b.name), objectName, "data"))
+		if err != nil {
+			continue
+		}
+		writers[i] = writer
+		missing++
+	}
+	if missing == 0 {
+		return nil
+	}
+	encoder, err := newEncoder(objMetadata.DataDisks, objMetadata.ParityDisks)
+	if err != nil {
+		CleanupWritersOnError(writers)
+		return err.Trace()
+	}
+	hasher := md5.New()
+	totalLeft := objMetadata.Size
+	for i := 0; i < objMetadata.ChunkCount; i++ {
+		decodedData, err := b.decodeEncodedData(totalLeft, int64(objMetadata.BlockSize), readers, encoder, nil)
+		if err != nil {
+			CleanupWritersOnError(writers)
+			return err.Trace()
+		}
+		hasher.Write(decodedData)
+		encodedBlocks, err := encoder.Encode(decodedData)
+		if err != nil {
+			CleanupWritersOnError(writers)
+			return err.Trace()
+		}
+		for blockIndex, writer := range writers {
+			if writer == nil {
+				continue
 			}
-			for bucket := range bucketMetadata.Buckets {
-				bucketSlice := fmt.Sprintf("%s$0$%d", bucket, order) // TODO handle node slices
-				err := disk.MakeDir(filepath.Join(donut.config.DonutName, bucketSlice))
-				if err != nil {
-					return err.Trace()
-				}
+			if _, err := writer.Write(encodedBlocks[blockIndex]); err != nil {
+				// disk is failing, left for the next heal
+				dropWriter(writers, blockIndex)
 			}
 		}
+		totalLeft = totalLeft - int64(objMetadata.BlockSize)
+	}
+	// checksums of objects encrypted with server managed keys are of plain data, their slices are rebuilt as decoded
+	if !objMetadata.IsServerEncrypted() && hex.EncodeToString(hasher.Sum(nil)) != objMetadata.MD5Sum {
+		CleanupWritersOnError(writers)
+		return probe.NewError(ChecksumMismatch{})
+	}
+	closeWriters(writers)
+	// object metadata only ever refers to object data which is already in place
+	for i, writer := range writers {
+		if writer == nil {
+			continue
+		}
+		disk := disks[i]
+		objMetadataWriter, err := disk.CreateFile(filepath.Join(b.donutName, disk.bucketSlice(b.name), objectName, objectMetadataConfig))
+		if err != nil {
+			continue
+		}
+		if err := json.NewEncoder(objMetadataWriter).Encode(&objMetadata); err != nil {
+			objMetadataWriter.CloseAndPurge()
+			continue
+		}
+		objMetadataWriter.Close()
 	}
 	return nil
 }
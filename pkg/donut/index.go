@@ -0,0 +1,408 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// maxShardEntries - objects an index shard holds before it is split in two
+const maxShardEntries = 1000
+
+// bucketIndex sorted index of the objects of a bucket, shared by all copies of a bucket
+//
+// Object names are kept sorted in shards of bounded size, the manifest lists shards by the first
+// object name each of them holds. Adding an object rewrites only the shard it falls in, the manifest
+// is rewritten only when a shard grows beyond maxShardEntries and is split in two.
+type bucketIndex struct {
+	manifest *indexManifest
+}
+
+// indexManifest shards of an index, ordered by the object names they hold
+type indexManifest struct {
+	Version   string       `json:"version"`
+	NextShard int          `json:"nextShard"`
+	Shards    []indexShard `json:"shards"`
+}
+
+// indexShard shard holding object names from First up to the First of the next shard
+type indexShard struct {
+	ID    int    `json:"id"`
+	First string `json:"first"`
+}
+
+// indexShardObjects sorted object names held by a shard
+type indexShardObjects struct {
+	Version string   `json:"version"`
+	Objects []string `json:"objects"`
+}
+
+// newIndexManifest - manifest of an empty index, the first shard always starts at ""
+func newIndexManifest() *indexManifest {
+	return &indexManifest{
+		Version:   indexVersion,
+		NextShard: 1,
+		Shards:    []indexShard{{ID: 0}},
+	}
+}
+
+// findShard - position of the shard an object name falls in
+func (m *indexManifest) findShard(objectName string) int {
+	return sort.Search(len(m.Shards), func(i int) bool {
+		return m.Shards[i].First > objectName
+	}) - 1
+}
+
+// inShard - does an object name fall in the shard at given position
+func (m *indexManifest) inShard(i int, objectName string) bool {
+	if objectName < m.Shards[i].First {
+		return false
+	}
+	return i+1 == len(m.Shards) || objectName < m.Shards[i+1].First
+}
+
+// shardConfig - name of the file holding a shard
+func shardConfig(id int) string {
+	return fmt.Sprintf("shard-%d.json", id)
+}
+
+// indexPath - path of an index file on a disk
+func (b bucket) indexPath(disk nodeDisk, name string) string {
+	return filepath.Join(b.donutName, disk.bucketSlice(b.name), indexDir, name)
+}
+
+// writeIndexFile - write an index file to all disks
+func (b bucket) writeIndexFile(name string, v interface{}) *probe.Error {
+	writers, err := b.createWriters(func(disk nodeDisk) string {
+		return b.indexPath(disk, name)
+	})
+	if err != nil {
+		return err.Trace()
+	}
+	return encodeWriters(writers, v)
+}
+
+// readIndexFile - read an index file from the first disk it can be read from
+func (b bucket) readIndexFile(name string, v interface{}) *probe.Error {
+	return b.decodeFile(func(disk nodeDisk) string {
+		return b.indexPath(disk, name)
+	}, v)
+}
+
+// getIndexManifest - manifest of the index, read once and kept in memory
+func (b bucket) getIndexManifest() (*indexManifest, *probe.Error) {
+	if b.index.manifest != nil {
+		return b.index.manifest, nil
+	}
+	manifest := new(indexManifest)
+	if err := b.readIndexFile(indexManifestConfig, manifest); err != nil {
+		if !os.IsNotExist(err.ToGoError()) {
+			return nil, err.Trace()
+		}
+		manifest = newIndexManifest()
+	}
+	b.index.manifest = manifest
+	return manifest, nil
+}
+
+// readIndexShard - object names held by the shard at given position
+func (b bucket) readIndexShard(manifest *indexManifest, i int) ([]string, *probe.Error) {
+	shard := indexShardObjects{}
+	if err := b.readIndexFile(shardConfig(manifest.Shards[i].ID), &shard); err != nil {
+		if os.IsNotExist(err.ToGoError()) {
+			return nil, nil
+		}
+		return nil, err.Trace()
+	}
+	// names split off to the next shard are left behind if a split is interrupted
+	var objects []string
+	for _, objectName := range shard.Objects {
+		if manifest.inShard(i, objectName) {
+			objects = append(objects, objectName)
+		}
+	}
+	return objects, nil
+}
+
+// writeIndexShard - write object names held by a shard
+func (b bucket) writeIndexShard(id int, objects []string) *probe.Error {
+	return b.writeIndexFile(shardConfig(id), &indexShardObjects{
+		Version: indexVersion,
+		Objects: objects,
+	})
+}
+
+// hasObject - is object in the index
+func (b bucket) hasObject(objectName string) (bool, *probe.Error) {
+	manifest, err := b.getIndexManifest()
+	if err != nil {
+		return false, err.Trace()
+	}
+	objects, err := b.readIndexShard(manifest, manifest.findShard(objectName))
+	if err != nil {
+		return false, err.Trace()
+	}
+	i := sort.SearchStrings(objects, objectName)
+	return i < len(objects) && objects[i] == objectName, nil
+}
+
+// indexObject - add an object to the index
+func (b bucket) indexObject(objectName string) *probe.Error {
+	manifest, err := b.getIndexManifest()
+	if err != nil {
+		return err.Trace()
+	}
+	i := manifest.findShard(objectName)
+	objects, err := b.readIndexShard(manifest, i)
+	if err != nil {
+		return err.Trace()
+	}
+	pos := sort.SearchStrings(objects, objectName)
+	if pos < len(objects) && objects[pos] == objectName {
+		return nil
+	}
+	objects = append(objects, "")
+	copy(objects[pos+1:], objects[pos:])
+	objects[pos] = objectName
+	if len(objects) <= maxShardEntries {
+		return b.writeIndexShard(manifest.Shards[i].ID, objects)
+	}
+
+	// split the shard, the upper half is written to a new shard before the manifest refers to it
+	half := len(objects) / 2
+	newShard := indexShard{ID: manifest.NextShard, First: objects[half]}
+	if err := b.writeIndexShard(newShard.ID, objects[half:]); err != nil {
+		return err.Trace()
+	}
+	newManifest := &indexManifest{
+		Version:   indexVersion,
+		NextShard: manifest.NextShard + 1,
+	}
+	newManifest.Shards = append(newManifest.Shards, manifest.Shards[:i+1]...)
+	newManifest.Shards = append(newManifest.Shards, newShard)
+	newManifest.Shards = append(newManifest.Shards, manifest.Shards[i+1:]...)
+	if err := b.writeIndexFile(indexManifestConfig, newManifest); err != nil {
+		return err.Trace()
+	}
+	b.index.manifest = newManifest
+	return b.writeIndexShard(manifest.Shards[i].ID, objects[:half])
+}
+
+// indexNames - sorted object names from given name onwards, as held by the first shard having any
+func (b bucket) indexNames(from string) ([]string, *probe.Error) {
+	manifest, err := b.getIndexManifest()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	for i := manifest.findShard(from); i < len(manifest.Shards); i++ {
+		objects, err := b.readIndexShard(manifest, i)
+		if err != nil {
+			return nil, err.Trace()
+		}
+		if pos := sort.SearchStrings(objects, from); pos < len(objects) {
+			return objects[pos:], nil
+		}
+	}
+	return nil, nil
+}
+
+// listIndex - all object names in the index, sorted
+func (b bucket) listIndex() ([]string, *probe.Error) {
+	manifest, err := b.getIndexManifest()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	var objectNames []string
+	for i := range manifest.Shards {
+		objects, err := b.readIndexShard(manifest, i)
+		if err != nil {
+			return nil, err.Trace()
+		}
+		objectNames = append(objectNames, objects...)
+	}
+	return objectNames, nil
+}
+
+// writeIndex - write an index of given sorted object names, replacing the index in place
+func (b bucket) writeIndex(objectNames []string) *probe.Error {
+	manifest := newIndexManifest()
+	manifest.Shards = nil
+	manifest.NextShard = 0
+	// shards are left half full, room for objects to be added without splitting them right away
+	for start := 0; start == 0 || start < len(objectNames); start += maxShardEntries / 2 {
+		end := start + maxShardEntries/2
+		if end > len(objectNames) {
+			end = len(objectNames)
+		}
+		shard := indexShard{ID: manifest.NextShard}
+		if start > 0 {
+			shard.First = objectNames[start]
+		}
+		if err := b.writeIndexShard(shard.ID, objectNames[start:end]); err != nil {
+			return err.Trace()
+		}
+		manifest.Shards = append(manifest.Shards, shard)
+		manifest.NextShard++
+	}
+	if err := b.writeIndexFile(indexManifestConfig, manifest); err != nil {
+		return err.Trace()
+	}
+	b.index.manifest = manifest
+	return nil
+}
+
+// memoryIndex sorted index of object names kept in memory, laid out in shards like bucketIndex
+type memoryIndex struct {
+	shards [][]string
+}
+
+// newMemoryIndex - empty memory index
+func newMemoryIndex() *memoryIndex {
+	return &memoryIndex{shards: [][]string{nil}}
+}
+
+// findShard - position of the shard an object name falls in
+func (m *memoryIndex) findShard(objectName string) int {
+	return sort.Search(len(m.shards), func(i int) bool {
+		return i > 0 && m.shards[i][0] > objectName
+	}) - 1
+}
+
+// add - add an object name
+func (m *memoryIndex) add(objectName string) {
+	i := m.findShard(objectName)
+	objects := m.shards[i]
+	pos := sort.SearchStrings(objects, objectName)
+	if pos < len(objects) && objects[pos] == objectName {
+		return
+	}
+	objects = append(objects, "")
+	copy(objects[pos+1:], objects[pos:])
+	objects[pos] = objectName
+	m.shards[i] = objects
+	if len(objects) <= maxShardEntries {
+		return
+	}
+	half := len(objects) / 2
+	upper := append([]string(nil), objects[half:]...)
+	m.shards[i] = objects[:half:half]
+	m.shards = append(m.shards, nil)
+	copy(m.shards[i+2:], m.shards[i+1:])
+	m.shards[i+1] = upper
+}
+
+// remove - remove an object name
+func (m *memoryIndex) remove(objectName string) {
+	i := m.findShard(objectName)
+	objects := m.shards[i]
+	pos := sort.SearchStrings(objects, objectName)
+	if pos == len(objects) || objects[pos] != objectName {
+		return
+	}
+	m.shards[i] = append(objects[:pos], objects[pos+1:]...)
+	// empty shards are dropped, apart from the first one which always starts at ""
+	if len(m.shards[i]) == 0 && i > 0 {
+		m.shards = append(m.shards[:i], m.shards[i+1:]...)
+	}
+}
+
+// names - sorted object names from given name onwards, as held by the first shard having any
+func (m *memoryIndex) names(from string) ([]string, *probe.Error) {
+	for i := m.findShard(from); i < len(m.shards); i++ {
+		if pos := sort.SearchStrings(m.shards[i], from); pos < len(m.shards[i]) {
+			return m.shards[i][pos:], nil
+		}
+	}
+	return nil, nil
+}
+
+// prefixEnd - smallest name sorting after all names starting with prefix, "" if there is none
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
+
+// listNames - a page of object names and common prefixes, seeking through an index
+//
+// names returns sorted object names from given name onwards, a batch at a time, listing seeks
+// to the first name after marker within prefix, and past all names of a common prefix once it is
+// listed, so a page costs a seek per common prefix and the names it holds rather than the whole index.
+// Object names and common prefixes both count towards maxkeys, the last of them listed is the marker
+// for the next page.
+func listNames(names func(from string) ([]string, *probe.Error), prefix, marker, delimiter string, maxkeys int) (objects, commonPrefixes []string, nextMarker string, isTruncated bool, err *probe.Error) {
+	if maxkeys <= 0 {
+		maxkeys = 1000
+	}
+	commonPrefixes = []string{}
+	from := prefix
+	if marker >= from {
+		from = marker + "\x00"
+	}
+	for {
+		batch, err := names(from)
+		if err != nil {
+			return nil, nil, "", false, err.Trace()
+		}
+		if len(batch) == 0 {
+			return objects, commonPrefixes, nextMarker, false, nil
+		}
+		from = batch[len(batch)-1] + "\x00"
+		for _, objectName := range batch {
+			if !strings.HasPrefix(objectName, prefix) {
+				return objects, commonPrefixes, nextMarker, false, nil
+			}
+			entry, isPrefix := objectName, false
+			if delimiter != "" {
+				if i := strings.Index(objectName[len(prefix):], delimiter); i >= 0 {
+					entry, isPrefix = objectName[:len(prefix)+i+len(delimiter)], true
+				}
+			}
+			// a common prefix given as marker was listed in full by the previous page
+			if isPrefix && entry == marker {
+				if from = prefixEnd(entry); from == "" {
+					return objects, commonPrefixes, nextMarker, false, nil
+				}
+				break
+			}
+			if len(objects)+len(commonPrefixes) == maxkeys {
+				return objects, commonPrefixes, nextMarker, true, nil
+			}
+			nextMarker = entry
+			if !isPrefix {
+				objects = append(objects, objectName)
+				continue
+			}
+			commonPrefixes = append(commonPrefixes, entry)
+			if from = prefixEnd(entry); from == "" {
+				return objects, commonPrefixes, nextMarker, false, nil
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,318 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-xl/pkg/donut/disk"
+	"github.com/minio/minio-xl/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+type MyIndexSuite struct {
+	root string
+}
+
+var _ = Suite(&MyIndexSuite{})
+
+func (s *MyIndexSuite) SetUpSuite(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-index-")
+	c.Assert(err, IsNil)
+	s.root = root
+}
+
+func (s *MyIndexSuite) TearDownSuite(c *C) {
+	os.RemoveAll(s.root)
+}
+
+// newMemoryBucket - bucket over 4 memory disks
+func newMemoryBucket(c *C) bucket {
+	n, err := newNode("localhost")
+	c.Assert(err, IsNil)
+	for i := 0; i < 4; i++ {
+		c.Assert(n.AttachDisk(disk.NewMemory("disk"+strconv.Itoa(i)), i), IsNil)
+	}
+	b, _, err := newBucket("bucket", "private", "test", map[string]node{"localhost": n})
+	c.Assert(err, IsNil)
+	return b
+}
+
+// indexObjects - index objects in random order, replies their names sorted
+func indexObjects(c *C, b bucket, count int) []string {
+	var objectNames []string
+	for _, i := range rand.Perm(count) {
+		objectName := fmt.Sprintf("object-%05d", i)
+		c.Assert(b.indexObject(objectName), IsNil)
+		objectNames = append(objectNames, objectName)
+	}
+	sort.Strings(objectNames)
+	return objectNames
+}
+
+func (s *MyIndexSuite) TestIndex(c *C) {
+	b := newMemoryBucket(c)
+	objectNames, err := b.listIndex()
+	c.Assert(err, IsNil)
+	c.Assert(len(objectNames), Equals, 0)
+
+	expected := indexObjects(c, b, 2500)
+	objectNames, err = b.listIndex()
+	c.Assert(err, IsNil)
+	c.Assert(objectNames, DeepEquals, expected)
+	c.Assert(len(b.index.manifest.Shards) > 2, Equals, true)
+
+	// adding an object twice leaves a single entry
+	c.Assert(b.indexObject(expected[10]), IsNil)
+	objectNames, err = b.listIndex()
+	c.Assert(err, IsNil)
+	c.Assert(len(objectNames), Equals, len(expected))
+
+	exists, err := b.hasObject(expected[1234])
+	c.Assert(err, IsNil)
+	c.Assert(exists, Equals, true)
+	exists, err = b.hasObject("object-99999")
+	c.Assert(err, IsNil)
+	c.Assert(exists, Equals, false)
+
+	// index is read back from disks
+	b.index.manifest = nil
+	objectNames, err = b.listIndex()
+	c.Assert(err, IsNil)
+	c.Assert(objectNames, DeepEquals, expected)
+}
+
+func (s *MyIndexSuite) TestInterruptedSplit(c *C) {
+	b := newMemoryBucket(c)
+	expected := indexObjects(c, b, maxShardEntries+1)
+	c.Assert(len(b.index.manifest.Shards), Equals, 2)
+
+	// split shard still holding all of its objects, as if the split was interrupted
+	c.Assert(b.writeIndexShard(b.index.manifest.Shards[0].ID, expected), IsNil)
+	objectNames, err := b.listIndex()
+	c.Assert(err, IsNil)
+	c.Assert(objectNames, DeepEquals, expected)
+}
+
+// listTestNames - object names nested under directories, in random order
+func listTestNames() []string {
+	var objectNames []string
+	for i := 0; i < 30; i++ {
+		for j := 0; j < 100; j++ {
+			objectNames = append(objectNames, fmt.Sprintf("dir-%02d/obj-%03d", i, j))
+		}
+	}
+	for i := 0; i < 1500; i++ {
+		objectNames = append(objectNames, fmt.Sprintf("top-%04d", i))
+	}
+	for i, j := range rand.Perm(len(objectNames)) {
+		objectNames[i], objectNames[j] = objectNames[j], objectNames[i]
+	}
+	return objectNames
+}
+
+// expectedList - object names and common prefixes listed from given names, the long way
+func expectedList(objectNames []string, prefix, delimiter string) (objects, commonPrefixes []string) {
+	objectNames = append([]string(nil), objectNames...)
+	sort.Strings(objectNames)
+	for _, objectName := range objectNames {
+		if !strings.HasPrefix(objectName, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(objectName[len(prefix):], delimiter); i >= 0 {
+				commonPrefix := objectName[:len(prefix)+i+len(delimiter)]
+				if len(commonPrefixes) == 0 || commonPrefixes[len(commonPrefixes)-1] != commonPrefix {
+					commonPrefixes = append(commonPrefixes, commonPrefix)
+				}
+				continue
+			}
+		}
+		objects = append(objects, objectName)
+	}
+	return objects, commonPrefixes
+}
+
+// assertListPages - listing page by page matches listing the long way
+func assertListPages(c *C, names func(string) ([]string, *probe.Error), objectNames []string) {
+	for _, list := range []struct{ prefix, delimiter string }{
+		{"", ""},
+		{"", "/"},
+		{"dir-1", "/"},
+		{"dir-05/", "/"},
+		{"top-1", ""},
+		{"dir-", "-"},
+		{"none", "/"},
+	} {
+		expectedObjects, expectedPrefixes := expectedList(objectNames, list.prefix, list.delimiter)
+		for _, maxkeys := range []int{1, 7, 1000} {
+			var objects, commonPrefixes []string
+			marker := ""
+			for {
+				pageObjects, pagePrefixes, nextMarker, isTruncated, err := listNames(names, list.prefix, marker, list.delimiter, maxkeys)
+				c.Assert(err, IsNil)
+				c.Assert(len(pageObjects)+len(pagePrefixes) <= maxkeys, Equals, true)
+				objects = append(objects, pageObjects...)
+				commonPrefixes = append(commonPrefixes, pagePrefixes...)
+				if !isTruncated {
+					break
+				}
+				marker = nextMarker
+			}
+			c.Assert(objects, DeepEquals, expectedObjects)
+			c.Assert(commonPrefixes, DeepEquals, expectedPrefixes)
+		}
+	}
+}
+
+func (s *MyIndexSuite) TestListMemoryIndex(c *C) {
+	index := newMemoryIndex()
+	objectNames := listTestNames()
+	for _, objectName := range objectNames {
+		index.add(objectName)
+	}
+	c.Assert(len(index.shards) > 2, Equals, true)
+	assertListPages(c, index.names, objectNames)
+
+	// removed objects are no longer listed
+	for _, objectName := range objectNames[:len(objectNames)/2] {
+		index.remove(objectName)
+	}
+	assertListPages(c, index.names, objectNames[len(objectNames)/2:])
+}
+
+func (s *MyIndexSuite) TestListIndex(c *C) {
+	b := newMemoryBucket(c)
+	objectNames := listTestNames()
+	for _, objectName := range objectNames[:1000] {
+		c.Assert(b.indexObject(objectName), IsNil)
+	}
+	assertListPages(c, b.indexNames, objectNames[:1000])
+
+	sorted := append([]string(nil), objectNames...)
+	sort.Strings(sorted)
+	c.Assert(b.writeIndex(sorted), IsNil)
+	assertListPages(c, b.indexNames, objectNames)
+}
+
+func (s *MyIndexSuite) TestMigrateBucketMetadata(c *C) {
+	root, terr := ioutil.TempDir(s.root, "donut-")
+	c.Assert(terr, IsNil)
+	diskPaths := make([]string, 4)
+	for i := range diskPaths {
+		diskPaths[i] = filepath.Join(root, strconv.Itoa(i))
+		c.Assert(os.MkdirAll(diskPaths[i], 0700), IsNil)
+	}
+	data := []byte("hello migration")
+	putFormatObject(c, diskPaths, data)
+
+	// rewrite the donut the way it was kept before bucket metadata was kept per bucket
+	d, err := openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	bucketMetadata, err := d.(API).getBucketMetadata("bucket")
+	c.Assert(err, IsNil)
+	legacy := legacyBuckets{
+		Version: bucketMetadataVersion,
+		Buckets: map[string]legacyBucketMetadata{
+			"bucket": {
+				BucketMetadata: bucketMetadata,
+				BucketObjects:  map[string]struct{}{"object": {}},
+			},
+		},
+	}
+	legacyJSON, jerr := json.Marshal(&legacy)
+	c.Assert(jerr, IsNil)
+	for _, diskPath := range diskPaths {
+		c.Assert(ioutil.WriteFile(filepath.Join(diskPath, "test", bucketMetadataConfig), legacyJSON, 0600), IsNil)
+		slices, err := filepath.Glob(filepath.Join(diskPath, "test", "bucket$*"))
+		c.Assert(err, IsNil)
+		c.Assert(len(slices), Equals, 1)
+		c.Assert(os.Remove(filepath.Join(slices[0], bucketMetadataConfig)), IsNil)
+		c.Assert(os.RemoveAll(filepath.Join(slices[0], indexDir)), IsNil)
+	}
+
+	d, err = openDonut(c, "test", diskPaths)
+	c.Assert(err, IsNil)
+	assertFormatObject(c, d, data)
+	buckets, err := d.ListBuckets()
+	c.Assert(err, IsNil)
+	c.Assert(len(buckets), Equals, 1)
+	c.Assert(buckets[0].Name, Equals, "bucket")
+	c.Assert(buckets[0].ACL, Equals, bucketMetadata.ACL)
+	objects, _, err := d.ListObjects("bucket", BucketResourcesMetadata{Maxkeys: 1000})
+	c.Assert(err, IsNil)
+	c.Assert(len(objects), Equals, 1)
+	c.Assert(objects[0].Object, Equals, "object")
+
+	for _, diskPath := range diskPaths {
+		_, err := os.Stat(filepath.Join(diskPath, "test", bucketMetadataConfig))
+		c.Assert(os.IsNotExist(err), Equals, true)
+	}
+}
+
+// BenchmarkListFirstPage - first page of a bucket of ten million objects, cached in memory
+func BenchmarkListFirstPage(b *testing.B) {
+	index := newMemoryIndex()
+	for i := 0; i < 10000000; i++ {
+		index.add(fmt.Sprintf("dir-%03d/object-%08d", i%1000, i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, _, err := listNames(index.names, "dir-500/", "", "/", 1000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkListIndexPage - a page of a bucket of a million objects, read from disks
+func BenchmarkListIndexPage(b *testing.B) {
+	n, err := newNode("localhost")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := n.AttachDisk(disk.NewMemory("disk"+strconv.Itoa(i)), i); err != nil {
+			b.Fatal(err)
+		}
+	}
+	bkt, _, err := newBucket("bucket", "private", "test", map[string]node{"localhost": n})
+	if err != nil {
+		b.Fatal(err)
+	}
+	var objectNames []string
+	for i := 0; i < 1000000; i++ {
+		objectNames = append(objectNames, fmt.Sprintf("object-%08d", i))
+	}
+	if err := bkt.writeIndex(objectNames); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, _, err := listNames(bkt.indexNames, "", "object-00500000", "", 1000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
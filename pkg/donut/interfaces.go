@@ -19,6 +19,7 @@ package donut
 import (
 	"io"
 
+	"github.com/minio/minio-xl/pkg/donut/cache/data"
 	"github.com/minio/minio-xl/pkg/probe"
 	signv4 "github.com/minio/minio-xl/pkg/signature"
 )
@@ -37,25 +38,29 @@ type CloudStorage interface {
 	GetBucketMetadata(bucket string) (BucketMetadata, *probe.Error)
 	SetBucketMetadata(bucket string, metadata map[string]string) *probe.Error
 	ListBuckets() ([]BucketMetadata, *probe.Error)
-	MakeBucket(bucket string, ACL string, location io.Reader, signature *signv4.Signature) *probe.Error
+	MakeBucket(bucket string, ACL string, owner string, location io.Reader, signature *signv4.Signature) *probe.Error
+	GetBucketLocation(bucket string) (string, *probe.Error)
+	GetBucketNotification(bucket string) (NotificationConfiguration, *probe.Error)
+	SetBucketNotification(bucket string, config NotificationConfiguration) *probe.Error
 
 	// Bucket operations
 	ListObjects(string, BucketResourcesMetadata) ([]ObjectMetadata, BucketResourcesMetadata, *probe.Error)
 
 	// Object operations
-	GetObject(w io.Writer, bucket, object string, start, length int64) (int64, *probe.Error)
+	GetObject(w io.Writer, bucket, object string, start, length int64, encryption *Encryption) (int64, *probe.Error)
 	GetObjectMetadata(bucket, object string) (ObjectMetadata, *probe.Error)
-	// bucket, object, expectedMD5Sum, size, reader, metadata, signature
-	CreateObject(string, string, string, int64, io.Reader, map[string]string, *signv4.Signature) (ObjectMetadata, *probe.Error)
+	SetObjectMetadata(bucket, object string, metadata map[string]string) *probe.Error
+	// bucket, object, expectedMD5Sum, size, reader, metadata, signature, encryption
+	CreateObject(string, string, string, int64, io.Reader, map[string]string, *signv4.Signature, *Encryption) (ObjectMetadata, *probe.Error)
 
 	Multipart
 }
 
 // Multipart API
 type Multipart interface {
-	NewMultipartUpload(bucket, key, contentType string) (string, *probe.Error)
+	NewMultipartUpload(bucket, key string, metadata map[string]string, encryption *Encryption) (string, *probe.Error)
 	AbortMultipartUpload(bucket, key, uploadID string) *probe.Error
-	CreateObjectPart(string, string, string, int, string, string, int64, io.Reader, *signv4.Signature) (string, *probe.Error)
+	CreateObjectPart(string, string, string, int, string, string, int64, io.Reader, *signv4.Signature, *Encryption) (string, *probe.Error)
 	CompleteMultipartUpload(bucket, key, uploadID string, data io.Reader, signature *signv4.Signature) (ObjectMetadata, *probe.Error)
 	ListMultipartUploads(string, BucketMultipartResourcesMetadata) (BucketMultipartResourcesMetadata, *probe.Error)
 	ListObjectParts(string, string, ObjectResourcesMetadata) (ObjectResourcesMetadata, *probe.Error)
@@ -66,6 +71,9 @@ type Management interface {
 	Heal() *probe.Error
 	Rebalance() *probe.Error
 	Info() (map[string][]string, *probe.Error)
+	RotateKeys() *probe.Error
+	CacheStats() data.Stats
+	Region() string
 
 	AttachNode(hostname string, disks []string) *probe.Error
 	DetachNode(hostname string) *probe.Error
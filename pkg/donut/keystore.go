@@ -0,0 +1,228 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/probe"
+	"github.com/minio/minio-xl/pkg/quick"
+)
+
+// Please read for more information - http://docs.aws.amazon.com/AmazonS3/latest/dev/UsingServerSideEncryption.html
+//
+// Server managed encryption (SSE-S3) encrypts every object with its own random data key, the data key is
+// saved in object metadata sealed with a master key. Master keys are loaded from 'donut.keys' next to
+// donut config, new data keys are always sealed with the current master key. Rotating master keys
+// reseals data keys only, object data is never rewritten.
+
+// EncryptionMetadataKey bucket metadata key under which default encryption of a bucket is saved
+const EncryptionMetadataKey = "encryption"
+
+// KeyStore master keys of server managed encryption
+type KeyStore struct {
+	Version string `json:"version"`
+	// id of the master key new data keys are sealed with
+	Current string `json:"current"`
+	// hex encoded master keys by id
+	Keys map[string]string `json:"keys"`
+	// id of the master key data keys of all objects on disks were last resealed with
+	Resealed string `json:"resealed,omitempty"`
+}
+
+// getKeyStorePath get key store file path, saved next to donut config
+func getKeyStorePath() (string, *probe.Error) {
+	donutConfigPath, err := getDonutConfigPath()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(filepath.Dir(donutConfigPath), "donut.keys"), nil
+}
+
+// SaveKeyStore save master keys
+func SaveKeyStore(k *KeyStore) *probe.Error {
+	keyStorePath, err := getKeyStorePath()
+	if err != nil {
+		return err.Trace()
+	}
+	qc, err := quick.New(k)
+	if err != nil {
+		return err.Trace()
+	}
+	if err := qc.Save(keyStorePath); err != nil {
+		return err.Trace()
+	}
+	return nil
+}
+
+// LoadKeyStore load master keys, an empty key store is returned if none is configured
+func LoadKeyStore() (*KeyStore, *probe.Error) {
+	keyStorePath, err := getKeyStorePath()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	k := &KeyStore{}
+	k.Version = "0.0.1"
+	k.Keys = make(map[string]string)
+	qc, err := quick.New(k)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	if err := qc.Load(keyStorePath); err != nil {
+		if os.IsNotExist(err.ToGoError()) {
+			return k, nil
+		}
+		return nil, err.Trace()
+	}
+	k = qc.Data().(*KeyStore)
+	if k.isConfigured() {
+		if _, err := k.masterKey(k.Current); err != nil {
+			return nil, err.Trace()
+		}
+	}
+	return k, nil
+}
+
+// AddKey - add a new random master key and make it the current key, returns id of the new key
+func (k *KeyStore) AddKey() (string, *probe.Error) {
+	key := make([]byte, EncryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", probe.NewError(err)
+	}
+	// ids sort by creation time, random suffix keeps ids of keys added at once apart
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", probe.NewError(err)
+	}
+	id := time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+	if k.Keys == nil {
+		k.Keys = make(map[string]string)
+	}
+	k.Keys[id] = hex.EncodeToString(key)
+	k.Current = id
+	return id, nil
+}
+
+// isConfigured - returns true if a current master key is set
+func (k KeyStore) isConfigured() bool {
+	return k.Current != ""
+}
+
+// masterKey - master key by id
+func (k KeyStore) masterKey(id string) ([]byte, *probe.Error) {
+	encodedKey, ok := k.Keys[id]
+	if !ok {
+		return nil, probe.NewError(MasterKeyNotFound{KeyID: id})
+	}
+	key, err := hex.DecodeString(encodedKey)
+	if err != nil || len(key) != EncryptionKeySize {
+		return nil, probe.NewError(InvalidEncryptionKey{})
+	}
+	return key, nil
+}
+
+// newAEAD - AES-GCM with the master key of id
+func (k KeyStore) newAEAD(id string) (cipher.AEAD, *probe.Error) {
+	key, err := k.masterKey(id)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	block, e := aes.NewCipher(key)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	aead, e := cipher.NewGCM(block)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	return aead, nil
+}
+
+// sealKey - seal a data key with the current master key, sealed key is hex encoded nonce followed by sealed data
+func (k KeyStore) sealKey(dataKey []byte) (string, *probe.Error) {
+	if !k.isConfigured() {
+		return "", probe.NewError(KeyStoreNotConfigured{})
+	}
+	aead, err := k.newAEAD(k.Current)
+	if err != nil {
+		return "", err.Trace()
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", probe.NewError(err)
+	}
+	return hex.EncodeToString(aead.Seal(nonce, nonce, dataKey, []byte(k.Current))), nil
+}
+
+// unsealKey - unseal a data key sealed with the master key of id
+func (k KeyStore) unsealKey(id, sealedKey string) ([]byte, *probe.Error) {
+	aead, err := k.newAEAD(id)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	sealed, e := hex.DecodeString(sealedKey)
+	if e != nil || len(sealed) < aead.NonceSize() {
+		return nil, probe.NewError(InvalidEncryptionKey{})
+	}
+	dataKey, e := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if e != nil {
+		return nil, probe.NewError(InvalidEncryptionKey{})
+	}
+	return dataKey, nil
+}
+
+// newDataKey - server managed encryption of a new object, with a random data key sealed with the current master key
+func (k KeyStore) newDataKey(algorithm string) (*Encryption, *probe.Error) {
+	dataKey := make([]byte, EncryptionKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, probe.NewError(err)
+	}
+	sealedKey, err := k.sealKey(dataKey)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	return &Encryption{
+		ServerAlgorithm: algorithm,
+		dataKey:         dataKey,
+		masterKeyID:     k.Current,
+		sealedKey:       sealedKey,
+	}, nil
+}
+
+// reseal - seal data key of an object with the current master key, returns true if object metadata is changed
+func (k KeyStore) reseal(objMetadata *ObjectMetadata) (bool, *probe.Error) {
+	if !objMetadata.IsServerEncrypted() || objMetadata.EncryptionMasterKeyID == k.Current {
+		return false, nil
+	}
+	dataKey, err := k.unsealKey(objMetadata.EncryptionMasterKeyID, objMetadata.EncryptionSealedKey)
+	if err != nil {
+		return false, err.Trace()
+	}
+	sealedKey, err := k.sealKey(dataKey)
+	if err != nil {
+		return false, err.Trace()
+	}
+	objMetadata.EncryptionMasterKeyID = k.Current
+	objMetadata.EncryptionSealedKey = sealedKey
+	return true, nil
+}
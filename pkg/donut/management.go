@@ -48,15 +48,29 @@ func (donut API) AttachNode(hostname string, disks []string) *probe.Error {
 		return err.Trace()
 	}
 	donut.nodes[hostname] = n
-	for i, d := range disks {
-		newDisk, err := disk.New(d)
+	var nodeDisks []disk.Disk
+	for _, d := range disks {
+		newDisk, err := newNodeDisk(hostname, d)
 		if err != nil {
-			continue
+			newDisk = disk.NewOffline(d)
 		}
-		if err := newDisk.MakeDir(donut.config.DonutName); err != nil {
-			return err.Trace()
+		if faults, ok := donut.config.Faults[d]; ok {
+			newDisk = disk.NewFaulty(newDisk, faults)
+		}
+		nodeDisks = append(nodeDisks, newDisk)
+	}
+	// disks are attached in the order they were formatted in, not the order they are configured in
+	nodeDisks, err = donut.orderDisks(nodeDisks)
+	if err != nil {
+		return err.Trace(hostname)
+	}
+	for order, newDisk := range nodeDisks {
+		if newDisk.IsUsable() {
+			if err := newDisk.MakeDir(donut.config.DonutName); err != nil {
+				return err.Trace()
+			}
 		}
-		if err := n.AttachDisk(newDisk, i); err != nil {
+		if err := n.AttachDisk(newDisk, order); err != nil {
 			return err.Trace()
 		}
 	}
@@ -79,3 +93,80 @@ func (donut API) Heal() *probe.Error {
 	// TODO handle data heal
 	return donut.healBuckets()
 }
+
+// RotateKeys - reload master keys, data keys not sealed with the current master key are resealed
+func (donut API) RotateKeys() *probe.Error {
+	donut.lock.Lock()
+	defer donut.lock.Unlock()
+
+	keys, err := LoadKeyStore()
+	if err != nil {
+		return err.Trace()
+	}
+	*donut.keys = *keys
+	return donut.resealKeys()
+}
+
+// resealKeys - reseal data keys of all objects with the current master key, only object metadata is rewritten
+//
+// Objects on disks are scanned only if the master key changed since they were last resealed, the key
+// store records the master key once all of them are.
+func (donut API) resealKeys() *probe.Error {
+	if !donut.keys.isConfigured() {
+		return nil
+	}
+	if len(donut.config.NodeDiskMap) > 0 {
+		if donut.keys.Resealed == donut.keys.Current {
+			return nil
+		}
+		if err := donut.listDonutBuckets(); err != nil {
+			return err.Trace()
+		}
+		for bucketName, bucket := range donut.buckets {
+			objectNames, err := bucket.listIndex()
+			if err != nil {
+				return err.Trace()
+			}
+			for _, objectName := range objectNames {
+				objMetadata, err := donut.buckets[bucketName].GetObjectMetadata(objectName)
+				if err != nil {
+					return err.Trace()
+				}
+				resealed, err := donut.keys.reseal(&objMetadata)
+				if err != nil {
+					return err.Trace()
+				}
+				if !resealed {
+					continue
+				}
+				if err := donut.buckets[bucketName].SetObjectMetadata(objectName, objMetadata); err != nil {
+					return err.Trace()
+				}
+				// update cached copy of object metadata
+				if donut.storedBuckets.Exists(bucketName) {
+					storedBucket := donut.storedBuckets.Get(bucketName).(storedBucket)
+					if _, ok := storedBucket.objectMetadata[bucketName+"/"+objectName]; ok {
+						storedBucket.objectMetadata[bucketName+"/"+objectName] = objMetadata
+						donut.storedBuckets.Set(bucketName, storedBucket)
+					}
+				}
+			}
+		}
+		donut.keys.Resealed = donut.keys.Current
+		if err := SaveKeyStore(donut.keys); err != nil {
+			return err.Trace()
+		}
+		return nil
+	}
+	for bucketName, v := range donut.storedBuckets.GetAll() {
+		storedBucket := v.(storedBucket)
+		for objectKey, objMetadata := range storedBucket.objectMetadata {
+			if _, err := donut.keys.reseal(&objMetadata); err != nil {
+				return err.Trace()
+			}
+			storedBucket.objectMetadata[objectKey] = objMetadata
+		}
+		donut.storedBuckets.Set(bucketName, storedBucket)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// legacyBuckets metadata of all buckets along with all of their object names, kept in a single file
+// at the root of the donut before bucket metadata was kept per bucket
+type legacyBuckets struct {
+	Version string                          `json:"version"`
+	Buckets map[string]legacyBucketMetadata `json:"buckets"`
+}
+
+// legacyBucketMetadata bucket metadata along with all object names of the bucket
+type legacyBucketMetadata struct {
+	BucketMetadata
+	BucketObjects map[string]struct{} `json:"objects"`
+}
+
+// migrateBucketMetadata - move metadata kept in a single file into bucket metadata and object index of
+// every bucket, the single file is removed only once all buckets are migrated
+func (donut API) migrateBucketMetadata() *probe.Error {
+	metadata, err := donut.getLegacyBucketMetadata()
+	if err != nil {
+		if os.IsNotExist(err.ToGoError()) {
+			return nil
+		}
+		return err.Trace()
+	}
+	if err := donut.listDonutBuckets(); err != nil {
+		return err.Trace()
+	}
+	for bucketName, bucketMetadata := range metadata.Buckets {
+		bkt, ok := donut.buckets[bucketName]
+		if !ok {
+			// none of the bucket slices are left
+			continue
+		}
+		var objectNames []string
+		for objectName := range bucketMetadata.BucketObjects {
+			objectNames = append(objectNames, objectName)
+		}
+		sort.Strings(objectNames)
+		if err := bkt.writeIndex(objectNames); err != nil {
+			return err.Trace(bucketName)
+		}
+		if err := bkt.setBucketMetadata(bucketMetadata.BucketMetadata); err != nil {
+			return err.Trace(bucketName)
+		}
+	}
+	disks, err := listNodeDisks(donut.nodes)
+	if err != nil {
+		return err.Trace()
+	}
+	for _, disk := range disks {
+		disk.Delete(filepath.Join(donut.config.DonutName, bucketMetadataConfig))
+	}
+	return nil
+}
+
+// getLegacyBucketMetadataReaders - readers are returned in map rather than slice
+func (donut API) getLegacyBucketMetadataReaders() (map[int]io.ReadCloser, *probe.Error) {
+	readers := make(map[int]io.ReadCloser)
+	disks, err := listNodeDisks(donut.nodes)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	var bucketMetaDataReader io.ReadCloser
+	var openErr *probe.Error
+	for i, disk := range disks {
+		bucketMetaDataReader, err = disk.Open(filepath.Join(donut.config.DonutName, bucketMetadataConfig))
+		if err != nil {
+			// a missing file is reported over a failing disk, to tell a new donut apart
+			if openErr == nil || os.IsNotExist(err.ToGoError()) {
+				openErr = err
+			}
+			continue
+		}
+		readers[i] = bucketMetaDataReader
+	}
+	if len(readers) == 0 && openErr != nil {
+		return nil, openErr.Trace()
+	}
+	return readers, nil
+}
+
+// getLegacyBucketMetadata - metadata of all buckets, as kept before bucket metadata was kept per bucket
+func (donut API) getLegacyBucketMetadata() (*legacyBuckets, *probe.Error) {
+	metadata := &legacyBuckets{}
+	readers, err := donut.getLegacyBucketMetadataReaders()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	for _, reader := range readers {
+		defer reader.Close()
+	}
+	{
+		var err error
+		for _, reader := range readers {
+			jenc := json.NewDecoder(reader)
+			if err = jenc.Decode(metadata); err == nil {
+				return metadata, nil
+			}
+		}
+		return nil, probe.NewError(err)
+	}
+}
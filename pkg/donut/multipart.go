@@ -41,8 +41,8 @@ import (
 
 /// V2 API functions
 
-// NewMultipartUpload - initiate a new multipart session
-func (donut API) NewMultipartUpload(bucket, key, contentType string) (string, *probe.Error) {
+// NewMultipartUpload - initiate a new multipart session, parts are to be uploaded with the same encryption
+func (donut API) NewMultipartUpload(bucket, key string, metadata map[string]string, encryption *Encryption) (string, *probe.Error) {
 	donut.lock.Lock()
 	defer donut.lock.Unlock()
 
@@ -53,7 +53,7 @@ func (donut API) NewMultipartUpload(bucket, key, contentType string) (string, *p
 		return "", probe.NewError(ObjectNameInvalid{Object: key})
 	}
 	//	if len(donut.config.NodeDiskMap) > 0 {
-	//		return donut.newMultipartUpload(bucket, key, contentType)
+	//		return donut.newMultipartUpload(bucket, key, metadata)
 	//	}
 	if !donut.storedBuckets.Exists(bucket) {
 		return "", probe.NewError(BucketNotFound{Bucket: bucket})
@@ -63,6 +63,11 @@ func (donut API) NewMultipartUpload(bucket, key, contentType string) (string, *p
 	if _, ok := storedBucket.objectMetadata[objectKey]; ok == true {
 		return "", probe.NewError(ObjectExists{Object: key})
 	}
+	// data key of server managed encryption is chosen for the whole session
+	encryption, err := donut.newObjectEncryption(storedBucket.bucketMetadata, encryption)
+	if err != nil {
+		return "", err.Trace()
+	}
 	id := []byte(strconv.Itoa(rand.Int()) + bucket + key + time.Now().UTC().String())
 	uploadIDSum := sha512.Sum512(id)
 	uploadID := base64.URLEncoding.EncodeToString(uploadIDSum[:])[:47]
@@ -71,6 +76,8 @@ func (donut API) NewMultipartUpload(bucket, key, contentType string) (string, *p
 		UploadID:   uploadID,
 		Initiated:  time.Now().UTC(),
 		TotalParts: 0,
+		Metadata:   metadata,
+		encryption: encryption,
 	}
 	storedBucket.partMetadata[key] = make(map[int]PartMetadata)
 	multiPartCache := data.NewCache(0)
@@ -109,9 +116,9 @@ func (donut API) AbortMultipartUpload(bucket, key, uploadID string) *probe.Error
 }
 
 // CreateObjectPart - create a part in a multipart session
-func (donut API) CreateObjectPart(bucket, key, uploadID string, partID int, contentType, expectedMD5Sum string, size int64, data io.Reader, signature *signv4.Signature) (string, *probe.Error) {
+func (donut API) CreateObjectPart(bucket, key, uploadID string, partID int, contentType, expectedMD5Sum string, size int64, data io.Reader, signature *signv4.Signature, encryption *Encryption) (string, *probe.Error) {
 	donut.lock.Lock()
-	etag, err := donut.createObjectPart(bucket, key, uploadID, partID, "", expectedMD5Sum, size, data, signature)
+	etag, err := donut.createObjectPart(bucket, key, uploadID, partID, "", expectedMD5Sum, size, data, signature, encryption)
 	donut.lock.Unlock()
 	// possible free
 	debug.FreeOSMemory()
@@ -120,7 +127,7 @@ func (donut API) CreateObjectPart(bucket, key, uploadID string, partID int, cont
 }
 
 // createObject - internal wrapper function called by CreateObjectPart
-func (donut API) createObjectPart(bucket, key, uploadID string, partID int, contentType, expectedMD5Sum string, size int64, data io.Reader, signature *signv4.Signature) (string, *probe.Error) {
+func (donut API) createObjectPart(bucket, key, uploadID string, partID int, contentType, expectedMD5Sum string, size int64, data io.Reader, signature *signv4.Signature, encryption *Encryption) (string, *probe.Error) {
 	if !IsValidBucket(bucket) {
 		return "", probe.NewError(BucketNameInvalid{Bucket: bucket})
 	}
@@ -161,6 +168,23 @@ func (donut API) createObjectPart(bucket, key, uploadID string, partID int, cont
 	if strBucket.multiPartSession[key].UploadID != uploadID {
 		return "", probe.NewError(InvalidUploadID{UploadID: uploadID})
 	}
+	// parts are accepted only with the encryption the session was initiated with, parts of server
+	// managed encryption carry no encryption
+	sessionEncryption := strBucket.multiPartSession[key].encryption
+	if sessionEncryption != nil && sessionEncryption.isServerManaged() {
+		if encryption != nil {
+			return "", probe.NewError(ServerEncryptedObject{Object: key})
+		}
+	} else if !isSameEncryption(sessionEncryption, encryption) {
+		switch {
+		case sessionEncryption == nil:
+			return "", probe.NewError(ObjectNotEncrypted{Object: key})
+		case encryption == nil:
+			return "", probe.NewError(EncryptionKeyMissing{Object: key})
+		default:
+			return "", probe.NewError(EncryptionKeyMismatch{Object: key})
+		}
+	}
 
 	// get object key
 	parts := strBucket.partMetadata[key]
@@ -202,13 +226,14 @@ func (donut API) createObjectPart(bucket, key, uploadID string, partID int, cont
 			go debug.FreeOSMemory()
 		}
 	}
+	if err != io.EOF {
+		donut.multiPartObjects[uploadID].Delete(partID)
+		return "", probe.NewError(err)
+	}
 	if totalLength != size {
 		donut.multiPartObjects[uploadID].Delete(partID)
 		return "", probe.NewError(IncompleteBody{Bucket: bucket, Object: key})
 	}
-	if err != io.EOF {
-		return "", probe.NewError(err)
-	}
 
 	md5SumBytes := hash.Sum(nil)
 	md5Sum := hex.EncodeToString(md5SumBytes)
@@ -247,6 +272,22 @@ func (donut API) createObjectPart(bucket, key, uploadID string, partID int, cont
 	return md5Sum, nil
 }
 
+// getMultipartMetadata - metadata provided when the multipart session was initiated
+func (donut API) getMultipartMetadata(bucket, key string) map[string]string {
+	if !donut.storedBuckets.Exists(bucket) {
+		return nil
+	}
+	return donut.storedBuckets.Get(bucket).(storedBucket).multiPartSession[key].Metadata
+}
+
+// getMultipartEncryption - encryption the multipart session was initiated with
+func (donut API) getMultipartEncryption(bucket, key string) *Encryption {
+	if !donut.storedBuckets.Exists(bucket) {
+		return nil
+	}
+	return donut.storedBuckets.Get(bucket).(storedBucket).multiPartSession[key].encryption
+}
+
 // cleanupMultipartSession invoked during an abort or complete multipart session to cleanup session from memory
 func (donut API) cleanupMultipartSession(bucket, key, uploadID string) {
 	storedBucket := donut.storedBuckets.Get(bucket).(storedBucket)
@@ -297,13 +338,14 @@ func (donut API) CompleteMultipartUpload(bucket, key, uploadID string, data io.R
 	if err != nil {
 		return ObjectMetadata{}, err.Trace()
 	}
-	objectMetadata, err := donut.createObject(bucket, key, "", "", size, fullObjectReader, nil)
+	objectMetadata, err := donut.createObject(bucket, key, "", size, fullObjectReader, donut.getMultipartMetadata(bucket, key), nil, donut.getMultipartEncryption(bucket, key))
 	if err != nil {
 		// No need to call internal cleanup functions here, caller should call AbortMultipartUpload()
 		// which would in-turn cleanup properly in accordance with S3 Spec
 		return ObjectMetadata{}, err.Trace()
 	}
 	donut.cleanupMultipartSession(bucket, key, uploadID)
+	donut.notify(EventObjectCreatedCompleteMultipartUpload, objectMetadata)
 	return objectMetadata, nil
 }
 
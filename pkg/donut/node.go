@@ -17,10 +17,46 @@
 package donut
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+
 	"github.com/minio/minio-xl/pkg/donut/disk"
 	"github.com/minio/minio-xl/pkg/probe"
 )
 
+// internal variable only accessed via get/set methods
+var remoteDisk func(hostname, diskPath string) (disk.Disk, *probe.Error)
+
+// SetRemoteDisk - set how disks of nodes on other servers are reached
+func SetRemoteDisk(newRemoteDisk func(hostname, diskPath string) (disk.Disk, *probe.Error)) {
+	remoteDisk = newRemoteDisk
+}
+
+// isRemoteNode - nodes are local if named after this server, nodes named by an address 'host:port' are always remote
+func isRemoteNode(hostname string) bool {
+	if _, _, err := net.SplitHostPort(hostname); err == nil {
+		return true
+	}
+	if hostname == "localhost" {
+		return false
+	}
+	localHostname, err := os.Hostname()
+	return err != nil || hostname != localHostname
+}
+
+// newNodeDisk - open a disk of a node, local or remote
+func newNodeDisk(hostname, diskPath string) (disk.Disk, *probe.Error) {
+	if !isRemoteNode(hostname) {
+		return disk.New(diskPath)
+	}
+	if remoteDisk == nil {
+		return nil, probe.NewError(NotImplemented{Function: "remote disk " + hostname})
+	}
+	return remoteDisk(hostname, diskPath)
+}
+
 // node struct internal
 type node struct {
 	hostname string
@@ -65,6 +101,45 @@ func (n node) DetachDisk(diskOrder int) *probe.Error {
 	return nil
 }
 
+// nodeDisk disk of a node along with its place in the donut
+type nodeDisk struct {
+	disk.Disk
+	nodeSlice int
+	order     int
+}
+
+// bucketSlice - name of the directory holding slices of a bucket on this disk
+func (d nodeDisk) bucketSlice(bucket string) string {
+	return fmt.Sprintf("%s$%d$%d", bucket, d.nodeSlice, d.order)
+}
+
+// listNodeDisks - disks of all nodes, nodes ordered by hostname and disks by order
+//
+// erasure blocks are laid out in this order, it has to stay the same across restarts
+func listNodeDisks(nodes map[string]node) ([]nodeDisk, *probe.Error) {
+	var hostnames []string
+	for hostname := range nodes {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	var disks []nodeDisk
+	for nodeSlice, hostname := range hostnames {
+		nDisks, err := nodes[hostname].ListDisks()
+		if err != nil {
+			return nil, err.Trace()
+		}
+		var orders []int
+		for order := range nDisks {
+			orders = append(orders, order)
+		}
+		sort.Ints(orders)
+		for _, order := range orders {
+			disks = append(disks, nodeDisk{Disk: nDisks[order], nodeSlice: nodeSlice, order: order})
+		}
+	}
+	return disks, nil
+}
+
 // SaveConfig - save node configuration
 func (n node) SaveConfig() *probe.Error {
 	return probe.NewError(NotImplemented{Function: "SaveConfig"})
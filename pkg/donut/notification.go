@@ -0,0 +1,273 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Please read for more information - http://docs.aws.amazon.com/AmazonS3/latest/dev/NotificationHowTo.html
+//
+// Notification configuration is saved as is in bucket metadata under the key 'notification'.
+// Targets are not configured per bucket, they are defined in donut config and referred to
+// by their ARN 'arn:minio:sqs::<id>:<type>'.
+
+const (
+	// NotificationMetadataKey metadata key under which notification configuration is saved
+	NotificationMetadataKey = "notification"
+
+	// maximum number of rules allowed in a notification configuration
+	maxNotificationRules = 100
+)
+
+// List of events sent to notification targets
+const (
+	EventObjectCreatedPut                     = "s3:ObjectCreated:Put"
+	EventObjectCreatedCompleteMultipartUpload = "s3:ObjectCreated:CompleteMultipartUpload"
+	// objects cannot be deleted yet, removal events are accepted in rules but never sent,
+	// objects evicted from memory are not deleted
+	EventObjectRemovedDelete = "s3:ObjectRemoved:Delete"
+)
+
+// list of events allowed in a notification rule
+var notificationEvents = map[string]bool{
+	"s3:ObjectCreated:*":                      true,
+	EventObjectCreatedPut:                     true,
+	EventObjectCreatedCompleteMultipartUpload: true,
+	"s3:ObjectRemoved:*":                      true,
+	EventObjectRemovedDelete:                  true,
+}
+
+// FilterRule - a single key name filter, name is either 'prefix' or 'suffix'
+type FilterRule struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+// NotificationRule - a single notification rule sending events to a queue
+type NotificationRule struct {
+	ID     string   `xml:"Id,omitempty"`
+	Queue  string   `xml:"Queue"`
+	Events []string `xml:"Event"`
+	Filter struct {
+		S3Key struct {
+			FilterRules []FilterRule `xml:"FilterRule,omitempty"`
+		} `xml:"S3Key,omitempty"`
+	} `xml:"Filter,omitempty"`
+}
+
+// NotificationConfiguration - format for put and get bucket notification
+type NotificationConfiguration struct {
+	XMLName            xml.Name           `xml:"NotificationConfiguration" json:"-"`
+	QueueConfiguration []NotificationRule `xml:"QueueConfiguration"`
+}
+
+// ParseNotificationConfiguration - parse a notification configuration document
+func ParseNotificationConfiguration(data []byte) (NotificationConfiguration, *probe.Error) {
+	config := NotificationConfiguration{}
+	if err := xml.Unmarshal(data, &config); err != nil {
+		return NotificationConfiguration{}, probe.NewError(MalformedXML{})
+	}
+	if len(config.QueueConfiguration) > maxNotificationRules {
+		return NotificationConfiguration{}, probe.NewError(MalformedXML{})
+	}
+	for _, rule := range config.QueueConfiguration {
+		if len(rule.Events) == 0 {
+			return NotificationConfiguration{}, probe.NewError(MalformedXML{})
+		}
+		for _, event := range rule.Events {
+			if !notificationEvents[event] {
+				return NotificationConfiguration{}, probe.NewError(InvalidEventName{Name: event})
+			}
+		}
+		// prefix and suffix may be given only once each
+		names := make(map[string]bool)
+		for _, filterRule := range rule.Filter.S3Key.FilterRules {
+			if filterRule.Name != "prefix" && filterRule.Name != "suffix" {
+				return NotificationConfiguration{}, probe.NewError(MalformedXML{})
+			}
+			if names[filterRule.Name] {
+				return NotificationConfiguration{}, probe.NewError(MalformedXML{})
+			}
+			names[filterRule.Name] = true
+		}
+	}
+	return config, nil
+}
+
+// parseTargetARN - parse target id and type from 'arn:minio:sqs::<id>:<type>'
+func parseTargetARN(arn string) (id, targetType string, ok bool) {
+	fields := strings.Split(arn, ":")
+	if len(fields) != 6 || fields[0] != "arn" || fields[1] != "minio" || fields[2] != "sqs" {
+		return "", "", false
+	}
+	if fields[4] == "" || fields[5] == "" {
+		return "", "", false
+	}
+	return fields[4], fields[5], true
+}
+
+// matchEvent - returns true if event name is selected by the rule, '*' selects all events of a kind
+func (r NotificationRule) matchEvent(eventName string) bool {
+	for _, event := range r.Events {
+		if event == eventName {
+			return true
+		}
+		if strings.HasSuffix(event, ":*") && strings.HasPrefix(eventName, strings.TrimSuffix(event, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKey - returns true if object name satisfies prefix and suffix filters of the rule
+func (r NotificationRule) matchKey(object string) bool {
+	for _, filterRule := range r.Filter.S3Key.FilterRules {
+		switch filterRule.Name {
+		case "prefix":
+			if !strings.HasPrefix(object, filterRule.Value) {
+				return false
+			}
+		case "suffix":
+			if !strings.HasSuffix(object, filterRule.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NotificationEvent - message delivered to notification targets
+type NotificationEvent struct {
+	Records []EventRecord `json:"Records"`
+}
+
+// EventRecord - a single event record, follows S3 event message structure
+type EventRecord struct {
+	EventVersion string    `json:"eventVersion"`
+	EventSource  string    `json:"eventSource"`
+	EventTime    string    `json:"eventTime"`
+	EventName    string    `json:"eventName"`
+	S3           EventData `json:"s3"`
+}
+
+// EventData - bucket and object the event refers to
+type EventData struct {
+	SchemaVersion   string `json:"s3SchemaVersion"`
+	ConfigurationID string `json:"configurationId"`
+	Bucket          struct {
+		Name string `json:"name"`
+		ARN  string `json:"arn"`
+	} `json:"bucket"`
+	Object struct {
+		Key  string `json:"key"`
+		Size int64  `json:"size,omitempty"`
+		ETag string `json:"eTag,omitempty"`
+	} `json:"object"`
+}
+
+// newNotificationEvent - create a new event for object metadata
+func newNotificationEvent(eventName, configurationID string, objectMetadata ObjectMetadata) NotificationEvent {
+	record := EventRecord{
+		EventVersion: "2.0",
+		EventSource:  "minio:s3",
+		EventTime:    time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		// event names do not carry the 's3:' prefix in messages
+		EventName: strings.TrimPrefix(eventName, "s3:"),
+	}
+	record.S3.SchemaVersion = "1.0"
+	record.S3.ConfigurationID = configurationID
+	record.S3.Bucket.Name = objectMetadata.Bucket
+	record.S3.Bucket.ARN = "arn:aws:s3:::" + objectMetadata.Bucket
+	record.S3.Object.Key = url.QueryEscape(objectMetadata.Object)
+	record.S3.Object.Size = objectMetadata.Size
+	record.S3.Object.ETag = objectMetadata.MD5Sum
+	return NotificationEvent{Records: []EventRecord{record}}
+}
+
+// notify - queue event for every notification rule of the bucket matching the object, caller should hold donut.lock
+func (donut API) notify(eventName string, objectMetadata ObjectMetadata) {
+	if donut.notifier == nil {
+		return
+	}
+	if !donut.storedBuckets.Exists(objectMetadata.Bucket) {
+		return
+	}
+	bucketMetadata := donut.storedBuckets.Get(objectMetadata.Bucket).(storedBucket).bucketMetadata
+	notification, ok := bucketMetadata.Metadata[NotificationMetadataKey]
+	if !ok {
+		return
+	}
+	config, err := ParseNotificationConfiguration([]byte(notification))
+	if err != nil {
+		return
+	}
+	for _, rule := range config.QueueConfiguration {
+		if !rule.matchEvent(eventName) || !rule.matchKey(objectMetadata.Object) {
+			continue
+		}
+		id, _, ok := parseTargetARN(rule.Queue)
+		if !ok {
+			continue
+		}
+		donut.notifier.enqueue(id, newNotificationEvent(eventName, rule.ID, objectMetadata))
+	}
+}
+
+// SetBucketNotification - set notification configuration of a bucket, an empty configuration removes it
+func (donut API) SetBucketNotification(bucket string, config NotificationConfiguration) *probe.Error {
+	for _, rule := range config.QueueConfiguration {
+		id, targetType, ok := parseTargetARN(rule.Queue)
+		if !ok {
+			return probe.NewError(InvalidTargetARN{ARN: rule.Queue})
+		}
+		target, ok := donut.config.NotificationTargets[id]
+		if !ok || target.Type != targetType {
+			return probe.NewError(InvalidTargetARN{ARN: rule.Queue})
+		}
+	}
+	if len(config.QueueConfiguration) == 0 {
+		return donut.SetBucketMetadata(bucket, map[string]string{NotificationMetadataKey: ""}).Trace()
+	}
+	data, err := xml.Marshal(config)
+	if err != nil {
+		return probe.NewError(err)
+	}
+	return donut.SetBucketMetadata(bucket, map[string]string{NotificationMetadataKey: string(data)}).Trace()
+}
+
+// GetBucketNotification - get notification configuration of a bucket
+func (donut API) GetBucketNotification(bucket string) (NotificationConfiguration, *probe.Error) {
+	bucketMetadata, err := donut.GetBucketMetadata(bucket)
+	if err != nil {
+		return NotificationConfiguration{}, err.Trace()
+	}
+	notification, ok := bucketMetadata.Metadata[NotificationMetadataKey]
+	if !ok {
+		return NotificationConfiguration{}, nil
+	}
+	config, err := ParseNotificationConfiguration([]byte(notification))
+	if err != nil {
+		return NotificationConfiguration{}, err.Trace()
+	}
+	return config, nil
+}
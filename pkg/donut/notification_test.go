@@ -0,0 +1,277 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type MyNotificationSuite struct {
+	root     string
+	listener net.Listener
+	socketCh chan NotificationEvent
+	webhook  *httptest.Server
+	lock     sync.Mutex
+	accept   bool
+	received []NotificationEvent
+}
+
+var _ = Suite(&MyNotificationSuite{})
+
+var dn Interface
+
+func (s *MyNotificationSuite) SetUpSuite(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "donut-")
+	c.Assert(err, IsNil)
+	s.root = root
+	s.accept = false
+	s.received = nil
+
+	s.listener, err = net.Listen("unix", filepath.Join(root, "events.sock"))
+	c.Assert(err, IsNil)
+	s.socketCh = make(chan NotificationEvent, 10)
+	go func() {
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				return
+			}
+			line, err := bufio.NewReader(conn).ReadBytes('\n')
+			conn.Close()
+			if err != nil {
+				continue
+			}
+			event := NotificationEvent{}
+			if err := json.Unmarshal(line, &event); err == nil {
+				s.socketCh <- event
+			}
+		}
+	}()
+
+	s.webhook = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if !s.accept {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		event := NotificationEvent{}
+		if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.received = append(s.received, event)
+	}))
+
+	conf := new(Config)
+	conf.Version = "0.0.1"
+	conf.MaxSize = 100000
+	conf.NotificationTargets = map[string]NotificationTarget{
+		"1": {Type: TargetLog, Endpoint: filepath.Join(root, "events.log")},
+		"2": {Type: TargetSocket, Endpoint: filepath.Join(root, "events.sock")},
+		"3": {Type: TargetWebhook, Endpoint: s.webhook.URL},
+	}
+	SetDonutConfigPath(filepath.Join(root, "donut.json"))
+	perr := SaveConfig(conf)
+	c.Assert(perr, IsNil)
+
+	dn, perr = New()
+	c.Assert(perr, IsNil)
+}
+
+func (s *MyNotificationSuite) TearDownSuite(c *C) {
+	s.listener.Close()
+	s.webhook.Close()
+	os.RemoveAll(s.root)
+}
+
+// wait for condition to become true, delivery is asynchronous
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return false
+}
+
+func readEventLog(path string) []NotificationEvent {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var events []NotificationEvent
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		event := NotificationEvent{}
+		if err := json.Unmarshal([]byte(line), &event); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (s *MyNotificationSuite) TestNotificationConfiguration(c *C) {
+	c.Assert(dn.MakeBucket("notify-config", "private", "", nil, nil), IsNil)
+
+	config, err := dn.GetBucketNotification("notify-config")
+	c.Assert(err, IsNil)
+	c.Assert(len(config.QueueConfiguration), Equals, 0)
+
+	_, err = ParseNotificationConfiguration([]byte("<NotificationConfiguration><QueueConfiguration>" +
+		"<Queue>arn:minio:sqs::1:log</Queue><Event>s3:ObjectAccessed:*</Event></QueueConfiguration></NotificationConfiguration>"))
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), DeepEquals, InvalidEventName{Name: "s3:ObjectAccessed:*"})
+
+	_, err = ParseNotificationConfiguration([]byte("<NotificationConfiguration><QueueConfiguration>" +
+		"<Queue>arn:minio:sqs::1:log</Queue><Event>s3:ObjectCreated:*</Event><Filter><S3Key>" +
+		"<FilterRule><Name>infix</Name><Value>a</Value></FilterRule></S3Key></Filter></QueueConfiguration></NotificationConfiguration>"))
+	c.Assert(err, Not(IsNil))
+
+	config, err = ParseNotificationConfiguration([]byte("<NotificationConfiguration><QueueConfiguration>" +
+		"<Queue>arn:minio:sqs::9:log</Queue><Event>s3:ObjectCreated:*</Event></QueueConfiguration></NotificationConfiguration>"))
+	c.Assert(err, IsNil)
+	err = dn.SetBucketNotification("notify-config", config)
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), DeepEquals, InvalidTargetARN{ARN: "arn:minio:sqs::9:log"})
+
+	// target type has to match the configured target
+	config.QueueConfiguration[0].Queue = "arn:minio:sqs::1:webhook"
+	err = dn.SetBucketNotification("notify-config", config)
+	c.Assert(err, Not(IsNil))
+
+	config.QueueConfiguration[0].ID = "logger"
+	config.QueueConfiguration[0].Queue = "arn:minio:sqs::1:log"
+	c.Assert(dn.SetBucketNotification("notify-config", config), IsNil)
+	config, err = dn.GetBucketNotification("notify-config")
+	c.Assert(err, IsNil)
+	c.Assert(len(config.QueueConfiguration), Equals, 1)
+	c.Assert(config.QueueConfiguration[0].ID, Equals, "logger")
+
+	c.Assert(dn.SetBucketNotification("notify-config", NotificationConfiguration{}), IsNil)
+	config, err = dn.GetBucketNotification("notify-config")
+	c.Assert(err, IsNil)
+	c.Assert(len(config.QueueConfiguration), Equals, 0)
+
+	err = dn.SetBucketNotification("notify-none", NotificationConfiguration{})
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *MyNotificationSuite) TestNotificationDelivery(c *C) {
+	c.Assert(dn.MakeBucket("notify-delivery", "private", "", nil, nil), IsNil)
+
+	data := "<NotificationConfiguration>" +
+		"<QueueConfiguration><Id>photos</Id><Queue>arn:minio:sqs::1:log</Queue><Event>s3:ObjectCreated:*</Event>" +
+		"<Filter><S3Key><FilterRule><Name>prefix</Name><Value>photos/</Value></FilterRule>" +
+		"<FilterRule><Name>suffix</Name><Value>.jpg</Value></FilterRule></S3Key></Filter></QueueConfiguration>" +
+		"<QueueConfiguration><Id>uploads</Id><Queue>arn:minio:sqs::2:socket</Queue>" +
+		"<Event>s3:ObjectCreated:CompleteMultipartUpload</Event></QueueConfiguration>" +
+		"</NotificationConfiguration>"
+	config, err := ParseNotificationConfiguration([]byte(data))
+	c.Assert(err, IsNil)
+	c.Assert(dn.SetBucketNotification("notify-delivery", config), IsNil)
+
+	_, err = dn.CreateObject("notify-delivery", "docs/one.txt", "", int64(len("one")), bytes.NewReader([]byte("one")), nil, nil, nil)
+	c.Assert(err, IsNil)
+	objectMetadata, err := dn.CreateObject("notify-delivery", "photos/two.jpg", "", int64(len("two")), bytes.NewReader([]byte("two")), nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	logFile := filepath.Join(s.root, "events.log")
+	c.Assert(waitFor(func() bool { return len(readEventLog(logFile)) == 1 }), Equals, true)
+	record := readEventLog(logFile)[0].Records[0]
+	c.Assert(record.EventName, Equals, "ObjectCreated:Put")
+	c.Assert(record.S3.ConfigurationID, Equals, "photos")
+	c.Assert(record.S3.Bucket.Name, Equals, "notify-delivery")
+	c.Assert(record.S3.Object.Key, Equals, "photos%2Ftwo.jpg")
+	c.Assert(record.S3.Object.Size, Equals, int64(3))
+	c.Assert(record.S3.Object.ETag, Equals, objectMetadata.MD5Sum)
+
+	uploadID, err := dn.NewMultipartUpload("notify-delivery", "videos/three.mp4", nil, nil)
+	c.Assert(err, IsNil)
+	etag, err := dn.CreateObjectPart("notify-delivery", "videos/three.mp4", uploadID, 1, "", "", int64(len("three")), bytes.NewReader([]byte("three")), nil, nil)
+	c.Assert(err, IsNil)
+	completeParts, perr := xml.Marshal(CompleteMultipartUpload{Part: []CompletePart{{PartNumber: 1, ETag: etag}}})
+	c.Assert(perr, IsNil)
+	_, err = dn.CompleteMultipartUpload("notify-delivery", "videos/three.mp4", uploadID, bytes.NewReader(completeParts), nil)
+	c.Assert(err, IsNil)
+
+	select {
+	case event := <-s.socketCh:
+		c.Assert(event.Records[0].EventName, Equals, "ObjectCreated:CompleteMultipartUpload")
+		c.Assert(event.Records[0].S3.Object.Key, Equals, "videos%2Fthree.mp4")
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting for socket notification")
+	}
+	// no further events for the log target
+	c.Assert(len(readEventLog(logFile)), Equals, 1)
+}
+
+func (s *MyNotificationSuite) TestNotificationRetry(c *C) {
+	c.Assert(dn.MakeBucket("notify-retry", "private", "", nil, nil), IsNil)
+
+	data := "<NotificationConfiguration><QueueConfiguration><Queue>arn:minio:sqs::3:webhook</Queue>" +
+		"<Event>s3:ObjectCreated:Put</Event></QueueConfiguration></NotificationConfiguration>"
+	config, err := ParseNotificationConfiguration([]byte(data))
+	c.Assert(err, IsNil)
+	c.Assert(dn.SetBucketNotification("notify-retry", config), IsNil)
+
+	_, err = dn.CreateObject("notify-retry", "one", "", int64(len("one")), bytes.NewReader([]byte("one")), nil, nil, nil)
+	c.Assert(err, IsNil)
+	_, err = dn.CreateObject("notify-retry", "two", "", int64(len("two")), bytes.NewReader([]byte("two")), nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	n := dn.(API).notifier
+	queued := func() int {
+		n.lock.Lock()
+		defer n.lock.Unlock()
+		return n.queued
+	}
+	// webhook is unavailable, events stay queued on disk
+	n.deliverQueued()
+	c.Assert(queued(), Equals, 2)
+	entries, perr := ioutil.ReadDir(n.queueDir)
+	c.Assert(perr, IsNil)
+	c.Assert(len(entries), Equals, 2)
+
+	s.lock.Lock()
+	s.accept = true
+	s.lock.Unlock()
+
+	n.deliverQueued()
+	c.Assert(waitFor(func() bool { return queued() == 0 }), Equals, true)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c.Assert(len(s.received), Equals, 2)
+	// events are delivered in order
+	c.Assert(s.received[0].Records[0].S3.Object.Key, Equals, "one")
+	c.Assert(s.received[1].Records[0].S3.Object.Key, Equals, "two")
+}
@@ -0,0 +1,268 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/atomic"
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Events are queued as one file each in a directory next to donut config, a single goroutine
+// delivers them in order and removes them on success. Failed deliveries stay queued and are
+// retried periodically, across restarts as well.
+
+// List of notification target types
+const (
+	// HTTP POST of the event to a URL
+	TargetWebhook = "webhook"
+	// append event as a JSON line to a local file
+	TargetLog = "log"
+	// write event as a JSON line to a unix socket
+	TargetSocket = "socket"
+)
+
+const (
+	// maximum number of events waiting to be delivered, newer events are dropped beyond this
+	maxQueuedEvents = 10000
+	// interval between delivery retries of queued events
+	notifyRetryInterval = 30 * time.Second
+	// timeout for a single delivery
+	notifyTimeout = 10 * time.Second
+)
+
+// NotificationTarget - destination for bucket events
+type NotificationTarget struct {
+	Type     string `json:"type"`
+	Endpoint string `json:"endpoint"`
+}
+
+// queuedEvent - event waiting for delivery to a target
+type queuedEvent struct {
+	Target string            `json:"target"`
+	Event  NotificationEvent `json:"event"`
+}
+
+type notifier struct {
+	targets  map[string]NotificationTarget
+	queueDir string
+	lock     *sync.Mutex
+	queued   int
+	sequence uint64
+	wakeup   chan struct{}
+	// serializes delivery so that no event is sent twice
+	deliverLock *sync.Mutex
+}
+
+// newNotifier - initialize queue directory and start delivering events
+func newNotifier(targets map[string]NotificationTarget, queueDir string) (*notifier, *probe.Error) {
+	for _, target := range targets {
+		switch target.Type {
+		case TargetWebhook, TargetLog, TargetSocket:
+		default:
+			return nil, probe.NewError(InvalidArgument{})
+		}
+		if target.Endpoint == "" {
+			return nil, probe.NewError(InvalidArgument{})
+		}
+	}
+	if err := os.MkdirAll(queueDir, 0700); err != nil {
+		return nil, probe.NewError(err)
+	}
+	// events left over from a previous run are delivered first
+	entries, err := ioutil.ReadDir(queueDir)
+	if err != nil {
+		return nil, probe.NewError(err)
+	}
+	n := &notifier{
+		targets:     targets,
+		queueDir:    queueDir,
+		lock:        new(sync.Mutex),
+		queued:      len(entries),
+		sequence:    uint64(time.Now().UnixNano()),
+		wakeup:      make(chan struct{}, 1),
+		deliverLock: new(sync.Mutex),
+	}
+	go n.run()
+	n.signal()
+	return n, nil
+}
+
+// signal - wake up delivery without blocking
+func (n *notifier) signal() {
+	select {
+	case n.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue - persist event for delivery to target
+func (n *notifier) enqueue(target string, event NotificationEvent) {
+	n.lock.Lock()
+	if n.queued >= maxQueuedEvents {
+		n.lock.Unlock()
+		log.Printf("Notification queue full, dropping event %s for target %s", event.Records[0].EventName, target)
+		return
+	}
+	n.queued++
+	n.sequence++
+	// file names sort in the order of events
+	name := fmt.Sprintf("%020d.json", n.sequence)
+	n.lock.Unlock()
+
+	if err := n.writeEvent(filepath.Join(n.queueDir, name), queuedEvent{Target: target, Event: event}); err != nil {
+		n.lock.Lock()
+		n.queued--
+		n.lock.Unlock()
+		log.Printf("Unable to queue notification for target %s: %s", target, err)
+		return
+	}
+	n.signal()
+}
+
+func (n *notifier) writeEvent(path string, item queuedEvent) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	file, err := atomic.FileCreate(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.CloseAndPurge()
+		return err
+	}
+	return file.Close()
+}
+
+func (n *notifier) run() {
+	ticker := time.NewTicker(notifyRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.wakeup:
+		case <-ticker.C:
+		}
+		n.deliverQueued()
+	}
+}
+
+// deliverQueued - deliver queued events in order, after a failure further events of the same target wait for the next retry
+func (n *notifier) deliverQueued() {
+	n.deliverLock.Lock()
+	defer n.deliverLock.Unlock()
+
+	entries, err := ioutil.ReadDir(n.queueDir)
+	if err != nil {
+		log.Printf("Unable to read notification queue: %s", err)
+		return
+	}
+	failed := make(map[string]bool)
+	for _, entry := range entries {
+		// skip files still being written
+		if filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(n.queueDir, entry.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		item := queuedEvent{}
+		if err := json.Unmarshal(data, &item); err != nil {
+			n.remove(path)
+			continue
+		}
+		if failed[item.Target] {
+			continue
+		}
+		target, ok := n.targets[item.Target]
+		if !ok {
+			// target no longer configured
+			n.remove(path)
+			continue
+		}
+		if err := sendEvent(target, item.Event); err != nil {
+			log.Printf("Unable to deliver notification to target %s: %s", item.Target, err)
+			failed[item.Target] = true
+			continue
+		}
+		n.remove(path)
+	}
+}
+
+func (n *notifier) remove(path string) {
+	if err := os.Remove(path); err != nil {
+		return
+	}
+	n.lock.Lock()
+	n.queued--
+	n.lock.Unlock()
+}
+
+// sendEvent - deliver a single event to target
+func sendEvent(target NotificationTarget, event NotificationEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	switch target.Type {
+	case TargetWebhook:
+		client := http.Client{Timeout: notifyTimeout}
+		resp, err := client.Post(target.Endpoint, "application/json", bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook returned %s", resp.Status)
+		}
+		return nil
+	case TargetLog:
+		file, err := os.OpenFile(target.Endpoint, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	case TargetSocket:
+		conn, err := net.DialTimeout("unix", target.Endpoint, notifyTimeout)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetWriteDeadline(time.Now().Add(notifyTimeout))
+		_, err = conn.Write(append(data, '\n'))
+		return err
+	}
+	return fmt.Errorf("unknown target type %s", target.Type)
+}
@@ -0,0 +1,137 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package donut
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Please read for more information - http://docs.aws.amazon.com/AmazonS3/latest/dev/object-tagging.html
+//
+// Tags are saved in object and bucket metadata under the key 'tagging', URL query
+// encoded in the same form as the 'x-amz-tagging' header.
+
+const (
+	// TaggingMetadataKey metadata key under which tags are saved
+	TaggingMetadataKey = "tagging"
+
+	// maximum number of tags allowed on an object
+	maxObjectTags = 10
+	// maximum number of tags allowed on a bucket
+	maxBucketTags = 50
+	// maximum length of a tag key in unicode characters
+	maxTagKeyLength = 128
+	// maximum length of a tag value in unicode characters
+	maxTagValueLength = 256
+)
+
+// Tags container for a tag set
+type Tags map[string]string
+
+// DecodeTags - decode tags from their URL query encoded form, a tag key can appear only once
+func DecodeTags(encoded string) (Tags, *probe.Error) {
+	tags := make(Tags)
+	if strings.TrimSpace(encoded) == "" {
+		return tags, nil
+	}
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		return nil, probe.NewError(InvalidTag{})
+	}
+	for key, value := range values {
+		if len(value) != 1 {
+			return nil, probe.NewError(InvalidTag{Key: key})
+		}
+		tags[key] = value[0]
+	}
+	return tags, nil
+}
+
+// Encode - URL query encode tags, keys are sorted
+func (t Tags) Encode() string {
+	var keys []string
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, url.QueryEscape(key)+"="+url.QueryEscape(t[key]))
+	}
+	return strings.Join(values, "&")
+}
+
+// isValidTags - verify tag count, key and value lengths
+func isValidTags(tags Tags, maxTags int) *probe.Error {
+	if len(tags) > maxTags {
+		return probe.NewError(InvalidTag{})
+	}
+	for key, value := range tags {
+		if key == "" || utf8.RuneCountInString(key) > maxTagKeyLength {
+			return probe.NewError(InvalidTag{Key: key})
+		}
+		if utf8.RuneCountInString(value) > maxTagValueLength {
+			return probe.NewError(InvalidTag{Key: key})
+		}
+		// prefix is reserved for system tags
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return probe.NewError(InvalidTag{Key: key})
+		}
+	}
+	return nil
+}
+
+// IsValidObjectTags - verify tags in accordance with object tagging limits
+func IsValidObjectTags(tags Tags) *probe.Error {
+	return isValidTags(tags, maxObjectTags)
+}
+
+// IsValidBucketTags - verify tags in accordance with bucket tagging limits
+func IsValidBucketTags(tags Tags) *probe.Error {
+	return isValidTags(tags, maxBucketTags)
+}
+
+// TagFilter - selects objects by key prefix and tags, used by lifecycle rules
+type TagFilter struct {
+	Prefix string
+	Tags   Tags
+}
+
+// Match - returns true if object name has the filter prefix and carries all of the filter tags
+func (f TagFilter) Match(object ObjectMetadata) bool {
+	if !strings.HasPrefix(object.Object, f.Prefix) {
+		return false
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	tags, err := DecodeTags(object.Metadata[TaggingMetadataKey])
+	if err != nil {
+		return false
+	}
+	for key, value := range f.Tags {
+		if v, ok := tags[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
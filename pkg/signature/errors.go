@@ -16,6 +16,8 @@
 
 package signature
 
+import "strconv"
+
 // MissingDateHeader date header missing
 type MissingDateHeader struct{}
 
@@ -46,3 +48,50 @@ type DoesNotMatch struct {
 func (e DoesNotMatch) Error() string {
 	return "The request signature we calculated does not match the signature you provided"
 }
+
+// MalformedChunk aws-chunked payload is not well-formed or ends prematurely
+type MalformedChunk struct{}
+
+func (e MalformedChunk) Error() string {
+	return "Malformed aws-chunked payload"
+}
+
+// PolicyConditionFailed form values do not satisfy a condition of POST policy
+type PolicyConditionFailed struct {
+	Operator string
+	Field    string
+	Value    string
+}
+
+func (e PolicyConditionFailed) Error() string {
+	return "Policy condition failed: [\"" + e.Operator + "\", \"$" + e.Field + "\", \"" + e.Value + "\"]"
+}
+
+// PolicyExtraField form field not covered by any condition of POST policy
+type PolicyExtraField struct {
+	Field string
+}
+
+func (e PolicyExtraField) Error() string {
+	return "Extra input field not covered by policy: " + e.Field
+}
+
+// ContentLengthTooLarge uploaded data exceeds maximum of POST policy content-length-range
+type ContentLengthTooLarge struct {
+	Size int64
+	Max  int64
+}
+
+func (e ContentLengthTooLarge) Error() string {
+	return "Your proposed upload exceeds the maximum allowed size: " + strconv.FormatInt(e.Max, 10)
+}
+
+// ContentLengthTooSmall uploaded data is short of minimum of POST policy content-length-range
+type ContentLengthTooSmall struct {
+	Size int64
+	Min  int64
+}
+
+func (e ContentLengthTooSmall) Error() string {
+	return "Your proposed upload is smaller than the minimum allowed size: " + strconv.FormatInt(e.Min, 10)
+}
@@ -19,7 +19,11 @@ package signature
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/minio/minio-xl/pkg/probe"
@@ -34,13 +38,27 @@ func toString(val interface{}) string {
 	return ""
 }
 
-// toInteger _ Safely convert interface to integer without causing panic.
-func toInteger(val interface{}) int {
+// toInteger - Safely convert interface to integer without causing panic, JSON numbers
+// are decoded as float64 and some clients send numbers as strings.
+func toInteger(val interface{}) (int64, bool) {
 	switch v := val.(type) {
 	case int:
-		return v
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, false
+		}
+		return int64(v), true
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return i, true
 	}
-	return 0
+	return 0, false
 }
 
 // isString - Safely check if val is of type string without causing panic.
@@ -52,22 +70,34 @@ func isString(val interface{}) bool {
 	return false
 }
 
+// PostPolicyCondition - a single "eq" or "starts-with" condition on a form field
+type PostPolicyCondition struct {
+	Operator string
+	// form field name in canonical header form, without leading '$'
+	Field string
+	Value string
+}
+
 // PostPolicyForm provides strict static type conversion and validation for Amazon S3's POST policy JSON string.
 type PostPolicyForm struct {
 	Expiration time.Time // Expiration date and time of the POST policy.
 	Conditions struct {  // Conditional policy structure.
-		Policies map[string]struct {
-			Operator string
-			Value    string
-		}
+		Policies           []PostPolicyCondition
 		ContentLengthRange struct {
-			Min int
-			Max int
+			Valid bool // content-length-range is optional
+			Min   int64
+			Max   int64
 		}
 	}
 }
 
-// ParsePostPolicyForm - Parse JSON policy string into typed POostPolicyForm structure.
+// canonicalFieldName - form field names are case insensitive, forms are parsed with
+// http.CanonicalHeaderKey() so use the same
+func canonicalFieldName(field string) string {
+	return http.CanonicalHeaderKey(strings.TrimPrefix(field, "$"))
+}
+
+// ParsePostPolicyForm - Parse JSON policy string into typed PostPolicyForm structure.
 func ParsePostPolicyForm(policy string) (PostPolicyForm, *probe.Error) {
 	// Convert po into interfaces and
 	// perform strict type conversion using reflection.
@@ -88,10 +118,6 @@ func ParsePostPolicyForm(policy string) (PostPolicyForm, *probe.Error) {
 	if e != nil {
 		return PostPolicyForm{}, probe.NewError(e)
 	}
-	parsedPolicy.Conditions.Policies = make(map[string]struct {
-		Operator string
-		Value    string
-	})
 
 	// Parse conditions.
 	for _, val := range rawPolicy.Conditions {
@@ -105,13 +131,11 @@ func ParsePostPolicyForm(policy string) (PostPolicyForm, *probe.Error) {
 				}
 				// {"acl": "public-read" } is an alternate way to indicate - [ "eq", "$acl", "public-read" ]
 				// In this case we will just collapse this into "eq" for all use cases.
-				parsedPolicy.Conditions.Policies["$"+k] = struct {
-					Operator string
-					Value    string
-				}{
+				parsedPolicy.Conditions.Policies = append(parsedPolicy.Conditions.Policies, PostPolicyCondition{
 					Operator: "eq",
+					Field:    canonicalFieldName(k),
 					Value:    toString(v),
-				}
+				})
 			}
 		case []interface{}: // Handle array types.
 			if len(condt) != 3 { // Return error if we have insufficient elements.
@@ -128,21 +152,26 @@ func ParsePostPolicyForm(policy string) (PostPolicyForm, *probe.Error) {
 					}
 				}
 				operator, matchType, value := toString(condt[0]), toString(condt[1]), toString(condt[2])
-				parsedPolicy.Conditions.Policies[matchType] = struct {
-					Operator string
-					Value    string
-				}{
+				// array conditions always refer to fields as '$field'
+				if !strings.HasPrefix(matchType, "$") || len(matchType) == 1 {
+					return parsedPolicy, probe.NewError(fmt.Errorf("Malformed conditional fields â€˜%sâ€™ of type â€˜%sâ€™ found in POST policy form.",
+						condt, reflect.TypeOf(condt).String()))
+				}
+				parsedPolicy.Conditions.Policies = append(parsedPolicy.Conditions.Policies, PostPolicyCondition{
 					Operator: operator,
+					Field:    canonicalFieldName(matchType),
 					Value:    value,
-				}
+				})
 			case "content-length-range":
-				parsedPolicy.Conditions.ContentLengthRange = struct {
-					Min int
-					Max int
-				}{
-					Min: toInteger(condt[1]),
-					Max: toInteger(condt[2]),
+				min, okMin := toInteger(condt[1])
+				max, okMax := toInteger(condt[2])
+				if !okMin || !okMax || min < 0 || min > max {
+					return parsedPolicy, probe.NewError(fmt.Errorf("Malformed conditional fields â€˜%sâ€™ of type â€˜%sâ€™ found in POST policy form.",
+						condt, reflect.TypeOf(condt).String()))
 				}
+				parsedPolicy.Conditions.ContentLengthRange.Valid = true
+				parsedPolicy.Conditions.ContentLengthRange.Min = min
+				parsedPolicy.Conditions.ContentLengthRange.Max = max
 			default:
 				// Condition should be valid.
 				return parsedPolicy, probe.NewError(fmt.Errorf("Unknown type â€˜%sâ€™ of conditional field value â€˜%sâ€™ found in POST policy form.",
@@ -155,3 +184,85 @@ func ParsePostPolicyForm(policy string) (PostPolicyForm, *probe.Error) {
 	}
 	return parsedPolicy, nil
 }
+
+// form fields which need not be covered by any policy condition
+var ignoredFormFields = map[string]bool{
+	"Awsaccesskeyid":  true,
+	"Bucket":          true, // bucket comes from the request path, it is matched only if there is a condition
+	"File":            true,
+	"Policy":          true,
+	"Signature":       true,
+	"X-Amz-Signature": true,
+}
+
+// match - returns true if value satisfies the condition
+func (c PostPolicyCondition) match(value string) bool {
+	switch c.Operator {
+	case "eq":
+		return value == c.Value
+	case "starts-with":
+		// Content-Type may carry a list of types, each of them has to match
+		if c.Field == "Content-Type" {
+			for _, v := range strings.Split(value, ",") {
+				if !strings.HasPrefix(strings.TrimSpace(v), c.Value) {
+					return false
+				}
+			}
+			return true
+		}
+		return strings.HasPrefix(value, c.Value)
+	}
+	return false
+}
+
+// CheckFormValues - verify that form values satisfy every condition of the policy and that every
+// form field is covered by a condition, fields prefixed with 'x-ignore-' are not checked
+func (p PostPolicyForm) CheckFormValues(formValues map[string]string) *probe.Error {
+	covered := make(map[string]bool)
+	for _, condition := range p.Conditions.Policies {
+		if !condition.match(formValues[condition.Field]) {
+			return probe.NewError(PolicyConditionFailed{Operator: condition.Operator, Field: condition.Field, Value: condition.Value})
+		}
+		covered[condition.Field] = true
+	}
+	for field := range formValues {
+		field = canonicalFieldName(field)
+		if covered[field] || ignoredFormFields[field] || strings.HasPrefix(field, "X-Ignore-") {
+			continue
+		}
+		return probe.NewError(PolicyExtraField{Field: field})
+	}
+	return nil
+}
+
+type contentLengthRangeReader struct {
+	reader   io.Reader
+	min, max int64
+	size     int64
+}
+
+func (r *contentLengthRangeReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.size += int64(n)
+	if r.size > r.max {
+		return n, ContentLengthTooLarge{Size: r.size, Max: r.max}
+	}
+	if err == io.EOF && r.size < r.min {
+		return n, ContentLengthTooSmall{Size: r.size, Min: r.min}
+	}
+	return n, err
+}
+
+// ContentLengthRangeReader - returns a reader which fails as soon as data read exceeds the maximum of
+// content-length-range, or at the end of data when it is short of its minimum. Reader is returned
+// as is if the policy has no content-length-range condition.
+func (p PostPolicyForm) ContentLengthRangeReader(reader io.Reader) io.Reader {
+	if !p.Conditions.ContentLengthRange.Valid {
+		return reader
+	}
+	return &contentLengthRangeReader{
+		reader: reader,
+		min:    p.Conditions.ContentLengthRange.Min,
+		max:    p.Conditions.ContentLengthRange.Max,
+	}
+}
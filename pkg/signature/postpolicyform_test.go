@@ -0,0 +1,88 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"bytes"
+	"io/ioutil"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func newPostPolicy(conditions string) string {
+	return `{"expiration": "` + time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano) + `", "conditions": [` + conditions + `]}`
+}
+
+func (s *MySuite) TestPostPolicyForm(c *C) {
+	policy, err := ParsePostPolicyForm(newPostPolicy(`{"bucket": "bucket"}, ["starts-with", "$key", "user/"], ["eq", "$x-amz-meta-color", "blue"], ["starts-with", "$Content-Type", "image/"], ["content-length-range", 1, 1024]`))
+	c.Assert(err, IsNil)
+	c.Assert(len(policy.Conditions.Policies), Equals, 4)
+	c.Assert(policy.Conditions.ContentLengthRange.Valid, Equals, true)
+	c.Assert(policy.Conditions.ContentLengthRange.Min, Equals, int64(1))
+	c.Assert(policy.Conditions.ContentLengthRange.Max, Equals, int64(1024))
+
+	formValues := map[string]string{
+		"Bucket":           "bucket",
+		"Key":              "user/photo.png",
+		"X-Amz-Meta-Color": "blue",
+		"Content-Type":     "image/png, image/gif",
+		"Policy":           "policy",
+		"X-Amz-Signature":  "signature",
+		"X-Ignore-Field":   "ignored",
+	}
+	c.Assert(policy.CheckFormValues(formValues), IsNil)
+
+	formValues["Content-Type"] = "image/png, text/plain"
+	err = policy.CheckFormValues(formValues)
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, PolicyConditionFailed{})
+
+	formValues["Content-Type"] = "image/png"
+	formValues["Key"] = "other/photo.png"
+	err = policy.CheckFormValues(formValues)
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, PolicyConditionFailed{})
+
+	formValues["Key"] = "user/photo.png"
+	formValues["X-Amz-Meta-Size"] = "large"
+	err = policy.CheckFormValues(formValues)
+	c.Assert(err, Not(IsNil))
+	c.Assert(err.ToGoError(), FitsTypeOf, PolicyExtraField{})
+
+	_, err = ParsePostPolicyForm(newPostPolicy(`["eq", "key", "user/"]`))
+	c.Assert(err, Not(IsNil))
+	_, err = ParsePostPolicyForm(newPostPolicy(`["content-length-range", 10, 1]`))
+	c.Assert(err, Not(IsNil))
+	_, err = ParsePostPolicyForm(newPostPolicy(`["not-equal", "$key", "user/"]`))
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *MySuite) TestContentLengthRangeReader(c *C) {
+	policy, err := ParsePostPolicyForm(newPostPolicy(`["content-length-range", "4", "8"]`))
+	c.Assert(err, IsNil)
+
+	data, e := ioutil.ReadAll(policy.ContentLengthRangeReader(bytes.NewReader([]byte("hello"))))
+	c.Assert(e, IsNil)
+	c.Assert(data, DeepEquals, []byte("hello"))
+
+	_, e = ioutil.ReadAll(policy.ContentLengthRangeReader(bytes.NewReader([]byte("hello world"))))
+	c.Assert(e, FitsTypeOf, ContentLengthTooLarge{})
+
+	_, e = ioutil.ReadAll(policy.ContentLengthRangeReader(bytes.NewReader([]byte("hi"))))
+	c.Assert(e, FitsTypeOf, ContentLengthTooSmall{})
+}
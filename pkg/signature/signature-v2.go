@@ -0,0 +1,193 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Please read for more information - http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html
+//
+// Signature version 2 covers request method, a few standard headers, 'x-amz-*' headers and the
+// resource, payload is not signed.
+
+// SignatureV2 - local variables
+type SignatureV2 struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	Presigned       bool
+	Signature       string
+	Request         *http.Request
+}
+
+// list of sub-resources included in the canonicalized resource, sorted
+var resourceListV2 = []string{
+	"acl",
+	"cors",
+	"delete",
+	"lifecycle",
+	"location",
+	"logging",
+	"notification",
+	"partNumber",
+	"policy",
+	"requestPayment",
+	"response-cache-control",
+	"response-content-disposition",
+	"response-content-encoding",
+	"response-content-language",
+	"response-content-type",
+	"response-expires",
+	"tagging",
+	"torrent",
+	"uploadId",
+	"uploads",
+	"versionId",
+	"versioning",
+	"versions",
+	"website",
+}
+
+// getCanonicalizedAmzHeaders - lower case 'x-amz-*' headers sorted by name, one per line
+func (r SignatureV2) getCanonicalizedAmzHeaders() string {
+	var headers []string
+	vals := make(map[string][]string)
+	for k, vv := range r.Request.Header {
+		k = strings.ToLower(k)
+		if !strings.HasPrefix(k, "x-amz-") {
+			continue
+		}
+		headers = append(headers, k)
+		vals[k] = vv
+	}
+	sort.Strings(headers)
+
+	var buf bytes.Buffer
+	for _, k := range headers {
+		buf.WriteString(k)
+		buf.WriteByte(':')
+		for idx, v := range vals[k] {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strings.TrimSpace(v))
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// getCanonicalizedResource - encoded path followed by the sub-resources of the request
+func (r SignatureV2) getCanonicalizedResource() string {
+	encodedPath := getURLEncodedName(r.Request.URL.Path)
+	// convert any space strings back to "+"
+	encodedPath = strings.Replace(encodedPath, "+", "%20", -1)
+
+	query := r.Request.URL.Query()
+	var resources []string
+	for _, resource := range resourceListV2 {
+		values, ok := query[resource]
+		if !ok {
+			continue
+		}
+		if len(values) == 0 || values[0] == "" {
+			resources = append(resources, resource)
+			continue
+		}
+		resources = append(resources, resource+"="+values[0])
+	}
+	if len(resources) == 0 {
+		return encodedPath
+	}
+	return encodedPath + "?" + strings.Join(resources, "&")
+}
+
+// getStringToSign - string to sign, date is either the request date or presigned expiry
+//
+// StringToSign =
+//  <HTTPMethod>\n
+//  <Content-MD5>\n
+//  <Content-Type>\n
+//  <Date>\n
+//  <CanonicalizedAmzHeaders><CanonicalizedResource>
+//
+func (r SignatureV2) getStringToSign(date string) string {
+	return strings.Join([]string{
+		r.Request.Method,
+		r.Request.Header.Get("Content-MD5"),
+		r.Request.Header.Get("Content-Type"),
+		date,
+		r.getCanonicalizedAmzHeaders() + r.getCanonicalizedResource(),
+	}, "\n")
+}
+
+// getSignature - base64 encoded HMAC-SHA1 of string to sign
+func (r SignatureV2) getSignature(stringToSign string) string {
+	hash := hmac.New(sha1.New, []byte(r.SecretAccessKey))
+	hash.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// DoesSignatureMatch - Verify authorization header in accordance with
+//     - http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html
+// returns true if matches, false otherwise. if error is not nil then it is always false
+func (r *SignatureV2) DoesSignatureMatch() (bool, *probe.Error) {
+	// date line is empty when 'x-amz-date' is set, it is part of amz headers instead
+	date := ""
+	if r.Request.Header.Get("X-Amz-Date") == "" {
+		if date = r.Request.Header.Get("Date"); date == "" {
+			return false, probe.NewError(MissingDateHeader{})
+		}
+	}
+	newSignature := r.getSignature(r.getStringToSign(date))
+	if !hmac.Equal([]byte(newSignature), []byte(r.Signature)) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// DoesPresignedSignatureMatch - Verify query string authentication in accordance with
+//     - http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html#RESTAuthenticationQueryStringAuth
+// returns true if matches, false otherwise. if error is not nil then it is always false
+func (r *SignatureV2) DoesPresignedSignatureMatch() (bool, *probe.Error) {
+	expires := r.Request.URL.Query().Get("Expires")
+	if expires == "" {
+		return false, probe.NewError(MissingExpiresQuery{})
+	}
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return false, probe.NewError(err)
+	}
+	if time.Now().UTC().Unix() > expiresUnix {
+		return false, probe.NewError(ExpiredPresignedRequest{})
+	}
+	newSignature := r.getSignature(r.getStringToSign(expires))
+	if !hmac.Equal([]byte(newSignature), []byte(r.Signature)) {
+		return false, nil
+	}
+	return true, nil
+}
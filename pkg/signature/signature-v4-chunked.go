@@ -0,0 +1,163 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/crypto/sha256"
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Please read for more information - http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+//
+// Payload is sent as a sequence of chunks, each chunk carries a signature computed over the
+// signature of the previous chunk, the first chunk chains on the seed signature of the request.
+//
+//   <hex size>;chunk-signature=<signature>\r\n<data>\r\n
+//
+// Payload ends with a chunk of size zero.
+
+// StreamingPayload - value of 'x-amz-content-sha256' for payloads sent in aws-chunked encoding
+const StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+
+const (
+	chunkAlgorithm = "AWS4-HMAC-SHA256-PAYLOAD"
+	// sha256 of an empty string
+	emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// maximum size of a single chunk, chunk data is held in memory until verified
+	maxChunkSize = 16 * 1024 * 1024
+)
+
+// IsStreamingPayload - returns true if request payload is sent in aws-chunked encoding
+func IsStreamingPayload(req *http.Request) bool {
+	return req.Header.Get("X-Amz-Content-Sha256") == StreamingPayload
+}
+
+type chunkedReader struct {
+	reader    *bufio.Reader
+	signature *Signature
+	date      time.Time
+	// signing key is the same for all chunks
+	signingKey []byte
+	// signature of the previous chunk
+	previous string
+	// verified data of current chunk not yet read
+	chunk []byte
+	err   error
+}
+
+// NewChunkedReader - decode aws-chunked payload, if signature is not nil seed signature of the request
+// and signature of every chunk are verified, a chunk is returned only once it is verified
+func NewChunkedReader(reader io.Reader, signature *Signature) (io.Reader, *probe.Error) {
+	c := &chunkedReader{
+		reader:    bufio.NewReader(reader),
+		signature: signature,
+	}
+	if signature != nil {
+		ok, err := signature.DoesSignatureMatch(StreamingPayload)
+		if err != nil {
+			return nil, err.Trace()
+		}
+		if !ok {
+			return nil, probe.NewError(DoesNotMatch{})
+		}
+		date := signature.Request.Header.Get("X-Amz-Date")
+		if date == "" {
+			date = signature.Request.Header.Get("Date")
+		}
+		t, e := time.Parse(iso8601Format, date)
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		c.date = t
+		c.signingKey = signature.getSigningKey(t)
+		c.previous = signature.Signature
+	}
+	return c, nil
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for len(c.chunk) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.err = c.readChunk()
+	}
+	n := copy(p, c.chunk)
+	c.chunk = c.chunk[n:]
+	return n, nil
+}
+
+// readChunk - read and verify next chunk, returns io.EOF after the last chunk
+func (c *chunkedReader) readChunk() error {
+	// chunk header is short, a line longer than buffer size is malformed anyway
+	line, err := c.reader.ReadSlice('\n')
+	if err != nil {
+		return MalformedChunk{}
+	}
+	header := strings.TrimSuffix(string(line), "\r\n")
+	if len(header) == len(line) {
+		return MalformedChunk{}
+	}
+	fields := strings.SplitN(header, ";", 2)
+	if len(fields) != 2 || !strings.HasPrefix(fields[1], "chunk-signature=") {
+		return MalformedChunk{}
+	}
+	size, err := strconv.ParseInt(fields[0], 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return MalformedChunk{}
+	}
+	chunkSignature := strings.TrimPrefix(fields[1], "chunk-signature=")
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		return MalformedChunk{}
+	}
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		return MalformedChunk{}
+	}
+	data = data[:size]
+
+	if c.signature != nil {
+		stringToSign := strings.Join([]string{
+			chunkAlgorithm,
+			c.date.Format(iso8601Format),
+			c.signature.getScope(c.date),
+			c.previous,
+			emptySHA256,
+			hex.EncodeToString(sha256.Sum256(data)),
+		}, "\n")
+		newSignature := c.signature.getSignature(c.signingKey, stringToSign)
+		if newSignature != chunkSignature {
+			return DoesNotMatch{SignatureSent: chunkSignature, SignatureCalculated: newSignature}
+		}
+		c.previous = chunkSignature
+	}
+	if size == 0 {
+		return io.EOF
+	}
+	c.chunk = data
+	return nil
+}
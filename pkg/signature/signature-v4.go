@@ -37,6 +37,7 @@ import (
 type Signature struct {
 	AccessKeyID     string
 	SecretAccessKey string
+	Region          string
 	Presigned       bool
 	PresignedPolicy string
 	SignedHeaders   []string
@@ -50,6 +51,14 @@ const (
 	yyyymmdd         = "20060102"
 )
 
+// UnsignedPayload - value of 'x-amz-content-sha256' for payloads not covered by the signature
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// IsUnsignedPayload - returns true if request payload is not covered by the signature
+func IsUnsignedPayload(req *http.Request) bool {
+	return req.Header.Get("X-Amz-Content-Sha256") == UnsignedPayload
+}
+
 // sumHMAC calculate hmac between two input byte array
 func sumHMAC(key []byte, data []byte) []byte {
 	hash := hmac.New(sha256.New, key)
@@ -201,7 +210,7 @@ func (r Signature) getPresignedCanonicalRequest(presignedQuery string) string {
 		rawQuery,
 		r.getCanonicalHeaders(r.extractSignedHeaders()),
 		r.getSignedHeaders(r.extractSignedHeaders()),
-		"UNSIGNED-PAYLOAD",
+		UnsignedPayload,
 	}, "\n")
 	return canonicalRequest
 }
@@ -210,7 +219,7 @@ func (r Signature) getPresignedCanonicalRequest(presignedQuery string) string {
 func (r Signature) getScope(t time.Time) string {
 	scope := strings.Join([]string{
 		t.Format(yyyymmdd),
-		"milkyway",
+		r.Region,
 		"s3",
 		"aws4_request",
 	}, "/")
@@ -229,7 +238,7 @@ func (r Signature) getStringToSign(canonicalRequest string, t time.Time) string
 func (r Signature) getSigningKey(t time.Time) []byte {
 	secret := r.SecretAccessKey
 	date := sumHMAC([]byte("AWS4"+secret), []byte(t.Format(yyyymmdd)))
-	region := sumHMAC(date, []byte("milkyway"))
+	region := sumHMAC(date, []byte(r.Region))
 	service := sumHMAC(region, []byte("s3"))
 	signingKey := sumHMAC(service, []byte("aws4_request"))
 	return signingKey
@@ -286,6 +295,10 @@ func (r *Signature) DoesPresignedSignatureMatch() (bool, *probe.Error) {
 	query.Set("X-Amz-Expires", strconv.Itoa(expireSeconds))
 	query.Set("X-Amz-SignedHeaders", r.getSignedHeaders(r.extractSignedHeaders()))
 	query.Set("X-Amz-Credential", r.AccessKeyID+"/"+r.getScope(t))
+	// temporary credentials carry their session token
+	if _, ok := r.Request.URL.Query()["X-Amz-Security-Token"]; ok {
+		query.Set("X-Amz-Security-Token", r.Request.URL.Query().Get("X-Amz-Security-Token"))
+	}
 
 	encodedQuery := query.Encode()
 	newSignature := r.getSignature(r.getSigningKey(t), r.getStringToSign(r.getPresignedCanonicalRequest(encodedQuery), t))
@@ -301,8 +314,10 @@ func (r *Signature) DoesPresignedSignatureMatch() (bool, *probe.Error) {
 //     - http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
 // returns true if matches, false otherwise. if error is not nil then it is always false
 func (r *Signature) DoesSignatureMatch(hashedPayload string) (bool, *probe.Error) {
-	// set new calulated payload
-	r.Request.Header.Set("X-Amz-Content-Sha256", hashedPayload)
+	// set new calulated payload, unless payload is not part of the signature
+	if !IsUnsignedPayload(r.Request) {
+		r.Request.Header.Set("X-Amz-Content-Sha256", hashedPayload)
+	}
 
 	// Add date if not present throw error
 	var date string
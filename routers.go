@@ -29,12 +29,27 @@ import (
 func registerAPI(mux *router.Router, a API) {
 	mux.HandleFunc("/", a.ListBucketsHandler).Methods("GET")
 	mux.HandleFunc("/{bucket}", a.GetBucketACLHandler).Queries("acl", "").Methods("GET")
+	mux.HandleFunc("/{bucket}", a.GetBucketLocationHandler).Queries("location", "").Methods("GET")
+	mux.HandleFunc("/{bucket}", a.GetBucketCORSHandler).Queries("cors", "").Methods("GET")
+	mux.HandleFunc("/{bucket}", a.GetBucketTaggingHandler).Queries("tagging", "").Methods("GET")
+	mux.HandleFunc("/{bucket}", a.GetBucketNotificationHandler).Queries("notification", "").Methods("GET")
+	mux.HandleFunc("/{bucket}", a.GetBucketWebsiteHandler).Queries("website", "").Methods("GET")
+	mux.HandleFunc("/{bucket}", a.GetBucketEncryptionHandler).Queries("encryption", "").Methods("GET")
+	mux.HandleFunc("/{bucket}", a.ListObjectsV2Handler).Queries("list-type", "2").Methods("GET")
 	mux.HandleFunc("/{bucket}", a.ListObjectsHandler).Methods("GET")
 	mux.HandleFunc("/{bucket}", a.PutBucketACLHandler).Queries("acl", "").Methods("PUT")
+	mux.HandleFunc("/{bucket}", a.PutBucketCORSHandler).Queries("cors", "").Methods("PUT")
+	mux.HandleFunc("/{bucket}", a.PutBucketTaggingHandler).Queries("tagging", "").Methods("PUT")
+	mux.HandleFunc("/{bucket}", a.PutBucketNotificationHandler).Queries("notification", "").Methods("PUT")
+	mux.HandleFunc("/{bucket}", a.PutBucketWebsiteHandler).Queries("website", "").Methods("PUT")
+	mux.HandleFunc("/{bucket}", a.PutBucketEncryptionHandler).Queries("encryption", "").Methods("PUT")
 	mux.HandleFunc("/{bucket}", a.PutBucketHandler).Methods("PUT")
 	mux.HandleFunc("/{bucket}", a.HeadBucketHandler).Methods("HEAD")
 	mux.HandleFunc("/{bucket}", a.PostPolicyBucketHandler).Methods("POST")
 	mux.HandleFunc("/{bucket}/{object:.*}", a.HeadObjectHandler).Methods("HEAD")
+	mux.HandleFunc("/{bucket}/{object:.*}", a.GetObjectTaggingHandler).Queries("tagging", "").Methods("GET")
+	mux.HandleFunc("/{bucket}/{object:.*}", a.PutObjectTaggingHandler).Queries("tagging", "").Methods("PUT")
+	mux.HandleFunc("/{bucket}/{object:.*}", a.DeleteObjectTaggingHandler).Queries("tagging", "").Methods("DELETE")
 	mux.HandleFunc("/{bucket}/{object:.*}", a.PutObjectPartHandler).Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}").Methods("PUT")
 	mux.HandleFunc("/{bucket}/{object:.*}", a.ListObjectPartsHandler).Queries("uploadId", "{uploadId:.*}").Methods("GET")
 	mux.HandleFunc("/{bucket}/{object:.*}", a.CompleteMultipartUploadHandler).Queries("uploadId", "{uploadId:.*}").Methods("POST")
@@ -43,6 +58,11 @@ func registerAPI(mux *router.Router, a API) {
 	mux.HandleFunc("/{bucket}/{object:.*}", a.GetObjectHandler).Methods("GET")
 	mux.HandleFunc("/{bucket}/{object:.*}", a.PutObjectHandler).Methods("PUT")
 
+	mux.HandleFunc("/{bucket}", a.DeleteBucketCORSHandler).Queries("cors", "").Methods("DELETE")
+	mux.HandleFunc("/{bucket}", a.DeleteBucketTaggingHandler).Queries("tagging", "").Methods("DELETE")
+	mux.HandleFunc("/{bucket}", a.DeleteBucketWebsiteHandler).Queries("website", "").Methods("DELETE")
+	mux.HandleFunc("/{bucket}", a.DeleteBucketEncryptionHandler).Queries("encryption", "").Methods("DELETE")
+
 	// not implemented yet
 	mux.HandleFunc("/{bucket}", a.DeleteBucketHandler).Methods("DELETE")
 
@@ -59,11 +79,15 @@ type APIOperation struct {
 type API struct {
 	OP        chan APIOperation
 	Donut     donut.Interface
-	Anonymous bool // do not checking for incoming signatures, allow all requests
+	Anonymous bool   // do not checking for incoming signatures, allow all requests
+	TLS       bool   // server is reachable over TLS only, required for requests carrying encryption keys
+	Region    string // region of the server, as configured at start
 }
 
 // getNewAPI instantiate a new minio API
 func getNewAPI(anonymous bool) API {
+	// disks of nodes on other servers are reached over their storage rpc
+	donut.SetRemoteDisk(newRemoteDisk)
 	// ignore errors for now
 	d, err := donut.New()
 	fatalIf(err.Trace(), "Instantiating donut failed.", nil)
@@ -72,6 +96,7 @@ func getNewAPI(anonymous bool) API {
 		OP:        make(chan APIOperation),
 		Donut:     d,
 		Anonymous: anonymous,
+		Region:    d.Region(),
 	}
 }
 
@@ -79,18 +104,20 @@ func getAPIHandler(anonymous bool, api API) http.Handler {
 	var mwHandlers = []MiddlewareHandler{
 		TimeValidityHandler,
 		IgnoreResourcesHandler,
-		CorsHandler,
 	}
 	if !anonymous {
-		mwHandlers = append(mwHandlers, SignatureHandler)
+		mwHandlers = append(mwHandlers, SignatureHandler(api))
 	}
+	// preflight requests carry no credentials, handle them before signature verification
+	mwHandlers = append(mwHandlers, CorsHandler(api))
 	mux := router.NewRouter()
 	registerAPI(mux, api)
 	apiHandler := registerCustomMiddleware(mux, mwHandlers...)
 	return apiHandler
 }
 
-func getServerRPCHandler(anonymous bool) http.Handler {
+// getServerRPCHandler rpc handler for server, reporting on given donut if any
+func getServerRPCHandler(anonymous bool, d donut.Interface) http.Handler {
 	var mwHandlers = []MiddlewareHandler{
 		TimeValidityHandler,
 	}
@@ -100,8 +127,12 @@ func getServerRPCHandler(anonymous bool) http.Handler {
 
 	s := jsonrpc.NewServer()
 	s.RegisterCodec(json.NewCodec(), "application/json")
-	s.RegisterService(new(serverRPCService), "Server")
+	s.RegisterService(&serverRPCService{donut: d}, "Server")
 	s.RegisterService(new(donutRPCService), "Donut")
+	// raw disk access is never served without signature verification
+	if !anonymous {
+		s.RegisterService(newStorageRPCService(), "Storage")
+	}
 	mux := router.NewRouter()
 	mux.Handle("/rpc", s)
 
@@ -40,8 +40,12 @@ type rpcRequest struct {
 	transport http.RoundTripper
 }
 
-// newRPCRequest initiate a new client RPC request
+// newRPCRequest initiate a new client RPC request, signed with the keys of an admin user
 func newRPCRequest(config *AuthConfig, url string, op rpcOperation, transport http.RoundTripper) (*rpcRequest, *probe.Error) {
+	user, ok := findAdminUser(config)
+	if !ok {
+		return nil, probe.NewError(errAccessKeyIDInvalid)
+	}
 	t := time.Now().UTC()
 	params, err := json.EncodeClientRequest(op.Method, op.Request)
 	if err != nil {
@@ -132,7 +136,7 @@ func newRPCRequest(config *AuthConfig, url string, op rpcOperation, transport ht
 	stringToSign = stringToSign + scope + "\n"
 	stringToSign = stringToSign + hex.EncodeToString(sum256([]byte(canonicalRequest)))
 
-	date := sumHMAC([]byte("MINIO"+config.Users["admin"].SecretAccessKey), []byte(t.Format(yyyymmdd)))
+	date := sumHMAC([]byte("MINIO"+user.SecretAccessKey), []byte(t.Format(yyyymmdd)))
 	region := sumHMAC(date, []byte("milkyway"))
 	service := sumHMAC(region, []byte("rpc"))
 	signingKey := sumHMAC(service, []byte("rpc_request"))
@@ -141,7 +145,7 @@ func newRPCRequest(config *AuthConfig, url string, op rpcOperation, transport ht
 
 	// final Authorization header
 	parts := []string{
-		rpcAuthHeaderPrefix + " Credential=admin/" + scope,
+		rpcAuthHeaderPrefix + " Credential=" + user.AccessKeyID + "/" + scope,
 		"SignedHeaders=" + signedHeaders,
 		"Signature=" + signature,
 	}
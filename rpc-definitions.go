@@ -16,6 +16,8 @@
 
 package main
 
+import "time"
+
 //// In memory metadata
 
 //// RPC params
@@ -25,6 +27,20 @@ type AuthArgs struct {
 	User string `json:"user"`
 }
 
+// RotateAuthArgs key rotation params
+type RotateAuthArgs struct {
+	User  string `json:"user"`
+	Grace int64  `json:"graceSeconds"` // previous keys stay valid this long once promoted, until revoked if zero
+}
+
+// SessionAuthArgs temporary credentials params
+type SessionAuthArgs struct {
+	User     string   `json:"user"`
+	Duration int64    `json:"durationSeconds"` // defaults to an hour
+	ReadOnly bool     `json:"readOnly"`
+	Buckets  []string `json:"buckets"` // optional policy, 'bucket' or 'bucket/prefix'
+}
+
 // DonutArg donut params
 type DonutArg struct{}
 
@@ -38,6 +54,37 @@ type ControllerArgs struct {
 	ID   string `json:"id"`
 }
 
+// StorageArgs storage params, a file or directory on a disk exported by the server
+type StorageArgs struct {
+	Disk string `json:"disk"`
+	Path string `json:"path"`
+}
+
+// StorageReadArgs storage params to read next part of a file opened for reading
+type StorageReadArgs struct {
+	Handle string `json:"handle"`
+	Length int64  `json:"length"`
+}
+
+// StorageWriteArgs storage params to write next part of a file opened for writing
+type StorageWriteArgs struct {
+	Handle string `json:"handle"`
+	Data   []byte `json:"data"`
+}
+
+// StorageCloseArgs storage params to close an open file, purge discards a file being written
+type StorageCloseArgs struct {
+	Handle string `json:"handle"`
+	Purge  bool   `json:"purge"`
+}
+
+// StorageRenameArgs storage params to rename a file
+type StorageRenameArgs struct {
+	Disk    string `json:"disk"`
+	Path    string `json:"path"`
+	NewPath string `json:"newPath"`
+}
+
 //// RPC replies
 
 // ServerRep server reply container for Server.List
@@ -71,6 +118,21 @@ type DiskStatsRep struct {
 type MemStatsRep struct {
 	Total uint64 `json:"total"`
 	Free  uint64 `json:"free"`
+
+	// object data cache of the server
+	Cache CacheStatsRep `json:"cache"`
+}
+
+// CacheStatsRep object data cache statistics of a server
+type CacheStatsRep struct {
+	MaxSize  uint64 `json:"maxSize"`
+	Size     uint64 `json:"size"`
+	Items    int    `json:"items"`
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+	Evicted  int    `json:"evicted"`
+	Expired  int    `json:"expired"`
+	Rejected int    `json:"rejected"`
 }
 
 // Network metadata of a server
@@ -116,6 +178,28 @@ type AuthRep struct {
 	SecretAccessKey string `json:"secretAccessKey"`
 }
 
+// RotateAuthRep reply with primary and secondary keys of the user
+type RotateAuthRep struct {
+	Name            string   `json:"name"`
+	AccessKeyID     string   `json:"accessKeyId"`
+	SecretAccessKey string   `json:"secretAccessKey"`
+	Secondary       *AuthKey `json:"secondary,omitempty"`
+}
+
+// AuthAuditRep reply with changes to keys of the user, or of all users
+type AuthAuditRep struct {
+	Entries []AuthAuditEntry `json:"entries"`
+}
+
+// SessionAuthRep reply with temporary credentials acting for the user
+type SessionAuthRep struct {
+	Name            string    `json:"name"`
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	SessionToken    string    `json:"sessionToken"`
+	Expiry          time.Time `json:"expiry"`
+}
+
 // DiscoverArgs array of IP addresses / names to discover
 type DiscoverArgs struct {
 	Hosts []string `json:"hosts"`
@@ -165,3 +249,35 @@ type DonutVersionRep struct {
 	Architecture    string `json:"arch"`
 	OperatingSystem string `json:"os"`
 }
+
+// StorageRep empty reply of storage operations
+type StorageRep struct{}
+
+// StorageFileInfoRep file or directory information
+type StorageFileInfoRep struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	IsDir   bool      `json:"isDir"`
+}
+
+// StorageListDirRep directories in a directory
+type StorageListDirRep struct {
+	Entries []StorageFileInfoRep `json:"entries"`
+}
+
+// StorageHandleRep handle of a file opened over storage rpc
+type StorageHandleRep struct {
+	Handle string `json:"handle"`
+}
+
+// StorageReadRep data read from a file, EOF is set once the end of file is reached and the file is closed
+type StorageReadRep struct {
+	Data []byte `json:"data"`
+	EOF  bool   `json:"eof"`
+}
+
+// StorageFSInfoRep filesystem information of a disk
+type StorageFSInfoRep struct {
+	Info map[string]string `json:"info"`
+}
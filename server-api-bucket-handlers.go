@@ -18,6 +18,7 @@ package main
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/gorilla/mux"
 	"github.com/minio/minio-xl/pkg/donut"
@@ -53,6 +54,10 @@ func (api API) ListMultipartUploadsHandler(w http.ResponseWriter, req *http.Requ
 
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, resources.Prefix, readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	resources, err := api.Donut.ListMultipartUploads(bucket, resources)
 	if err != nil {
@@ -66,7 +71,7 @@ func (api API) ListMultipartUploadsHandler(w http.ResponseWriter, req *http.Requ
 		return
 	}
 	// generate response
-	response := generateListMultipartUploadsResponse(bucket, resources)
+	response := generateListMultipartUploadsResponse(bucket, resources, api.getBucketOwner(bucket))
 	encodedSuccessResponse := encodeSuccessResponse(response)
 	// write headers
 	setCommonHeaders(w, len(encodedSuccessResponse))
@@ -106,11 +111,15 @@ func (api API) ListObjectsHandler(w http.ResponseWriter, req *http.Request) {
 
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, resources.Prefix, readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	objects, resources, err := api.Donut.ListObjects(bucket, resources)
 	if err == nil {
 		// generate response
-		response := generateListObjectsResponse(bucket, objects, resources)
+		response := generateListObjectsResponse(bucket, objects, resources, api.getBucketOwner(bucket))
 		encodedSuccessResponse := encodeSuccessResponse(response)
 		// write headers
 		setCommonHeaders(w, len(encodedSuccessResponse))
@@ -133,6 +142,79 @@ func (api API) ListObjectsHandler(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// ListObjectsV2Handler - GET Bucket (List Objects) Version 2
+// -------------------------
+// This implementation of the GET operation returns some or all (up to 1000)
+// of the objects in a bucket, a truncated listing is resumed from the
+// continuation token handed out along with it.
+//
+func (api API) ListObjectsV2Handler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	resources := getBucketResources(req.URL.Query())
+	if resources.Maxkeys < 0 {
+		writeErrorResponse(w, req, InvalidMaxKeys, req.URL.Path)
+		return
+	}
+	if resources.Maxkeys == 0 {
+		resources.Maxkeys = maxObjectList
+	}
+	if resources.EncodingType != "" && resources.EncodingType != "url" {
+		writeErrorResponse(w, req, InvalidEncodingType, req.URL.Path)
+		return
+	}
+	// continuation token takes precedence over start-after, the listing resumes after its marker
+	resources.Marker = resources.StartAfter
+	if resources.ContinuationToken != "" {
+		marker, err := getContinuationMarker(resources.ContinuationToken)
+		if err != nil {
+			writeErrorResponse(w, req, InvalidContinuationToken, req.URL.Path)
+			return
+		}
+		resources.Marker = marker
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, resources.Prefix, readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	objects, resources, err := api.Donut.ListObjects(bucket, resources)
+	if err == nil {
+		if resources.IsTruncated {
+			resources.NextContinuationToken = getContinuationToken(resources.NextMarker)
+		}
+		// generate response
+		response := generateListObjectsV2Response(bucket, objects, resources, api.getBucketOwner(bucket))
+		encodedSuccessResponse := encodeSuccessResponse(response)
+		// write headers
+		setCommonHeaders(w, len(encodedSuccessResponse))
+		// write body
+		w.Write(encodedSuccessResponse)
+		return
+	}
+	switch err.ToGoError().(type) {
+	case donut.BucketNameInvalid:
+		writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+	case donut.BucketNotFound:
+		writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+	case donut.ObjectNameInvalid:
+		writeErrorResponse(w, req, NoSuchKey, req.URL.Path)
+	default:
+		errorIf(err.Trace(), "ListObjectsV2 failed.", nil)
+		writeErrorResponse(w, req, InternalError, req.URL.Path)
+	}
+}
+
 // ListBucketsHandler - GET Service
 // -----------
 // This implementation of the GET operation returns a list of all buckets
@@ -147,17 +229,31 @@ func (api API) ListBucketsHandler(w http.ResponseWriter, req *http.Request) {
 		<-op.ProceedCh
 	}
 
-	// uncomment this when we have webcli
-	// without access key credentials one cannot list buckets
-	// if _, err := StripAccessKeyID(req); err != nil {
-	//	writeErrorResponse(w, req, AccessDenied,  req.URL.Path)
-	//	return
-	// }
+	// anonymous servers list every bucket
+	user := &AuthUser{Admin: true}
+	if !api.Anonymous {
+		var err *probe.Error
+		if user, err = getRequestUser(req); err != nil {
+			errorIf(err.Trace(), "Unable to find user of the request.", nil)
+			writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+			return
+		}
+	}
 
 	buckets, err := api.Donut.ListBuckets()
 	if err == nil {
+		// users other than admins see only buckets they own
+		if !user.Admin {
+			var ownedBuckets []donut.BucketMetadata
+			for _, bucket := range buckets {
+				if bucket.Owner == user.Name {
+					ownedBuckets = append(ownedBuckets, bucket)
+				}
+			}
+			buckets = ownedBuckets
+		}
 		// generate response
-		response := generateListBucketsResponse(buckets)
+		response := generateListBucketsResponse(buckets, getOwner(user.Name))
 		encodedSuccessResponse := encodeSuccessResponse(response)
 		// write headers
 		setCommonHeaders(w, len(encodedSuccessResponse))
@@ -182,7 +278,12 @@ func (api API) PutBucketHandler(w http.ResponseWriter, req *http.Request) {
 		<-op.ProceedCh
 	}
 
-	if _, err := stripAccessKeyID(req.Header.Get("Authorization")); err != nil {
+	if isRequestSignatureV2(req) {
+		if _, _, err := getCredentialsFromAuthV2(req.Header.Get("Authorization")); err != nil {
+			writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+			return
+		}
+	} else if _, err := stripAccessKeyID(req.Header.Get("Authorization"), api.Region); err != nil {
 		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
 		return
 	}
@@ -199,10 +300,10 @@ func (api API) PutBucketHandler(w http.ResponseWriter, req *http.Request) {
 
 	var signature *signv4.Signature
 	if !api.Anonymous {
-		if _, ok := req.Header["Authorization"]; ok {
+		if isRequestSignatureV4(req) {
 			// Init signature V4 verification
 			var err *probe.Error
-			signature, err = initSignatureV4(req)
+			signature, err = initSignatureV4(req, api.Region)
 			if err != nil {
 				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
 				writeErrorResponse(w, req, InternalError, req.URL.Path)
@@ -221,7 +322,23 @@ func (api API) PutBucketHandler(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	err := api.Donut.MakeBucket(bucket, getACLTypeString(aclType), req.Body, signature)
+	// buckets are owned by their creator, read-only users may not create buckets
+	var owner string
+	if !api.Anonymous {
+		user, err := getRequestUser(req)
+		if err != nil {
+			errorIf(err.Trace(), "Unable to find user of the request.", nil)
+			writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+			return
+		}
+		if user.ReadOnly || (user.Session != nil && !user.Session.isAllowed(bucket, "", ownerPermission)) {
+			writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+			return
+		}
+		owner = user.Name
+	}
+
+	err := api.Donut.MakeBucket(bucket, getACLTypeString(aclType), owner, req.Body, signature)
 	if err != nil {
 		errorIf(err.Trace(), "MakeBucket failed.", nil)
 		switch err.ToGoError().(type) {
@@ -233,6 +350,10 @@ func (api API) PutBucketHandler(w http.ResponseWriter, req *http.Request) {
 			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
 		case donut.BucketExists:
 			writeErrorResponse(w, req, BucketAlreadyExists, req.URL.Path)
+		case donut.MalformedXML:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		case donut.InvalidLocationConstraint:
+			writeErrorResponse(w, req, InvalidLocationConstraint, req.URL.Path)
 		default:
 			writeErrorResponse(w, req, InternalError, req.URL.Path)
 		}
@@ -286,10 +407,19 @@ func (api API) PostPolicyBucketHandler(w http.ResponseWriter, req *http.Request)
 	bucket := mux.Vars(req)["bucket"]
 	formValues["Bucket"] = bucket
 	object := formValues["Key"]
-	signature, perr := initPostPresignedPolicyV4(formValues)
+	signature, perr := initPostPresignedPolicyV4(formValues, api.Region)
 	if perr != nil {
 		errorIf(perr.Trace(), "Unable to initialize post policy presigned.", nil)
-		writeErrorResponse(w, req, MalformedPOSTRequest, req.URL.Path)
+		switch perr.ToGoError() {
+		case errInvalidRegion:
+			writeErrorResponse(w, req, AuthorizationQueryParametersError, req.URL.Path)
+		case errSessionExpired:
+			writeErrorResponse(w, req, ExpiredToken, req.URL.Path)
+		case errInvalidSessionToken:
+			writeErrorResponse(w, req, InvalidToken, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, MalformedPOSTRequest, req.URL.Path)
+		}
 		return
 	}
 	var ok bool
@@ -302,12 +432,37 @@ func (api API) PostPolicyBucketHandler(w http.ResponseWriter, req *http.Request)
 		writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
 		return
 	}
-	if perr = applyPolicy(formValues); perr != nil {
+	user, perr := getAuthUser(signature.AccessKeyID)
+	if perr != nil || !api.isUserAllowed(user, bucket, object, writePermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+	postPolicyForm, perr := applyPolicy(formValues)
+	if perr != nil {
 		errorIf(perr.Trace(), "Invalid request, policy doesn't match with the endpoint.", nil)
-		writeErrorResponse(w, req, MalformedPOSTRequest, req.URL.Path)
+		if perr.ToGoError() == errPolicyAlreadyExpired {
+			writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+			return
+		}
+		switch perr.ToGoError().(type) {
+		case signv4.PolicyConditionFailed, signv4.PolicyExtraField:
+			writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, MalformedPOSTRequest, req.URL.Path)
+		}
+		return
+	}
+	// form fields carry object metadata the same way headers do for PUT
+	formHeader := make(http.Header)
+	for key, value := range formValues {
+		formHeader.Set(key, value)
+	}
+	objectMetadata, perr := extractMetadata(formHeader)
+	if perr != nil {
+		writeErrorResponse(w, req, MetadataTooLarge, req.URL.Path)
 		return
 	}
-	metadata, perr := api.Donut.CreateObject(bucket, object, "", 0, fileBody, nil, nil)
+	metadata, perr := api.Donut.CreateObject(bucket, object, "", 0, postPolicyForm.ContentLengthRangeReader(fileBody), objectMetadata, nil, nil)
 	if perr != nil {
 		errorIf(perr.Trace(), "CreateObject failed.", nil)
 		switch perr.ToGoError().(type) {
@@ -327,13 +482,47 @@ func (api API) PostPolicyBucketHandler(w http.ResponseWriter, req *http.Request)
 			writeErrorResponse(w, req, EntityTooLarge, req.URL.Path)
 		case donut.InvalidDigest:
 			writeErrorResponse(w, req, InvalidDigest, req.URL.Path)
+		case signv4.ContentLengthTooLarge:
+			writeErrorResponse(w, req, EntityTooLarge, req.URL.Path)
+		case signv4.ContentLengthTooSmall:
+			writeErrorResponse(w, req, EntityTooSmall, req.URL.Path)
 		default:
 			writeErrorResponse(w, req, InternalError, req.URL.Path)
 		}
 		return
 	}
+	// 'redirect' is the deprecated name of 'success_action_redirect', redirect takes precedence
+	// over status and is ignored if it is not an absolute URL
+	redirect := formValues["Success_action_redirect"]
+	if redirect == "" {
+		redirect = formValues["Redirect"]
+	}
+	if redirectURL, err := url.Parse(redirect); redirect != "" && err == nil && redirectURL.IsAbs() {
+		query := redirectURL.Query()
+		query.Set("bucket", bucket)
+		query.Set("key", object)
+		query.Set("etag", "\""+metadata.MD5Sum+"\"")
+		redirectURL.RawQuery = query.Encode()
+		http.Redirect(w, req, redirectURL.String(), http.StatusSeeOther)
+		return
+	}
 	w.Header().Set("ETag", metadata.MD5Sum)
-	writeSuccessResponse(w)
+	switch formValues["Success_action_status"] {
+	case "200":
+		writeSuccessResponse(w)
+	case "201":
+		location := getRedirectLocation(req, "", "", bucket+"/"+object)
+		response := generatePostResponse(bucket, object, location, metadata.MD5Sum)
+		encodedSuccessResponse := encodeSuccessResponse(response)
+		// write headers
+		setCommonHeaders(w, len(encodedSuccessResponse))
+		w.WriteHeader(http.StatusCreated)
+		// write body
+		w.Write(encodedSuccessResponse)
+	default:
+		setCommonHeaders(w, 0)
+		w.WriteHeader(http.StatusNoContent)
+	}
 }
 
 // PutBucketACLHandler - PUT Bucket ACL
@@ -358,6 +547,10 @@ func (api API) PutBucketACLHandler(w http.ResponseWriter, req *http.Request) {
 
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	err := api.Donut.SetBucketMetadata(bucket, map[string]string{"acl": getACLTypeString(aclType)})
 	if err != nil {
@@ -393,6 +586,10 @@ func (api API) GetBucketACLHandler(w http.ResponseWriter, req *http.Request) {
 
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	bucketMetadata, err := api.Donut.GetBucketMetadata(bucket)
 	if err != nil {
@@ -408,7 +605,53 @@ func (api API) GetBucketACLHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	// generate response
-	response := generateAccessControlPolicyResponse(bucketMetadata.ACL)
+	response := generateAccessControlPolicyResponse(bucketMetadata.ACL, getOwner(bucketMetadata.Owner))
+	encodedSuccessResponse := encodeSuccessResponse(response)
+	// write headers
+	setCommonHeaders(w, len(encodedSuccessResponse))
+	// write body
+	w.Write(encodedSuccessResponse)
+}
+
+// GetBucketLocationHandler - GET Bucket location
+// ----------
+// This operation uses location subresource to return the region
+// a bucket resides in. Buckets in 'us-east-1' have an empty location
+// constraint.
+func (api API) GetBucketLocationHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	location, err := api.Donut.GetBucketLocation(bucket)
+	if err != nil {
+		errorIf(err.Trace(), "GetBucketLocation failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	response := LocationResponse{}
+	if location != "us-east-1" {
+		response.Location = location
+	}
 	encodedSuccessResponse := encodeSuccessResponse(response)
 	// write headers
 	setCommonHeaders(w, len(encodedSuccessResponse))
@@ -434,6 +677,10 @@ func (api API) HeadBucketHandler(w http.ResponseWriter, req *http.Request) {
 
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	_, err := api.Donut.GetBucketMetadata(bucket)
 	if err != nil {
@@ -450,3 +697,815 @@ func (api API) HeadBucketHandler(w http.ResponseWriter, req *http.Request) {
 	}
 	writeSuccessResponse(w)
 }
+
+// PutBucketCORSHandler - PUT Bucket CORS
+// ----------
+// This implementation of the PUT operation sets the CORS configuration
+// for a bucket, an existing configuration is replaced.
+func (api API) PutBucketCORSHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	var signature *signv4.Signature
+	if !api.Anonymous {
+		if isRequestSignatureV4(req) {
+			// Init signature V4 verification
+			var err *probe.Error
+			signature, err = initSignatureV4(req, api.Region)
+			if err != nil {
+				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+				return
+			}
+		}
+	}
+
+	if req.Body == nil {
+		writeErrorResponse(w, req, MissingRequestBodyError, req.URL.Path)
+		return
+	}
+	/// if Content-Length missing, deny the request
+	if req.Header.Get("Content-Length") == "" {
+		writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
+		return
+	}
+	corsBytes, err := readSignedPayload(req, maxCORSConfigSize, signature)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to read CORS configuration.", nil)
+		switch err.ToGoError() {
+		case errPayloadTooLarge:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		default:
+			switch err.ToGoError().(type) {
+			case signv4.DoesNotMatch:
+				writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			default:
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+			}
+		}
+		return
+	}
+	if _, ok := parseCORSConfiguration(corsBytes); !ok {
+		writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		return
+	}
+
+	err = api.Donut.SetBucketMetadata(bucket, map[string]string{corsMetadataKey: string(corsBytes)})
+	if err != nil {
+		errorIf(err.Trace(), "PutBucketCORS failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	writeSuccessResponse(w)
+}
+
+// GetBucketCORSHandler - GET Bucket CORS
+// ----------
+// This operation uses cors subresource to return the CORS configuration
+// of a bucket. This operation will return response of 404 if bucket
+// not found or if bucket has no CORS configuration.
+func (api API) GetBucketCORSHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	bucketMetadata, err := api.Donut.GetBucketMetadata(bucket)
+	if err != nil {
+		errorIf(err.Trace(), "GetBucketMetadata failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	config, ok := parseCORSConfiguration([]byte(bucketMetadata.Metadata[corsMetadataKey]))
+	if !ok {
+		writeErrorResponse(w, req, NoSuchCORSConfiguration, req.URL.Path)
+		return
+	}
+	encodedSuccessResponse := encodeSuccessResponse(config)
+	// write headers
+	setCommonHeaders(w, len(encodedSuccessResponse))
+	// write body
+	w.Write(encodedSuccessResponse)
+}
+
+// DeleteBucketCORSHandler - DELETE Bucket CORS
+// ----------
+// This operation removes the CORS configuration of a bucket, cross
+// origin requests to the bucket are denied afterwards.
+func (api API) DeleteBucketCORSHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	err := api.Donut.SetBucketMetadata(bucket, map[string]string{corsMetadataKey: ""})
+	if err != nil {
+		errorIf(err.Trace(), "DeleteBucketCORS failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	setCommonHeaders(w, 0)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PutBucketTaggingHandler - PUT Bucket tagging
+// ----------
+// This implementation of the PUT operation sets the tag set for a
+// bucket, an existing tag set is replaced.
+func (api API) PutBucketTaggingHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	var signature *signv4.Signature
+	if !api.Anonymous {
+		if isRequestSignatureV4(req) {
+			// Init signature V4 verification
+			var err *probe.Error
+			signature, err = initSignatureV4(req, api.Region)
+			if err != nil {
+				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+				return
+			}
+		}
+	}
+
+	if req.Body == nil {
+		writeErrorResponse(w, req, MissingRequestBodyError, req.URL.Path)
+		return
+	}
+	/// if Content-Length missing, deny the request
+	if req.Header.Get("Content-Length") == "" {
+		writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
+		return
+	}
+	taggingBytes, err := readSignedPayload(req, maxTaggingSize, signature)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to read tagging.", nil)
+		switch err.ToGoError() {
+		case errPayloadTooLarge:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		default:
+			switch err.ToGoError().(type) {
+			case signv4.DoesNotMatch:
+				writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			default:
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+			}
+		}
+		return
+	}
+	tags, err := parseTagging(taggingBytes)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to parse tagging.", nil)
+		switch err.ToGoError().(type) {
+		case donut.InvalidTag:
+			writeErrorResponse(w, req, InvalidTag, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		}
+		return
+	}
+	if err := donut.IsValidBucketTags(tags); err != nil {
+		writeErrorResponse(w, req, InvalidTag, req.URL.Path)
+		return
+	}
+
+	err = api.Donut.SetBucketMetadata(bucket, map[string]string{donut.TaggingMetadataKey: tags.Encode()})
+	if err != nil {
+		errorIf(err.Trace(), "PutBucketTagging failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	writeSuccessResponse(w)
+}
+
+// GetBucketTaggingHandler - GET Bucket tagging
+// ----------
+// This operation uses tagging subresource to return the tag set of a
+// bucket. This operation will return response of 404 if bucket not
+// found or if bucket has no tags.
+func (api API) GetBucketTaggingHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	bucketMetadata, err := api.Donut.GetBucketMetadata(bucket)
+	if err != nil {
+		errorIf(err.Trace(), "GetBucketMetadata failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	tags, err := donut.DecodeTags(bucketMetadata.Metadata[donut.TaggingMetadataKey])
+	if err != nil || len(tags) == 0 {
+		writeErrorResponse(w, req, NoSuchTagSet, req.URL.Path)
+		return
+	}
+	response := generateTaggingResponse(tags)
+	encodedSuccessResponse := encodeSuccessResponse(response)
+	// write headers
+	setCommonHeaders(w, len(encodedSuccessResponse))
+	// write body
+	w.Write(encodedSuccessResponse)
+}
+
+// DeleteBucketTaggingHandler - DELETE Bucket tagging
+// ----------
+// This operation removes the tag set of a bucket.
+func (api API) DeleteBucketTaggingHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	err := api.Donut.SetBucketMetadata(bucket, map[string]string{donut.TaggingMetadataKey: ""})
+	if err != nil {
+		errorIf(err.Trace(), "DeleteBucketTagging failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	setCommonHeaders(w, 0)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maximum size of a notification configuration document
+const maxNotificationConfigSize = 64 * 1024
+
+// PutBucketNotificationHandler - PUT Bucket notification
+// ----------
+// This implementation of the PUT operation sets the notification
+// configuration of a bucket, an empty configuration disables
+// notifications. Targets must be configured on the server.
+func (api API) PutBucketNotificationHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	var signature *signv4.Signature
+	if !api.Anonymous {
+		if isRequestSignatureV4(req) {
+			// Init signature V4 verification
+			var err *probe.Error
+			signature, err = initSignatureV4(req, api.Region)
+			if err != nil {
+				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+				return
+			}
+		}
+	}
+
+	if req.Body == nil {
+		writeErrorResponse(w, req, MissingRequestBodyError, req.URL.Path)
+		return
+	}
+	/// if Content-Length missing, deny the request
+	if req.Header.Get("Content-Length") == "" {
+		writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
+		return
+	}
+	configBytes, err := readSignedPayload(req, maxNotificationConfigSize, signature)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to read notification configuration.", nil)
+		switch err.ToGoError() {
+		case errPayloadTooLarge:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		default:
+			switch err.ToGoError().(type) {
+			case signv4.DoesNotMatch:
+				writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			default:
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+			}
+		}
+		return
+	}
+	config, err := donut.ParseNotificationConfiguration(configBytes)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to parse notification configuration.", nil)
+		switch err.ToGoError().(type) {
+		case donut.InvalidEventName:
+			writeErrorResponse(w, req, InvalidEventName, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		}
+		return
+	}
+
+	err = api.Donut.SetBucketNotification(bucket, config)
+	if err != nil {
+		errorIf(err.Trace(), "PutBucketNotification failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.InvalidTargetARN:
+			writeErrorResponse(w, req, InvalidTargetARN, req.URL.Path)
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	writeSuccessResponse(w)
+}
+
+// GetBucketNotificationHandler - GET Bucket notification
+// ----------
+// This operation uses notification subresource to return the
+// notification configuration of a bucket, an empty configuration
+// is returned if notifications are not enabled.
+func (api API) GetBucketNotificationHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	config, err := api.Donut.GetBucketNotification(bucket)
+	if err != nil {
+		errorIf(err.Trace(), "GetBucketNotification failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	encodedSuccessResponse := encodeSuccessResponse(config)
+	// write headers
+	setCommonHeaders(w, len(encodedSuccessResponse))
+	// write body
+	w.Write(encodedSuccessResponse)
+}
+
+// PutBucketWebsiteHandler - PUT Bucket website
+// ----------
+// This implementation of the PUT operation sets the website configuration
+// for a bucket, an existing configuration is replaced.
+func (api API) PutBucketWebsiteHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	var signature *signv4.Signature
+	if !api.Anonymous {
+		if isRequestSignatureV4(req) {
+			// Init signature V4 verification
+			var err *probe.Error
+			signature, err = initSignatureV4(req, api.Region)
+			if err != nil {
+				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+				return
+			}
+		}
+	}
+
+	if req.Body == nil {
+		writeErrorResponse(w, req, MissingRequestBodyError, req.URL.Path)
+		return
+	}
+	/// if Content-Length missing, deny the request
+	if req.Header.Get("Content-Length") == "" {
+		writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
+		return
+	}
+	websiteBytes, err := readSignedPayload(req, maxWebsiteConfigSize, signature)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to read website configuration.", nil)
+		switch err.ToGoError() {
+		case errPayloadTooLarge:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		default:
+			switch err.ToGoError().(type) {
+			case signv4.DoesNotMatch:
+				writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			default:
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+			}
+		}
+		return
+	}
+	if _, ok := parseWebsiteConfiguration(websiteBytes); !ok {
+		writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		return
+	}
+
+	err = api.Donut.SetBucketMetadata(bucket, map[string]string{websiteMetadataKey: string(websiteBytes)})
+	if err != nil {
+		errorIf(err.Trace(), "PutBucketWebsite failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	writeSuccessResponse(w)
+}
+
+// GetBucketWebsiteHandler - GET Bucket website
+// ----------
+// This operation uses website subresource to return the website configuration
+// of a bucket. This operation will return response of 404 if bucket
+// not found or if bucket has no website configuration.
+func (api API) GetBucketWebsiteHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	bucketMetadata, err := api.Donut.GetBucketMetadata(bucket)
+	if err != nil {
+		errorIf(err.Trace(), "GetBucketMetadata failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	config, ok := parseWebsiteConfiguration([]byte(bucketMetadata.Metadata[websiteMetadataKey]))
+	if !ok {
+		writeErrorResponse(w, req, NoSuchWebsiteConfiguration, req.URL.Path)
+		return
+	}
+	encodedSuccessResponse := encodeSuccessResponse(config)
+	// write headers
+	setCommonHeaders(w, len(encodedSuccessResponse))
+	// write body
+	w.Write(encodedSuccessResponse)
+}
+
+// DeleteBucketWebsiteHandler - DELETE Bucket website
+// ----------
+// This operation removes the website configuration of a bucket, the
+// bucket is no longer served on the website endpoint afterwards.
+func (api API) DeleteBucketWebsiteHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	err := api.Donut.SetBucketMetadata(bucket, map[string]string{websiteMetadataKey: ""})
+	if err != nil {
+		errorIf(err.Trace(), "DeleteBucketWebsite failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	setCommonHeaders(w, 0)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PutBucketEncryptionHandler - PUT Bucket encryption
+// ----------
+// This implementation of the PUT operation sets default encryption of
+// a bucket, new objects are encrypted with server managed keys.
+func (api API) PutBucketEncryptionHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	var signature *signv4.Signature
+	if !api.Anonymous {
+		if isRequestSignatureV4(req) {
+			// Init signature V4 verification
+			var err *probe.Error
+			signature, err = initSignatureV4(req, api.Region)
+			if err != nil {
+				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+				return
+			}
+		}
+	}
+
+	if req.Body == nil {
+		writeErrorResponse(w, req, MissingRequestBodyError, req.URL.Path)
+		return
+	}
+	/// if Content-Length missing, deny the request
+	if req.Header.Get("Content-Length") == "" {
+		writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
+		return
+	}
+	encryptionBytes, err := readSignedPayload(req, maxEncryptionConfigSize, signature)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to read encryption configuration.", nil)
+		switch err.ToGoError() {
+		case errPayloadTooLarge:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		default:
+			switch err.ToGoError().(type) {
+			case signv4.DoesNotMatch:
+				writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			default:
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+			}
+		}
+		return
+	}
+	algorithm, ok := parseEncryptionConfiguration(encryptionBytes)
+	if !ok {
+		writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		return
+	}
+
+	err = api.Donut.SetBucketMetadata(bucket, map[string]string{donut.EncryptionMetadataKey: algorithm})
+	if err != nil {
+		errorIf(err.Trace(), "PutBucketEncryption failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	writeSuccessResponse(w)
+}
+
+// GetBucketEncryptionHandler - GET Bucket encryption
+// ----------
+// This operation uses encryption subresource to return default encryption
+// of a bucket. This operation will return response of 404 if bucket
+// not found or if bucket has no default encryption.
+func (api API) GetBucketEncryptionHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	bucketMetadata, err := api.Donut.GetBucketMetadata(bucket)
+	if err != nil {
+		errorIf(err.Trace(), "GetBucketMetadata failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	algorithm, ok := bucketMetadata.Metadata[donut.EncryptionMetadataKey]
+	if !ok || algorithm == "" {
+		writeErrorResponse(w, req, NoSuchEncryptionConfiguration, req.URL.Path)
+		return
+	}
+	encodedSuccessResponse := encodeSuccessResponse(generateEncryptionConfiguration(algorithm))
+	// write headers
+	setCommonHeaders(w, len(encodedSuccessResponse))
+	// write body
+	w.Write(encodedSuccessResponse)
+}
+
+// DeleteBucketEncryptionHandler - DELETE Bucket encryption
+// ----------
+// This operation removes default encryption of a bucket, objects already
+// encrypted stay encrypted.
+func (api API) DeleteBucketEncryptionHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	if !api.isAllowed(req, bucket, "", ownerPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	err := api.Donut.SetBucketMetadata(bucket, map[string]string{donut.EncryptionMetadataKey: ""})
+	if err != nil {
+		errorIf(err.Trace(), "DeleteBucketEncryption failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	setCommonHeaders(w, 0)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,211 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Please read for more information - http://docs.aws.amazon.com/AmazonS3/latest/dev/cors.html
+//
+// CORS configuration is saved as is in bucket metadata under the key 'cors'
+
+const (
+	// bucket metadata key for CORS configuration
+	corsMetadataKey = "cors"
+
+	// maximum number of rules allowed in a CORS configuration
+	maxCORSRules = 100
+
+	// maximum size of a CORS configuration document
+	maxCORSConfigSize = 64 * 1024
+)
+
+// CORSRule - a single cross origin rule
+type CORSRule struct {
+	ID            string   `xml:"ID,omitempty"`
+	AllowedOrigin []string `xml:"AllowedOrigin"`
+	AllowedMethod []string `xml:"AllowedMethod"`
+	AllowedHeader []string `xml:"AllowedHeader,omitempty"`
+	ExposeHeader  []string `xml:"ExposeHeader,omitempty"`
+	MaxAgeSeconds int      `xml:"MaxAgeSeconds,omitempty"`
+}
+
+// CORSConfiguration - format for put and get bucket cors
+type CORSConfiguration struct {
+	XMLName  xml.Name   `xml:"CORSConfiguration" json:"-"`
+	CORSRule []CORSRule `xml:"CORSRule"`
+}
+
+// list of methods allowed in a CORS rule
+var corsAllowedMethods = map[string]bool{
+	"GET":    true,
+	"PUT":    true,
+	"POST":   true,
+	"DELETE": true,
+	"HEAD":   true,
+}
+
+// parseCORSConfiguration - parse and validate a CORS configuration document
+func parseCORSConfiguration(data []byte) (CORSConfiguration, bool) {
+	config := CORSConfiguration{}
+	if err := xml.Unmarshal(data, &config); err != nil {
+		return CORSConfiguration{}, false
+	}
+	if len(config.CORSRule) == 0 || len(config.CORSRule) > maxCORSRules {
+		return CORSConfiguration{}, false
+	}
+	for _, rule := range config.CORSRule {
+		if len(rule.AllowedOrigin) == 0 || len(rule.AllowedMethod) == 0 {
+			return CORSConfiguration{}, false
+		}
+		for _, method := range rule.AllowedMethod {
+			if !corsAllowedMethods[method] {
+				return CORSConfiguration{}, false
+			}
+		}
+		// only one wildcard is allowed for origins and headers
+		for _, origin := range rule.AllowedOrigin {
+			if strings.Count(origin, "*") > 1 {
+				return CORSConfiguration{}, false
+			}
+		}
+		for _, header := range rule.AllowedHeader {
+			if strings.Count(header, "*") > 1 {
+				return CORSConfiguration{}, false
+			}
+		}
+		if rule.MaxAgeSeconds < 0 {
+			return CORSConfiguration{}, false
+		}
+	}
+	return config, true
+}
+
+// wildcardMatch - match value against a pattern carrying at most one '*'
+func wildcardMatch(pattern, value string) bool {
+	index := strings.Index(pattern, "*")
+	if index < 0 {
+		return pattern == value
+	}
+	prefix, suffix := pattern[:index], pattern[index+1:]
+	if len(value) < len(prefix)+len(suffix) {
+		return false
+	}
+	return strings.HasPrefix(value, prefix) && strings.HasSuffix(value, suffix)
+}
+
+// matchOrigin - returns true if origin is allowed by the rule
+func (r CORSRule) matchOrigin(origin string) bool {
+	for _, allowedOrigin := range r.AllowedOrigin {
+		if wildcardMatch(allowedOrigin, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMethod - returns true if method is allowed by the rule
+func (r CORSRule) matchMethod(method string) bool {
+	for _, allowedMethod := range r.AllowedMethod {
+		if allowedMethod == method {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHeaders - returns true if every header is allowed by the rule, headers are case insensitive
+func (r CORSRule) matchHeaders(headers []string) bool {
+	for _, header := range headers {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+		var allowed bool
+		for _, allowedHeader := range r.AllowedHeader {
+			if wildcardMatch(strings.ToLower(allowedHeader), header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// isWildcardOrigin - returns true if rule allows any origin
+func (r CORSRule) isWildcardOrigin() bool {
+	for _, allowedOrigin := range r.AllowedOrigin {
+		if allowedOrigin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// findRule - first rule in configuration matching origin, method and headers
+func (c CORSConfiguration) findRule(origin, method string, headers []string) (CORSRule, bool) {
+	for _, rule := range c.CORSRule {
+		if rule.matchOrigin(origin) && rule.matchMethod(method) && rule.matchHeaders(headers) {
+			return rule, true
+		}
+	}
+	return CORSRule{}, false
+}
+
+// setCORSHeaders - set response headers common to preflight and actual requests
+func setCORSHeaders(w http.ResponseWriter, rule CORSRule, origin string) {
+	if rule.isWildcardOrigin() {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(rule.ExposeHeader) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeader, ", "))
+	}
+}
+
+// setCORSPreflightHeaders - set response headers for a preflight request
+func setCORSPreflightHeaders(w http.ResponseWriter, rule CORSRule, origin string, headers []string) {
+	setCORSHeaders(w, rule, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethod, ", "))
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if rule.MaxAgeSeconds > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(rule.MaxAgeSeconds))
+	}
+}
+
+// getCORSRequestHeaders - list of headers requested in 'Access-Control-Request-Headers'
+func getCORSRequestHeaders(req *http.Request) []string {
+	var headers []string
+	for _, header := range strings.Split(req.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = strings.TrimSpace(header)
+		if header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
@@ -73,6 +73,32 @@ type ListObjectsResponse struct {
 	Prefix     string
 }
 
+// ListObjectsV2Response - format for list objects version 2 response
+type ListObjectsV2Response struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult" json:"-"`
+
+	Name       string
+	Prefix     string
+	StartAfter string `xml:",omitempty"`
+
+	// Continuation token the listing was resumed from, and the one resuming it
+	// if the response is truncated.
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+
+	// Number of keys and common prefixes returned, never more than MaxKeys.
+	KeyCount  int
+	MaxKeys   int
+	Delimiter string `xml:",omitempty"`
+
+	// Encoding type used to encode object keys in the response.
+	EncodingType string `xml:",omitempty"`
+
+	IsTruncated    bool
+	Contents       []*Object
+	CommonPrefixes []*CommonPrefix
+}
+
 // Part container for part metadata
 type Part struct {
 	PartNumber   int
@@ -122,6 +148,12 @@ type ListMultipartUploadsResponse struct {
 	CommonPrefixes     []*CommonPrefix
 }
 
+// LocationResponse - format for location response
+type LocationResponse struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint" json:"-"`
+	Location string   `xml:",chardata"`
+}
+
 // ListBucketsResponse - format for list buckets response
 type ListBucketsResponse struct {
 	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult" json:"-"`
@@ -160,7 +192,8 @@ type Object struct {
 	LastModified string
 	Size         int64
 
-	Owner Owner
+	// Owner is left out of list objects version 2 responses unless asked for.
+	Owner *Owner `xml:",omitempty" json:",omitempty"`
 
 	// The class of storage used to store the object.
 	StorageClass string
@@ -194,20 +227,25 @@ type CompleteMultipartUploadResponse struct {
 	ETag     string
 }
 
+// PostResponse container for POST object response, returned only if 'success_action_status' is 201
+type PostResponse struct {
+	XMLName xml.Name `xml:"PostResponse" json:"-"`
+
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
 // List of not implemented bucket queries
 var notimplementedBucketResourceNames = map[string]bool{
 	"policy":         true,
-	"cors":           true,
 	"lifecycle":      true,
-	"location":       true,
 	"logging":        true,
-	"notification":   true,
 	"replication":    true,
-	"tagging":        true,
 	"versions":       true,
 	"requestPayment": true,
 	"versioning":     true,
-	"website":        true,
 }
 
 // List of not implemented object queries
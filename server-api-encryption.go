@@ -0,0 +1,181 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+
+	"github.com/minio/minio-xl/pkg/donut"
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Server side encryption with customer provided keys (SSE-C) headers
+const (
+	sseCustomerAlgorithmHeader = "X-Amz-Server-Side-Encryption-Customer-Algorithm"
+	sseCustomerKeyHeader       = "X-Amz-Server-Side-Encryption-Customer-Key"
+	sseCustomerKeyMD5Header    = "X-Amz-Server-Side-Encryption-Customer-Key-Md5"
+)
+
+// Server side encryption with server managed keys (SSE-S3) header
+const sseHeader = "X-Amz-Server-Side-Encryption"
+
+// maximum size of a server side encryption configuration document
+const maxEncryptionConfigSize = 64 * 1024
+
+// EncryptionRule - default encryption of new objects in a bucket
+type EncryptionRule struct {
+	ApplyServerSideEncryptionByDefault struct {
+		SSEAlgorithm string
+	}
+}
+
+// ServerSideEncryptionConfiguration - format for put and get bucket encryption, default encryption is
+// saved in bucket metadata as the algorithm only
+type ServerSideEncryptionConfiguration struct {
+	XMLName xml.Name         `xml:"ServerSideEncryptionConfiguration" json:"-"`
+	Rules   []EncryptionRule `xml:"Rule"`
+}
+
+// parseEncryptionConfiguration - parse encryption configuration, returns algorithm of the only rule allowed
+func parseEncryptionConfiguration(data []byte) (string, bool) {
+	config := ServerSideEncryptionConfiguration{}
+	if err := xml.Unmarshal(data, &config); err != nil {
+		return "", false
+	}
+	if len(config.Rules) != 1 {
+		return "", false
+	}
+	algorithm := config.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm
+	if algorithm != donut.EncryptionAlgorithm {
+		return "", false
+	}
+	return algorithm, true
+}
+
+// generateEncryptionConfiguration - encryption configuration with default algorithm of a bucket
+func generateEncryptionConfiguration(algorithm string) ServerSideEncryptionConfiguration {
+	rule := EncryptionRule{}
+	rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm = algorithm
+	return ServerSideEncryptionConfiguration{Rules: []EncryptionRule{rule}}
+}
+
+// isRequestEncrypted - returns true if request carries any of the SSE-C headers
+func isRequestEncrypted(req *http.Request) bool {
+	return req.Header.Get(sseCustomerAlgorithmHeader) != "" ||
+		req.Header.Get(sseCustomerKeyHeader) != "" ||
+		req.Header.Get(sseCustomerKeyMD5Header) != ""
+}
+
+// getRequestEncryption - customer provided key or server managed encryption of the request, nil if request carries none
+func (api API) getRequestEncryption(req *http.Request) (*donut.Encryption, *probe.Error) {
+	if method := req.Header.Get(sseHeader); method != "" {
+		if isRequestEncrypted(req) {
+			return nil, probe.NewError(errConflictingEncryption)
+		}
+		if method != donut.EncryptionAlgorithm {
+			return nil, probe.NewError(errInvalidEncryptionMethod)
+		}
+		return &donut.Encryption{ServerAlgorithm: method}, nil
+	}
+	if !isRequestEncrypted(req) {
+		return nil, nil
+	}
+	// keys are never accepted in clear text
+	if !api.TLS {
+		return nil, probe.NewError(errInsecureEncryptionRequest)
+	}
+	algorithm := req.Header.Get(sseCustomerAlgorithmHeader)
+	if algorithm != donut.EncryptionAlgorithm {
+		return nil, probe.NewError(errInvalidEncryptionAlgorithm)
+	}
+	key, err := base64.StdEncoding.DecodeString(req.Header.Get(sseCustomerKeyHeader))
+	if err != nil || len(key) != donut.EncryptionKeySize {
+		return nil, probe.NewError(errInvalidEncryptionKey)
+	}
+	keyMD5 := md5.Sum(key)
+	if req.Header.Get(sseCustomerKeyMD5Header) != base64.StdEncoding.EncodeToString(keyMD5[:]) {
+		return nil, probe.NewError(errEncryptionKeyMD5Mismatch)
+	}
+	return &donut.Encryption{
+		CustomerAlgorithm: algorithm,
+		CustomerKey:       key,
+	}, nil
+}
+
+// writeEncryptionErrorResponse - write error response for invalid SSE-C headers, or keys not matching the object
+func writeEncryptionErrorResponse(w http.ResponseWriter, req *http.Request, err *probe.Error) {
+	errorIf(err.Trace(), "Encryption of the request is invalid.", nil)
+	switch err.ToGoError().(type) {
+	case donut.EncryptionKeyMissing:
+		writeErrorResponse(w, req, SSECustomerKeyMissing, req.URL.Path)
+	case donut.EncryptionKeyMismatch:
+		writeErrorResponse(w, req, SSECustomerKeyMismatch, req.URL.Path)
+	case donut.ObjectNotEncrypted, donut.ServerEncryptedObject:
+		writeErrorResponse(w, req, SSEObjectNotEncrypted, req.URL.Path)
+	case donut.KeyStoreNotConfigured:
+		writeErrorResponse(w, req, NotImplemented, req.URL.Path)
+	case donut.InvalidEncryptionAlgorithm:
+		writeErrorResponse(w, req, InvalidEncryptionAlgorithm, req.URL.Path)
+	case donut.InvalidEncryptionKey:
+		writeErrorResponse(w, req, InvalidSSECustomerKey, req.URL.Path)
+	default:
+		switch err.ToGoError() {
+		case errInsecureEncryptionRequest:
+			writeErrorResponse(w, req, InsecureSSECustomerRequest, req.URL.Path)
+		case errInvalidEncryptionAlgorithm:
+			writeErrorResponse(w, req, InvalidEncryptionAlgorithm, req.URL.Path)
+		case errInvalidEncryptionKey:
+			writeErrorResponse(w, req, InvalidSSECustomerKey, req.URL.Path)
+		case errEncryptionKeyMD5Mismatch:
+			writeErrorResponse(w, req, SSECustomerKeyMD5Mismatch, req.URL.Path)
+		case errInvalidEncryptionMethod:
+			writeErrorResponse(w, req, InvalidEncryptionMethod, req.URL.Path)
+		case errConflictingEncryption:
+			writeErrorResponse(w, req, SSEConflictingHeaders, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+	}
+}
+
+// setEncryptionHeaders - confirm encryption of the request in the response
+func setEncryptionHeaders(w http.ResponseWriter, req *http.Request, encryption *donut.Encryption) {
+	if encryption == nil {
+		return
+	}
+	if encryption.ServerAlgorithm != "" {
+		w.Header().Set(sseHeader, encryption.ServerAlgorithm)
+		return
+	}
+	w.Header().Set(sseCustomerAlgorithmHeader, encryption.CustomerAlgorithm)
+	w.Header().Set(sseCustomerKeyMD5Header, req.Header.Get(sseCustomerKeyMD5Header))
+}
+
+// setObjectEncryptionHeaders - encryption headers of an object, objects encrypted with customer provided
+// keys are confirmed with the key of the request
+func setObjectEncryptionHeaders(w http.ResponseWriter, req *http.Request, metadata donut.ObjectMetadata) {
+	switch {
+	case metadata.IsServerEncrypted():
+		w.Header().Set(sseHeader, metadata.EncryptionAlgorithm)
+	case metadata.IsEncrypted():
+		w.Header().Set(sseCustomerAlgorithmHeader, metadata.EncryptionAlgorithm)
+		w.Header().Set(sseCustomerKeyMD5Header, req.Header.Get(sseCustomerKeyMD5Header))
+	}
+}
@@ -71,11 +71,36 @@ const (
 	InvalidPartOrder
 	AuthorizationHeaderMalformed
 	MalformedPOSTRequest
+	NoSuchCORSConfiguration
+	CORSForbidden
+	InvalidTag
+	NoSuchTagSet
+	InvalidTargetARN
+	InvalidEventName
+	NoSuchWebsiteConfiguration
+	AuthorizationQueryParametersError
+	InvalidLocationConstraint
+	MetadataTooLarge
+	ExpiredToken
+	InvalidToken
+	InsecureSSECustomerRequest
+	InvalidEncryptionAlgorithm
+	InvalidSSECustomerKey
+	SSECustomerKeyMD5Mismatch
+	SSECustomerKeyMissing
+	SSECustomerKeyMismatch
+	SSEObjectNotEncrypted
+	InvalidEncryptionMethod
+	SSEConflictingHeaders
+	NoSuchEncryptionConfiguration
+	InvalidContinuationToken
+	InvalidEncodingType
+	InvalidRedirectLocation
 )
 
 // Error codes, non exhaustive list - standard HTTP errors
 const (
-	NotAcceptable = iota + 31
+	NotAcceptable = iota + 56
 )
 
 // APIError code to Error structure map
@@ -100,6 +125,21 @@ var errorCodeResponse = map[int]APIError{
 		Description:    "Argument partNumberMarker must be an integer.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	InvalidContinuationToken: {
+		Code:           "InvalidArgument",
+		Description:    "The continuation token provided is incorrect.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InvalidEncodingType: {
+		Code:           "InvalidArgument",
+		Description:    "Invalid Encoding Method specified in Request.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InvalidRedirectLocation: {
+		Code:           "InvalidRedirectLocation",
+		Description:    "The website redirect location must have a prefix of 'http://' or 'https://' or '/'.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 	AccessDenied: {
 		Code:           "AccessDenied",
 		Description:    "Access Denied.",
@@ -227,7 +267,7 @@ var errorCodeResponse = map[int]APIError{
 	},
 	AuthorizationHeaderMalformed: {
 		Code:           "AuthorizationHeaderMalformed",
-		Description:    "The authorization header is malformed; the region is wrong; expecting 'milkyway'.",
+		Description:    "The authorization header is malformed; the region is wrong.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
 	MalformedPOSTRequest: {
@@ -235,6 +275,116 @@ var errorCodeResponse = map[int]APIError{
 		Description:    "The body of your POST request is not well-formed multipart/form-data.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	NoSuchCORSConfiguration: {
+		Code:           "NoSuchCORSConfiguration",
+		Description:    "The CORS configuration does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	CORSForbidden: {
+		Code:           "AccessForbidden",
+		Description:    "CORSResponse: This CORS request is not allowed.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	InvalidTag: {
+		Code:           "InvalidTag",
+		Description:    "The tag provided was not a valid tag.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	NoSuchTagSet: {
+		Code:           "NoSuchTagSet",
+		Description:    "The TagSet does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	InvalidTargetARN: {
+		Code:           "InvalidArgument",
+		Description:    "A specified destination ARN does not exist or is not well-formed.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InvalidEventName: {
+		Code:           "InvalidArgument",
+		Description:    "The event is not supported for notifications.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	NoSuchWebsiteConfiguration: {
+		Code:           "NoSuchWebsiteConfiguration",
+		Description:    "The specified bucket does not have a website configuration.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	AuthorizationQueryParametersError: {
+		Code:           "AuthorizationQueryParametersError",
+		Description:    "Error parsing the X-Amz-Credential parameter; the region is wrong.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InvalidLocationConstraint: {
+		Code:           "InvalidLocationConstraint",
+		Description:    "The specified location constraint is not valid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	MetadataTooLarge: {
+		Code:           "MetadataTooLarge",
+		Description:    "Your metadata headers exceed the maximum allowed metadata size.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ExpiredToken: {
+		Code:           "ExpiredToken",
+		Description:    "The provided token has expired.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InvalidToken: {
+		Code:           "InvalidToken",
+		Description:    "The provided token is malformed or otherwise invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InsecureSSECustomerRequest: {
+		Code:           "InvalidRequest",
+		Description:    "Requests specifying Server Side Encryption with Customer provided keys must be made over a secure connection.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InvalidEncryptionAlgorithm: {
+		Code:           "InvalidEncryptionAlgorithmError",
+		Description:    "The encryption request you specified is not valid. The valid value is AES256.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InvalidSSECustomerKey: {
+		Code:           "InvalidArgument",
+		Description:    "The secret key was invalid for the specified algorithm.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	SSECustomerKeyMD5Mismatch: {
+		Code:           "InvalidArgument",
+		Description:    "The calculated MD5 hash of the key did not match the hash that was provided.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	SSECustomerKeyMissing: {
+		Code:           "InvalidRequest",
+		Description:    "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	SSECustomerKeyMismatch: {
+		Code:           "AccessDenied",
+		Description:    "The provided encryption key does not match the key the object was encrypted with.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	SSEObjectNotEncrypted: {
+		Code:           "InvalidRequest",
+		Description:    "The encryption parameters are not applicable to this object.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	InvalidEncryptionMethod: {
+		Code:           "InvalidArgument",
+		Description:    "The encryption method specified is not supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	SSEConflictingHeaders: {
+		Code:           "InvalidArgument",
+		Description:    "Server side encryption specified with both SSE-C and SSE-S3 headers.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	NoSuchEncryptionConfiguration: {
+		Code:           "ServerSideEncryptionConfigurationNotFoundError",
+		Description:    "The server side encryption configuration was not found.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
 }
 
 // errorCodeError provides errorCode to Error. It returns empty if the code provided is unknown
@@ -80,6 +80,10 @@ func setObjectHeaders(w http.ResponseWriter, metadata donut.ObjectMetadata, cont
 	w.Header().Set("Content-Type", metadata.Metadata["contentType"])
 	w.Header().Set("ETag", "\""+metadata.MD5Sum+"\"")
 	w.Header().Set("Last-Modified", lastModified)
+	if location, ok := metadata.Metadata[websiteRedirectMetadataKey]; ok {
+		w.Header().Set("x-amz-website-redirect-location", location)
+	}
+	setMetadataHeaders(w, metadata.Metadata)
 
 	// set content range
 	if contentRange != nil {
@@ -0,0 +1,104 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Please read for more information - http://docs.aws.amazon.com/AmazonS3/latest/dev/UsingMetadata.html
+//
+// User metadata and standard headers are saved in object metadata under their canonical header
+// name, for example 'X-Amz-Meta-Color' or 'Cache-Control'. Content type is saved as 'contentType'.
+
+// userMetadataPrefix - prefix of user-defined metadata headers
+const userMetadataPrefix = "X-Amz-Meta-"
+
+// maximum size of user-defined metadata, sum of the sizes of all keys and values
+const maxUserMetadataSize = 2 * 1024
+
+// list of standard headers saved along with the object
+var standardMetadataHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Expires",
+}
+
+// isUserMetadata - returns true if header carries user-defined metadata
+func isUserMetadata(key string) bool {
+	return strings.HasPrefix(http.CanonicalHeaderKey(key), userMetadataPrefix)
+}
+
+// extractMetadata - user-defined and standard metadata from request headers or form values
+func extractMetadata(header http.Header) (map[string]string, *probe.Error) {
+	metadata := make(map[string]string)
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		metadata["contentType"] = contentType
+	}
+	for _, key := range standardMetadataHeaders {
+		if value := header.Get(key); value != "" {
+			metadata[key] = value
+		}
+	}
+	// 'aws-chunked' only describes how the payload was sent, it is not saved
+	if encoding, ok := metadata["Content-Encoding"]; ok {
+		var encodings []string
+		for _, value := range strings.Split(encoding, ",") {
+			if value = strings.TrimSpace(value); value != "aws-chunked" {
+				encodings = append(encodings, value)
+			}
+		}
+		if len(encodings) == 0 {
+			delete(metadata, "Content-Encoding")
+		} else {
+			metadata["Content-Encoding"] = strings.Join(encodings, ",")
+		}
+	}
+	var size int
+	for key, values := range header {
+		if !isUserMetadata(key) {
+			continue
+		}
+		key = http.CanonicalHeaderKey(key)
+		value := strings.Join(values, ",")
+		size += len(strings.TrimPrefix(key, userMetadataPrefix)) + len(value)
+		if size > maxUserMetadataSize {
+			return nil, probe.NewError(errMetadataTooLarge)
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+// setMetadataHeaders - write user-defined and standard metadata of an object
+func setMetadataHeaders(w http.ResponseWriter, metadata map[string]string) {
+	for _, key := range standardMetadataHeaders {
+		if value, ok := metadata[key]; ok {
+			w.Header().Set(key, value)
+		}
+	}
+	for key, value := range metadata {
+		if isUserMetadata(key) {
+			w.Header().Set(key, value)
+		}
+	}
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 
@@ -48,6 +49,15 @@ func (api API) GetObjectHandler(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	bucket = vars["bucket"]
 	object = vars["object"]
+	if !api.isAllowed(req, bucket, object, readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+	encryption, err := api.getRequestEncryption(req)
+	if err != nil {
+		writeEncryptionErrorResponse(w, req, err)
+		return
+	}
 
 	metadata, err := api.Donut.GetObjectMetadata(bucket, object)
 	if err != nil {
@@ -66,14 +76,19 @@ func (api API) GetObjectHandler(w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
+	if err := donut.VerifyEncryption(metadata, encryption); err != nil {
+		writeEncryptionErrorResponse(w, req, err)
+		return
+	}
 	var hrange *httpRange
 	hrange, err = getRequestedRange(req.Header.Get("Range"), metadata.Size)
 	if err != nil {
 		writeErrorResponse(w, req, InvalidRange, req.URL.Path)
 		return
 	}
+	setObjectEncryptionHeaders(w, req, metadata)
 	setObjectHeaders(w, metadata, hrange)
-	if _, err = api.Donut.GetObject(w, bucket, object, hrange.start, hrange.length); err != nil {
+	if _, err = api.Donut.GetObject(w, bucket, object, hrange.start, hrange.length, encryption); err != nil {
 		errorIf(err.Trace(), "GetObject failed.", nil)
 		return
 	}
@@ -96,6 +111,15 @@ func (api API) HeadObjectHandler(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	bucket = vars["bucket"]
 	object = vars["object"]
+	if !api.isAllowed(req, bucket, object, readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+	encryption, err := api.getRequestEncryption(req)
+	if err != nil {
+		writeEncryptionErrorResponse(w, req, err)
+		return
+	}
 
 	metadata, err := api.Donut.GetObjectMetadata(bucket, object)
 	if err != nil {
@@ -114,6 +138,11 @@ func (api API) HeadObjectHandler(w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
+	if err := donut.VerifyEncryption(metadata, encryption); err != nil {
+		writeEncryptionErrorResponse(w, req, err)
+		return
+	}
+	setObjectEncryptionHeaders(w, req, metadata)
 	setObjectHeaders(w, metadata, nil)
 	w.WriteHeader(http.StatusOK)
 }
@@ -135,6 +164,10 @@ func (api API) PutObjectHandler(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	bucket = vars["bucket"]
 	object = vars["object"]
+	if !api.isAllowed(req, bucket, object, writePermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	// get Content-MD5 sent by client and verify if valid
 	md5 := req.Header.Get("Content-MD5")
@@ -148,6 +181,14 @@ func (api API) PutObjectHandler(w http.ResponseWriter, req *http.Request) {
 		writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
 		return
 	}
+	/// payload in aws-chunked encoding carries its decoded size separately
+	if signv4.IsStreamingPayload(req) {
+		size = req.Header.Get("X-Amz-Decoded-Content-Length")
+		if size == "" {
+			writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
+			return
+		}
+	}
 	/// maximum Upload size for objects in a single operation
 	if isMaxObjectSize(size) {
 		writeErrorResponse(w, req, EntityTooLarge, req.URL.Path)
@@ -173,12 +214,18 @@ func (api API) PutObjectHandler(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	encryption, perr := api.getRequestEncryption(req)
+	if perr != nil {
+		writeEncryptionErrorResponse(w, req, perr)
+		return
+	}
+
 	var signature *signv4.Signature
 	if !api.Anonymous {
-		if _, ok := req.Header["Authorization"]; ok {
+		if isRequestSignatureV4(req) {
 			// Init signature V4 verification
 			var err *probe.Error
-			signature, err = initSignatureV4(req)
+			signature, err = initSignatureV4(req, api.Region)
 			if err != nil {
 				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
 				writeErrorResponse(w, req, InternalError, req.URL.Path)
@@ -187,7 +234,58 @@ func (api API) PutObjectHandler(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	metadata, err := api.Donut.CreateObject(bucket, object, md5, sizeInt64, req.Body, nil, signature)
+	var payload io.Reader = req.Body
+	if signv4.IsStreamingPayload(req) {
+		var err *probe.Error
+		payload, err = signv4.NewChunkedReader(req.Body, signature)
+		if err != nil {
+			errorIf(err.Trace(), "Initializing aws-chunked payload failed.", nil)
+			switch err.ToGoError().(type) {
+			case signv4.DoesNotMatch:
+				writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			case signv4.MissingDateHeader:
+				writeErrorResponse(w, req, RequestTimeTooSkewed, req.URL.Path)
+			default:
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+			}
+			return
+		}
+		// chunk signatures are verified while reading the payload
+		signature = nil
+	}
+	if signature != nil && signv4.IsUnsignedPayload(req) {
+		// payload is not signed, verify headers only and do not hash the payload
+		ok, err := signature.DoesSignatureMatch(signv4.UnsignedPayload)
+		if err != nil {
+			errorIf(err.Trace(), "Unable to verify signature.", nil)
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+			return
+		}
+		if !ok {
+			writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			return
+		}
+		signature = nil
+	}
+
+	tags, err := getRequestTags(req)
+	if err != nil {
+		writeErrorResponse(w, req, InvalidTag, req.URL.Path)
+		return
+	}
+	objectMetadata, err := extractMetadata(req.Header)
+	if err != nil {
+		writeErrorResponse(w, req, MetadataTooLarge, req.URL.Path)
+		return
+	}
+	objectMetadata[donut.TaggingMetadataKey] = tags.Encode()
+	location := req.Header.Get("x-amz-website-redirect-location")
+	if location != "" && !isValidRedirectLocation(location) {
+		writeErrorResponse(w, req, InvalidRedirectLocation, req.URL.Path)
+		return
+	}
+	objectMetadata[websiteRedirectMetadataKey] = location
+	metadata, err := api.Donut.CreateObject(bucket, object, md5, sizeInt64, payload, objectMetadata, signature, encryption)
 	if err != nil {
 		errorIf(err.Trace(), "CreateObject failed.", nil)
 		switch err.ToGoError().(type) {
@@ -203,21 +301,215 @@ func (api API) PutObjectHandler(w http.ResponseWriter, req *http.Request) {
 			writeErrorResponse(w, req, RequestTimeTooSkewed, req.URL.Path)
 		case signv4.DoesNotMatch:
 			writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
-		case donut.IncompleteBody:
+		case donut.IncompleteBody, signv4.MalformedChunk:
 			writeErrorResponse(w, req, IncompleteBody, req.URL.Path)
 		case donut.EntityTooLarge:
 			writeErrorResponse(w, req, EntityTooLarge, req.URL.Path)
 		case donut.InvalidDigest:
 			writeErrorResponse(w, req, InvalidDigest, req.URL.Path)
+		case donut.KeyStoreNotConfigured:
+			writeEncryptionErrorResponse(w, req, err)
 		default:
 			writeErrorResponse(w, req, InternalError, req.URL.Path)
 		}
 		return
 	}
+	setObjectEncryptionHeaders(w, req, metadata)
 	w.Header().Set("ETag", metadata.MD5Sum)
 	writeSuccessResponse(w)
 }
 
+// PutObjectTaggingHandler - PUT Object tagging
+// ----------
+// This implementation of the PUT operation sets the tag set for an
+// object, an existing tag set is replaced.
+func (api API) PutObjectTaggingHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	object := vars["object"]
+	if !api.isAllowed(req, bucket, object, writePermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	var signature *signv4.Signature
+	if !api.Anonymous {
+		if isRequestSignatureV4(req) {
+			// Init signature V4 verification
+			var err *probe.Error
+			signature, err = initSignatureV4(req, api.Region)
+			if err != nil {
+				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+				return
+			}
+		}
+	}
+
+	if req.Body == nil {
+		writeErrorResponse(w, req, MissingRequestBodyError, req.URL.Path)
+		return
+	}
+	/// if Content-Length missing, deny the request
+	if req.Header.Get("Content-Length") == "" {
+		writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
+		return
+	}
+	taggingBytes, err := readSignedPayload(req, maxTaggingSize, signature)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to read tagging.", nil)
+		switch err.ToGoError() {
+		case errPayloadTooLarge:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		default:
+			switch err.ToGoError().(type) {
+			case signv4.DoesNotMatch:
+				writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			default:
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+			}
+		}
+		return
+	}
+	tags, err := parseTagging(taggingBytes)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to parse tagging.", nil)
+		switch err.ToGoError().(type) {
+		case donut.InvalidTag:
+			writeErrorResponse(w, req, InvalidTag, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, MalformedXML, req.URL.Path)
+		}
+		return
+	}
+	if err := donut.IsValidObjectTags(tags); err != nil {
+		writeErrorResponse(w, req, InvalidTag, req.URL.Path)
+		return
+	}
+
+	err = api.Donut.SetObjectMetadata(bucket, object, map[string]string{donut.TaggingMetadataKey: tags.Encode()})
+	if err != nil {
+		errorIf(err.Trace(), "PutObjectTagging failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.ObjectNotFound:
+			writeErrorResponse(w, req, NoSuchKey, req.URL.Path)
+		case donut.ObjectNameInvalid:
+			writeErrorResponse(w, req, NoSuchKey, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	writeSuccessResponse(w)
+}
+
+// GetObjectTaggingHandler - GET Object tagging
+// ----------
+// This operation uses tagging subresource to return the tag set of an object.
+func (api API) GetObjectTaggingHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	object := vars["object"]
+	if !api.isAllowed(req, bucket, object, readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	metadata, err := api.Donut.GetObjectMetadata(bucket, object)
+	if err != nil {
+		errorIf(err.Trace(), "GetObjectMetadata failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.ObjectNotFound:
+			writeErrorResponse(w, req, NoSuchKey, req.URL.Path)
+		case donut.ObjectNameInvalid:
+			writeErrorResponse(w, req, NoSuchKey, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	tags, err := donut.DecodeTags(metadata.Metadata[donut.TaggingMetadataKey])
+	if err != nil {
+		errorIf(err.Trace(), "Unable to decode object tags.", nil)
+		writeErrorResponse(w, req, InternalError, req.URL.Path)
+		return
+	}
+	response := generateTaggingResponse(tags)
+	encodedSuccessResponse := encodeSuccessResponse(response)
+	// write headers
+	setCommonHeaders(w, len(encodedSuccessResponse))
+	// write body
+	w.Write(encodedSuccessResponse)
+}
+
+// DeleteObjectTaggingHandler - DELETE Object tagging
+// ----------
+// This operation removes the tag set of an object.
+func (api API) DeleteObjectTaggingHandler(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	vars := mux.Vars(req)
+	bucket := vars["bucket"]
+	object := vars["object"]
+	if !api.isAllowed(req, bucket, object, writePermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+
+	err := api.Donut.SetObjectMetadata(bucket, object, map[string]string{donut.TaggingMetadataKey: ""})
+	if err != nil {
+		errorIf(err.Trace(), "DeleteObjectTagging failed.", nil)
+		switch err.ToGoError().(type) {
+		case donut.BucketNameInvalid:
+			writeErrorResponse(w, req, InvalidBucketName, req.URL.Path)
+		case donut.BucketNotFound:
+			writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		case donut.ObjectNotFound:
+			writeErrorResponse(w, req, NoSuchKey, req.URL.Path)
+		case donut.ObjectNameInvalid:
+			writeErrorResponse(w, req, NoSuchKey, req.URL.Path)
+		default:
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+		}
+		return
+	}
+	setCommonHeaders(w, 0)
+	w.WriteHeader(http.StatusNoContent)
+}
+
 /// Multipart API
 
 // NewMultipartUploadHandler - New multipart upload
@@ -240,13 +532,41 @@ func (api API) NewMultipartUploadHandler(w http.ResponseWriter, req *http.Reques
 	vars := mux.Vars(req)
 	bucket = vars["bucket"]
 	object = vars["object"]
+	if !api.isAllowed(req, bucket, object, writePermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
-	uploadID, err := api.Donut.NewMultipartUpload(bucket, object, req.Header.Get("Content-Type"))
+	tags, err := getRequestTags(req)
+	if err != nil {
+		writeErrorResponse(w, req, InvalidTag, req.URL.Path)
+		return
+	}
+	metadata, err := extractMetadata(req.Header)
+	if err != nil {
+		writeErrorResponse(w, req, MetadataTooLarge, req.URL.Path)
+		return
+	}
+	metadata[donut.TaggingMetadataKey] = tags.Encode()
+	location := req.Header.Get("x-amz-website-redirect-location")
+	if location != "" && !isValidRedirectLocation(location) {
+		writeErrorResponse(w, req, InvalidRedirectLocation, req.URL.Path)
+		return
+	}
+	metadata[websiteRedirectMetadataKey] = location
+	encryption, err := api.getRequestEncryption(req)
+	if err != nil {
+		writeEncryptionErrorResponse(w, req, err)
+		return
+	}
+	uploadID, err := api.Donut.NewMultipartUpload(bucket, object, metadata, encryption)
 	if err != nil {
 		errorIf(err.Trace(), "NewMultipartUpload failed.", nil)
 		switch err.ToGoError().(type) {
 		case donut.ObjectExists:
 			writeErrorResponse(w, req, MethodNotAllowed, req.URL.Path)
+		case donut.KeyStoreNotConfigured:
+			writeEncryptionErrorResponse(w, req, err)
 		default:
 			writeErrorResponse(w, req, InternalError, req.URL.Path)
 		}
@@ -256,6 +576,7 @@ func (api API) NewMultipartUploadHandler(w http.ResponseWriter, req *http.Reques
 	response := generateInitiateMultipartUploadResponse(bucket, object, uploadID)
 	encodedSuccessResponse := encodeSuccessResponse(response)
 	// write headers
+	setEncryptionHeaders(w, req, encryption)
 	setCommonHeaders(w, len(encodedSuccessResponse))
 	// write body
 	w.Write(encodedSuccessResponse)
@@ -285,6 +606,14 @@ func (api API) PutObjectPartHandler(w http.ResponseWriter, req *http.Request) {
 		writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
 		return
 	}
+	/// payload in aws-chunked encoding carries its decoded size separately
+	if signv4.IsStreamingPayload(req) {
+		size = req.Header.Get("X-Amz-Decoded-Content-Length")
+		if size == "" {
+			writeErrorResponse(w, req, MissingContentLength, req.URL.Path)
+			return
+		}
+	}
 
 	/// maximum Upload size for multipart objects in a single operation
 	if isMaxObjectSize(size) {
@@ -305,6 +634,10 @@ func (api API) PutObjectPartHandler(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
 	object := vars["object"]
+	if !api.isAllowed(req, bucket, object, writePermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	uploadID := req.URL.Query().Get("uploadId")
 	partIDString := req.URL.Query().Get("partNumber")
@@ -319,12 +652,18 @@ func (api API) PutObjectPartHandler(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	encryption, perr := api.getRequestEncryption(req)
+	if perr != nil {
+		writeEncryptionErrorResponse(w, req, perr)
+		return
+	}
+
 	var signature *signv4.Signature
 	if !api.Anonymous {
-		if _, ok := req.Header["Authorization"]; ok {
+		if isRequestSignatureV4(req) {
 			// Init signature V4 verification
 			var err *probe.Error
-			signature, err = initSignatureV4(req)
+			signature, err = initSignatureV4(req, api.Region)
 			if err != nil {
 				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
 				writeErrorResponse(w, req, InternalError, req.URL.Path)
@@ -333,10 +672,46 @@ func (api API) PutObjectPartHandler(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	calculatedMD5, err := api.Donut.CreateObjectPart(bucket, object, uploadID, partID, "", md5, sizeInt64, req.Body, signature)
+	var payload io.Reader = req.Body
+	if signv4.IsStreamingPayload(req) {
+		var err *probe.Error
+		payload, err = signv4.NewChunkedReader(req.Body, signature)
+		if err != nil {
+			errorIf(err.Trace(), "Initializing aws-chunked payload failed.", nil)
+			switch err.ToGoError().(type) {
+			case signv4.DoesNotMatch:
+				writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			case signv4.MissingDateHeader:
+				writeErrorResponse(w, req, RequestTimeTooSkewed, req.URL.Path)
+			default:
+				writeErrorResponse(w, req, InternalError, req.URL.Path)
+			}
+			return
+		}
+		// chunk signatures are verified while reading the payload
+		signature = nil
+	}
+	if signature != nil && signv4.IsUnsignedPayload(req) {
+		// payload is not signed, verify headers only and do not hash the payload
+		ok, err := signature.DoesSignatureMatch(signv4.UnsignedPayload)
+		if err != nil {
+			errorIf(err.Trace(), "Unable to verify signature.", nil)
+			writeErrorResponse(w, req, InternalError, req.URL.Path)
+			return
+		}
+		if !ok {
+			writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
+			return
+		}
+		signature = nil
+	}
+
+	calculatedMD5, err := api.Donut.CreateObjectPart(bucket, object, uploadID, partID, "", md5, sizeInt64, payload, signature, encryption)
 	if err != nil {
 		errorIf(err.Trace(), "CreateObjectPart failed.", nil)
 		switch err.ToGoError().(type) {
+		case donut.EncryptionKeyMissing, donut.EncryptionKeyMismatch, donut.ObjectNotEncrypted, donut.ServerEncryptedObject:
+			writeEncryptionErrorResponse(w, req, err)
 		case donut.InvalidUploadID:
 			writeErrorResponse(w, req, NoSuchUpload, req.URL.Path)
 		case donut.ObjectExists:
@@ -345,7 +720,7 @@ func (api API) PutObjectPartHandler(w http.ResponseWriter, req *http.Request) {
 			writeErrorResponse(w, req, BadDigest, req.URL.Path)
 		case signv4.DoesNotMatch:
 			writeErrorResponse(w, req, SignatureDoesNotMatch, req.URL.Path)
-		case donut.IncompleteBody:
+		case donut.IncompleteBody, signv4.MalformedChunk:
 			writeErrorResponse(w, req, IncompleteBody, req.URL.Path)
 		case donut.EntityTooLarge:
 			writeErrorResponse(w, req, EntityTooLarge, req.URL.Path)
@@ -356,6 +731,7 @@ func (api API) PutObjectPartHandler(w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
+	setEncryptionHeaders(w, req, encryption)
 	w.Header().Set("ETag", calculatedMD5)
 	writeSuccessResponse(w)
 }
@@ -374,6 +750,10 @@ func (api API) AbortMultipartUploadHandler(w http.ResponseWriter, req *http.Requ
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
 	object := vars["object"]
+	if !api.isAllowed(req, bucket, object, writePermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	objectResourcesMetadata := getObjectResources(req.URL.Query())
 
@@ -419,6 +799,10 @@ func (api API) ListObjectPartsHandler(w http.ResponseWriter, req *http.Request)
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
 	object := vars["object"]
+	if !api.isAllowed(req, bucket, object, readPermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	objectResourcesMetadata, err := api.Donut.ListObjectParts(bucket, object, objectResourcesMetadata)
 	if err != nil {
@@ -431,7 +815,7 @@ func (api API) ListObjectPartsHandler(w http.ResponseWriter, req *http.Request)
 		}
 		return
 	}
-	response := generateListPartsResponse(objectResourcesMetadata)
+	response := generateListPartsResponse(objectResourcesMetadata, api.getBucketOwner(bucket))
 	encodedSuccessResponse := encodeSuccessResponse(response)
 	// write headers
 	setCommonHeaders(w, len(encodedSuccessResponse))
@@ -453,15 +837,19 @@ func (api API) CompleteMultipartUploadHandler(w http.ResponseWriter, req *http.R
 	vars := mux.Vars(req)
 	bucket := vars["bucket"]
 	object := vars["object"]
+	if !api.isAllowed(req, bucket, object, writePermission) {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
 
 	objectResourcesMetadata := getObjectResources(req.URL.Query())
 
 	var signature *signv4.Signature
 	if !api.Anonymous {
-		if _, ok := req.Header["Authorization"]; ok {
+		if isRequestSignatureV4(req) {
 			// Init signature V4 verification
 			var err *probe.Error
-			signature, err = initSignatureV4(req)
+			signature, err = initSignatureV4(req, api.Region)
 			if err != nil {
 				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
 				writeErrorResponse(w, req, InternalError, req.URL.Path)
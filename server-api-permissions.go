@@ -0,0 +1,169 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Users are granted access to buckets they own, created buckets are owned by their creator. Besides
+// owned buckets a user may access resources listed in its 'buckets' entries, either a whole bucket
+// as 'bucket' or objects under a prefix as 'bucket/prefix'. Admin users have full access to every
+// bucket, read-only users may never write.
+
+// permission - permission required by an API operation
+type permission int
+
+const (
+	// read objects and bucket metadata
+	readPermission permission = iota
+	// create, overwrite and delete objects
+	writePermission
+	// change bucket configuration or delete the bucket, granted only to owner
+	ownerPermission
+)
+
+// getRequestAccessKeyID - access key id of a request signed with any of the supported signatures
+func getRequestAccessKeyID(req *http.Request) (string, *probe.Error) {
+	switch {
+	case isRequestSignatureV4(req):
+		credentialElements, err := getCredentialsFromAuth(req.Header.Get("Authorization"))
+		if err != nil {
+			return "", err.Trace()
+		}
+		return credentialElements[0], nil
+	case isRequestPresignedSignatureV4(req):
+		return strings.Split(req.URL.Query().Get("X-Amz-Credential"), "/")[0], nil
+	case isRequestSignatureV2(req):
+		accessKeyID, _, err := getCredentialsFromAuthV2(req.Header.Get("Authorization"))
+		if err != nil {
+			return "", err.Trace()
+		}
+		return accessKeyID, nil
+	case isRequestPresignedSignatureV2(req):
+		return req.URL.Query().Get("AWSAccessKeyId"), nil
+	}
+	return "", probe.NewError(errMissingAuthHeaderValue)
+}
+
+// getAuthUser - user owning access key id, temporary credentials resolve to the user they act for
+func getAuthUser(accessKeyID string) (*AuthUser, *probe.Error) {
+	authConfig, err := LoadConfig()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	if user, ok := findAuthUser(authConfig, accessKeyID); ok {
+		return user, nil
+	}
+	if session, ok := authConfig.Sessions[accessKeyID]; ok {
+		return getSessionUser(authConfig, session)
+	}
+	return nil, probe.NewError(errAccessKeyIDInvalid)
+}
+
+// getRequestUser - user the request is signed by
+func getRequestUser(req *http.Request) (*AuthUser, *probe.Error) {
+	accessKeyID, err := getRequestAccessKeyID(req)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	user, err := getAuthUser(accessKeyID)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	return user, nil
+}
+
+// isUserAllowed - returns true if user may access object, or objects under prefix, of bucket with permission
+func (api API) isUserAllowed(user *AuthUser, bucket, object string, perm permission) bool {
+	// policy of temporary credentials applies to admins as well
+	if user.Session != nil && !user.Session.isAllowed(bucket, object, perm) {
+		return false
+	}
+	if user.Admin {
+		return true
+	}
+	if user.ReadOnly && perm != readPermission {
+		return false
+	}
+	bucketMetadata, err := api.Donut.GetBucketMetadata(bucket)
+	if err != nil {
+		// missing buckets are reported by the handlers as usual
+		return true
+	}
+	// buckets created anonymously or before ownership was recorded are owned by admins only
+	if bucketMetadata.Owner != "" && bucketMetadata.Owner == user.Name {
+		return true
+	}
+	if perm == ownerPermission {
+		return false
+	}
+	return isResourceListed(user.Buckets, bucket, object)
+}
+
+// isResourceListed - returns true if object, or objects under prefix, of bucket match any
+// of 'bucket' or 'bucket/prefix' resources
+func isResourceListed(resources []string, bucket, object string) bool {
+	for _, resource := range resources {
+		resourceBucket, resourcePrefix := resource, ""
+		if i := strings.Index(resource, "/"); i != -1 {
+			resourceBucket, resourcePrefix = resource[:i], resource[i+1:]
+		}
+		if resourceBucket == bucket && strings.HasPrefix(object, resourcePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowed - returns true if the user signing the request may access object, or objects
+// under prefix, of bucket with permission, all requests are allowed for anonymous servers
+func (api API) isAllowed(req *http.Request, bucket, object string, perm permission) bool {
+	if api.Anonymous {
+		return true
+	}
+	user, err := getRequestUser(req)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to find user of the request.", nil)
+		return false
+	}
+	return api.isUserAllowed(user, bucket, object, perm)
+}
+
+// getOwner - owner of a bucket for responses, buckets created anonymously or before
+// ownership was recorded are owned by 'minio'
+func getOwner(name string) Owner {
+	if name == "" {
+		name = "minio"
+	}
+	return Owner{
+		ID:          name,
+		DisplayName: name,
+	}
+}
+
+// getBucketOwner - owner of bucket for responses
+func (api API) getBucketOwner(bucket string) Owner {
+	bucketMetadata, err := api.Donut.GetBucketMetadata(bucket)
+	if err != nil {
+		return getOwner("")
+	}
+	return getOwner(bucketMetadata.Owner)
+}
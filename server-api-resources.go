@@ -17,6 +17,7 @@
 package main
 
 import (
+	"encoding/base64"
 	"net/url"
 	"strconv"
 
@@ -30,9 +31,26 @@ func getBucketResources(values url.Values) (v donut.BucketResourcesMetadata) {
 	v.Maxkeys, _ = strconv.Atoi(values.Get("max-keys"))
 	v.Delimiter = values.Get("delimiter")
 	v.EncodingType = values.Get("encoding-type")
+	v.StartAfter = values.Get("start-after")
+	v.ContinuationToken = values.Get("continuation-token")
+	v.FetchOwner = values.Get("fetch-owner") == "true"
 	return
 }
 
+// getContinuationToken - opaque token resuming a listing after given marker
+func getContinuationToken(marker string) string {
+	return base64.URLEncoding.EncodeToString([]byte(marker))
+}
+
+// getContinuationMarker - marker a continuation token resumes a listing after
+func getContinuationMarker(token string) (string, error) {
+	marker, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	return string(marker), nil
+}
+
 // part bucket url queries for ?uploads
 func getBucketMultipartResources(values url.Values) (v donut.BucketMultipartResourcesMetadata) {
 	v.Prefix = values.Get("prefix")
@@ -18,6 +18,7 @@ package main
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/minio/minio-xl/pkg/donut"
 )
@@ -33,13 +34,9 @@ const (
 //
 // output:
 // populated struct that can be serialized to match xml and json api spec output
-func generateListBucketsResponse(buckets []donut.BucketMetadata) ListBucketsResponse {
+func generateListBucketsResponse(buckets []donut.BucketMetadata, owner Owner) ListBucketsResponse {
 	var listbuckets []*Bucket
 	var data = ListBucketsResponse{}
-	var owner = Owner{}
-
-	owner.ID = "minio"
-	owner.DisplayName = "minio"
 
 	for _, bucket := range buckets {
 		var listbucket = &Bucket{}
@@ -55,15 +52,12 @@ func generateListBucketsResponse(buckets []donut.BucketMetadata) ListBucketsResp
 }
 
 // generates an AccessControlPolicy response for the said ACL.
-func generateAccessControlPolicyResponse(acl donut.BucketACL) AccessControlPolicyResponse {
+func generateAccessControlPolicyResponse(acl donut.BucketACL, owner Owner) AccessControlPolicyResponse {
 	accessCtrlPolicyResponse := AccessControlPolicyResponse{}
-	accessCtrlPolicyResponse.Owner = Owner{
-		ID:          "minio",
-		DisplayName: "minio",
-	}
+	accessCtrlPolicyResponse.Owner = owner
 	defaultGrant := Grant{}
-	defaultGrant.Grantee.ID = "minio"
-	defaultGrant.Grantee.DisplayName = "minio"
+	defaultGrant.Grantee.ID = owner.ID
+	defaultGrant.Grantee.DisplayName = owner.DisplayName
 	defaultGrant.Permission = "FULL_CONTROL"
 	accessCtrlPolicyResponse.AccessControlList.Grant = append(accessCtrlPolicyResponse.AccessControlList.Grant, defaultGrant)
 	switch {
@@ -92,15 +86,11 @@ func generateAccessControlPolicyResponse(acl donut.BucketACL) AccessControlPolic
 }
 
 // generates an ListObjects response for the said bucket with other enumerated options.
-func generateListObjectsResponse(bucket string, objects []donut.ObjectMetadata, bucketResources donut.BucketResourcesMetadata) ListObjectsResponse {
+func generateListObjectsResponse(bucket string, objects []donut.ObjectMetadata, bucketResources donut.BucketResourcesMetadata, owner Owner) ListObjectsResponse {
 	var contents []*Object
 	var prefixes []*CommonPrefix
-	var owner = Owner{}
 	var data = ListObjectsResponse{}
 
-	owner.ID = "minio"
-	owner.DisplayName = "minio"
-
 	for _, object := range objects {
 		var content = &Object{}
 		if object.Object == "" {
@@ -111,7 +101,7 @@ func generateListObjectsResponse(bucket string, objects []donut.ObjectMetadata,
 		content.ETag = "\"" + object.MD5Sum + "\""
 		content.Size = object.Size
 		content.StorageClass = "STANDARD"
-		content.Owner = owner
+		content.Owner = &owner
 		contents = append(contents, content)
 	}
 	// TODO - support EncodingType in xml decoding
@@ -121,7 +111,10 @@ func generateListObjectsResponse(bucket string, objects []donut.ObjectMetadata,
 	data.Prefix = bucketResources.Prefix
 	data.Delimiter = bucketResources.Delimiter
 	data.Marker = bucketResources.Marker
-	data.NextMarker = bucketResources.NextMarker
+	// next marker is only returned along with a delimiter, otherwise the last key is the next marker
+	if bucketResources.Delimiter != "" {
+		data.NextMarker = bucketResources.NextMarker
+	}
 	data.IsTruncated = bucketResources.IsTruncated
 	for _, prefix := range bucketResources.CommonPrefixes {
 		var prefixItem = &CommonPrefix{}
@@ -132,6 +125,56 @@ func generateListObjectsResponse(bucket string, objects []donut.ObjectMetadata,
 	return data
 }
 
+// encodeListName - encode an object name or prefix in a listing as asked by its encoding type
+func encodeListName(name, encodingType string) string {
+	if encodingType == "url" {
+		return url.QueryEscape(name)
+	}
+	return name
+}
+
+// generates an ListObjectsV2 response for the said bucket with other enumerated options.
+func generateListObjectsV2Response(bucket string, objects []donut.ObjectMetadata, bucketResources donut.BucketResourcesMetadata, owner Owner) ListObjectsV2Response {
+	var contents []*Object
+	var prefixes []*CommonPrefix
+	var data = ListObjectsV2Response{}
+
+	encodingType := bucketResources.EncodingType
+	for _, object := range objects {
+		var content = &Object{}
+		if object.Object == "" {
+			continue
+		}
+		content.Key = encodeListName(object.Object, encodingType)
+		content.LastModified = object.Created.Format(rfcFormat)
+		content.ETag = "\"" + object.MD5Sum + "\""
+		content.Size = object.Size
+		content.StorageClass = "STANDARD"
+		if bucketResources.FetchOwner {
+			content.Owner = &owner
+		}
+		contents = append(contents, content)
+	}
+	data.Name = bucket
+	data.Contents = contents
+	data.MaxKeys = bucketResources.Maxkeys
+	data.Prefix = encodeListName(bucketResources.Prefix, encodingType)
+	data.Delimiter = encodeListName(bucketResources.Delimiter, encodingType)
+	data.StartAfter = encodeListName(bucketResources.StartAfter, encodingType)
+	data.EncodingType = encodingType
+	data.ContinuationToken = bucketResources.ContinuationToken
+	data.NextContinuationToken = bucketResources.NextContinuationToken
+	data.IsTruncated = bucketResources.IsTruncated
+	for _, prefix := range bucketResources.CommonPrefixes {
+		var prefixItem = &CommonPrefix{}
+		prefixItem.Prefix = encodeListName(prefix, encodingType)
+		prefixes = append(prefixes, prefixItem)
+	}
+	data.CommonPrefixes = prefixes
+	data.KeyCount = len(contents) + len(prefixes)
+	return data
+}
+
 // generateInitiateMultipartUploadResponse
 func generateInitiateMultipartUploadResponse(bucket, key, uploadID string) InitiateMultipartUploadResponse {
 	return InitiateMultipartUploadResponse{
@@ -151,18 +194,26 @@ func generateCompleteMultpartUploadResponse(bucket, key, location, etag string)
 	}
 }
 
+// generatePostResponse
+func generatePostResponse(bucket, key, location, etag string) PostResponse {
+	return PostResponse{
+		Location: location,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     etag,
+	}
+}
+
 // generateListPartsResult
-func generateListPartsResponse(objectMetadata donut.ObjectResourcesMetadata) ListPartsResponse {
+func generateListPartsResponse(objectMetadata donut.ObjectResourcesMetadata, owner Owner) ListPartsResponse {
 	// TODO - support EncodingType in xml decoding
 	listPartsResponse := ListPartsResponse{}
 	listPartsResponse.Bucket = objectMetadata.Bucket
 	listPartsResponse.Key = objectMetadata.Key
 	listPartsResponse.UploadID = objectMetadata.UploadID
 	listPartsResponse.StorageClass = "STANDARD"
-	listPartsResponse.Initiator.ID = "minio"
-	listPartsResponse.Initiator.DisplayName = "minio"
-	listPartsResponse.Owner.ID = "minio"
-	listPartsResponse.Owner.DisplayName = "minio"
+	listPartsResponse.Initiator = Initiator(owner)
+	listPartsResponse.Owner = owner
 
 	listPartsResponse.MaxParts = objectMetadata.MaxParts
 	listPartsResponse.PartNumberMarker = objectMetadata.PartNumberMarker
@@ -182,7 +233,7 @@ func generateListPartsResponse(objectMetadata donut.ObjectResourcesMetadata) Lis
 }
 
 // generateListMultipartUploadsResponse
-func generateListMultipartUploadsResponse(bucket string, metadata donut.BucketMultipartResourcesMetadata) ListMultipartUploadsResponse {
+func generateListMultipartUploadsResponse(bucket string, metadata donut.BucketMultipartResourcesMetadata, owner Owner) ListMultipartUploadsResponse {
 	listMultipartUploadsResponse := ListMultipartUploadsResponse{}
 	listMultipartUploadsResponse.Bucket = bucket
 	listMultipartUploadsResponse.Delimiter = metadata.Delimiter
@@ -201,6 +252,8 @@ func generateListMultipartUploadsResponse(bucket string, metadata donut.BucketMu
 		newUpload.UploadID = upload.UploadID
 		newUpload.Key = upload.Key
 		newUpload.Initiated = upload.Initiated.Format(rfcFormat)
+		newUpload.Initiator = Initiator(owner)
+		newUpload.Owner = owner
 		listMultipartUploadsResponse.Upload = append(listMultipartUploadsResponse.Upload, newUpload)
 	}
 	return listMultipartUploadsResponse
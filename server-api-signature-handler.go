@@ -28,15 +28,33 @@ import (
 
 type signatureHandler struct {
 	handler http.Handler
+	region  string
 }
 
-// SignatureHandler to validate authorization header for the incoming request.
-func SignatureHandler(h http.Handler) http.Handler {
-	return signatureHandler{h}
+// SignatureHandler to validate authorization header for the incoming request, credentials
+// are accepted for the region of the server only
+func SignatureHandler(api API) MiddlewareHandler {
+	return func(h http.Handler) http.Handler {
+		return signatureHandler{handler: h, region: api.Region}
+	}
 }
 
 func isRequestSignatureV4(req *http.Request) bool {
 	if _, ok := req.Header["Authorization"]; ok {
+		return strings.HasPrefix(req.Header.Get("Authorization"), authHeaderPrefix)
+	}
+	return false
+}
+
+func isRequestSignatureV2(req *http.Request) bool {
+	if _, ok := req.Header["Authorization"]; ok {
+		return strings.HasPrefix(req.Header.Get("Authorization"), authHeaderPrefixV2+" ")
+	}
+	return false
+}
+
+func isRequestPresignedSignatureV2(req *http.Request) bool {
+	if _, ok := req.URL.Query()["AWSAccessKeyId"]; ok {
 		return ok
 	}
 	return false
@@ -66,28 +84,42 @@ func (s signatureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	var signature *signv4.Signature
 	if isRequestSignatureV4(r) {
+		// region is verified for all requests, including those verified upwards
+		if err := isValidRegion(r.Header.Get("Authorization"), s.region); err != nil && err.ToGoError() == errInvalidRegion {
+			errorIf(err.Trace(), "Unknown region in authorization header.", nil)
+			writeErrorResponse(w, r, AuthorizationHeaderMalformed, r.URL.Path)
+			return
+		}
+		// Init signature V4 verification, credentials are verified for all requests
+		var err *probe.Error
+		signature, err = initSignatureV4(r, s.region)
+		if err != nil {
+			switch err.ToGoError() {
+			case errInvalidRegion:
+				errorIf(err.Trace(), "Unknown region in authorization header.", nil)
+				writeErrorResponse(w, r, AuthorizationHeaderMalformed, r.URL.Path)
+				return
+			case errAccessKeyIDInvalid:
+				errorIf(err.Trace(), "Invalid access key id.", nil)
+				writeErrorResponse(w, r, InvalidAccessKeyID, r.URL.Path)
+				return
+			case errSessionExpired:
+				errorIf(err.Trace(), "Temporary credentials expired.", nil)
+				writeErrorResponse(w, r, ExpiredToken, r.URL.Path)
+				return
+			case errInvalidSessionToken:
+				errorIf(err.Trace(), "Invalid session token.", nil)
+				writeErrorResponse(w, r, InvalidToken, r.URL.Path)
+				return
+			default:
+				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
+				writeErrorResponse(w, r, InternalError, r.URL.Path)
+				return
+			}
+		}
 		// For PUT and POST requests with payload, send the call upwards for verification.
 		// Or PUT and POST requests without payload, verify here.
 		if (r.Body == nil && (r.Method == "PUT" || r.Method == "POST")) || (r.Method != "PUT" && r.Method != "POST") {
-			// Init signature V4 verification
-			var err *probe.Error
-			signature, err = initSignatureV4(r)
-			if err != nil {
-				switch err.ToGoError() {
-				case errInvalidRegion:
-					errorIf(err.Trace(), "Unknown region in authorization header.", nil)
-					writeErrorResponse(w, r, AuthorizationHeaderMalformed, r.URL.Path)
-					return
-				case errAccessKeyIDInvalid:
-					errorIf(err.Trace(), "Invalid access key id.", nil)
-					writeErrorResponse(w, r, InvalidAccessKeyID, r.URL.Path)
-					return
-				default:
-					errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
-					writeErrorResponse(w, r, InternalError, r.URL.Path)
-					return
-				}
-			}
 			ok, err := signature.DoesSignatureMatch(hex.EncodeToString(sha256.Sum256([]byte(""))))
 			if err != nil {
 				errorIf(err.Trace(), "Unable to verify signature.", nil)
@@ -104,13 +136,25 @@ func (s signatureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	if isRequestPresignedSignatureV4(r) {
 		var err *probe.Error
-		signature, err = initPresignedSignatureV4(r)
+		signature, err = initPresignedSignatureV4(r, s.region)
 		if err != nil {
 			switch err.ToGoError() {
+			case errInvalidRegion:
+				errorIf(err.Trace(), "Unknown region in credential.", nil)
+				writeErrorResponse(w, r, AuthorizationQueryParametersError, r.URL.Path)
+				return
 			case errAccessKeyIDInvalid:
 				errorIf(err.Trace(), "Invalid access key id requested.", nil)
 				writeErrorResponse(w, r, InvalidAccessKeyID, r.URL.Path)
 				return
+			case errSessionExpired:
+				errorIf(err.Trace(), "Temporary credentials expired.", nil)
+				writeErrorResponse(w, r, ExpiredToken, r.URL.Path)
+				return
+			case errInvalidSessionToken:
+				errorIf(err.Trace(), "Invalid session token.", nil)
+				writeErrorResponse(w, r, InvalidToken, r.URL.Path)
+				return
 			default:
 				errorIf(err.Trace(), "Initializing signature v4 failed.", nil)
 				writeErrorResponse(w, r, InternalError, r.URL.Path)
@@ -130,5 +174,51 @@ func (s signatureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.handler.ServeHTTP(w, r)
 		return
 	}
+	if isRequestSignatureV2(r) || isRequestPresignedSignatureV2(r) {
+		// payload is not part of signature v2, verify all requests here.
+		var signature *signv4.SignatureV2
+		var err *probe.Error
+		if isRequestSignatureV2(r) {
+			signature, err = initSignatureV2(r)
+		} else {
+			signature, err = initPresignedSignatureV2(r)
+		}
+		if err != nil {
+			switch err.ToGoError() {
+			case errAccessKeyIDInvalid:
+				errorIf(err.Trace(), "Invalid access key id.", nil)
+				writeErrorResponse(w, r, InvalidAccessKeyID, r.URL.Path)
+				return
+			default:
+				errorIf(err.Trace(), "Initializing signature v2 failed.", nil)
+				writeErrorResponse(w, r, AccessDenied, r.URL.Path)
+				return
+			}
+		}
+		var ok bool
+		if signature.Presigned {
+			ok, err = signature.DoesPresignedSignatureMatch()
+		} else {
+			ok, err = signature.DoesSignatureMatch()
+		}
+		if err != nil {
+			errorIf(err.Trace(), "Unable to verify signature.", nil)
+			switch err.ToGoError().(type) {
+			case signv4.ExpiredPresignedRequest, signv4.MissingExpiresQuery:
+				writeErrorResponse(w, r, AccessDenied, r.URL.Path)
+			case signv4.MissingDateHeader:
+				writeErrorResponse(w, r, RequestTimeTooSkewed, r.URL.Path)
+			default:
+				writeErrorResponse(w, r, InternalError, r.URL.Path)
+			}
+			return
+		}
+		if !ok {
+			writeErrorResponse(w, r, SignatureDoesNotMatch, r.URL.Path)
+			return
+		}
+		s.handler.ServeHTTP(w, r)
+		return
+	}
 	writeErrorResponse(w, r, AccessDenied, r.URL.Path)
 }
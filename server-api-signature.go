@@ -17,8 +17,8 @@
 package main
 
 import (
-	"bytes"
 	"encoding/base64"
+	"encoding/hex"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
@@ -26,14 +26,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/minio/minio-xl/pkg/crypto/sha256"
 	"github.com/minio/minio-xl/pkg/probe"
 	signv4 "github.com/minio/minio-xl/pkg/signature"
 )
 
 const (
-	authHeaderPrefix = "AWS4-HMAC-SHA256"
-	iso8601Format    = "20060102T150405Z"
-	yyyymmdd         = "20060102"
+	authHeaderPrefix   = "AWS4-HMAC-SHA256"
+	authHeaderPrefixV2 = "AWS"
+	iso8601Format      = "20060102T150405Z"
+	yyyymmdd           = "20060102"
 )
 
 // getCredentialsFromAuth parse credentials tag from authorization value
@@ -69,22 +71,22 @@ func getCredentialsFromAuth(authValue string) ([]string, *probe.Error) {
 	return credentialElements, nil
 }
 
-// verify if authHeader value has valid region
-func isValidRegion(authHeaderValue string) *probe.Error {
+// verify if authHeader value has the region of the server
+func isValidRegion(authHeaderValue, serverRegion string) *probe.Error {
 	credentialElements, err := getCredentialsFromAuth(authHeaderValue)
 	if err != nil {
 		return err.Trace()
 	}
 	region := credentialElements[2]
-	if region != "milkyway" {
+	if region != serverRegion {
 		return probe.NewError(errInvalidRegion)
 	}
 	return nil
 }
 
 // stripAccessKeyID - strip only access key id from auth header
-func stripAccessKeyID(authHeaderValue string) (string, *probe.Error) {
-	if err := isValidRegion(authHeaderValue); err != nil {
+func stripAccessKeyID(authHeaderValue, serverRegion string) (string, *probe.Error) {
+	if err := isValidRegion(authHeaderValue, serverRegion); err != nil {
 		return "", err.Trace()
 	}
 	credentialElements, err := getCredentialsFromAuth(authHeaderValue)
@@ -99,166 +101,233 @@ func stripAccessKeyID(authHeaderValue string) (string, *probe.Error) {
 }
 
 // initSignatureV4 initializing signature verification
-func initSignatureV4(req *http.Request) (*signv4.Signature, *probe.Error) {
+func initSignatureV4(req *http.Request, serverRegion string) (*signv4.Signature, *probe.Error) {
 	// strip auth from authorization header
 	authHeaderValue := req.Header.Get("Authorization")
-	accessKeyID, err := stripAccessKeyID(authHeaderValue)
+	accessKeyID, err := stripAccessKeyID(authHeaderValue, serverRegion)
 	if err != nil {
 		return nil, err.Trace()
 	}
-	authConfig, err := LoadConfig()
+	user, err := getAuthUser(accessKeyID)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	if err := verifySession(user, req.Header.Get("X-Amz-Security-Token")); err != nil {
+		return nil, err.Trace()
+	}
+	credentialElements, err := getCredentialsFromAuth(authHeaderValue)
 	if err != nil {
 		return nil, err.Trace()
 	}
 	authFields := strings.Split(strings.TrimSpace(authHeaderValue), ",")
 	signedHeaders := strings.Split(strings.Split(strings.TrimSpace(authFields[1]), "=")[1], ";")
-	signature := strings.Split(strings.TrimSpace(authFields[2]), "=")[1]
-	for _, user := range authConfig.Users {
-		if user.AccessKeyID == accessKeyID {
-			signature := &signv4.Signature{
-				AccessKeyID:     user.AccessKeyID,
-				SecretAccessKey: user.SecretAccessKey,
-				Signature:       signature,
-				SignedHeaders:   signedHeaders,
-				Request:         req,
-			}
-			return signature, nil
+	signature := &signv4.Signature{
+		AccessKeyID:     user.AccessKeyID,
+		SecretAccessKey: user.SecretAccessKey,
+		Region:          credentialElements[2],
+		Signature:       strings.Split(strings.TrimSpace(authFields[2]), "=")[1],
+		SignedHeaders:   signedHeaders,
+		Request:         req,
+	}
+	return signature, nil
+}
+
+// readSignedPayload reads request body up to maxSize bytes and verifies its payload signature
+func readSignedPayload(req *http.Request, maxSize int64, signature *signv4.Signature) ([]byte, *probe.Error) {
+	payload, err := ioutil.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	if err != nil {
+		return nil, probe.NewError(err)
+	}
+	if int64(len(payload)) > maxSize {
+		return nil, probe.NewError(errPayloadTooLarge)
+	}
+	if signature != nil {
+		ok, err := signature.DoesSignatureMatch(hex.EncodeToString(sha256.Sum256(payload)))
+		if err != nil {
+			return nil, err.Trace()
+		}
+		if !ok {
+			return nil, probe.NewError(signv4.DoesNotMatch{})
 		}
 	}
-	return nil, probe.NewError(errAccessKeyIDInvalid)
+	return payload, nil
 }
 
+// extractHTTPFormValues - form values preceding the file field, file is returned as a reader on
+// the request body to be streamed, fields following the file are ignored
 func extractHTTPFormValues(reader *multipart.Reader) (io.Reader, map[string]string, *probe.Error) {
 	/// HTML Form values
 	formValues := make(map[string]string)
-	filePart := new(bytes.Buffer)
-	var err error
-	for err == nil {
-		var part *multipart.Part
-		part, err = reader.NextPart()
-		if part != nil {
-			if part.FileName() == "" {
-				buffer, err := ioutil.ReadAll(part)
-				if err != nil {
-					return nil, nil, probe.NewError(err)
-				}
-				formValues[http.CanonicalHeaderKey(part.FormName())] = string(buffer)
-			} else {
-				_, err := io.Copy(filePart, part)
-				if err != nil {
-					return nil, nil, probe.NewError(err)
-				}
-			}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, nil, probe.NewError(errPolicyMissingFile)
+		}
+		if err != nil {
+			return nil, nil, probe.NewError(err)
 		}
+		if part.FileName() != "" || strings.EqualFold(part.FormName(), "file") {
+			return part, formValues, nil
+		}
+		buffer, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, nil, probe.NewError(err)
+		}
+		formValues[http.CanonicalHeaderKey(part.FormName())] = string(buffer)
 	}
-	return filePart, formValues, nil
 }
 
-func applyPolicy(formValues map[string]string) *probe.Error {
+// applyPolicy - verify form values against POST policy, returns the policy for its
+// content-length-range to be enforced while the file is read
+func applyPolicy(formValues map[string]string) (signv4.PostPolicyForm, *probe.Error) {
 	if formValues["X-Amz-Algorithm"] != "AWS4-HMAC-SHA256" {
-		return probe.NewError(errUnsupportedAlgorithm)
+		return signv4.PostPolicyForm{}, probe.NewError(errUnsupportedAlgorithm)
 	}
 	/// Decoding policy
 	policyBytes, err := base64.StdEncoding.DecodeString(formValues["Policy"])
 	if err != nil {
-		return probe.NewError(err)
+		return signv4.PostPolicyForm{}, probe.NewError(err)
 	}
 	postPolicyForm, perr := signv4.ParsePostPolicyForm(string(policyBytes))
 	if perr != nil {
-		return perr.Trace()
+		return signv4.PostPolicyForm{}, perr.Trace()
 	}
 	if !postPolicyForm.Expiration.After(time.Now().UTC()) {
-		return probe.NewError(errPolicyAlreadyExpired)
-	}
-	if postPolicyForm.Conditions.Policies["$bucket"].Operator == "eq" {
-		if formValues["Bucket"] != postPolicyForm.Conditions.Policies["$bucket"].Value {
-			return probe.NewError(errPolicyMissingFields)
-		}
+		return signv4.PostPolicyForm{}, probe.NewError(errPolicyAlreadyExpired)
 	}
-	if postPolicyForm.Conditions.Policies["$x-amz-date"].Operator == "eq" {
-		if formValues["X-Amz-Date"] != postPolicyForm.Conditions.Policies["$x-amz-date"].Value {
-			return probe.NewError(errPolicyMissingFields)
-		}
-	}
-	if postPolicyForm.Conditions.Policies["$Content-Type"].Operator == "starts-with" {
-		if !strings.HasPrefix(formValues["Content-Type"], postPolicyForm.Conditions.Policies["$Content-Type"].Value) {
-			return probe.NewError(errPolicyMissingFields)
-		}
-	}
-	if postPolicyForm.Conditions.Policies["$Content-Type"].Operator == "eq" {
-		if formValues["Content-Type"] != postPolicyForm.Conditions.Policies["$Content-Type"].Value {
-			return probe.NewError(errPolicyMissingFields)
-		}
-	}
-	if postPolicyForm.Conditions.Policies["$key"].Operator == "starts-with" {
-		if !strings.HasPrefix(formValues["Key"], postPolicyForm.Conditions.Policies["$key"].Value) {
-			return probe.NewError(errPolicyMissingFields)
-		}
-	}
-	if postPolicyForm.Conditions.Policies["$key"].Operator == "eq" {
-		if formValues["Key"] != postPolicyForm.Conditions.Policies["$key"].Value {
-			return probe.NewError(errPolicyMissingFields)
-		}
+	if perr := postPolicyForm.CheckFormValues(formValues); perr != nil {
+		return signv4.PostPolicyForm{}, perr.Trace()
 	}
-	return nil
+	return postPolicyForm, nil
 }
 
 // initPostPresignedPolicyV4 initializing post policy signature verification
-func initPostPresignedPolicyV4(formValues map[string]string) (*signv4.Signature, *probe.Error) {
+func initPostPresignedPolicyV4(formValues map[string]string, serverRegion string) (*signv4.Signature, *probe.Error) {
 	credentialElements := strings.Split(strings.TrimSpace(formValues["X-Amz-Credential"]), "/")
 	if len(credentialElements) != 5 {
 		return nil, probe.NewError(errCredentialTagMalformed)
 	}
+	if credentialElements[2] != serverRegion {
+		return nil, probe.NewError(errInvalidRegion)
+	}
 	accessKeyID := credentialElements[0]
 	if !IsValidAccessKey(accessKeyID) {
 		return nil, probe.NewError(errAccessKeyIDInvalid)
 	}
-	authConfig, perr := LoadConfig()
-	if perr != nil {
-		return nil, perr.Trace()
-	}
-	for _, user := range authConfig.Users {
-		if user.AccessKeyID == accessKeyID {
-			signature := &signv4.Signature{
-				AccessKeyID:     user.AccessKeyID,
-				SecretAccessKey: user.SecretAccessKey,
-				Signature:       formValues["X-Amz-Signature"],
-				PresignedPolicy: formValues["Policy"],
-			}
-			return signature, nil
-		}
+	user, err := getAuthUser(accessKeyID)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	if err := verifySession(user, formValues["X-Amz-Security-Token"]); err != nil {
+		return nil, err.Trace()
+	}
+	signature := &signv4.Signature{
+		AccessKeyID:     user.AccessKeyID,
+		SecretAccessKey: user.SecretAccessKey,
+		Region:          credentialElements[2],
+		Signature:       formValues["X-Amz-Signature"],
+		PresignedPolicy: formValues["Policy"],
 	}
-	return nil, probe.NewError(errAccessKeyIDInvalid)
+	return signature, nil
 }
 
 // initPresignedSignatureV4 initializing presigned signature verification
-func initPresignedSignatureV4(req *http.Request) (*signv4.Signature, *probe.Error) {
+func initPresignedSignatureV4(req *http.Request, serverRegion string) (*signv4.Signature, *probe.Error) {
 	credentialElements := strings.Split(strings.TrimSpace(req.URL.Query().Get("X-Amz-Credential")), "/")
 	if len(credentialElements) != 5 {
 		return nil, probe.NewError(errCredentialTagMalformed)
 	}
+	if credentialElements[2] != serverRegion {
+		return nil, probe.NewError(errInvalidRegion)
+	}
 	accessKeyID := credentialElements[0]
 	if !IsValidAccessKey(accessKeyID) {
 		return nil, probe.NewError(errAccessKeyIDInvalid)
 	}
-	authConfig, err := LoadConfig()
+	user, err := getAuthUser(accessKeyID)
 	if err != nil {
 		return nil, err.Trace()
 	}
+	if err := verifySession(user, req.URL.Query().Get("X-Amz-Security-Token")); err != nil {
+		return nil, err.Trace()
+	}
 	signedHeaders := strings.Split(strings.TrimSpace(req.URL.Query().Get("X-Amz-SignedHeaders")), ";")
-	signature := strings.TrimSpace(req.URL.Query().Get("X-Amz-Signature"))
-	for _, user := range authConfig.Users {
-		if user.AccessKeyID == accessKeyID {
-			signature := &signv4.Signature{
-				AccessKeyID:     user.AccessKeyID,
-				SecretAccessKey: user.SecretAccessKey,
-				Signature:       signature,
-				SignedHeaders:   signedHeaders,
-				Presigned:       true,
-				Request:         req,
-			}
-			return signature, nil
-		}
+	signature := &signv4.Signature{
+		AccessKeyID:     user.AccessKeyID,
+		SecretAccessKey: user.SecretAccessKey,
+		Region:          credentialElements[2],
+		Signature:       strings.TrimSpace(req.URL.Query().Get("X-Amz-Signature")),
+		SignedHeaders:   signedHeaders,
+		Presigned:       true,
+		Request:         req,
+	}
+	return signature, nil
+}
+
+// getCredentialsFromAuthV2 parse access key id and signature from signature v2 authorization value 'AWS AccessKeyId:Signature'
+func getCredentialsFromAuthV2(authValue string) (string, string, *probe.Error) {
+	if authValue == "" {
+		return "", "", probe.NewError(errMissingAuthHeaderValue)
+	}
+	authFields := strings.Fields(strings.TrimSpace(authValue))
+	if len(authFields) != 2 {
+		return "", "", probe.NewError(errInvalidAuthHeaderValue)
+	}
+	if authFields[0] != authHeaderPrefixV2 {
+		return "", "", probe.NewError(errInvalidAuthHeaderPrefix)
+	}
+	credentials := strings.SplitN(authFields[1], ":", 2)
+	if len(credentials) != 2 || credentials[1] == "" {
+		return "", "", probe.NewError(errMissingFieldsSignatureTag)
+	}
+	if !IsValidAccessKey(credentials[0]) {
+		return "", "", probe.NewError(errAccessKeyIDInvalid)
+	}
+	return credentials[0], credentials[1], nil
+}
+
+// initSignatureV2 initializing signature v2 verification
+func initSignatureV2(req *http.Request) (*signv4.SignatureV2, *probe.Error) {
+	accessKeyID, signature, err := getCredentialsFromAuthV2(req.Header.Get("Authorization"))
+	if err != nil {
+		return nil, err.Trace()
+	}
+	authConfig, err := LoadConfig()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	user, ok := findAuthUser(authConfig, accessKeyID)
+	if !ok {
+		return nil, probe.NewError(errAccessKeyIDInvalid)
+	}
+	return &signv4.SignatureV2{
+		AccessKeyID:     user.AccessKeyID,
+		SecretAccessKey: user.SecretAccessKey,
+		Signature:       signature,
+		Request:         req,
+	}, nil
+}
+
+// initPresignedSignatureV2 initializing presigned signature v2 verification
+func initPresignedSignatureV2(req *http.Request) (*signv4.SignatureV2, *probe.Error) {
+	accessKeyID := strings.TrimSpace(req.URL.Query().Get("AWSAccessKeyId"))
+	if !IsValidAccessKey(accessKeyID) {
+		return nil, probe.NewError(errAccessKeyIDInvalid)
+	}
+	authConfig, err := LoadConfig()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	user, ok := findAuthUser(authConfig, accessKeyID)
+	if !ok {
+		return nil, probe.NewError(errAccessKeyIDInvalid)
+	}
+	signature := &signv4.SignatureV2{
+		AccessKeyID:     user.AccessKeyID,
+		SecretAccessKey: user.SecretAccessKey,
+		Signature:       req.URL.Query().Get("Signature"),
+		Presigned:       true,
+		Request:         req,
 	}
-	return nil, probe.NewError(errAccessKeyIDInvalid)
+	return signature, nil
 }
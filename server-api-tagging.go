@@ -0,0 +1,85 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+
+	"github.com/minio/minio-xl/pkg/donut"
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// maximum size of a tagging document
+const maxTaggingSize = 64 * 1024
+
+// Tag - a single tag key and value
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Tagging - format for put and get tagging
+type Tagging struct {
+	XMLName xml.Name `xml:"Tagging" json:"-"`
+	TagSet  struct {
+		Tag []Tag
+	}
+}
+
+// parseTagging - parse a tagging document into tags, a tag key can appear only once
+func parseTagging(data []byte) (donut.Tags, *probe.Error) {
+	tagging := Tagging{}
+	if err := xml.Unmarshal(data, &tagging); err != nil {
+		return nil, probe.NewError(donut.MalformedXML{})
+	}
+	tags := make(donut.Tags)
+	for _, tag := range tagging.TagSet.Tag {
+		if _, ok := tags[tag.Key]; ok {
+			return nil, probe.NewError(donut.InvalidTag{Key: tag.Key})
+		}
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// generateTaggingResponse - generates a tagging response for the said tags, sorted by key
+func generateTaggingResponse(tags donut.Tags) Tagging {
+	var keys []string
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tagging := Tagging{}
+	for _, key := range keys {
+		tagging.TagSet.Tag = append(tagging.TagSet.Tag, Tag{Key: key, Value: tags[key]})
+	}
+	return tagging
+}
+
+// getRequestTags - tags requested in 'x-amz-tagging' header
+func getRequestTags(req *http.Request) (donut.Tags, *probe.Error) {
+	tags, err := donut.DecodeTags(req.Header.Get("x-amz-tagging"))
+	if err != nil {
+		return nil, err.Trace()
+	}
+	if err := donut.IsValidObjectTags(tags); err != nil {
+		return nil, err.Trace()
+	}
+	return tags, nil
+}
@@ -0,0 +1,317 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Please read for more information - http://docs.aws.amazon.com/AmazonS3/latest/dev/WebsiteHosting.html
+//
+// Website configuration is saved as is in bucket metadata under the key 'website'. Buckets are
+// served on the website endpoint by Host, either the full host name or its first label.
+
+const (
+	// bucket metadata key for website configuration
+	websiteMetadataKey = "website"
+
+	// object metadata key for 'x-amz-website-redirect-location'
+	websiteRedirectMetadataKey = "websiteRedirectLocation"
+
+	// maximum number of routing rules allowed in a website configuration
+	maxRoutingRules = 50
+
+	// maximum size of a website configuration document
+	maxWebsiteConfigSize = 64 * 1024
+)
+
+// RoutingRule - redirect requests matching a condition
+type RoutingRule struct {
+	Condition struct {
+		KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+		HTTPErrorCodeReturnedEquals int    `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+	} `xml:"Condition"`
+	Redirect struct {
+		Protocol             string `xml:"Protocol,omitempty"`
+		HostName             string `xml:"HostName,omitempty"`
+		ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+		ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+		HTTPRedirectCode     int    `xml:"HttpRedirectCode,omitempty"`
+	} `xml:"Redirect"`
+}
+
+// WebsiteConfiguration - format for put and get bucket website
+type WebsiteConfiguration struct {
+	XMLName               xml.Name `xml:"WebsiteConfiguration" json:"-"`
+	RedirectAllRequestsTo *struct {
+		HostName string `xml:"HostName"`
+		Protocol string `xml:"Protocol,omitempty"`
+	} `xml:"RedirectAllRequestsTo,omitempty"`
+	IndexDocument *struct {
+		Suffix string `xml:"Suffix"`
+	} `xml:"IndexDocument,omitempty"`
+	ErrorDocument *struct {
+		Key string `xml:"Key"`
+	} `xml:"ErrorDocument,omitempty"`
+	RoutingRules struct {
+		RoutingRule []RoutingRule `xml:"RoutingRule,omitempty"`
+	} `xml:"RoutingRules,omitempty"`
+}
+
+// isValidRedirectProtocol - protocol is optional, otherwise either 'http' or 'https'
+func isValidRedirectProtocol(protocol string) bool {
+	return protocol == "" || protocol == "http" || protocol == "https"
+}
+
+// parseWebsiteConfiguration - parse and validate a website configuration document
+func parseWebsiteConfiguration(data []byte) (WebsiteConfiguration, bool) {
+	config := WebsiteConfiguration{}
+	if err := xml.Unmarshal(data, &config); err != nil {
+		return WebsiteConfiguration{}, false
+	}
+	// redirecting all requests excludes every other setting
+	if config.RedirectAllRequestsTo != nil {
+		if config.RedirectAllRequestsTo.HostName == "" || !isValidRedirectProtocol(config.RedirectAllRequestsTo.Protocol) {
+			return WebsiteConfiguration{}, false
+		}
+		if config.IndexDocument != nil || config.ErrorDocument != nil || len(config.RoutingRules.RoutingRule) > 0 {
+			return WebsiteConfiguration{}, false
+		}
+		return config, true
+	}
+	if config.IndexDocument == nil || config.IndexDocument.Suffix == "" || strings.Contains(config.IndexDocument.Suffix, "/") {
+		return WebsiteConfiguration{}, false
+	}
+	if config.ErrorDocument != nil && config.ErrorDocument.Key == "" {
+		return WebsiteConfiguration{}, false
+	}
+	if len(config.RoutingRules.RoutingRule) > maxRoutingRules {
+		return WebsiteConfiguration{}, false
+	}
+	for _, rule := range config.RoutingRules.RoutingRule {
+		if rule.Redirect.ReplaceKeyPrefixWith != "" && rule.Redirect.ReplaceKeyWith != "" {
+			return WebsiteConfiguration{}, false
+		}
+		if !isValidRedirectProtocol(rule.Redirect.Protocol) {
+			return WebsiteConfiguration{}, false
+		}
+		if rule.Redirect.HTTPRedirectCode != 0 && (rule.Redirect.HTTPRedirectCode < 300 || rule.Redirect.HTTPRedirectCode > 399) {
+			return WebsiteConfiguration{}, false
+		}
+	}
+	return config, true
+}
+
+// match - returns true if key and error code, zero if none yet, satisfy the rule condition
+func (r RoutingRule) match(key string, errorCode int) bool {
+	if r.Condition.HTTPErrorCodeReturnedEquals != 0 && r.Condition.HTTPErrorCodeReturnedEquals != errorCode {
+		return false
+	}
+	return strings.HasPrefix(key, r.Condition.KeyPrefixEquals)
+}
+
+// location - redirect location for key
+func (r RoutingRule) location(req *http.Request, key string) string {
+	switch {
+	case r.Redirect.ReplaceKeyWith != "":
+		key = r.Redirect.ReplaceKeyWith
+	case r.Redirect.ReplaceKeyPrefixWith != "":
+		key = r.Redirect.ReplaceKeyPrefixWith + strings.TrimPrefix(key, r.Condition.KeyPrefixEquals)
+	}
+	return getRedirectLocation(req, r.Redirect.Protocol, r.Redirect.HostName, key)
+}
+
+// redirectCode - redirect status code of the rule, 301 by default
+func (r RoutingRule) redirectCode() int {
+	if r.Redirect.HTTPRedirectCode == 0 {
+		return http.StatusMovedPermanently
+	}
+	return r.Redirect.HTTPRedirectCode
+}
+
+// findRoutingRule - first routing rule matching key and error code
+func (c WebsiteConfiguration) findRoutingRule(key string, errorCode int) (RoutingRule, bool) {
+	for _, rule := range c.RoutingRules.RoutingRule {
+		if rule.match(key, errorCode) {
+			return rule, true
+		}
+	}
+	return RoutingRule{}, false
+}
+
+// isValidRedirectLocation - object redirect location is either a path on the same host or an
+// absolute 'http' or 'https' url, '//host' and '/\host' are taken by browsers for another host
+func isValidRedirectLocation(location string) bool {
+	if strings.HasPrefix(location, "/") {
+		return !strings.HasPrefix(location, "//") && !strings.HasPrefix(location, "/\\")
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// getRedirectLocation - absolute location for key, protocol and host default to those of the request
+func getRedirectLocation(req *http.Request, protocol, host, key string) string {
+	if protocol == "" {
+		protocol = "http"
+		if req.TLS != nil {
+			protocol = "https"
+		}
+	}
+	if host == "" {
+		host = req.Host
+	}
+	return protocol + "://" + host + "/" + key
+}
+
+type websiteHandler struct {
+	api API
+}
+
+// getWebsiteHandler - handler serving buckets as static websites
+func getWebsiteHandler(api API) http.Handler {
+	return websiteHandler{api}
+}
+
+// getWebsiteBucket - bucket addressed by request host, full host name is preferred over its first label
+func (h websiteHandler) getWebsiteBucket(req *http.Request) string {
+	host := req.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	if _, err := h.api.Donut.GetBucketMetadata(host); err == nil {
+		return host
+	}
+	return strings.Split(host, ".")[0]
+}
+
+func (h websiteHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// Ticket master block
+	{
+		op := APIOperation{}
+		op.ProceedCh = make(chan struct{})
+		h.api.OP <- op
+		// block until Ticket master gives us a go
+		<-op.ProceedCh
+	}
+
+	if req.Method != "GET" && req.Method != "HEAD" {
+		writeErrorResponse(w, req, MethodNotAllowed, req.URL.Path)
+		return
+	}
+	bucket := h.getWebsiteBucket(req)
+	bucketMetadata, err := h.api.Donut.GetBucketMetadata(bucket)
+	if err != nil {
+		writeErrorResponse(w, req, NoSuchBucket, req.URL.Path)
+		return
+	}
+	config, ok := parseWebsiteConfiguration([]byte(bucketMetadata.Metadata[websiteMetadataKey]))
+	if !ok {
+		writeErrorResponse(w, req, NoSuchWebsiteConfiguration, req.URL.Path)
+		return
+	}
+	// website content is public, only buckets readable by everyone are served
+	if !bucketMetadata.ACL.IsPublicRead() && !bucketMetadata.ACL.IsPublicReadWrite() {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return
+	}
+	if config.RedirectAllRequestsTo != nil {
+		location := getRedirectLocation(req, config.RedirectAllRequestsTo.Protocol, config.RedirectAllRequestsTo.HostName, strings.TrimPrefix(req.URL.Path, "/"))
+		http.Redirect(w, req, location, http.StatusMovedPermanently)
+		return
+	}
+
+	key := strings.TrimPrefix(req.URL.Path, "/")
+	if rule, ok := config.findRoutingRule(key, 0); ok && rule.Condition.HTTPErrorCodeReturnedEquals == 0 {
+		http.Redirect(w, req, rule.location(req, key), rule.redirectCode())
+		return
+	}
+	// directory like keys are served by their index document
+	object := key
+	if object == "" || strings.HasSuffix(object, "/") {
+		object = object + config.IndexDocument.Suffix
+	}
+	metadata, err := h.api.Donut.GetObjectMetadata(bucket, object)
+	if err != nil {
+		// a key without trailing slash may still name a directory
+		if object == key {
+			if _, err := h.api.Donut.GetObjectMetadata(bucket, key+"/"+config.IndexDocument.Suffix); err == nil {
+				http.Redirect(w, req, "/"+key+"/", http.StatusFound)
+				return
+			}
+		}
+		if rule, ok := config.findRoutingRule(key, http.StatusNotFound); ok {
+			http.Redirect(w, req, rule.location(req, key), rule.redirectCode())
+			return
+		}
+		if config.ErrorDocument != nil {
+			if h.serveObject(w, req, bucket, config.ErrorDocument.Key, http.StatusNotFound) {
+				return
+			}
+		}
+		writeErrorResponse(w, req, NoSuchKey, req.URL.Path)
+		return
+	}
+	// locations are verified when objects are written, objects written before are not redirected
+	if location := metadata.Metadata[websiteRedirectMetadataKey]; location != "" && isValidRedirectLocation(location) {
+		http.Redirect(w, req, location, http.StatusMovedPermanently)
+		return
+	}
+	h.serveObject(w, req, bucket, object, http.StatusOK)
+}
+
+// serveObject - write object with status code, returns false if object was not found
+func (h websiteHandler) serveObject(w http.ResponseWriter, req *http.Request, bucket, object string, statusCode int) bool {
+	metadata, err := h.api.Donut.GetObjectMetadata(bucket, object)
+	if err != nil {
+		return false
+	}
+	// encrypted objects are readable only with their key, which website requests cannot carry
+	if metadata.IsEncrypted() && !metadata.IsServerEncrypted() {
+		writeErrorResponse(w, req, AccessDenied, req.URL.Path)
+		return true
+	}
+	var hrange *httpRange
+	if statusCode == http.StatusOK {
+		hrange, err = getRequestedRange(req.Header.Get("Range"), metadata.Size)
+		if err != nil {
+			writeErrorResponse(w, req, InvalidRange, req.URL.Path)
+			return true
+		}
+	}
+	setObjectHeaders(w, metadata, hrange)
+	if hrange == nil || (hrange.start == 0 && hrange.length == 0) {
+		w.WriteHeader(statusCode)
+	}
+	if req.Method == "HEAD" {
+		return true
+	}
+	var start, length int64
+	if hrange != nil {
+		start, length = hrange.start, hrange.length
+	}
+	if _, err = h.api.Donut.GetObject(w, bucket, object, start, length, nil); err != nil {
+		errorIf(err.Trace(), "GetObject failed.", nil)
+	}
+	return true
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"time"
 
 	"github.com/minio/minio-xl/pkg/probe"
 	"github.com/minio/minio-xl/pkg/quick"
@@ -27,15 +28,54 @@ import (
 
 // AuthUser container
 type AuthUser struct {
-	Name            string `json:"name"`
-	AccessKeyID     string `json:"accessKeyId"`
-	SecretAccessKey string `json:"secretAccessKey"`
+	Name            string   `json:"name"`
+	AccessKeyID     string   `json:"accessKeyId"`
+	SecretAccessKey string   `json:"secretAccessKey"`
+	Admin           bool     `json:"admin,omitempty"`    // full access to every bucket
+	ReadOnly        bool     `json:"readOnly,omitempty"` // no write access, even to owned buckets
+	Buckets         []string `json:"buckets,omitempty"`  // 'bucket' or 'bucket/prefix' accessible besides owned buckets
+
+	// key pair being rotated in or out, accepted along with the primary keys until it expires
+	Secondary *AuthKey `json:"secondary,omitempty"`
+
+	// temporary credentials the user was resolved from, not saved
+	Session *AuthSession `json:"-"`
+}
+
+// AuthKey container for a secondary key pair of a user
+type AuthKey struct {
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	Expiry          time.Time `json:"expiry"` // zero if valid until revoked
+}
+
+// AuthAuditEntry container for a change to the keys of a user
+type AuthAuditEntry struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user"`
+	Action      string    `json:"action"`
+	AccessKeyID string    `json:"accessKeyId"`
+}
+
+// AuthSession container for temporary credentials acting for a user
+type AuthSession struct {
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	SessionToken    string    `json:"sessionToken"`
+	User            string    `json:"user"` // name of the user the session acts for
+	Expiry          time.Time `json:"expiry"`
+
+	// optional policy, narrows down permissions of the user
+	ReadOnly bool     `json:"readOnly,omitempty"`
+	Buckets  []string `json:"buckets,omitempty"`
 }
 
 // AuthConfig auth keys
 type AuthConfig struct {
-	Version string
-	Users   map[string]*AuthUser
+	Version  string
+	Users    map[string]*AuthUser
+	Sessions map[string]*AuthSession // temporary credentials by access key id
+	Audit    []AuthAuditEntry        // changes to keys of users, oldest first
 }
 
 // getAuthConfigPath get users config path
@@ -128,6 +168,7 @@ func LoadConfig() (*AuthConfig, *probe.Error) {
 	a := &AuthConfig{}
 	a.Version = "0.0.1"
 	a.Users = make(map[string]*AuthUser)
+	a.Sessions = make(map[string]*AuthSession)
 	qc, err := quick.New(a)
 	if err != nil {
 		return nil, err.Trace()
@@ -135,5 +176,28 @@ func LoadConfig() (*AuthConfig, *probe.Error) {
 	if err := qc.Load(authConfigFile); err != nil {
 		return nil, err.Trace()
 	}
-	return qc.Data().(*AuthConfig), nil
+	a = qc.Data().(*AuthConfig)
+	if migrateConfig(a) {
+		if err := SaveConfig(a); err != nil {
+			return nil, err.Trace()
+		}
+	}
+	return a, nil
+}
+
+// migrateConfig - upgrades configs written before users had permissions, every key had full
+// access then, so all users are made admins to keep them working, returns true if changed
+func migrateConfig(a *AuthConfig) bool {
+	if len(a.Users) == 0 {
+		return false
+	}
+	for _, user := range a.Users {
+		if user.Admin {
+			return false
+		}
+	}
+	for _, user := range a.Users {
+		user.Admin = true
+	}
+	return true
 }
@@ -0,0 +1,88 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Keys of a user are rotated without downtime by adding a secondary key pair, promoting it to
+// primary once clients are moving over, and revoking the previous keys when they are done. Both
+// key pairs are accepted while the secondary key pair is valid.
+
+// maxAuthAuditEntries - audit trail keeps only the latest changes
+const maxAuthAuditEntries = 1000
+
+// actions recorded in the audit trail
+const (
+	authActionGenerate = "generate"
+	authActionReset    = "reset"
+	authActionAdd      = "add"
+	authActionPromote  = "promote"
+	authActionRevoke   = "revoke"
+)
+
+// isExpired - returns true if secondary keys are past their expiry
+func (k AuthKey) isExpired() bool {
+	return !k.Expiry.IsZero() && !time.Now().UTC().Before(k.Expiry)
+}
+
+// findAuthUser - user owning access key id, either as primary or valid secondary keys, the user
+// returned carries the key pair matched
+func findAuthUser(authConfig *AuthConfig, accessKeyID string) (*AuthUser, bool) {
+	for _, user := range authConfig.Users {
+		if user.AccessKeyID == accessKeyID {
+			return user, true
+		}
+		if user.Secondary != nil && user.Secondary.AccessKeyID == accessKeyID && !user.Secondary.isExpired() {
+			secondaryUser := *user
+			secondaryUser.AccessKeyID = user.Secondary.AccessKeyID
+			secondaryUser.SecretAccessKey = user.Secondary.SecretAccessKey
+			return &secondaryUser, true
+		}
+	}
+	return nil, false
+}
+
+// findAdminUser - admin user rpc requests are signed with, first one by name if there are several
+func findAdminUser(authConfig *AuthConfig) (*AuthUser, bool) {
+	var names []string
+	for name, user := range authConfig.Users {
+		if user.Admin {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, false
+	}
+	sort.Strings(names)
+	return authConfig.Users[names[0]], true
+}
+
+// auditAuth - record change to the keys of a user in the audit trail
+func auditAuth(authConfig *AuthConfig, user, action, accessKeyID string) {
+	authConfig.Audit = append(authConfig.Audit, AuthAuditEntry{
+		Time:        time.Now().UTC(),
+		User:        user,
+		Action:      action,
+		AccessKeyID: accessKeyID,
+	})
+	if len(authConfig.Audit) > maxAuthAuditEntries {
+		authConfig.Audit = authConfig.Audit[len(authConfig.Audit)-maxAuthAuditEntries:]
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// Temporary credentials are handed out by the controller, they act for a user until they expire and
+// are valid only along with their session token sent in 'X-Amz-Security-Token'. An optional policy
+// narrows down permissions of the user, it never extends them.
+
+const (
+	// default lifetime of temporary credentials
+	defaultSessionDuration = time.Hour
+	// limits of the lifetime of temporary credentials
+	minSessionDuration = 15 * time.Minute
+	maxSessionDuration = 36 * time.Hour
+	// size of session token in bytes before encoding
+	sessionTokenLength = 96
+)
+
+// generateSessionToken - generate random base64 session token
+func generateSessionToken() (string, *probe.Error) {
+	rb := make([]byte, sessionTokenLength)
+	if _, err := rand.Read(rb); err != nil {
+		return "", probe.NewError(err)
+	}
+	return base64.StdEncoding.EncodeToString(rb), nil
+}
+
+// isExpired - returns true if temporary credentials are past their expiry
+func (s AuthSession) isExpired() bool {
+	return !time.Now().UTC().Before(s.Expiry)
+}
+
+// isAllowed - returns true if policy of the session permits access to object, or objects under prefix, of bucket
+func (s AuthSession) isAllowed(bucket, object string, perm permission) bool {
+	if s.ReadOnly && perm != readPermission {
+		return false
+	}
+	return len(s.Buckets) == 0 || isResourceListed(s.Buckets, bucket, object)
+}
+
+// getSessionUser - user temporary credentials act for, with the credentials of the session
+func getSessionUser(authConfig *AuthConfig, session *AuthSession) (*AuthUser, *probe.Error) {
+	for _, user := range authConfig.Users {
+		if user.Name == session.User {
+			sessionUser := *user
+			sessionUser.AccessKeyID = session.AccessKeyID
+			sessionUser.SecretAccessKey = session.SecretAccessKey
+			sessionUser.Session = session
+			// sessions narrowed down by a policy do not act as admin, even for an admin
+			sessionUser.Admin = user.Admin && !session.ReadOnly && len(session.Buckets) == 0
+			return &sessionUser, nil
+		}
+	}
+	// credentials outlive removed users only until they are used
+	return nil, probe.NewError(errAccessKeyIDInvalid)
+}
+
+// verifySession - temporary credentials are valid only along with their token and until they expire,
+// long-term credentials must not carry a token
+func verifySession(user *AuthUser, sessionToken string) *probe.Error {
+	if user.Session == nil {
+		if sessionToken != "" {
+			return probe.NewError(errInvalidSessionToken)
+		}
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(sessionToken), []byte(user.Session.SessionToken)) != 1 {
+		return probe.NewError(errInvalidSessionToken)
+	}
+	if user.Session.isExpired() {
+		return probe.NewError(errSessionExpired)
+	}
+	return nil
+}
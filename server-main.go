@@ -119,6 +119,26 @@ func configureServerRPC(conf minioConfig, rpcHandler http.Handler) (*http.Server
 	return rpcServer, nil
 }
 
+// configureWebsiteServer configure static website server
+func configureWebsiteServer(conf minioConfig, websiteHandler http.Handler) (*http.Server, *probe.Error) {
+	websiteServer := &http.Server{
+		Addr:           conf.WebsiteAddress,
+		Handler:        websiteHandler,
+		MaxHeaderBytes: 1 << 20,
+	}
+
+	if conf.TLS {
+		var err error
+		websiteServer.TLSConfig = &tls.Config{}
+		websiteServer.TLSConfig.Certificates = make([]tls.Certificate, 1)
+		websiteServer.TLSConfig.Certificates[0], err = tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, probe.NewError(err)
+		}
+	}
+	return websiteServer, nil
+}
+
 // Start ticket master
 func startTM(api API) {
 	for {
@@ -131,16 +151,25 @@ func startTM(api API) {
 // startServer starts an s3 compatible cloud storage server
 func startServer(conf minioConfig) *probe.Error {
 	minioAPI := getNewAPI(conf.Anonymous)
+	minioAPI.TLS = conf.TLS
 	apiHandler := getAPIHandler(conf.Anonymous, minioAPI)
 	apiServer, err := configureAPIServer(conf, apiHandler)
 	if err != nil {
 		return err.Trace()
 	}
-	rpcServer, err := configureServerRPC(conf, getServerRPCHandler(conf.Anonymous))
+	rpcServer, err := configureServerRPC(conf, getServerRPCHandler(conf.Anonymous, minioAPI.Donut))
+	servers := []*http.Server{apiServer, rpcServer}
+	if conf.WebsiteAddress != "" {
+		websiteServer, err := configureWebsiteServer(conf, getWebsiteHandler(minioAPI))
+		if err != nil {
+			return err.Trace()
+		}
+		servers = append(servers, websiteServer)
+	}
 
 	// start ticket master
 	go startTM(minioAPI)
-	if err := minhttp.ListenAndServe(apiServer, rpcServer); err != nil {
+	if err := minhttp.ListenAndServe(servers...); err != nil {
 		return err.Trace()
 	}
 	return nil
@@ -154,13 +183,14 @@ func getServerConfig(c *cli.Context) minioConfig {
 	}
 	tls := (certFile != "" && keyFile != "")
 	return minioConfig{
-		Address:    c.GlobalString("address"),
-		RPCAddress: c.GlobalString("address-server-rpc"),
-		Anonymous:  c.GlobalBool("anonymous"),
-		TLS:        tls,
-		CertFile:   certFile,
-		KeyFile:    keyFile,
-		RateLimit:  c.GlobalInt("ratelimit"),
+		Address:        c.GlobalString("address"),
+		RPCAddress:     c.GlobalString("address-server-rpc"),
+		WebsiteAddress: c.GlobalString("address-website"),
+		Anonymous:      c.GlobalBool("anonymous"),
+		TLS:            tls,
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		RateLimit:      c.GlobalInt("ratelimit"),
 	}
 }
 
@@ -21,10 +21,15 @@ import (
 	"os"
 	"runtime"
 
+	"github.com/minio/minio-xl/pkg/donut"
 	"github.com/minio/minio-xl/pkg/probe"
+	"github.com/minio/minio-xl/pkg/sys"
 )
 
-type serverRPCService struct{}
+type serverRPCService struct {
+	// donut served by the server, its cache statistics are left out if not set
+	donut donut.Interface
+}
 
 func (s *serverRPCService) Add(r *http.Request, arg *ServerArg, rep *ServerRep) error {
 	rep.Host = "192.168.1.1:9002"
@@ -33,8 +38,25 @@ func (s *serverRPCService) Add(r *http.Request, arg *ServerArg, rep *ServerRep)
 }
 
 func (s *serverRPCService) MemStats(r *http.Request, arg *ServerArg, rep *MemStatsRep) error {
-	rep.Total = 64 * 1024 * 1024 * 1024
-	rep.Free = 9 * 1024 * 1024 * 1024
+	memStats, err := sys.GetMemStats()
+	if err != nil {
+		return probe.WrapError(probe.NewError(err))
+	}
+	rep.Total = memStats.Total
+	rep.Free = memStats.Free
+	if s.donut != nil {
+		cacheStats := s.donut.CacheStats()
+		rep.Cache = CacheStatsRep{
+			MaxSize:  cacheStats.MaxSize,
+			Size:     cacheStats.Bytes,
+			Items:    cacheStats.Items,
+			Hits:     cacheStats.Hits,
+			Misses:   cacheStats.Misses,
+			Evicted:  cacheStats.Evicted,
+			Expired:  cacheStats.Expired,
+			Rejected: cacheStats.Rejected,
+		}
+	}
 	return nil
 }
 
@@ -16,7 +16,13 @@
 
 package main
 
-import . "gopkg.in/check.v1"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
 
 type MySuite struct{}
 
@@ -35,3 +41,40 @@ func (s *MySuite) TestAuth(c *C) {
 	c.Log(string(secretID))
 	c.Log(string(accessID))
 }
+
+func (s *MySuite) TestMigrateConfig(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "minio-auth-")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(root)
+
+	configPath := customConfigPath
+	defer SetAuthConfigPath(configPath)
+	SetAuthConfigPath(root)
+
+	// users.json as written before users had permissions
+	oldConfig := `{
+	"Version": "0.0.1",
+	"Users": {
+		"admin": {"name": "admin", "accessKeyId": "admin", "secretAccessKey": "secret1"},
+		"user": {"name": "user", "accessKeyId": "WLGDGYAQYIGI833EV05A", "secretAccessKey": "secret2"}
+	}
+}`
+	c.Assert(ioutil.WriteFile(filepath.Join(root, "users.json"), []byte(oldConfig), 0600), IsNil)
+
+	config, perr := LoadConfig()
+	c.Assert(perr, IsNil)
+	c.Assert(config.Users["admin"].Admin, Equals, true)
+	c.Assert(config.Users["user"].Admin, Equals, true)
+
+	user, ok := findAdminUser(config)
+	c.Assert(ok, Equals, true)
+	c.Assert(user.AccessKeyID, Equals, "admin")
+
+	// migration is saved, and not repeated once users have permissions
+	config.Users["user"].Admin = false
+	c.Assert(SaveConfig(config), IsNil)
+	config, perr = LoadConfig()
+	c.Assert(perr, IsNil)
+	c.Assert(config.Users["admin"].Admin, Equals, true)
+	c.Assert(config.Users["user"].Admin, Equals, false)
+}
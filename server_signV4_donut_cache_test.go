@@ -18,11 +18,17 @@ package main
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,6 +36,7 @@ import (
 	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 
 	"github.com/minio/minio-xl/pkg/donut"
 	. "gopkg.in/check.v1"
@@ -47,6 +54,8 @@ var _ = Suite(&MyAPIDonutCacheSuite{})
 
 var testAPIDonutCacheServer *httptest.Server
 
+var testWebsiteDonutCacheServer *httptest.Server
+
 func (s *MyAPIDonutCacheSuite) SetUpSuite(c *C) {
 	root, err := ioutil.TempDir(os.TempDir(), "api-")
 	c.Assert(err, IsNil)
@@ -82,11 +91,13 @@ func (s *MyAPIDonutCacheSuite) SetUpSuite(c *C) {
 	httpHandler := getAPIHandler(false, minioAPI)
 	go startTM(minioAPI)
 	testAPIDonutCacheServer = httptest.NewServer(httpHandler)
+	testWebsiteDonutCacheServer = httptest.NewServer(getWebsiteHandler(minioAPI))
 }
 
 func (s *MyAPIDonutCacheSuite) TearDownSuite(c *C) {
 	os.RemoveAll(s.root)
 	testAPIDonutCacheServer.Close()
+	testWebsiteDonutCacheServer.Close()
 }
 
 func (s *MyAPIDonutCacheSuite) newRequest(method, urlStr string, contentLength int64, body io.ReadSeeker) (*http.Request, error) {
@@ -211,6 +222,125 @@ func (s *MyAPIDonutCacheSuite) newRequest(method, urlStr string, contentLength i
 	return req, nil
 }
 
+// signRequestV2 - sign request with signature version 2, request is expected to carry neither
+// sub-resources nor 'x-amz-*' headers
+func signRequestV2(req *http.Request, accessKeyID, secretAccessKey string) {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	// 'x-amz-*' headers and sub-resources used by tests
+	var amzKeys []string
+	amzValues := make(map[string]string)
+	for k, v := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-amz-") {
+			amzKeys = append(amzKeys, k)
+			amzValues[k] = strings.Join(v, ",")
+		}
+	}
+	sort.Strings(amzKeys)
+	var amzHeaders []string
+	for _, k := range amzKeys {
+		amzHeaders = append(amzHeaders, k+":"+amzValues[k]+"\n")
+	}
+	var resources []string
+	query := req.URL.Query()
+	for _, resource := range []string{"partNumber", "uploadId", "uploads"} {
+		if _, ok := query[resource]; ok {
+			if value := query.Get(resource); value != "" {
+				resources = append(resources, resource+"="+value)
+			} else {
+				resources = append(resources, resource)
+			}
+		}
+	}
+	resource := req.URL.Path
+	if len(resources) > 0 {
+		resource += "?" + strings.Join(resources, "&")
+	}
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		req.Header.Get("Date"),
+		strings.Join(amzHeaders, "") + resource,
+	}, "\n")
+	hash := hmac.New(sha1.New, []byte(secretAccessKey))
+	hash.Write([]byte(stringToSign))
+	signature := base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	req.Header.Set("Authorization", "AWS "+accessKeyID+":"+signature)
+}
+
+// newPostPolicyRequest - POST form upload signed with signature version 4, policy carries given conditions
+// along with those on algorithm, credential and date, form values are sent as is before the file
+func newPostPolicyRequest(urlStr, region, accessKeyID, secretAccessKey string, conditions []string, formValues map[string]string, data []byte) (*http.Request, error) {
+	t := time.Now().UTC()
+	credential := strings.Join([]string{accessKeyID, t.Format(yyyymmdd), region, "s3", "aws4_request"}, "/")
+	conditions = append(conditions,
+		`{"x-amz-algorithm": "AWS4-HMAC-SHA256"}`,
+		`{"x-amz-credential": "`+credential+`"}`,
+		`{"x-amz-date": "`+t.Format(iso8601Format)+`"}`,
+	)
+	policy := `{"expiration": "` + t.Add(time.Hour).Format(time.RFC3339Nano) + `", "conditions": [` + strings.Join(conditions, ", ") + `]}`
+	encodedPolicy := base64.StdEncoding.EncodeToString([]byte(policy))
+
+	date := sumHMAC([]byte("AWS4"+secretAccessKey), []byte(t.Format(yyyymmdd)))
+	regionKey := sumHMAC(date, []byte(region))
+	service := sumHMAC(regionKey, []byte("s3"))
+	signingKey := sumHMAC(service, []byte("aws4_request"))
+	signature := hex.EncodeToString(sumHMAC(signingKey, []byte(encodedPolicy)))
+
+	fields := map[string]string{
+		"policy":           encodedPolicy,
+		"x-amz-algorithm":  "AWS4-HMAC-SHA256",
+		"x-amz-credential": credential,
+		"x-amz-date":       t.Format(iso8601Format),
+		"x-amz-signature":  signature,
+	}
+	for k, v := range formValues {
+		fields[k] = v
+	}
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	file, err := writer.CreateFormFile("file", "upload.txt")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = file.Write(data); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", urlStr, &buffer)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req, nil
+}
+
+// presignRequestV2 - add signature version 2 query string authentication valid for an hour
+func presignRequestV2(req *http.Request, accessKeyID, secretAccessKey string) {
+	expires := strconv.FormatInt(time.Now().UTC().Add(time.Hour).Unix(), 10)
+	stringToSign := strings.Join([]string{
+		req.Method,
+		"",
+		"",
+		expires,
+		req.URL.Path,
+	}, "\n")
+	hash := hmac.New(sha1.New, []byte(secretAccessKey))
+	hash.Write([]byte(stringToSign))
+	query := req.URL.Query()
+	query.Set("AWSAccessKeyId", accessKeyID)
+	query.Set("Expires", expires)
+	query.Set("Signature", base64.StdEncoding.EncodeToString(hash.Sum(nil)))
+	req.URL.RawQuery = query.Encode()
+}
+
 func (s *MyAPIDonutCacheSuite) TestDeleteBucket(c *C) {
 	request, err := s.newRequest("DELETE", testAPIDonutCacheServer.URL+"/mybucket", 0, nil)
 	c.Assert(err, IsNil)
@@ -619,14 +749,14 @@ func (s *MyAPIDonutCacheSuite) TestContentTypePersists(c *C) {
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 
 	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/contenttype-persists/two", 0, nil)
 	c.Assert(err, IsNil)
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 }
 
 func (s *MyAPIDonutCacheSuite) TestPartialContent(c *C) {
@@ -1017,6 +1147,1086 @@ func (s *MyAPIDonutCacheSuite) TestObjectMultipart(c *C) {
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 }
 
+func (s *MyAPIDonutCacheSuite) TestBucketCORS(c *C) {
+	request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+"/cors-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/cors-bucket?cors", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchCORSConfiguration", "The CORS configuration does not exist.", http.StatusNotFound)
+
+	corsConfig := `<CORSConfiguration><CORSRule><AllowedOrigin>http://*.example.com</AllowedOrigin>` +
+		`<AllowedMethod>GET</AllowedMethod><AllowedMethod>PUT</AllowedMethod><AllowedHeader>x-amz-*</AllowedHeader>` +
+		`<ExposeHeader>ETag</ExposeHeader><MaxAgeSeconds>3000</MaxAgeSeconds></CORSRule></CORSConfiguration>`
+	buffer := bytes.NewReader([]byte(corsConfig))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/cors-bucket?cors", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer = bytes.NewReader([]byte("<CORSConfiguration></CORSConfiguration>"))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/cors-bucket?cors", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/cors-bucket?cors", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	config := CORSConfiguration{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&config)
+	c.Assert(err, IsNil)
+	c.Assert(len(config.CORSRule), Equals, 1)
+	c.Assert(config.CORSRule[0].AllowedOrigin[0], Equals, "http://*.example.com")
+
+	// preflight from an allowed origin
+	request, err = http.NewRequest("OPTIONS", testAPIDonutCacheServer.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.com")
+	request.Header.Set("Access-Control-Request-Method", "PUT")
+	request.Header.Set("Access-Control-Request-Headers", "X-Amz-Date, x-amz-content-sha256")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Access-Control-Allow-Origin"), Equals, "http://www.example.com")
+	c.Assert(response.Header.Get("Access-Control-Allow-Methods"), Equals, "GET, PUT")
+	c.Assert(response.Header.Get("Access-Control-Allow-Headers"), Equals, "X-Amz-Date, x-amz-content-sha256")
+	c.Assert(response.Header.Get("Access-Control-Max-Age"), Equals, "3000")
+
+	// preflight with a header not allowed
+	request, err = http.NewRequest("OPTIONS", testAPIDonutCacheServer.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.com")
+	request.Header.Set("Access-Control-Request-Method", "PUT")
+	request.Header.Set("Access-Control-Request-Headers", "Content-Type")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessForbidden", "CORSResponse: This CORS request is not allowed.", http.StatusForbidden)
+
+	// preflight from an unknown origin
+	request, err = http.NewRequest("OPTIONS", testAPIDonutCacheServer.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.org")
+	request.Header.Set("Access-Control-Request-Method", "GET")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessForbidden", "CORSResponse: This CORS request is not allowed.", http.StatusForbidden)
+
+	// actual request from an allowed origin
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/cors-bucket", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.com")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Access-Control-Allow-Origin"), Equals, "http://www.example.com")
+	c.Assert(response.Header.Get("Access-Control-Expose-Headers"), Equals, "ETag")
+	c.Assert(response.Header.Get("Vary"), Equals, "Origin")
+
+	request, err = s.newRequest("DELETE", testAPIDonutCacheServer.URL+"/cors-bucket?cors", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = http.NewRequest("OPTIONS", testAPIDonutCacheServer.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.com")
+	request.Header.Set("Access-Control-Request-Method", "GET")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusForbidden)
+}
+
+func (s *MyAPIDonutCacheSuite) TestTagging(c *C) {
+	request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+"/tagging-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/tagging-bucket?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchTagSet", "The TagSet does not exist.", http.StatusNotFound)
+
+	tagging := `<Tagging><TagSet><Tag><Key>team</Key><Value>storage</Value></Tag></TagSet></Tagging>`
+	buffer := bytes.NewReader([]byte(tagging))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/tagging-bucket?tagging", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/tagging-bucket?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	tagsResponse := Tagging{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&tagsResponse)
+	c.Assert(err, IsNil)
+	c.Assert(len(tagsResponse.TagSet.Tag), Equals, 1)
+	c.Assert(tagsResponse.TagSet.Tag[0], DeepEquals, Tag{Key: "team", Value: "storage"})
+
+	buffer = bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/tagging-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-tagging", "project=minio&env=test")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	tagsResponse = Tagging{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&tagsResponse)
+	c.Assert(err, IsNil)
+	c.Assert(tagsResponse.TagSet.Tag, DeepEquals, []Tag{{Key: "env", Value: "test"}, {Key: "project", Value: "minio"}})
+
+	// duplicate tag keys are not allowed
+	tagging = `<Tagging><TagSet><Tag><Key>env</Key><Value>test</Value></Tag><Tag><Key>env</Key><Value>prod</Value></Tag></TagSet></Tagging>`
+	buffer = bytes.NewReader([]byte(tagging))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/tagging-bucket/object?tagging", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidTag", "The tag provided was not a valid tag.", http.StatusBadRequest)
+
+	tagging = `<Tagging><TagSet><Tag><Key>env</Key><Value>prod</Value></Tag></TagSet></Tagging>`
+	buffer = bytes.NewReader([]byte(tagging))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/tagging-bucket/object?tagging", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	tagsResponse = Tagging{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&tagsResponse)
+	c.Assert(err, IsNil)
+	c.Assert(tagsResponse.TagSet.Tag, DeepEquals, []Tag{{Key: "env", Value: "prod"}})
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/tagging-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+
+	request, err = s.newRequest("DELETE", testAPIDonutCacheServer.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	tagsResponse = Tagging{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&tagsResponse)
+	c.Assert(err, IsNil)
+	c.Assert(len(tagsResponse.TagSet.Tag), Equals, 0)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/tagging-bucket/none?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+
+	request, err = s.newRequest("DELETE", testAPIDonutCacheServer.URL+"/tagging-bucket?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/tagging-bucket?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchTagSet", "The TagSet does not exist.", http.StatusNotFound)
+}
+
+func (s *MyAPIDonutCacheSuite) TestBucketNotification(c *C) {
+	request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+"/notification-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/notification-bucket?notification", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	config := donut.NotificationConfiguration{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&config)
+	c.Assert(err, IsNil)
+	c.Assert(len(config.QueueConfiguration), Equals, 0)
+
+	// no targets are configured on this server
+	notification := `<NotificationConfiguration><QueueConfiguration><Queue>arn:minio:sqs::1:webhook</Queue>` +
+		`<Event>s3:ObjectCreated:*</Event></QueueConfiguration></NotificationConfiguration>`
+	buffer := bytes.NewReader([]byte(notification))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/notification-bucket?notification", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "A specified destination ARN does not exist or is not well-formed.", http.StatusBadRequest)
+
+	notification = `<NotificationConfiguration><QueueConfiguration><Queue>arn:minio:sqs::1:webhook</Queue>` +
+		`<Event>s3:ObjectRestore:*</Event></QueueConfiguration></NotificationConfiguration>`
+	buffer = bytes.NewReader([]byte(notification))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/notification-bucket?notification", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "The event is not supported for notifications.", http.StatusBadRequest)
+
+	buffer = bytes.NewReader([]byte("<NotificationConfiguration></NotificationConfiguration>"))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/notification-bucket?notification", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/notification-missing?notification", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+}
+
+func (s *MyAPIDonutCacheSuite) TestBucketWebsite(c *C) {
+	request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+"/website-bucket", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Add("x-amz-acl", "public-read")
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/website-bucket?website", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration.", http.StatusNotFound)
+
+	// redirects are verified as is, do not follow them
+	websiteGet := func(host, path string) *http.Response {
+		request, err := http.NewRequest("GET", testWebsiteDonutCacheServer.URL+path, nil)
+		c.Assert(err, IsNil)
+		request.Host = host
+		response, err := http.DefaultTransport.RoundTrip(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+	response = websiteGet("website-bucket", "/")
+	verifyError(c, response, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration.", http.StatusNotFound)
+
+	buffer := bytes.NewReader([]byte("<WebsiteConfiguration><ErrorDocument><Key>error.html</Key></ErrorDocument></WebsiteConfiguration>"))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/website-bucket?website", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest)
+
+	websiteConfig := `<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument>` +
+		`<ErrorDocument><Key>error.html</Key></ErrorDocument><RoutingRules>` +
+		`<RoutingRule><Condition><KeyPrefixEquals>old/</KeyPrefixEquals></Condition>` +
+		`<Redirect><ReplaceKeyPrefixWith>new/</ReplaceKeyPrefixWith></Redirect></RoutingRule>` +
+		`<RoutingRule><Condition><KeyPrefixEquals>missing/</KeyPrefixEquals><HttpErrorCodeReturnedEquals>404</HttpErrorCodeReturnedEquals></Condition>` +
+		`<Redirect><HostName>example.com</HostName><HttpRedirectCode>302</HttpRedirectCode></Redirect></RoutingRule>` +
+		`</RoutingRules></WebsiteConfiguration>`
+	buffer = bytes.NewReader([]byte(websiteConfig))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/website-bucket?website", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/website-bucket?website", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	config := WebsiteConfiguration{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&config)
+	c.Assert(err, IsNil)
+	c.Assert(config.IndexDocument.Suffix, Equals, "index.html")
+	c.Assert(len(config.RoutingRules.RoutingRule), Equals, 2)
+
+	objects := map[string]string{
+		"index.html":      "home",
+		"docs/index.html": "documentation",
+		"error.html":      "page not found",
+		"moved":           "moved",
+	}
+	for object, content := range objects {
+		buffer = bytes.NewReader([]byte(content))
+		request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/website-bucket/"+object, int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		if object == "moved" {
+			request.Header.Set("x-amz-website-redirect-location", "/docs/")
+		}
+
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	// redirects to other hosts are accepted only as absolute http or https urls
+	for _, location := range []string{"//example.com/", "/\\example.com/", "example.com", "javascript:alert(1)", "ftp://example.com/"} {
+		buffer = bytes.NewReader([]byte("moved"))
+		request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/website-bucket/moved-elsewhere", int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		request.Header.Set("x-amz-website-redirect-location", location)
+
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		verifyError(c, response, "InvalidRedirectLocation", "The website redirect location must have a prefix of 'http://' or 'https://' or '/'.", http.StatusBadRequest)
+	}
+
+	request, err = s.newRequest("HEAD", testAPIDonutCacheServer.URL+"/website-bucket/moved", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("x-amz-website-redirect-location"), Equals, "/docs/")
+
+	response = websiteGet("website-bucket", "/")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "home")
+
+	response = websiteGet("website-bucket.example.com", "/docs/")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "documentation")
+
+	response = websiteGet("website-bucket", "/docs")
+	c.Assert(response.StatusCode, Equals, http.StatusFound)
+	c.Assert(response.Header.Get("Location"), Equals, "/docs/")
+
+	response = websiteGet("website-bucket", "/moved")
+	c.Assert(response.StatusCode, Equals, http.StatusMovedPermanently)
+	c.Assert(response.Header.Get("Location"), Equals, "/docs/")
+
+	response = websiteGet("website-bucket", "/old/page.html")
+	c.Assert(response.StatusCode, Equals, http.StatusMovedPermanently)
+	c.Assert(response.Header.Get("Location"), Equals, "http://website-bucket/new/page.html")
+
+	response = websiteGet("website-bucket", "/missing/page.html")
+	c.Assert(response.StatusCode, Equals, http.StatusFound)
+	c.Assert(response.Header.Get("Location"), Equals, "http://example.com/missing/page.html")
+
+	response = websiteGet("website-bucket", "/nothing.html")
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+	responseBody, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "page not found")
+
+	// only public buckets are served
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/website-private", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Add("x-amz-acl", "private")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer = bytes.NewReader([]byte(websiteConfig))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/website-private?website", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	response = websiteGet("website-private", "/")
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	request, err = s.newRequest("DELETE", testAPIDonutCacheServer.URL+"/website-bucket?website", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	response = websiteGet("website-bucket", "/")
+	verifyError(c, response, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration.", http.StatusNotFound)
+}
+
+func (s *MyAPIDonutCacheSuite) TestBucketLocation(c *C) {
+	request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+"/location-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/location-bucket?location", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	location := LocationResponse{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&location)
+	c.Assert(err, IsNil)
+	c.Assert(location.Location, Equals, "milkyway")
+
+	buffer := bytes.NewReader([]byte("<CreateBucketConfiguration><LocationConstraint>milkyway</LocationConstraint></CreateBucketConfiguration>"))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/location-bucket-constraint", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer = bytes.NewReader([]byte("<CreateBucketConfiguration><LocationConstraint>eu-west-1</LocationConstraint></CreateBucketConfiguration>"))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/location-bucket-other", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidLocationConstraint", "The specified location constraint is not valid.", http.StatusBadRequest)
+
+	// requests signed for another region are rejected
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/location-bucket?location", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Authorization", strings.Replace(request.Header.Get("Authorization"), "/milkyway/", "/eu-west-1/", 1))
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AuthorizationHeaderMalformed", "The authorization header is malformed; the region is wrong.", http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/location-bucket-none?location", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+}
+
+func (s *MyAPIDonutCacheSuite) TestObjectMetadata(c *C) {
+	request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+"/metadata-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/metadata-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-meta-color", "blue")
+	request.Header.Add("x-amz-meta-tags", "one")
+	request.Header.Add("x-amz-meta-tags", "two")
+	request.Header.Set("Cache-Control", "max-age=60")
+	request.Header.Set("Content-Disposition", "attachment; filename=object.txt")
+	request.Header.Set("Content-Encoding", "identity")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	for _, method := range []string{"HEAD", "GET"} {
+		request, err = s.newRequest(method, testAPIDonutCacheServer.URL+"/metadata-bucket/object", 0, nil)
+		c.Assert(err, IsNil)
+
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		c.Assert(response.Header.Get("x-amz-meta-color"), Equals, "blue")
+		c.Assert(response.Header.Get("x-amz-meta-tags"), Equals, "one,two")
+		c.Assert(response.Header.Get("Cache-Control"), Equals, "max-age=60")
+		c.Assert(response.Header.Get("Content-Disposition"), Equals, "attachment; filename=object.txt")
+		c.Assert(response.Header.Get("Content-Encoding"), Equals, "identity")
+		c.Assert(response.Header.Get("Expires"), Equals, "")
+	}
+
+	buffer = bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIDonutCacheServer.URL+"/metadata-bucket/large", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-meta-one", strings.Repeat("a", 1024))
+	request.Header.Set("x-amz-meta-two", strings.Repeat("b", 1024))
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MetadataTooLarge", "Your metadata headers exceed the maximum allowed metadata size.", http.StatusBadRequest)
+}
+
+func (s *MyAPIDonutCacheSuite) TestSignatureV2(c *C) {
+	request, err := http.NewRequest("PUT", testAPIDonutCacheServer.URL+"/v2-bucket", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = http.NewRequest("PUT", testAPIDonutCacheServer.URL+"/v2-bucket/v2-object", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	request.Header.Set("Content-Type", "text/plain")
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = http.NewRequest("GET", testAPIDonutCacheServer.URL+"/v2-bucket/v2-object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+
+	// signed with a wrong secret access key
+	request, err = http.NewRequest("GET", testAPIDonutCacheServer.URL+"/v2-bucket/v2-object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, "wrong-secret-access-key")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden)
+
+	request, err = http.NewRequest("GET", testAPIDonutCacheServer.URL+"/v2-bucket/v2-object", nil)
+	c.Assert(err, IsNil)
+	presignRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+}
+
+func (s *MyAPIDonutCacheSuite) TestPostPolicy(c *C) {
+	request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+"/post-policy-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	conditions := []string{
+		`{"bucket": "post-policy-bucket"}`,
+		`["starts-with", "$key", "uploads/"]`,
+		`["starts-with", "$Content-Type", "text/"]`,
+		`{"success_action_status": "201"}`,
+		`["content-length-range", 1, 16]`,
+	}
+	formValues := map[string]string{
+		"key":                   "uploads/post-object",
+		"Content-Type":          "text/plain",
+		"success_action_status": "201",
+	}
+	request, err = newPostPolicyRequest(testAPIDonutCacheServer.URL+"/post-policy-bucket", "milkyway", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusCreated)
+
+	postResponse := PostResponse{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&postResponse)
+	c.Assert(err, IsNil)
+	c.Assert(postResponse.Bucket, Equals, "post-policy-bucket")
+	c.Assert(postResponse.Key, Equals, "uploads/post-object")
+
+	request, err = s.newRequest("GET", testAPIDonutCacheServer.URL+"/post-policy-bucket/uploads/post-object", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Content-Type"), Equals, "text/plain")
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+
+	// key not satisfying the policy
+	formValues["key"] = "other/post-object"
+	request, err = newPostPolicyRequest(testAPIDonutCacheServer.URL+"/post-policy-bucket", "milkyway", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// form field not covered by the policy
+	formValues["key"] = "uploads/post-object-extra"
+	formValues["x-amz-meta-color"] = "blue"
+	request, err = newPostPolicyRequest(testAPIDonutCacheServer.URL+"/post-policy-bucket", "milkyway", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// file exceeding content-length-range
+	delete(formValues, "x-amz-meta-color")
+	formValues["key"] = "uploads/post-object-large"
+	request, err = newPostPolicyRequest(testAPIDonutCacheServer.URL+"/post-policy-bucket", "milkyway", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world, hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.", http.StatusBadRequest)
+
+	request, err = s.newRequest("HEAD", testAPIDonutCacheServer.URL+"/post-policy-bucket/uploads/post-object-large", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+
+	// redirect on success
+	conditions = append(conditions[:3], `{"success_action_redirect": "http://example.com/uploaded"}`)
+	delete(formValues, "success_action_status")
+	formValues["key"] = "uploads/post-object-redirect"
+	formValues["success_action_redirect"] = "http://example.com/uploaded"
+	request, err = newPostPolicyRequest(testAPIDonutCacheServer.URL+"/post-policy-bucket", "milkyway", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = http.DefaultTransport.RoundTrip(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusSeeOther)
+	location, err := url.Parse(response.Header.Get("Location"))
+	c.Assert(err, IsNil)
+	c.Assert(location.Host, Equals, "example.com")
+	c.Assert(location.Query().Get("key"), Equals, "uploads/post-object-redirect")
+}
+
+func (s *MyAPIDonutCacheSuite) TestUserPermissions(c *C) {
+	client := http.Client{}
+	for _, path := range []string{"/permissions-bucket", "/permissions-bucket/shared/object", "/permissions-bucket/private/object"} {
+		request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+path, 0, nil)
+		c.Assert(err, IsNil)
+
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	authConfig, perr := LoadConfig()
+	c.Assert(perr, IsNil)
+	users := make(map[string]*AuthUser)
+	for _, name := range []string{"reader", "other", "administrator"} {
+		accessKeyID, perr := generateAccessKeyID()
+		c.Assert(perr, IsNil)
+		secretAccessKey, perr := generateSecretAccessKey()
+		c.Assert(perr, IsNil)
+		users[name] = &AuthUser{
+			Name:            name,
+			AccessKeyID:     string(accessKeyID),
+			SecretAccessKey: string(secretAccessKey),
+		}
+		authConfig.Users[users[name].AccessKeyID] = users[name]
+	}
+	users["reader"].ReadOnly = true
+	users["reader"].Buckets = []string{"permissions-bucket/shared/"}
+	users["administrator"].Admin = true
+	perr = SaveConfig(authConfig)
+	c.Assert(perr, IsNil)
+
+	verifyStatus := func(user *AuthUser, method, path string, statusCode int) *http.Response {
+		request, err := http.NewRequest(method, testAPIDonutCacheServer.URL+path, nil)
+		c.Assert(err, IsNil)
+		signRequestV2(request, user.AccessKeyID, user.SecretAccessKey)
+
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, statusCode)
+		return response
+	}
+	// read-only access to objects under a prefix
+	verifyStatus(users["reader"], "GET", "/permissions-bucket/shared/object", http.StatusOK)
+	verifyStatus(users["reader"], "GET", "/permissions-bucket/private/object", http.StatusForbidden)
+	verifyStatus(users["reader"], "PUT", "/permissions-bucket/shared/new-object", http.StatusForbidden)
+	verifyStatus(users["reader"], "PUT", "/reader-bucket", http.StatusForbidden)
+
+	// no access to buckets of other users, full access to own buckets
+	verifyStatus(users["other"], "GET", "/permissions-bucket/shared/object", http.StatusForbidden)
+	verifyStatus(users["other"], "PUT", "/permissions-bucket/shared/new-object", http.StatusForbidden)
+	verifyStatus(users["other"], "PUT", "/permissions-bucket?acl", http.StatusForbidden)
+	verifyStatus(users["other"], "PUT", "/other-bucket", http.StatusOK)
+	verifyStatus(users["other"], "PUT", "/other-bucket/object", http.StatusOK)
+
+	response := verifyStatus(users["other"], "GET", "/", http.StatusOK)
+	listBuckets := ListBucketsResponse{}
+	decoder := xml.NewDecoder(response.Body)
+	err := decoder.Decode(&listBuckets)
+	c.Assert(err, IsNil)
+	c.Assert(listBuckets.Owner.DisplayName, Equals, "other")
+	c.Assert(len(listBuckets.Buckets.Bucket), Equals, 1)
+	c.Assert(listBuckets.Buckets.Bucket[0].Name, Equals, "other-bucket")
+
+	// admin users have full access
+	verifyStatus(users["administrator"], "GET", "/permissions-bucket/private/object", http.StatusOK)
+	response = verifyStatus(users["administrator"], "GET", "/", http.StatusOK)
+	listBuckets = ListBucketsResponse{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&listBuckets)
+	c.Assert(err, IsNil)
+	var names []string
+	for _, bucket := range listBuckets.Buckets.Bucket {
+		names = append(names, bucket.Name)
+	}
+	c.Assert(strings.Join(names, ","), Matches, ".*other-bucket.*")
+	c.Assert(strings.Join(names, ","), Matches, ".*permissions-bucket.*")
+
+	// objects are listed with the bucket owner
+	request, err := s.newRequest("GET", testAPIDonutCacheServer.URL+"/permissions-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listObjects := ListObjectsResponse{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&listObjects)
+	c.Assert(err, IsNil)
+	c.Assert(len(listObjects.Contents), Equals, 2)
+	c.Assert(listObjects.Contents[0].Owner.DisplayName, Equals, "testuser")
+}
+
+func (s *MyAPIDonutCacheSuite) TestSSECustomerKey(c *C) {
+	key := bytes.Repeat([]byte("k"), 32)
+	keyMD5 := md5.Sum(key)
+	setEncryption := func(request *http.Request, key []byte) {
+		keyMD5 := md5.Sum(key)
+		request.Header.Set(sseCustomerAlgorithmHeader, "AES256")
+		request.Header.Set(sseCustomerKeyHeader, base64.StdEncoding.EncodeToString(key))
+		request.Header.Set(sseCustomerKeyMD5Header, base64.StdEncoding.EncodeToString(keyMD5[:]))
+	}
+
+	// keys are rejected by servers not running with TLS
+	request, err := http.NewRequest("PUT", testAPIDonutCacheServer.URL+"/sse-insecure-bucket/object", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidRequest", "Requests specifying Server Side Encryption with Customer provided keys must be made over a secure connection.", http.StatusBadRequest)
+
+	tlsAPI := getNewAPI(false)
+	tlsAPI.TLS = true
+	go startTM(tlsAPI)
+	tlsServer := httptest.NewTLSServer(getAPIHandler(false, tlsAPI))
+	defer tlsServer.Close()
+	tlsClient := tlsServer.Client()
+
+	request, err = http.NewRequest("PUT", tlsServer.URL+"/sse-bucket", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = http.NewRequest("PUT", tlsServer.URL+"/sse-bucket/object", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseCustomerAlgorithmHeader), Equals, "AES256")
+	c.Assert(response.Header.Get(sseCustomerKeyMD5Header), Equals, base64.StdEncoding.EncodeToString(keyMD5[:]))
+
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "hello world")
+
+	request, err = http.NewRequest("HEAD", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseCustomerAlgorithmHeader), Equals, "AES256")
+
+	// reads without the key are rejected
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidRequest", "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.", http.StatusBadRequest)
+
+	// reads with a wrong key are rejected
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, bytes.Repeat([]byte("w"), 32))
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "The provided encryption key does not match the key the object was encrypted with.", http.StatusForbidden)
+
+	// key must match its MD5
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	request.Header.Set(sseCustomerKeyMD5Header, base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "The calculated MD5 hash of the key did not match the hash that was provided.", http.StatusBadRequest)
+
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	request.Header.Set(sseCustomerAlgorithmHeader, "AES128")
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidEncryptionAlgorithmError", "The encryption request you specified is not valid. The valid value is AES256.", http.StatusBadRequest)
+
+	// parts are accepted only with the key the upload was initiated with
+	request, err = http.NewRequest("POST", tlsServer.URL+"/sse-bucket/multipart?uploads", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	decoder := xml.NewDecoder(response.Body)
+	newResponse := &InitiateMultipartUploadResponse{}
+	c.Assert(decoder.Decode(newResponse), IsNil)
+
+	request, err = http.NewRequest("PUT", tlsServer.URL+"/sse-bucket/multipart?uploadId="+newResponse.UploadID+"&partNumber=1", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidRequest", "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.", http.StatusBadRequest)
+}
+
+func (s *MyAPIDonutCacheSuite) TestServerSideEncryption(c *C) {
+	request, err := s.newRequest("PUT", testAPIDonutCacheServer.URL+"/sse-s3-plain-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	putObject := func(serverURL, bucket, object, method string) *http.Response {
+		request, err := http.NewRequest("PUT", serverURL+"/"+bucket+"/"+object, bytes.NewReader([]byte("hello world")))
+		c.Assert(err, IsNil)
+		if method != "" {
+			request.Header.Set(sseHeader, method)
+		}
+		signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+
+	// server managed keys require a master key
+	response = putObject(testAPIDonutCacheServer.URL, "sse-s3-plain-bucket", "object", "AES256")
+	verifyError(c, response, "NotImplemented", "A header you provided implies functionality that is not implemented.", http.StatusNotImplemented)
+
+	response = putObject(testAPIDonutCacheServer.URL, "sse-s3-plain-bucket", "object", "aws:kms")
+	verifyError(c, response, "InvalidArgument", "The encryption method specified is not supported.", http.StatusBadRequest)
+
+	keys, perr := donut.LoadKeyStore()
+	c.Assert(perr, IsNil)
+	_, perr = keys.AddKey()
+	c.Assert(perr, IsNil)
+	c.Assert(donut.SaveKeyStore(keys), IsNil)
+
+	keysAPI := getNewAPI(false)
+	go startTM(keysAPI)
+	keysServer := httptest.NewServer(getAPIHandler(false, keysAPI))
+	defer keysServer.Close()
+
+	request, err = s.newRequest("PUT", keysServer.URL+"/sse-s3-bucket", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	response = putObject(keysServer.URL, "sse-s3-bucket", "object", "AES256")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "AES256")
+
+	request, err = http.NewRequest("GET", keysServer.URL+"/sse-s3-bucket/object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "AES256")
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "hello world")
+
+	request, err = http.NewRequest("HEAD", keysServer.URL+"/sse-s3-bucket/object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "AES256")
+
+	// customer provided and server managed keys are exclusive
+	request, err = http.NewRequest("PUT", keysServer.URL+"/sse-s3-bucket/conflicting", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	request.Header.Set(sseHeader, "AES256")
+	request.Header.Set(sseCustomerAlgorithmHeader, "AES256")
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Server side encryption specified with both SSE-C and SSE-S3 headers.", http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", keysServer.URL+"/sse-s3-bucket?encryption", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "ServerSideEncryptionConfigurationNotFoundError", "The server side encryption configuration was not found.", http.StatusNotFound)
+
+	buffer := bytes.NewReader([]byte("<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>aws:kms</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>"))
+	request, err = s.newRequest("PUT", keysServer.URL+"/sse-s3-bucket?encryption", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest)
+
+	buffer = bytes.NewReader([]byte("<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>"))
+	request, err = s.newRequest("PUT", keysServer.URL+"/sse-s3-bucket?encryption", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", keysServer.URL+"/sse-s3-bucket?encryption", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	config := ServerSideEncryptionConfiguration{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(&config), IsNil)
+	c.Assert(len(config.Rules), Equals, 1)
+	c.Assert(config.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm, Equals, "AES256")
+
+	// new objects are encrypted by default
+	response = putObject(keysServer.URL, "sse-s3-bucket", "default", "")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "AES256")
+
+	request, err = s.newRequest("DELETE", keysServer.URL+"/sse-s3-bucket?encryption", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	response = putObject(keysServer.URL, "sse-s3-bucket", "plain", "")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "")
+}
+
 func verifyError(c *C, response *http.Response, code, description string, statusCode int) {
 	data, err := ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
@@ -18,6 +18,8 @@ package main
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/base64"
 	"io"
 	"io/ioutil"
 	"os"
@@ -31,6 +33,7 @@ import (
 	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 
 	"github.com/minio/minio-xl/pkg/donut"
 	. "gopkg.in/check.v1"
@@ -48,6 +51,8 @@ var _ = Suite(&MyAPISignatureV4Suite{})
 
 var testSignatureV4Server *httptest.Server
 
+var testWebsiteSignatureV4Server *httptest.Server
+
 // create a dummy TestNodeDiskMap
 func createTestNodeDiskMap(p string) map[string][]string {
 	nodes := make(map[string][]string)
@@ -72,6 +77,7 @@ func (s *MyAPISignatureV4Suite) SetUpSuite(c *C) {
 	conf := &donut.Config{}
 	conf.Version = "0.0.1"
 	conf.DonutName = "test"
+	conf.Region = "us-east-1"
 	conf.NodeDiskMap = createTestNodeDiskMap(root)
 	conf.MaxSize = 100000
 	donut.SetDonutConfigPath(filepath.Join(root, "donut.json"))
@@ -101,11 +107,13 @@ func (s *MyAPISignatureV4Suite) SetUpSuite(c *C) {
 	httpHandler := getAPIHandler(false, minioAPI)
 	go startTM(minioAPI)
 	testSignatureV4Server = httptest.NewServer(httpHandler)
+	testWebsiteSignatureV4Server = httptest.NewServer(getWebsiteHandler(minioAPI))
 }
 
 func (s *MyAPISignatureV4Suite) TearDownSuite(c *C) {
 	os.RemoveAll(s.root)
 	testSignatureV4Server.Close()
+	testWebsiteSignatureV4Server.Close()
 }
 
 func (s *MyAPISignatureV4Suite) newRequest(method, urlStr string, contentLength int64, body io.ReadSeeker) (*http.Request, error) {
@@ -202,7 +210,7 @@ func (s *MyAPISignatureV4Suite) newRequest(method, urlStr string, contentLength
 
 	scope := strings.Join([]string{
 		t.Format(yyyymmdd),
-		"milkyway",
+		"us-east-1",
 		"s3",
 		"aws4_request",
 	}, "/")
@@ -212,7 +220,7 @@ func (s *MyAPISignatureV4Suite) newRequest(method, urlStr string, contentLength
 	stringToSign = stringToSign + hex.EncodeToString(sum256([]byte(canonicalRequest)))
 
 	date := sumHMAC([]byte("AWS4"+s.secretAccessKey), []byte(t.Format(yyyymmdd)))
-	region := sumHMAC(date, []byte("milkyway"))
+	region := sumHMAC(date, []byte("us-east-1"))
 	service := sumHMAC(region, []byte("s3"))
 	signingKey := sumHMAC(service, []byte("aws4_request"))
 
@@ -638,14 +646,14 @@ func (s *MyAPISignatureV4Suite) TestContentTypePersists(c *C) {
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 
 	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/contenttype-persists/two", 0, nil)
 	c.Assert(err, IsNil)
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 }
 
 func (s *MyAPISignatureV4Suite) TestPartialContent(c *C) {
@@ -716,6 +724,94 @@ func (s *MyAPISignatureV4Suite) TestListObjectsHandlerErrors(c *C) {
 	verifyError(c, response, "InvalidArgument", "Argument maxKeys must be an integer between 0 and 2147483647.", http.StatusBadRequest)
 }
 
+func (s *MyAPISignatureV4Suite) TestListObjectsV2(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/listobjectsv2", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Add("x-amz-acl", "private")
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objects := []string{"dir/a=b", "dir/c", "top1", "top2", "top3"}
+	for _, object := range objects {
+		buffer := bytes.NewReader([]byte("hello world"))
+		request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/listobjectsv2/"+object, int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	listObjectsV2 := func(query string) ListObjectsV2Response {
+		request, err := s.newRequest("GET", testSignatureV4Server.URL+"/listobjectsv2?list-type=2&"+query, 0, nil)
+		c.Assert(err, IsNil)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		listObjects := ListObjectsV2Response{}
+		c.Assert(xml.NewDecoder(response.Body).Decode(&listObjects), IsNil)
+		return listObjects
+	}
+
+	// pages are resumed from continuation tokens
+	var keys []string
+	token := ""
+	for {
+		query := "max-keys=2"
+		if token != "" {
+			query += "&continuation-token=" + url.QueryEscape(token)
+		}
+		listObjects := listObjectsV2(query)
+		c.Assert(listObjects.KeyCount, Equals, len(listObjects.Contents))
+		c.Assert(listObjects.ContinuationToken, Equals, token)
+		for _, content := range listObjects.Contents {
+			c.Assert(content.Owner, IsNil)
+			keys = append(keys, content.Key)
+		}
+		if !listObjects.IsTruncated {
+			c.Assert(listObjects.NextContinuationToken, Equals, "")
+			break
+		}
+		c.Assert(listObjects.KeyCount, Equals, 2)
+		token = listObjects.NextContinuationToken
+		c.Assert(token, Not(Equals), "")
+	}
+	c.Assert(keys, DeepEquals, objects)
+
+	// common prefixes count as keys, owner is returned if asked for
+	listObjects := listObjectsV2("delimiter=/&fetch-owner=true")
+	c.Assert(listObjects.KeyCount, Equals, 4)
+	c.Assert(len(listObjects.CommonPrefixes), Equals, 1)
+	c.Assert(listObjects.CommonPrefixes[0].Prefix, Equals, "dir/")
+	c.Assert(len(listObjects.Contents), Equals, 3)
+	c.Assert(listObjects.Contents[0].Owner, Not(IsNil))
+
+	listObjects = listObjectsV2("start-after=top1")
+	c.Assert(listObjects.StartAfter, Equals, "top1")
+	c.Assert(len(listObjects.Contents), Equals, 2)
+	c.Assert(listObjects.Contents[0].Key, Equals, "top2")
+
+	listObjects = listObjectsV2("prefix=dir/&encoding-type=url")
+	c.Assert(listObjects.EncodingType, Equals, "url")
+	c.Assert(listObjects.Prefix, Equals, "dir%2F")
+	c.Assert(len(listObjects.Contents), Equals, 2)
+	c.Assert(listObjects.Contents[0].Key, Equals, "dir%2Fa%3Db")
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/listobjectsv2?list-type=2&continuation-token=%21%21", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "The continuation token provided is incorrect.", http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/listobjectsv2?list-type=2&encoding-type=base64", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Invalid Encoding Method specified in Request.", http.StatusBadRequest)
+}
+
 func (s *MyAPISignatureV4Suite) TestPutBucketErrors(c *C) {
 	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/putbucket-.", 0, nil)
 	c.Assert(err, IsNil)
@@ -1035,3 +1131,1168 @@ func (s *MyAPISignatureV4Suite) TestObjectMultipart(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 }
+
+func (s *MyAPISignatureV4Suite) TestBucketCORS(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/cors-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/cors-bucket?cors", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchCORSConfiguration", "The CORS configuration does not exist.", http.StatusNotFound)
+
+	corsConfig := `<CORSConfiguration><CORSRule><AllowedOrigin>http://*.example.com</AllowedOrigin>` +
+		`<AllowedMethod>GET</AllowedMethod><AllowedMethod>PUT</AllowedMethod><AllowedHeader>x-amz-*</AllowedHeader>` +
+		`<ExposeHeader>ETag</ExposeHeader><MaxAgeSeconds>3000</MaxAgeSeconds></CORSRule></CORSConfiguration>`
+	buffer := bytes.NewReader([]byte(corsConfig))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/cors-bucket?cors", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer = bytes.NewReader([]byte("<CORSConfiguration></CORSConfiguration>"))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/cors-bucket?cors", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest)
+
+	// configuration is read as a signed payload, oversized or tampered configurations are refused
+	buffer = bytes.NewReader(bytes.Repeat([]byte(" "), maxCORSConfigSize+1))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/cors-bucket?cors", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest)
+
+	buffer = bytes.NewReader([]byte(corsConfig))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/cors-bucket?cors", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	request.Body = ioutil.NopCloser(strings.NewReader(strings.Replace(corsConfig, "GET", "DEL", 1)))
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/cors-bucket?cors", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	config := CORSConfiguration{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&config)
+	c.Assert(err, IsNil)
+	c.Assert(len(config.CORSRule), Equals, 1)
+	c.Assert(config.CORSRule[0].AllowedOrigin[0], Equals, "http://*.example.com")
+
+	// preflight from an allowed origin
+	request, err = http.NewRequest("OPTIONS", testSignatureV4Server.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.com")
+	request.Header.Set("Access-Control-Request-Method", "PUT")
+	request.Header.Set("Access-Control-Request-Headers", "X-Amz-Date, x-amz-content-sha256")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Access-Control-Allow-Origin"), Equals, "http://www.example.com")
+	c.Assert(response.Header.Get("Access-Control-Allow-Methods"), Equals, "GET, PUT")
+	c.Assert(response.Header.Get("Access-Control-Allow-Headers"), Equals, "X-Amz-Date, x-amz-content-sha256")
+	c.Assert(response.Header.Get("Access-Control-Max-Age"), Equals, "3000")
+
+	// preflight with a header not allowed
+	request, err = http.NewRequest("OPTIONS", testSignatureV4Server.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.com")
+	request.Header.Set("Access-Control-Request-Method", "PUT")
+	request.Header.Set("Access-Control-Request-Headers", "Content-Type")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessForbidden", "CORSResponse: This CORS request is not allowed.", http.StatusForbidden)
+
+	// preflight from an unknown origin
+	request, err = http.NewRequest("OPTIONS", testSignatureV4Server.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.org")
+	request.Header.Set("Access-Control-Request-Method", "GET")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessForbidden", "CORSResponse: This CORS request is not allowed.", http.StatusForbidden)
+
+	// actual request from an allowed origin
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/cors-bucket", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.com")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Access-Control-Allow-Origin"), Equals, "http://www.example.com")
+	c.Assert(response.Header.Get("Access-Control-Expose-Headers"), Equals, "ETag")
+	c.Assert(response.Header.Get("Vary"), Equals, "Origin")
+
+	request, err = s.newRequest("DELETE", testSignatureV4Server.URL+"/cors-bucket?cors", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = http.NewRequest("OPTIONS", testSignatureV4Server.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "http://www.example.com")
+	request.Header.Set("Access-Control-Request-Method", "GET")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusForbidden)
+}
+
+func (s *MyAPISignatureV4Suite) TestTagging(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/tagging-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/tagging-bucket?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchTagSet", "The TagSet does not exist.", http.StatusNotFound)
+
+	tagging := `<Tagging><TagSet><Tag><Key>team</Key><Value>storage</Value></Tag></TagSet></Tagging>`
+	buffer := bytes.NewReader([]byte(tagging))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/tagging-bucket?tagging", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/tagging-bucket?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	tagsResponse := Tagging{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&tagsResponse)
+	c.Assert(err, IsNil)
+	c.Assert(len(tagsResponse.TagSet.Tag), Equals, 1)
+	c.Assert(tagsResponse.TagSet.Tag[0], DeepEquals, Tag{Key: "team", Value: "storage"})
+
+	buffer = bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/tagging-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-tagging", "project=minio&env=test")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	tagsResponse = Tagging{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&tagsResponse)
+	c.Assert(err, IsNil)
+	c.Assert(tagsResponse.TagSet.Tag, DeepEquals, []Tag{{Key: "env", Value: "test"}, {Key: "project", Value: "minio"}})
+
+	// duplicate tag keys are not allowed
+	tagging = `<Tagging><TagSet><Tag><Key>env</Key><Value>test</Value></Tag><Tag><Key>env</Key><Value>prod</Value></Tag></TagSet></Tagging>`
+	buffer = bytes.NewReader([]byte(tagging))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/tagging-bucket/object?tagging", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidTag", "The tag provided was not a valid tag.", http.StatusBadRequest)
+
+	tagging = `<Tagging><TagSet><Tag><Key>env</Key><Value>prod</Value></Tag></TagSet></Tagging>`
+	buffer = bytes.NewReader([]byte(tagging))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/tagging-bucket/object?tagging", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	tagsResponse = Tagging{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&tagsResponse)
+	c.Assert(err, IsNil)
+	c.Assert(tagsResponse.TagSet.Tag, DeepEquals, []Tag{{Key: "env", Value: "prod"}})
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/tagging-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+
+	request, err = s.newRequest("DELETE", testSignatureV4Server.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	tagsResponse = Tagging{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&tagsResponse)
+	c.Assert(err, IsNil)
+	c.Assert(len(tagsResponse.TagSet.Tag), Equals, 0)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/tagging-bucket/none?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+
+	request, err = s.newRequest("DELETE", testSignatureV4Server.URL+"/tagging-bucket?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/tagging-bucket?tagging", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchTagSet", "The TagSet does not exist.", http.StatusNotFound)
+}
+
+func (s *MyAPISignatureV4Suite) TestBucketNotification(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/notification-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/notification-bucket?notification", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	config := donut.NotificationConfiguration{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&config)
+	c.Assert(err, IsNil)
+	c.Assert(len(config.QueueConfiguration), Equals, 0)
+
+	// no targets are configured on this server
+	notification := `<NotificationConfiguration><QueueConfiguration><Queue>arn:minio:sqs::1:webhook</Queue>` +
+		`<Event>s3:ObjectCreated:*</Event></QueueConfiguration></NotificationConfiguration>`
+	buffer := bytes.NewReader([]byte(notification))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/notification-bucket?notification", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "A specified destination ARN does not exist or is not well-formed.", http.StatusBadRequest)
+
+	notification = `<NotificationConfiguration><QueueConfiguration><Queue>arn:minio:sqs::1:webhook</Queue>` +
+		`<Event>s3:ObjectRestore:*</Event></QueueConfiguration></NotificationConfiguration>`
+	buffer = bytes.NewReader([]byte(notification))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/notification-bucket?notification", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "The event is not supported for notifications.", http.StatusBadRequest)
+
+	buffer = bytes.NewReader([]byte("<NotificationConfiguration></NotificationConfiguration>"))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/notification-bucket?notification", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/notification-missing?notification", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+}
+
+func (s *MyAPISignatureV4Suite) TestBucketWebsite(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/website-bucket", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Add("x-amz-acl", "public-read")
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/website-bucket?website", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration.", http.StatusNotFound)
+
+	// redirects are verified as is, do not follow them
+	websiteGet := func(host, path string) *http.Response {
+		request, err := http.NewRequest("GET", testWebsiteSignatureV4Server.URL+path, nil)
+		c.Assert(err, IsNil)
+		request.Host = host
+		response, err := http.DefaultTransport.RoundTrip(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+	response = websiteGet("website-bucket", "/")
+	verifyError(c, response, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration.", http.StatusNotFound)
+
+	buffer := bytes.NewReader([]byte("<WebsiteConfiguration><ErrorDocument><Key>error.html</Key></ErrorDocument></WebsiteConfiguration>"))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/website-bucket?website", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest)
+
+	websiteConfig := `<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument>` +
+		`<ErrorDocument><Key>error.html</Key></ErrorDocument><RoutingRules>` +
+		`<RoutingRule><Condition><KeyPrefixEquals>old/</KeyPrefixEquals></Condition>` +
+		`<Redirect><ReplaceKeyPrefixWith>new/</ReplaceKeyPrefixWith></Redirect></RoutingRule>` +
+		`<RoutingRule><Condition><KeyPrefixEquals>missing/</KeyPrefixEquals><HttpErrorCodeReturnedEquals>404</HttpErrorCodeReturnedEquals></Condition>` +
+		`<Redirect><HostName>example.com</HostName><HttpRedirectCode>302</HttpRedirectCode></Redirect></RoutingRule>` +
+		`</RoutingRules></WebsiteConfiguration>`
+	buffer = bytes.NewReader([]byte(websiteConfig))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/website-bucket?website", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/website-bucket?website", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	config := WebsiteConfiguration{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&config)
+	c.Assert(err, IsNil)
+	c.Assert(config.IndexDocument.Suffix, Equals, "index.html")
+	c.Assert(len(config.RoutingRules.RoutingRule), Equals, 2)
+
+	objects := map[string]string{
+		"index.html":      "home",
+		"docs/index.html": "documentation",
+		"error.html":      "page not found",
+		"moved":           "moved",
+	}
+	for object, content := range objects {
+		buffer = bytes.NewReader([]byte(content))
+		request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/website-bucket/"+object, int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		if object == "moved" {
+			request.Header.Set("x-amz-website-redirect-location", "/docs/")
+		}
+
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	// redirects to other hosts are accepted only as absolute http or https urls
+	for _, location := range []string{"//example.com/", "/\\example.com/", "example.com", "javascript:alert(1)", "ftp://example.com/"} {
+		buffer = bytes.NewReader([]byte("moved"))
+		request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/website-bucket/moved-elsewhere", int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		request.Header.Set("x-amz-website-redirect-location", location)
+
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		verifyError(c, response, "InvalidRedirectLocation", "The website redirect location must have a prefix of 'http://' or 'https://' or '/'.", http.StatusBadRequest)
+	}
+
+	request, err = s.newRequest("HEAD", testSignatureV4Server.URL+"/website-bucket/moved", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("x-amz-website-redirect-location"), Equals, "/docs/")
+
+	response = websiteGet("website-bucket", "/")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "home")
+
+	response = websiteGet("website-bucket.example.com", "/docs/")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "documentation")
+
+	response = websiteGet("website-bucket", "/docs")
+	c.Assert(response.StatusCode, Equals, http.StatusFound)
+	c.Assert(response.Header.Get("Location"), Equals, "/docs/")
+
+	response = websiteGet("website-bucket", "/moved")
+	c.Assert(response.StatusCode, Equals, http.StatusMovedPermanently)
+	c.Assert(response.Header.Get("Location"), Equals, "/docs/")
+
+	response = websiteGet("website-bucket", "/old/page.html")
+	c.Assert(response.StatusCode, Equals, http.StatusMovedPermanently)
+	c.Assert(response.Header.Get("Location"), Equals, "http://website-bucket/new/page.html")
+
+	response = websiteGet("website-bucket", "/missing/page.html")
+	c.Assert(response.StatusCode, Equals, http.StatusFound)
+	c.Assert(response.Header.Get("Location"), Equals, "http://example.com/missing/page.html")
+
+	response = websiteGet("website-bucket", "/nothing.html")
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+	responseBody, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "page not found")
+
+	// only public buckets are served
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/website-private", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Add("x-amz-acl", "private")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer = bytes.NewReader([]byte(websiteConfig))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/website-private?website", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	response = websiteGet("website-private", "/")
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	request, err = s.newRequest("DELETE", testSignatureV4Server.URL+"/website-bucket?website", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	response = websiteGet("website-bucket", "/")
+	verifyError(c, response, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration.", http.StatusNotFound)
+}
+
+func (s *MyAPISignatureV4Suite) TestBucketLocation(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/location-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/location-bucket?location", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	location := LocationResponse{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&location)
+	c.Assert(err, IsNil)
+	c.Assert(location.Location, Equals, "")
+
+	buffer := bytes.NewReader([]byte("<CreateBucketConfiguration><LocationConstraint>us-east-1</LocationConstraint></CreateBucketConfiguration>"))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/location-bucket-constraint", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer = bytes.NewReader([]byte("<CreateBucketConfiguration><LocationConstraint>eu-west-1</LocationConstraint></CreateBucketConfiguration>"))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/location-bucket-other", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidLocationConstraint", "The specified location constraint is not valid.", http.StatusBadRequest)
+
+	// requests signed for another region are rejected
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/location-bucket?location", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Authorization", strings.Replace(request.Header.Get("Authorization"), "/us-east-1/", "/eu-west-1/", 1))
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AuthorizationHeaderMalformed", "The authorization header is malformed; the region is wrong.", http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/location-bucket-none?location", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+}
+
+func (s *MyAPISignatureV4Suite) TestObjectMetadata(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/metadata-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/metadata-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-meta-color", "blue")
+	request.Header.Add("x-amz-meta-tags", "one")
+	request.Header.Add("x-amz-meta-tags", "two")
+	request.Header.Set("Cache-Control", "max-age=60")
+	request.Header.Set("Content-Disposition", "attachment; filename=object.txt")
+	request.Header.Set("Content-Encoding", "identity")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	for _, method := range []string{"HEAD", "GET"} {
+		request, err = s.newRequest(method, testSignatureV4Server.URL+"/metadata-bucket/object", 0, nil)
+		c.Assert(err, IsNil)
+
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		c.Assert(response.Header.Get("x-amz-meta-color"), Equals, "blue")
+		c.Assert(response.Header.Get("x-amz-meta-tags"), Equals, "one,two")
+		c.Assert(response.Header.Get("Cache-Control"), Equals, "max-age=60")
+		c.Assert(response.Header.Get("Content-Disposition"), Equals, "attachment; filename=object.txt")
+		c.Assert(response.Header.Get("Content-Encoding"), Equals, "identity")
+		c.Assert(response.Header.Get("Expires"), Equals, "")
+	}
+
+	buffer = bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testSignatureV4Server.URL+"/metadata-bucket/large", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-meta-one", strings.Repeat("a", 1024))
+	request.Header.Set("x-amz-meta-two", strings.Repeat("b", 1024))
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MetadataTooLarge", "Your metadata headers exceed the maximum allowed metadata size.", http.StatusBadRequest)
+}
+
+func (s *MyAPISignatureV4Suite) TestSignatureV2(c *C) {
+	request, err := http.NewRequest("PUT", testSignatureV4Server.URL+"/v2-bucket", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = http.NewRequest("PUT", testSignatureV4Server.URL+"/v2-bucket/v2-object", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	request.Header.Set("Content-Type", "text/plain")
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = http.NewRequest("GET", testSignatureV4Server.URL+"/v2-bucket/v2-object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+
+	// signed with a wrong secret access key
+	request, err = http.NewRequest("GET", testSignatureV4Server.URL+"/v2-bucket/v2-object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, "wrong-secret-access-key")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden)
+
+	request, err = http.NewRequest("GET", testSignatureV4Server.URL+"/v2-bucket/v2-object", nil)
+	c.Assert(err, IsNil)
+	presignRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+}
+
+func (s *MyAPISignatureV4Suite) TestPostPolicy(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/post-policy-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	conditions := []string{
+		`{"bucket": "post-policy-bucket"}`,
+		`["starts-with", "$key", "uploads/"]`,
+		`["starts-with", "$Content-Type", "text/"]`,
+		`{"success_action_status": "201"}`,
+		`["content-length-range", 1, 16]`,
+	}
+	formValues := map[string]string{
+		"key":                   "uploads/post-object",
+		"Content-Type":          "text/plain",
+		"success_action_status": "201",
+	}
+	request, err = newPostPolicyRequest(testSignatureV4Server.URL+"/post-policy-bucket", "us-east-1", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusCreated)
+
+	postResponse := PostResponse{}
+	decoder := xml.NewDecoder(response.Body)
+	err = decoder.Decode(&postResponse)
+	c.Assert(err, IsNil)
+	c.Assert(postResponse.Bucket, Equals, "post-policy-bucket")
+	c.Assert(postResponse.Key, Equals, "uploads/post-object")
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/post-policy-bucket/uploads/post-object", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Content-Type"), Equals, "text/plain")
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+
+	// key not satisfying the policy
+	formValues["key"] = "other/post-object"
+	request, err = newPostPolicyRequest(testSignatureV4Server.URL+"/post-policy-bucket", "us-east-1", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// form field not covered by the policy
+	formValues["key"] = "uploads/post-object-extra"
+	formValues["x-amz-meta-color"] = "blue"
+	request, err = newPostPolicyRequest(testSignatureV4Server.URL+"/post-policy-bucket", "us-east-1", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// file exceeding content-length-range
+	delete(formValues, "x-amz-meta-color")
+	formValues["key"] = "uploads/post-object-large"
+	request, err = newPostPolicyRequest(testSignatureV4Server.URL+"/post-policy-bucket", "us-east-1", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world, hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.", http.StatusBadRequest)
+
+	request, err = s.newRequest("HEAD", testSignatureV4Server.URL+"/post-policy-bucket/uploads/post-object-large", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+
+	// redirect on success
+	conditions = append(conditions[:3], `{"success_action_redirect": "http://example.com/uploaded"}`)
+	delete(formValues, "success_action_status")
+	formValues["key"] = "uploads/post-object-redirect"
+	formValues["success_action_redirect"] = "http://example.com/uploaded"
+	request, err = newPostPolicyRequest(testSignatureV4Server.URL+"/post-policy-bucket", "us-east-1", s.accessKeyID, s.secretAccessKey, conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = http.DefaultTransport.RoundTrip(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusSeeOther)
+	location, err := url.Parse(response.Header.Get("Location"))
+	c.Assert(err, IsNil)
+	c.Assert(location.Host, Equals, "example.com")
+	c.Assert(location.Query().Get("key"), Equals, "uploads/post-object-redirect")
+}
+
+func (s *MyAPISignatureV4Suite) TestPostPolicySession(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/post-policy-session-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// temporary credentials acting for the test user
+	authConfig, perr := LoadConfig()
+	c.Assert(perr, IsNil)
+	sessionAccessKeyID, perr := generateAccessKeyID()
+	c.Assert(perr, IsNil)
+	sessionSecretAccessKey, perr := generateSecretAccessKey()
+	c.Assert(perr, IsNil)
+	sessionToken, perr := generateSessionToken()
+	c.Assert(perr, IsNil)
+	if authConfig.Sessions == nil {
+		authConfig.Sessions = make(map[string]*AuthSession)
+	}
+	authConfig.Sessions[string(sessionAccessKeyID)] = &AuthSession{
+		AccessKeyID:     string(sessionAccessKeyID),
+		SecretAccessKey: string(sessionSecretAccessKey),
+		SessionToken:    sessionToken,
+		User:            "testuser",
+		Expiry:          time.Now().UTC().Add(time.Hour),
+	}
+	c.Assert(SaveConfig(authConfig), IsNil)
+
+	conditions := []string{
+		`{"bucket": "post-policy-session-bucket"}`,
+		`["starts-with", "$key", "uploads/"]`,
+		`{"x-amz-security-token": "` + sessionToken + `"}`,
+	}
+	formValues := map[string]string{
+		"key":                  "uploads/post-object",
+		"x-amz-security-token": sessionToken,
+	}
+	request, err = newPostPolicyRequest(testSignatureV4Server.URL+"/post-policy-session-bucket", "us-east-1", string(sessionAccessKeyID), string(sessionSecretAccessKey), conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = s.newRequest("GET", testSignatureV4Server.URL+"/post-policy-session-bucket/uploads/post-object", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+
+	// temporary credentials are not valid without their session token
+	conditions = conditions[:2]
+	delete(formValues, "x-amz-security-token")
+	request, err = newPostPolicyRequest(testSignatureV4Server.URL+"/post-policy-session-bucket", "us-east-1", string(sessionAccessKeyID), string(sessionSecretAccessKey), conditions, formValues, []byte("hello world"))
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidToken", "The provided token is malformed or otherwise invalid.", http.StatusBadRequest)
+}
+
+func (s *MyAPISignatureV4Suite) TestUserPermissions(c *C) {
+	client := http.Client{}
+	for _, path := range []string{"/permissions-bucket", "/permissions-bucket/shared/object", "/permissions-bucket/private/object"} {
+		request, err := s.newRequest("PUT", testSignatureV4Server.URL+path, 0, nil)
+		c.Assert(err, IsNil)
+
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	authConfig, perr := LoadConfig()
+	c.Assert(perr, IsNil)
+	users := make(map[string]*AuthUser)
+	for _, name := range []string{"reader", "other", "administrator"} {
+		accessKeyID, perr := generateAccessKeyID()
+		c.Assert(perr, IsNil)
+		secretAccessKey, perr := generateSecretAccessKey()
+		c.Assert(perr, IsNil)
+		users[name] = &AuthUser{
+			Name:            name,
+			AccessKeyID:     string(accessKeyID),
+			SecretAccessKey: string(secretAccessKey),
+		}
+		authConfig.Users[users[name].AccessKeyID] = users[name]
+	}
+	users["reader"].ReadOnly = true
+	users["reader"].Buckets = []string{"permissions-bucket/shared/"}
+	users["administrator"].Admin = true
+	perr = SaveConfig(authConfig)
+	c.Assert(perr, IsNil)
+
+	verifyStatus := func(user *AuthUser, method, path string, statusCode int) *http.Response {
+		request, err := http.NewRequest(method, testSignatureV4Server.URL+path, nil)
+		c.Assert(err, IsNil)
+		signRequestV2(request, user.AccessKeyID, user.SecretAccessKey)
+
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, statusCode)
+		return response
+	}
+	// read-only access to objects under a prefix
+	verifyStatus(users["reader"], "GET", "/permissions-bucket/shared/object", http.StatusOK)
+	verifyStatus(users["reader"], "GET", "/permissions-bucket/private/object", http.StatusForbidden)
+	verifyStatus(users["reader"], "PUT", "/permissions-bucket/shared/new-object", http.StatusForbidden)
+	verifyStatus(users["reader"], "PUT", "/reader-bucket", http.StatusForbidden)
+
+	// no access to buckets of other users, full access to own buckets
+	verifyStatus(users["other"], "GET", "/permissions-bucket/shared/object", http.StatusForbidden)
+	verifyStatus(users["other"], "PUT", "/permissions-bucket/shared/new-object", http.StatusForbidden)
+	verifyStatus(users["other"], "PUT", "/permissions-bucket?acl", http.StatusForbidden)
+	verifyStatus(users["other"], "PUT", "/other-bucket", http.StatusOK)
+	verifyStatus(users["other"], "PUT", "/other-bucket/object", http.StatusOK)
+
+	response := verifyStatus(users["other"], "GET", "/", http.StatusOK)
+	listBuckets := ListBucketsResponse{}
+	decoder := xml.NewDecoder(response.Body)
+	err := decoder.Decode(&listBuckets)
+	c.Assert(err, IsNil)
+	c.Assert(listBuckets.Owner.DisplayName, Equals, "other")
+	c.Assert(len(listBuckets.Buckets.Bucket), Equals, 1)
+	c.Assert(listBuckets.Buckets.Bucket[0].Name, Equals, "other-bucket")
+
+	// admin users have full access
+	verifyStatus(users["administrator"], "GET", "/permissions-bucket/private/object", http.StatusOK)
+	response = verifyStatus(users["administrator"], "GET", "/", http.StatusOK)
+	listBuckets = ListBucketsResponse{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&listBuckets)
+	c.Assert(err, IsNil)
+	var names []string
+	for _, bucket := range listBuckets.Buckets.Bucket {
+		names = append(names, bucket.Name)
+	}
+	c.Assert(strings.Join(names, ","), Matches, ".*other-bucket.*")
+	c.Assert(strings.Join(names, ","), Matches, ".*permissions-bucket.*")
+
+	// objects are listed with the bucket owner
+	request, err := s.newRequest("GET", testSignatureV4Server.URL+"/permissions-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listObjects := ListObjectsResponse{}
+	decoder = xml.NewDecoder(response.Body)
+	err = decoder.Decode(&listObjects)
+	c.Assert(err, IsNil)
+	c.Assert(len(listObjects.Contents), Equals, 2)
+	c.Assert(listObjects.Contents[0].Owner.DisplayName, Equals, "testuser")
+}
+
+func (s *MyAPISignatureV4Suite) TestSSECustomerKey(c *C) {
+	key := bytes.Repeat([]byte("k"), 32)
+	keyMD5 := md5.Sum(key)
+	setEncryption := func(request *http.Request, key []byte) {
+		keyMD5 := md5.Sum(key)
+		request.Header.Set(sseCustomerAlgorithmHeader, "AES256")
+		request.Header.Set(sseCustomerKeyHeader, base64.StdEncoding.EncodeToString(key))
+		request.Header.Set(sseCustomerKeyMD5Header, base64.StdEncoding.EncodeToString(keyMD5[:]))
+	}
+
+	// keys are rejected by servers not running with TLS
+	request, err := http.NewRequest("PUT", testSignatureV4Server.URL+"/sse-insecure-bucket/object", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidRequest", "Requests specifying Server Side Encryption with Customer provided keys must be made over a secure connection.", http.StatusBadRequest)
+
+	tlsAPI := getNewAPI(false)
+	tlsAPI.TLS = true
+	go startTM(tlsAPI)
+	tlsServer := httptest.NewTLSServer(getAPIHandler(false, tlsAPI))
+	defer tlsServer.Close()
+	tlsClient := tlsServer.Client()
+
+	request, err = http.NewRequest("PUT", tlsServer.URL+"/sse-bucket", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = http.NewRequest("PUT", tlsServer.URL+"/sse-bucket/object", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseCustomerAlgorithmHeader), Equals, "AES256")
+	c.Assert(response.Header.Get(sseCustomerKeyMD5Header), Equals, base64.StdEncoding.EncodeToString(keyMD5[:]))
+
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "hello world")
+
+	request, err = http.NewRequest("HEAD", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseCustomerAlgorithmHeader), Equals, "AES256")
+
+	// reads without the key are rejected
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidRequest", "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.", http.StatusBadRequest)
+
+	// reads with a wrong key are rejected
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, bytes.Repeat([]byte("w"), 32))
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "The provided encryption key does not match the key the object was encrypted with.", http.StatusForbidden)
+
+	// key must match its MD5
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	request.Header.Set(sseCustomerKeyMD5Header, base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "The calculated MD5 hash of the key did not match the hash that was provided.", http.StatusBadRequest)
+
+	request, err = http.NewRequest("GET", tlsServer.URL+"/sse-bucket/object", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	request.Header.Set(sseCustomerAlgorithmHeader, "AES128")
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidEncryptionAlgorithmError", "The encryption request you specified is not valid. The valid value is AES256.", http.StatusBadRequest)
+
+	// parts are accepted only with the key the upload was initiated with
+	request, err = http.NewRequest("POST", tlsServer.URL+"/sse-bucket/multipart?uploads", nil)
+	c.Assert(err, IsNil)
+	setEncryption(request, key)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	decoder := xml.NewDecoder(response.Body)
+	newResponse := &InitiateMultipartUploadResponse{}
+	c.Assert(decoder.Decode(newResponse), IsNil)
+
+	request, err = http.NewRequest("PUT", tlsServer.URL+"/sse-bucket/multipart?uploadId="+newResponse.UploadID+"&partNumber=1", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = tlsClient.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidRequest", "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.", http.StatusBadRequest)
+}
+
+func (s *MyAPISignatureV4Suite) TestServerSideEncryption(c *C) {
+	request, err := s.newRequest("PUT", testSignatureV4Server.URL+"/sse-s3-plain-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	putObject := func(serverURL, bucket, object, method string) *http.Response {
+		request, err := http.NewRequest("PUT", serverURL+"/"+bucket+"/"+object, bytes.NewReader([]byte("hello world")))
+		c.Assert(err, IsNil)
+		if method != "" {
+			request.Header.Set(sseHeader, method)
+		}
+		signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+
+	// server managed keys require a master key
+	response = putObject(testSignatureV4Server.URL, "sse-s3-plain-bucket", "object", "AES256")
+	verifyError(c, response, "NotImplemented", "A header you provided implies functionality that is not implemented.", http.StatusNotImplemented)
+
+	response = putObject(testSignatureV4Server.URL, "sse-s3-plain-bucket", "object", "aws:kms")
+	verifyError(c, response, "InvalidArgument", "The encryption method specified is not supported.", http.StatusBadRequest)
+
+	keys, perr := donut.LoadKeyStore()
+	c.Assert(perr, IsNil)
+	_, perr = keys.AddKey()
+	c.Assert(perr, IsNil)
+	c.Assert(donut.SaveKeyStore(keys), IsNil)
+
+	keysAPI := getNewAPI(false)
+	go startTM(keysAPI)
+	keysServer := httptest.NewServer(getAPIHandler(false, keysAPI))
+	defer keysServer.Close()
+
+	request, err = s.newRequest("PUT", keysServer.URL+"/sse-s3-bucket", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	response = putObject(keysServer.URL, "sse-s3-bucket", "object", "AES256")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "AES256")
+
+	request, err = http.NewRequest("GET", keysServer.URL+"/sse-s3-bucket/object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "AES256")
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "hello world")
+
+	request, err = http.NewRequest("HEAD", keysServer.URL+"/sse-s3-bucket/object", nil)
+	c.Assert(err, IsNil)
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "AES256")
+
+	// customer provided and server managed keys are exclusive
+	request, err = http.NewRequest("PUT", keysServer.URL+"/sse-s3-bucket/conflicting", bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
+	request.Header.Set(sseHeader, "AES256")
+	request.Header.Set(sseCustomerAlgorithmHeader, "AES256")
+	signRequestV2(request, s.accessKeyID, s.secretAccessKey)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Server side encryption specified with both SSE-C and SSE-S3 headers.", http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", keysServer.URL+"/sse-s3-bucket?encryption", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "ServerSideEncryptionConfigurationNotFoundError", "The server side encryption configuration was not found.", http.StatusNotFound)
+
+	buffer := bytes.NewReader([]byte("<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>aws:kms</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>"))
+	request, err = s.newRequest("PUT", keysServer.URL+"/sse-s3-bucket?encryption", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest)
+
+	buffer = bytes.NewReader([]byte("<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>"))
+	request, err = s.newRequest("PUT", keysServer.URL+"/sse-s3-bucket?encryption", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", keysServer.URL+"/sse-s3-bucket?encryption", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	config := ServerSideEncryptionConfiguration{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(&config), IsNil)
+	c.Assert(len(config.Rules), Equals, 1)
+	c.Assert(config.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm, Equals, "AES256")
+
+	// new objects are encrypted by default
+	response = putObject(keysServer.URL, "sse-s3-bucket", "default", "")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "AES256")
+
+	request, err = s.newRequest("DELETE", keysServer.URL+"/sse-s3-bucket?encryption", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	response = putObject(keysServer.URL, "sse-s3-bucket", "plain", "")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get(sseHeader), Equals, "")
+}
@@ -0,0 +1,306 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/rpc/v2/json"
+	"github.com/minio/minio-xl/pkg/donut/disk"
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// remoteDisk disk of another server, reached over its storage rpc service
+type remoteDisk struct {
+	url  string
+	path string
+}
+
+// storageRPCURL - rpc url of a node, 'https://' prefix selects TLS and port defaults to the rpc port
+func storageRPCURL(hostname string) string {
+	u := &url.URL{Scheme: "http", Path: "/rpc"}
+	if strings.HasPrefix(hostname, "https://") {
+		u.Scheme = "https"
+		hostname = strings.TrimPrefix(hostname, "https://")
+	}
+	u.Host = hostname
+	if _, _, err := net.SplitHostPort(hostname); err != nil {
+		u.Host = hostname + ":9002"
+	}
+	return u.String()
+}
+
+// newRemoteDisk - disk of a node on another server
+func newRemoteDisk(hostname, diskPath string) (disk.Disk, *probe.Error) {
+	return remoteDisk{
+		url:  storageRPCURL(hostname),
+		path: diskPath,
+	}, nil
+}
+
+// call - make a storage rpc call signed with current admin credentials, missing files are reported as such
+func (d remoteDisk) call(method string, args interface{}, reply interface{}) *probe.Error {
+	config, err := readAuthConfig()
+	if err != nil {
+		return err.Trace()
+	}
+	op := rpcOperation{
+		Method:  "Storage." + method,
+		Request: args,
+	}
+	request, err := newRPCRequest(config, d.url, op, nil)
+	if err != nil {
+		return err.Trace()
+	}
+	resp, err := request.Do()
+	if err != nil {
+		return err.Trace()
+	}
+	defer resp.Body.Close()
+	if e := json.DecodeClientResponse(resp.Body, reply); e != nil {
+		if e.Error() == errStorageNotFound.Error() {
+			return probe.NewError(&os.PathError{Op: method, Path: d.url + ":" + d.path, Err: os.ErrNotExist})
+		}
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// IsUsable - is disk usable, alive
+func (d remoteDisk) IsUsable() bool {
+	_, err := d.FSInfo()
+	return err == nil
+}
+
+// GetPath - get root disk path
+func (d remoteDisk) GetPath() string {
+	return d.path
+}
+
+// FSInfo - get disk filesystem and its usage information
+func (d remoteDisk) FSInfo() (map[string]string, *probe.Error) {
+	var reply StorageFSInfoRep
+	if err := d.call("FSInfo", StorageArgs{Disk: d.path}, &reply); err != nil {
+		return nil, err.Trace()
+	}
+	return reply.Info, nil
+}
+
+// MakeDir - make a directory
+func (d remoteDisk) MakeDir(dirname string) *probe.Error {
+	var reply StorageRep
+	return d.call("MakeDir", StorageArgs{Disk: d.path, Path: dirname}, &reply).Trace(dirname)
+}
+
+// ListDir - list a directory, get only directories
+func (d remoteDisk) ListDir(dirname string) ([]os.FileInfo, *probe.Error) {
+	var reply StorageListDirRep
+	if err := d.call("ListDir", StorageArgs{Disk: d.path, Path: dirname}, &reply); err != nil {
+		return nil, err.Trace(dirname)
+	}
+	var directories []os.FileInfo
+	for _, entry := range reply.Entries {
+		directories = append(directories, remoteFileInfo{entry})
+	}
+	return directories, nil
+}
+
+// CreateFile - create a file, written contents are sent storageChunkSize at a time
+func (d remoteDisk) CreateFile(filename string) (disk.File, *probe.Error) {
+	if filename == "" {
+		return nil, probe.NewError(disk.InvalidArgument{})
+	}
+	var reply StorageHandleRep
+	if err := d.call("CreateFile", StorageArgs{Disk: d.path, Path: filename}, &reply); err != nil {
+		return nil, err.Trace(filename)
+	}
+	return &remoteFile{disk: d, handle: reply.Handle}, nil
+}
+
+// Open - open a file for reading, it is kept open by the remote disk until read or closed
+func (d remoteDisk) Open(filename string) (io.ReadCloser, *probe.Error) {
+	if filename == "" {
+		return nil, probe.NewError(disk.InvalidArgument{})
+	}
+	var reply StorageHandleRep
+	if err := d.call("OpenFile", StorageArgs{Disk: d.path, Path: filename}, &reply); err != nil {
+		return nil, err.Trace(filename)
+	}
+	return &remoteReader{disk: d, handle: reply.Handle}, nil
+}
+
+// Stat - file or directory information
+func (d remoteDisk) Stat(filename string) (os.FileInfo, *probe.Error) {
+	var reply StorageFileInfoRep
+	if err := d.call("Stat", StorageArgs{Disk: d.path, Path: filename}, &reply); err != nil {
+		return nil, err.Trace(filename)
+	}
+	return remoteFileInfo{reply}, nil
+}
+
+// Rename - rename a file replacing the destination
+func (d remoteDisk) Rename(oldname, newname string) *probe.Error {
+	var reply StorageRep
+	return d.call("Rename", StorageRenameArgs{Disk: d.path, Path: oldname, NewPath: newname}, &reply).Trace(oldname, newname)
+}
+
+// Delete - delete a file or a directory tree
+func (d remoteDisk) Delete(filename string) *probe.Error {
+	var reply StorageRep
+	return d.call("Delete", StorageArgs{Disk: d.path, Path: filename}, &reply).Trace(filename)
+}
+
+// remoteFileInfo file or directory information of a remote disk
+type remoteFileInfo struct {
+	rep StorageFileInfoRep
+}
+
+func (f remoteFileInfo) Name() string       { return filepath.Base(f.rep.Name) }
+func (f remoteFileInfo) Size() int64        { return f.rep.Size }
+func (f remoteFileInfo) ModTime() time.Time { return f.rep.ModTime }
+func (f remoteFileInfo) IsDir() bool        { return f.rep.IsDir }
+func (f remoteFileInfo) Sys() interface{}   { return nil }
+func (f remoteFileInfo) Mode() os.FileMode {
+	if f.rep.IsDir {
+		return os.ModeDir | 0700
+	}
+	return 0600
+}
+
+// remoteFile file being written to a remote disk, storageChunkSize at a time
+type remoteFile struct {
+	disk   remoteDisk
+	handle string
+	buffer bytes.Buffer
+	closed bool
+}
+
+// flush - send upto size bytes of written contents
+func (f *remoteFile) flush(size int) *probe.Error {
+	var reply StorageRep
+	args := StorageWriteArgs{Handle: f.handle, Data: f.buffer.Next(size)}
+	return f.disk.call("WriteFile", args, &reply).Trace()
+}
+
+func (f *remoteFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrInvalid
+	}
+	f.buffer.Write(p)
+	for f.buffer.Len() >= storageChunkSize {
+		if err := f.flush(storageChunkSize); err != nil {
+			return 0, probe.WrapError(err.Trace(f.handle))
+		}
+	}
+	return len(p), nil
+}
+
+// closeFile - close the file on the remote disk
+func (f *remoteFile) closeFile(purge bool) *probe.Error {
+	var reply StorageRep
+	return f.disk.call("CloseFile", StorageCloseArgs{Handle: f.handle, Purge: purge}, &reply).Trace()
+}
+
+// Close - send remaining written contents, the file is visible once closed
+func (f *remoteFile) Close() error {
+	if f.closed {
+		return os.ErrInvalid
+	}
+	f.closed = true
+	if f.buffer.Len() > 0 {
+		if err := f.flush(f.buffer.Len()); err != nil {
+			f.closeFile(true)
+			return probe.WrapError(err.Trace(f.handle))
+		}
+	}
+	if err := f.closeFile(false); err != nil {
+		return probe.WrapError(err.Trace(f.handle))
+	}
+	return nil
+}
+
+// CloseAndPurge - discard written contents
+func (f *remoteFile) CloseAndPurge() error {
+	if f.closed {
+		return os.ErrInvalid
+	}
+	f.closed = true
+	f.buffer.Reset()
+	if err := f.closeFile(true); err != nil {
+		return probe.WrapError(err.Trace(f.handle))
+	}
+	return nil
+}
+
+// remoteReader file being read from a remote disk, storageChunkSize at a time
+type remoteReader struct {
+	disk   remoteDisk
+	handle string
+	data   []byte
+	eof    bool
+}
+
+// fill - read next part of the file, the file is closed by the remote disk at the end of file
+func (r *remoteReader) fill() *probe.Error {
+	var reply StorageReadRep
+	args := StorageReadArgs{
+		Handle: r.handle,
+		Length: storageChunkSize,
+	}
+	if err := r.disk.call("ReadFile", args, &reply); err != nil {
+		r.eof = true
+		return err.Trace()
+	}
+	r.data = reply.Data
+	r.eof = reply.EOF
+	return nil
+}
+
+func (r *remoteReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		if err := r.fill(); err != nil {
+			return 0, probe.WrapError(err)
+		}
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// Close - close the file on the remote disk unless read until the end of file
+func (r *remoteReader) Close() error {
+	if r.eof {
+		return nil
+	}
+	r.eof = true
+	r.data = nil
+	var reply StorageRep
+	if err := r.disk.call("CloseFile", StorageCloseArgs{Handle: r.handle}, &reply); err != nil {
+		return probe.WrapError(err.Trace(r.handle))
+	}
+	return nil
+}
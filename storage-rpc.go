@@ -0,0 +1,340 @@
+/*
+ * Minio Cloud Storage, (C) 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-xl/pkg/donut"
+	"github.com/minio/minio-xl/pkg/donut/disk"
+	"github.com/minio/minio-xl/pkg/probe"
+)
+
+// storageChunkSize maximum data read from or written to a file per storage rpc call
+const storageChunkSize = 1024 * 1024
+
+// storageFileTimeout open files left unused for this long are closed, files being written are discarded
+const storageFileTimeout = 10 * time.Minute
+
+// storageRPCService disk operations on disks of this server, for donuts spanning several servers
+type storageRPCService struct {
+	lock  *sync.Mutex
+	disks map[string]disk.Disk
+	files map[string]*storageFile
+}
+
+// storageFile file kept open across storage rpc calls, either for reading or for writing
+type storageFile struct {
+	reader   io.ReadCloser
+	writer   disk.File
+	lastUsed time.Time
+}
+
+// close - close the file, a file being written is discarded on purge
+func (f *storageFile) close(purge bool) error {
+	if f.reader != nil {
+		return f.reader.Close()
+	}
+	if purge {
+		return f.writer.CloseAndPurge()
+	}
+	return f.writer.Close()
+}
+
+// newStorageRPCService - instantiate a new storage rpc service
+func newStorageRPCService() *storageRPCService {
+	return &storageRPCService{
+		lock:  &sync.Mutex{},
+		disks: make(map[string]disk.Disk),
+		files: make(map[string]*storageFile),
+	}
+}
+
+// addFile - keep an open file under a new random handle, files left unused by their clients are closed
+func (s *storageRPCService) addFile(f *storageFile) (string, *probe.Error) {
+	handle, err := generateAccessKeyID()
+	if err != nil {
+		return "", err.Trace()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for h, open := range s.files {
+		if time.Since(open.lastUsed) > storageFileTimeout {
+			open.close(true)
+			delete(s.files, h)
+		}
+	}
+	f.lastUsed = time.Now()
+	s.files[string(handle)] = f
+	return string(handle), nil
+}
+
+// takeFile - take an open file out for the duration of a call, putFile puts it back
+func (s *storageRPCService) takeFile(handle string) (*storageFile, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, ok := s.files[handle]
+	if !ok {
+		return nil, errInvalidStorageHandle
+	}
+	delete(s.files, handle)
+	return f, nil
+}
+
+// putFile - put back an open file once a call is done with it
+func (s *storageRPCService) putFile(handle string, f *storageFile) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f.lastUsed = time.Now()
+	s.files[handle] = f
+}
+
+// getDisk - disk of a request, only disks part of the donut config are served
+func (s *storageRPCService) getDisk(diskPath string) (disk.Disk, *probe.Error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if d, ok := s.disks[diskPath]; ok {
+		return d, nil
+	}
+	conf, err := donut.LoadConfig()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	for _, disks := range conf.NodeDiskMap {
+		for _, d := range disks {
+			if d != diskPath {
+				continue
+			}
+			newDisk, err := disk.New(diskPath)
+			if err != nil {
+				return nil, err.Trace(diskPath)
+			}
+			s.disks[diskPath] = newDisk
+			return newDisk, nil
+		}
+	}
+	return nil, probe.NewError(errDiskNotExported)
+}
+
+// storagePath - path of a request, kept within the disk
+func storagePath(path string) string {
+	return strings.TrimPrefix(filepath.Clean("/"+path), "/")
+}
+
+// storageError - error reply of a storage request, missing files are told apart from other errors
+func storageError(err *probe.Error) error {
+	if os.IsNotExist(err.ToGoError()) {
+		return errStorageNotFound
+	}
+	return probe.WrapError(err)
+}
+
+// newStorageFileInfoRep - reply for file or directory information
+func newStorageFileInfoRep(st os.FileInfo) StorageFileInfoRep {
+	return StorageFileInfoRep{
+		Name:    st.Name(),
+		Size:    st.Size(),
+		ModTime: st.ModTime(),
+		IsDir:   st.IsDir(),
+	}
+}
+
+// MakeDir - make a directory
+func (s *storageRPCService) MakeDir(r *http.Request, args *StorageArgs, reply *StorageRep) error {
+	d, err := s.getDisk(args.Disk)
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	if err := d.MakeDir(storagePath(args.Path)); err != nil {
+		return storageError(err.Trace())
+	}
+	return nil
+}
+
+// ListDir - list directories in a directory
+func (s *storageRPCService) ListDir(r *http.Request, args *StorageArgs, reply *StorageListDirRep) error {
+	d, err := s.getDisk(args.Disk)
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	dirs, err := d.ListDir(storagePath(args.Path))
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	reply.Entries = []StorageFileInfoRep{}
+	for _, dir := range dirs {
+		reply.Entries = append(reply.Entries, newStorageFileInfoRep(dir))
+	}
+	return nil
+}
+
+// Stat - file or directory information
+func (s *storageRPCService) Stat(r *http.Request, args *StorageArgs, reply *StorageFileInfoRep) error {
+	d, err := s.getDisk(args.Disk)
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	st, err := d.Stat(storagePath(args.Path))
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	*reply = newStorageFileInfoRep(st)
+	return nil
+}
+
+// Rename - rename a file replacing the destination
+func (s *storageRPCService) Rename(r *http.Request, args *StorageRenameArgs, reply *StorageRep) error {
+	d, err := s.getDisk(args.Disk)
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	if err := d.Rename(storagePath(args.Path), storagePath(args.NewPath)); err != nil {
+		return storageError(err.Trace())
+	}
+	return nil
+}
+
+// Delete - delete a file or a directory tree
+func (s *storageRPCService) Delete(r *http.Request, args *StorageArgs, reply *StorageRep) error {
+	d, err := s.getDisk(args.Disk)
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	if err := d.Delete(storagePath(args.Path)); err != nil {
+		return storageError(err.Trace())
+	}
+	return nil
+}
+
+// FSInfo - filesystem information of a disk
+func (s *storageRPCService) FSInfo(r *http.Request, args *StorageArgs, reply *StorageFSInfoRep) error {
+	d, err := s.getDisk(args.Disk)
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	reply.Info, err = d.FSInfo()
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	return nil
+}
+
+// OpenFile - open a file for reading, it is read with ReadFile until the end of file or closed with CloseFile
+func (s *storageRPCService) OpenFile(r *http.Request, args *StorageArgs, reply *StorageHandleRep) error {
+	d, err := s.getDisk(args.Disk)
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	reader, err := d.Open(storagePath(args.Path))
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	reply.Handle, err = s.addFile(&storageFile{reader: reader})
+	if err != nil {
+		reader.Close()
+		return storageError(err.Trace())
+	}
+	return nil
+}
+
+// ReadFile - read next part of an open file, upto storageChunkSize at a time
+func (s *storageRPCService) ReadFile(r *http.Request, args *StorageReadArgs, reply *StorageReadRep) error {
+	f, e := s.takeFile(args.Handle)
+	if e != nil {
+		return e
+	}
+	if f.reader == nil {
+		s.putFile(args.Handle, f)
+		return errInvalidStorageHandle
+	}
+	length := args.Length
+	if length <= 0 || length > storageChunkSize {
+		length = storageChunkSize
+	}
+	reply.Data = make([]byte, length)
+	n, e := io.ReadFull(f.reader, reply.Data)
+	reply.Data = reply.Data[:n]
+	switch e {
+	case nil:
+		s.putFile(args.Handle, f)
+	case io.EOF, io.ErrUnexpectedEOF:
+		reply.EOF = true
+		f.close(false)
+	default:
+		f.close(false)
+		return storageError(probe.NewError(e))
+	}
+	return nil
+}
+
+// CreateFile - create a file, it is written with WriteFile and visible only once closed with CloseFile
+func (s *storageRPCService) CreateFile(r *http.Request, args *StorageArgs, reply *StorageHandleRep) error {
+	d, err := s.getDisk(args.Disk)
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	writer, err := d.CreateFile(storagePath(args.Path))
+	if err != nil {
+		return storageError(err.Trace())
+	}
+	reply.Handle, err = s.addFile(&storageFile{writer: writer})
+	if err != nil {
+		writer.CloseAndPurge()
+		return storageError(err.Trace())
+	}
+	return nil
+}
+
+// WriteFile - write next part of a created file, the file is discarded on errors
+func (s *storageRPCService) WriteFile(r *http.Request, args *StorageWriteArgs, reply *StorageRep) error {
+	f, e := s.takeFile(args.Handle)
+	if e != nil {
+		return e
+	}
+	if f.writer == nil {
+		s.putFile(args.Handle, f)
+		return errInvalidStorageHandle
+	}
+	if _, e := f.writer.Write(args.Data); e != nil {
+		f.close(true)
+		return storageError(probe.NewError(e))
+	}
+	s.putFile(args.Handle, f)
+	return nil
+}
+
+// CloseFile - close an open file, a created file is made visible unless purged
+func (s *storageRPCService) CloseFile(r *http.Request, args *StorageCloseArgs, reply *StorageRep) error {
+	f, e := s.takeFile(args.Handle)
+	if e != nil {
+		return e
+	}
+	if e := f.close(args.Purge); e != nil {
+		return storageError(probe.NewError(e))
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+/*
+ * Minio Cloud Storage, (C) 2014 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/rpc/v2/json"
+	"github.com/minio/minio-xl/pkg/donut"
+	. "gopkg.in/check.v1"
+)
+
+type MyStorageRPCSuite struct {
+	root    string
+	servers []*httptest.Server
+	hosts   []string
+}
+
+var _ = Suite(&MyStorageRPCSuite{})
+
+// storage rpc servers on localhost ports, each exporting 4 disks of one node
+func (s *MyStorageRPCSuite) SetUpSuite(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "storage-")
+	c.Assert(err, IsNil)
+	s.root = root
+
+	secretAccessKey, perr := generateSecretAccessKey()
+	c.Assert(perr, IsNil)
+	authConf := &AuthConfig{}
+	authConf.Users = make(map[string]*AuthUser)
+	authConf.Users["admin"] = &AuthUser{
+		Name:            "admin",
+		AccessKeyID:     "admin",
+		SecretAccessKey: string(secretAccessKey),
+		Admin:           true,
+	}
+	SetAuthConfigPath(root)
+	c.Assert(SaveConfig(authConf), IsNil)
+
+	for i := 0; i < 2; i++ {
+		server := httptest.NewServer(getServerRPCHandler(false, nil))
+		u, err := url.Parse(server.URL)
+		c.Assert(err, IsNil)
+		s.servers = append(s.servers, server)
+		s.hosts = append(s.hosts, u.Host)
+	}
+	// nodes are laid out in order of their hostnames
+	sort.Strings(s.hosts)
+
+	conf := &donut.Config{}
+	conf.Version = "0.0.1"
+	conf.DonutName = "test"
+	conf.MaxSize = 100000000
+	conf.NodeDiskMap = make(map[string][]string)
+	for i, host := range s.hosts {
+		for j := 0; j < 4; j++ {
+			diskPath := s.diskPath(i, j)
+			c.Assert(os.MkdirAll(diskPath, 0700), IsNil)
+			conf.NodeDiskMap[host] = append(conf.NodeDiskMap[host], diskPath)
+		}
+	}
+	donut.SetDonutConfigPath(filepath.Join(root, "donut.json"))
+	c.Assert(donut.SaveConfig(conf), IsNil)
+	donut.SetRemoteDisk(newRemoteDisk)
+}
+
+func (s *MyStorageRPCSuite) TearDownSuite(c *C) {
+	for _, server := range s.servers {
+		server.Close()
+	}
+	os.RemoveAll(s.root)
+}
+
+// diskPath - path of a disk of a node
+func (s *MyStorageRPCSuite) diskPath(node, order int) string {
+	return filepath.Join(s.root, strconv.Itoa(node), strconv.Itoa(order))
+}
+
+func (s *MyStorageRPCSuite) TestRemoteDisk(c *C) {
+	d, err := newRemoteDisk(s.hosts[0], s.diskPath(0, 0))
+	c.Assert(err, IsNil)
+	c.Assert(d.IsUsable(), Equals, true)
+
+	c.Assert(d.MakeDir("dir/sub"), IsNil)
+	// large enough to be read in several parts
+	data := bytes.Repeat([]byte("hello"), storageChunkSize)
+	f, err := d.CreateFile("dir/file")
+	c.Assert(err, IsNil)
+	for i := 0; i < len(data); i += 12345 {
+		end := i + 12345
+		if end > len(data) {
+			end = len(data)
+		}
+		_, e := f.Write(data[i:end])
+		c.Assert(e, IsNil)
+	}
+	c.Assert(f.Close(), IsNil)
+
+	st, err := d.Stat("dir/file")
+	c.Assert(err, IsNil)
+	c.Assert(st.Size(), Equals, int64(len(data)))
+	r, err := d.Open("dir/file")
+	c.Assert(err, IsNil)
+	readData, e := ioutil.ReadAll(r)
+	c.Assert(e, IsNil)
+	c.Assert(bytes.Equal(readData, data), Equals, true)
+	c.Assert(r.Close(), IsNil)
+
+	// files closed before the end of file are closed by the remote disk as well
+	r, err = d.Open("dir/file")
+	c.Assert(err, IsNil)
+	_, e = r.Read(make([]byte, 10))
+	c.Assert(e, IsNil)
+	c.Assert(r.Close(), IsNil)
+
+	// purged files are never visible
+	f, err = d.CreateFile("dir/purged")
+	c.Assert(err, IsNil)
+	_, e = f.Write(data)
+	c.Assert(e, IsNil)
+	c.Assert(f.CloseAndPurge(), IsNil)
+	_, err = d.Stat("dir/purged")
+	c.Assert(err, Not(IsNil))
+	c.Assert(os.IsNotExist(err.ToGoError()), Equals, true)
+
+	dirs, err := d.ListDir("dir")
+	c.Assert(err, IsNil)
+	c.Assert(len(dirs), Equals, 1)
+	c.Assert(dirs[0].Name(), Equals, "sub")
+	c.Assert(dirs[0].IsDir(), Equals, true)
+
+	c.Assert(d.Delete("dir/file"), IsNil)
+	_, err = d.Open("dir/file")
+	c.Assert(err, Not(IsNil))
+	c.Assert(os.IsNotExist(err.ToGoError()), Equals, true)
+
+	// paths stay within the disk
+	c.Assert(d.MakeDir("../../escape"), IsNil)
+	_, e = os.Stat(filepath.Join(s.diskPath(0, 0), "escape"))
+	c.Assert(e, IsNil)
+}
+
+func (s *MyStorageRPCSuite) TestRemoteDiskNotExported(c *C) {
+	d, err := newRemoteDisk(s.hosts[0], s.root)
+	c.Assert(err, IsNil)
+	c.Assert(d.IsUsable(), Equals, false)
+	err = d.MakeDir("dir")
+	c.Assert(err, Not(IsNil))
+	c.Assert(os.IsNotExist(err.ToGoError()), Equals, false)
+}
+
+func (s *MyStorageRPCSuite) TestUnsignedStorage(c *C) {
+	for _, anonymous := range []bool{false, true} {
+		server := httptest.NewServer(getServerRPCHandler(anonymous, nil))
+		for _, op := range []rpcOperation{
+			{Method: "Storage.CreateFile", Request: StorageArgs{Disk: s.diskPath(0, 0), Path: "unsigned"}},
+			{Method: "Storage.WriteFile", Request: StorageWriteArgs{Handle: "handle", Data: []byte("hello")}},
+		} {
+			params, e := json.EncodeClientRequest(op.Method, op.Request)
+			c.Assert(e, IsNil)
+			resp, e := http.Post(server.URL+"/rpc", "application/json", bytes.NewReader(params))
+			c.Assert(e, IsNil)
+			var reply StorageHandleRep
+			c.Assert(json.DecodeClientResponse(resp.Body, &reply), Not(IsNil))
+			resp.Body.Close()
+		}
+		server.Close()
+		_, e := os.Stat(filepath.Join(s.diskPath(0, 0), "unsigned"))
+		c.Assert(os.IsNotExist(e), Equals, true)
+	}
+}
+
+func (s *MyStorageRPCSuite) TestDonutAcrossServers(c *C) {
+	d, err := donut.New()
+	c.Assert(err, IsNil)
+	nodes, err := d.Info()
+	c.Assert(err, IsNil)
+	c.Assert(len(nodes), Equals, 2)
+
+	c.Assert(d.MakeBucket("bucket", "private", "", nil, nil), IsNil)
+	data := bytes.Repeat([]byte("0123456789"), 300000)
+	_, err = d.CreateObject("bucket", "object", "", int64(len(data)), bytes.NewReader(data), nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	var buffer bytes.Buffer
+	_, err = d.GetObject(&buffer, "bucket", "object", 0, 0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(buffer.Bytes(), data), Equals, true)
+
+	// object is striped across disks of both servers
+	for i := range s.hosts {
+		for j := 0; j < 4; j++ {
+			bucketSlice := "bucket$" + strconv.Itoa(i) + "$" + strconv.Itoa(j)
+			_, e := os.Stat(filepath.Join(s.diskPath(i, j), "test", bucketSlice, "object", "data"))
+			c.Assert(e, IsNil)
+		}
+	}
+}
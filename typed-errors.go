@@ -66,8 +66,47 @@ var errUnsupportedAlgorithm = errors.New("Unsupported Algorithm")
 // header which is already expired.
 var errPolicyAlreadyExpired = errors.New("Policy already expired")
 
-// errPolicyMissingFields means that form values and policy header have some fields missing.
-var errPolicyMissingFields = errors.New("Some fields are missing or do not match in policy")
+// errPolicyMissingFile means that POST form carries no file field.
+var errPolicyMissingFile = errors.New("POST requires exactly one file upload per request")
 
 // errMissingDateHeader means that date header is missing
 var errMissingDateHeader = errors.New("Missing date header on the request")
+
+// errPayloadTooLarge means that the request body is bigger than allowed for the operation.
+var errPayloadTooLarge = errors.New("Request payload too large")
+
+// errMetadataTooLarge means that user-defined metadata exceeds the allowed size.
+var errMetadataTooLarge = errors.New("Metadata too large")
+
+// errSessionExpired means that temporary credentials of the request are past their expiry.
+var errSessionExpired = errors.New("Session expired")
+
+// errInvalidSessionToken means that the session token does not belong to the temporary credentials.
+var errInvalidSessionToken = errors.New("Invalid session token")
+
+// errInsecureEncryptionRequest means that customer provided keys are sent without TLS.
+var errInsecureEncryptionRequest = errors.New("Encryption keys require a secure connection")
+
+// errInvalidEncryptionAlgorithm means that the requested encryption algorithm is unsupported.
+var errInvalidEncryptionAlgorithm = errors.New("Invalid encryption algorithm")
+
+// errInvalidEncryptionKey means that the customer provided key is not a valid AES256 key.
+var errInvalidEncryptionKey = errors.New("Invalid encryption key")
+
+// errEncryptionKeyMD5Mismatch means that the customer provided key does not match its MD5.
+var errEncryptionKeyMD5Mismatch = errors.New("Encryption key MD5 mismatch")
+
+// errInvalidEncryptionMethod means that the requested server side encryption is unsupported.
+var errInvalidEncryptionMethod = errors.New("Invalid encryption method")
+
+// errConflictingEncryption means that both customer provided and server managed keys are requested.
+var errConflictingEncryption = errors.New("Conflicting encryption headers")
+
+// errStorageNotFound means that a file or directory requested over storage rpc does not exist.
+var errStorageNotFound = errors.New("File not found")
+
+// errDiskNotExported means that a disk requested over storage rpc is not part of the donut.
+var errDiskNotExported = errors.New("Disk not exported")
+
+// errInvalidStorageHandle means that a file handle used over storage rpc is closed or unknown.
+var errInvalidStorageHandle = errors.New("Invalid file handle")